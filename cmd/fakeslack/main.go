@@ -0,0 +1,32 @@
+// Command fakeslack stands in for Slack so the Slack adapter can be run
+// without a workspace. It serves the Web API, records messages, and
+// delivers signed events and slash commands to Opus on behalf of users:
+//
+//	go run ./cmd/fakeslack &
+//	SLACK_BOT_TOKEN=xoxb-fake SLACK_SIGNING_SECRET=secret \
+//	    SLACK_API_URL=http://localhost:18092/api SLACK_USERS=U01=jsmith \
+//	    SLACK_ALERT_CHANNELS="*=C0STORE,dairy=C0DAIRY" USERS_FILE=users.yaml go run ./cmd/server
+//	curl -d '{"user": "U01", "channel": "C0DAIRY", "text": "<@UOPUSBOT> how much milk is left?"}' localhost:18092/send
+//	curl localhost:18092/messages?channel=C0DAIRY
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/dokk-dev/opus/internal/channels/slack"
+)
+
+func main() {
+	addr := flag.String("addr", ":18092", "listen address")
+	token := flag.String("token", "xoxb-fake", "accepted bot token")
+	signingSecret := flag.String("signing-secret", "secret", "secret events and commands are signed with")
+	opusURL := flag.String("opus", "http://localhost:8080", "Opus server events are delivered to")
+	flag.Parse()
+
+	fake := slack.NewFakeServer(*token, *signingSecret, *opusURL)
+
+	log.Printf("Fake Slack listening on %s, delivering to %s", *addr, *opusURL)
+	log.Fatal(http.ListenAndServe(*addr, fake))
+}
@@ -0,0 +1,34 @@
+// Command fakesms stands in for Twilio so the SMS adapter can be run
+// without an account. It serves the Messages API, records what Opus
+// texts, and delivers signed messaging webhooks to Opus on behalf of
+// phones:
+//
+//	go run ./cmd/fakesms &
+//	SMS_ACCOUNT_SID=ACfake SMS_AUTH_TOKEN=secret SMS_FROM=+15550100000 \
+//	    SMS_API_URL=http://localhost:18094 SMS_WEBHOOK_URL=http://localhost:8080/sms/messages \
+//	    SMS_USERS=+15551230001=jsmith SMS_ONCALL=dairy=jsmith USERS_FILE=users.yaml go run ./cmd/server
+//	curl -d '{"from": "+15551230001", "body": "how much milk is left?"}' localhost:18094/send
+//	curl localhost:18094/messages?number=%2B15551230001
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/dokk-dev/opus/internal/channels/sms"
+)
+
+func main() {
+	addr := flag.String("addr", ":18094", "listen address")
+	accountSID := flag.String("account-sid", "ACfake", "accepted account SID")
+	authToken := flag.String("auth-token", "secret", "auth token webhooks are signed with")
+	number := flag.String("number", "+15550100000", "the account's phone number")
+	webhookURL := flag.String("webhook", "http://localhost:8080/sms/messages", "Opus messaging webhook")
+	flag.Parse()
+
+	fake := sms.NewFakeServer(*accountSID, *authToken, *number, *webhookURL)
+
+	log.Printf("Fake Twilio listening on %s, delivering to %s", *addr, *webhookURL)
+	log.Fatal(http.ListenAndServe(*addr, fake))
+}
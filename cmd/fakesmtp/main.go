@@ -0,0 +1,40 @@
+// Command fakesmtp is an SMTP sink so the email notifier can be run
+// without a mail server. It accepts whatever Opus sends and shows it over
+// HTTP:
+//
+//	go run ./cmd/fakesmtp &
+//	EMAIL_SMTP_HOST=localhost EMAIL_SMTP_PORT=1025 EMAIL_SMTP_TLS=none \
+//	    EMAIL_FROM=opus@store42.example.com USERS_FILE=users.yaml go run ./cmd/server
+//	curl -X POST -H "Authorization: Bearer $TOKEN" localhost:8080/api/v1/notifications/digest
+//	curl localhost:18095/messages?to=jsmith@store42.example.com
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/dokk-dev/opus/internal/channels/email"
+)
+
+func main() {
+	smtpAddr := flag.String("smtp", ":1025", "SMTP listen address")
+	httpAddr := flag.String("http", ":18095", "HTTP listen address for reading messages")
+	username := flag.String("username", "", "required SMTP username, if any")
+	password := flag.String("password", "", "required SMTP password")
+	flag.Parse()
+
+	fake := email.NewFakeServer(*username, *password)
+
+	l, err := net.Listen("tcp", *smtpAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		log.Fatal(fake.Serve(l))
+	}()
+
+	log.Printf("Fake SMTP listening on %s, messages on %s", *smtpAddr, *httpAddr)
+	log.Fatal(http.ListenAndServe(*httpAddr, fake))
+}
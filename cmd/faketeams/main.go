@@ -0,0 +1,39 @@
+// Command faketeams stands in for the Bot Connector so the Teams adapter
+// can be run without a tenant. It serves the OpenID metadata, signing keys
+// and token endpoint, records the activities Opus sends, and delivers
+// signed activities to Opus on behalf of users:
+//
+//	go run ./cmd/faketeams &
+//	TEAMS_APP_ID=opus-bot TEAMS_APP_PASSWORD=secret \
+//	    TEAMS_OPENID_URL=http://localhost:18093/v1/.well-known/openidconfiguration \
+//	    TEAMS_TOKEN_URL=http://localhost:18093/botframework.com/oauth2/v2.0/token \
+//	    TEAMS_SERVICE_URL=http://localhost:18093/ TEAMS_USERS=aad-jsmith=jsmith \
+//	    TEAMS_ALERT_CHANNELS="*=19:store@thread.tacv2,dairy=19:dairy@thread.tacv2" \
+//	    USERS_FILE=users.yaml go run ./cmd/server
+//	curl -d '{"user": "29:jsmith", "aadObjectId": "aad-jsmith", "conversation": "a:jsmith", "text": "how much milk is left?"}' localhost:18093/send
+//	curl localhost:18093/messages?conversation=a:jsmith
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/dokk-dev/opus/internal/channels/teams"
+)
+
+func main() {
+	addr := flag.String("addr", ":18093", "listen address")
+	appID := flag.String("app-id", "opus-bot", "bot app ID tokens are issued for")
+	appPassword := flag.String("app-password", "secret", "bot app password")
+	opusURL := flag.String("opus", "http://localhost:8080", "Opus server activities are delivered to")
+	flag.Parse()
+
+	fake, err := teams.NewFakeServer(*appID, *appPassword, *opusURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Fake Bot Connector listening on %s, delivering to %s", *addr, *opusURL)
+	log.Fatal(http.ListenAndServe(*addr, fake))
+}
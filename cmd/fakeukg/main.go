@@ -0,0 +1,30 @@
+// Command fakeukg serves recorded UKG fixtures so the UKG connector can be
+// run without a tenant. Point the server at it with
+//
+//	go run ./cmd/fakeukg &
+//	UKG_URL=http://localhost:18090 UKG_CLIENT_ID=opus UKG_CLIENT_SECRET=secret \
+//	    UKG_DEPARTMENTS="Meat & Seafood=meat" go run ./cmd/server
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/dokk-dev/opus/internal/connectors/ukg"
+)
+
+func main() {
+	addr := flag.String("addr", ":18090", "listen address")
+	clientID := flag.String("client-id", "opus", "accepted OAuth client ID")
+	clientSecret := flag.String("client-secret", "secret", "accepted OAuth client secret")
+	flag.Parse()
+
+	fake, err := ukg.NewFakeServer(*clientID, *clientSecret)
+	if err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	log.Printf("Fake UKG listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, fake))
+}
@@ -0,0 +1,32 @@
+// Command passwd prints a bcrypt hash of a password for the users file.
+// The password is read from the first line of standard input.
+//
+//	echo -n 'secret' | go run ./cmd/passwd
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func main() {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("Failed to read password: %v", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		log.Fatal("Password must not be empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		log.Fatalf("Failed to hash password: %v", err)
+	}
+	fmt.Println(string(hash))
+}
@@ -10,9 +10,31 @@ import (
 	"time"
 
 	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/alerts"
 	"github.com/dokk-dev/opus/internal/api"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/channels/email"
+	"github.com/dokk-dev/opus/internal/channels/slack"
+	"github.com/dokk-dev/opus/internal/channels/sms"
+	"github.com/dokk-dev/opus/internal/channels/teams"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/checkout"
 	"github.com/dokk-dev/opus/internal/config"
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/connectors/periscope"
+	"github.com/dokk-dev/opus/internal/connectors/pos"
+	"github.com/dokk-dev/opus/internal/connectors/ukg"
+	"github.com/dokk-dev/opus/internal/conversation"
+	"github.com/dokk-dev/opus/internal/demo"
+	"github.com/dokk-dev/opus/internal/departments"
 	"github.com/dokk-dev/opus/internal/gateway"
+	"github.com/dokk-dev/opus/internal/imports"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/knowledge"
+	"github.com/dokk-dev/opus/internal/notify"
+	"github.com/dokk-dev/opus/internal/production"
+	"github.com/dokk-dev/opus/internal/schedule"
+	"github.com/dokk-dev/opus/internal/storedata"
 )
 
 func main() {
@@ -33,14 +55,407 @@ func main() {
 	}
 	cancel()
 
+	// Conversation history is kept within each model's context window
+	budget := ai.NewTokenBudget(cfg.ContextWindows)
+	ollamaClient.SetContextWindow(budget.Window(cfg.OllamaModel))
+
+	providers := []ai.Provider{ollamaClient}
+
+	// Claude is only used when Ollama can't answer
+	if cfg.ClaudeFallback {
+		if cfg.ClaudeAPIKey != "" {
+			providers = append(providers, ai.NewClaudeClient(cfg.ClaudeURL, cfg.ClaudeAPIKey, cfg.ClaudeModel))
+			log.Printf("Claude fallback enabled (model: %s)", cfg.ClaudeModel)
+		} else {
+			log.Printf("Warning: CLAUDE_FALLBACK is set but CLAUDE_API_KEY is empty - fallback disabled")
+		}
+	}
+
+	// Inventory lives in PostgreSQL when configured, otherwise in memory,
+	// seeded with demo items unless Periscope fills it
+	usePeriscope := cfg.PeriscopeDir != "" || cfg.PeriscopeURL != ""
+	var items inventory.Repository
+	if cfg.DatabaseURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		pgItems, err := inventory.NewPostgresRepository(ctx, cfg.DatabaseURL)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to open inventory database: %v", err)
+		}
+		items = pgItems
+		log.Println("Storing inventory in PostgreSQL")
+	} else if usePeriscope {
+		items = inventory.NewMemoryRepository()
+		log.Println("Using in-memory inventory")
+	} else {
+		memItems := inventory.NewMemoryRepository()
+		if err := memItems.Save(context.Background(), demo.Inventory()...); err != nil {
+			log.Fatalf("Failed to load demo inventory: %v", err)
+		}
+		items = memItems
+		log.Println("Using in-memory demo inventory")
+	}
+	defer items.Close()
+
+	// Connectors link external systems and are kept connected for the
+	// life of the server
+	connectorRegistry := connectors.NewRegistry(connectors.Options{ProbeInterval: cfg.ConnectorProbeInterval})
+	if cfg.MockConnector {
+		if err := connectorRegistry.Register(connectors.NewMock("mock")); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+	}
+
+	// Schedules come from UKG when configured, otherwise they're demo
+	// data. Coverage targets aren't kept in UKG, so the demo ones are used
+	// either way.
+	schedules := schedule.NewMemoryRepository()
+	if cfg.UKGURL != "" {
+		if err := schedules.SaveTargets(context.Background(), demo.CoverageTargets()...); err != nil {
+			log.Fatalf("Failed to load coverage targets: %v", err)
+		}
+		workforce := ukg.New(ukg.Config{
+			BaseURL:      cfg.UKGURL,
+			ClientID:     cfg.UKGClientID,
+			ClientSecret: cfg.UKGClientSecret,
+			Interval:     cfg.UKGSyncInterval,
+			Departments:  cfg.UKGDepartments,
+		}, schedules)
+		if err := connectorRegistry.Register(workforce); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		log.Printf("Syncing schedules from UKG at %s", cfg.UKGURL)
+	} else if err := demo.SeedSchedule(context.Background(), schedules, time.Now()); err != nil {
+		log.Fatalf("Failed to load demo schedule: %v", err)
+	}
+
+	// Lane status and sales come from the POS feed when configured,
+	// otherwise they're demo data
+	lanes := checkout.NewMemoryRepository()
+	if cfg.POSFeed != "" {
+		source, err := pos.ParseSource(cfg.POSFeed)
+		if err != nil {
+			log.Fatalf("Failed to configure POS connector: %v", err)
+		}
+		if err := connectorRegistry.Register(pos.New(source, lanes)); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		log.Printf("Reading POS events from %s", source)
+	} else if err := demo.SeedCheckout(context.Background(), lanes, time.Now()); err != nil {
+		log.Fatalf("Failed to load demo lanes: %v", err)
+	}
+
+	// Item master, counts, production plans and shrink are imported from
+	// Periscope when configured, and each import is recorded as a run
+	plans := production.NewMemoryRepository()
+	importRuns := imports.NewMemoryRepository()
+	if usePeriscope {
+		importer := periscope.New(periscope.Config{
+			Dir:         cfg.PeriscopeDir,
+			URL:         cfg.PeriscopeURL,
+			APIKey:      cfg.PeriscopeAPIKey,
+			Interval:    cfg.PeriscopeInterval,
+			Departments: cfg.PeriscopeDepartments,
+		}, items, plans, importRuns)
+		if err := connectorRegistry.Register(importer); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		log.Println("Importing inventory and production from Periscope")
+	} else if err := demo.SeedProduction(context.Background(), plans, time.Now()); err != nil {
+		log.Fatalf("Failed to load demo production: %v", err)
+	}
+
+	// Alerts are raised from inventory, temperatures, coverage and lanes.
+	// Temperatures are demo readings until sensors are connected.
+	alertRepo := alerts.NewMemoryRepository()
+	alertEngine := alerts.NewEngine(alertRepo,
+		&alerts.LowStock{Items: items},
+		&alerts.Expiring{Items: items, Within: 48 * time.Hour},
+		&alerts.Temperature{Sensors: demo.Sensors{}, Stale: 15 * time.Minute},
+		&alerts.Coverage{Schedules: schedules, Lookahead: 48 * time.Hour},
+		&alerts.Lanes{Lanes: lanes, Assistance: 3 * time.Minute, Offline: 15 * time.Minute},
+	)
+
+	// Tools let agents look up store data instead of guessing
+	tools := ai.NewToolRegistry()
+	ai.RegisterStoreTools(tools, storedata.New(items, schedules, alertRepo, lanes))
+
+	// Department agents are defined in YAML so stores can add their own
+	definitions, err := departments.Load(cfg.DepartmentsDir)
+	if err != nil {
+		log.Fatalf("Failed to load department definitions: %v", err)
+	}
+
 	// Initialize AI router with department agents
-	aiRouter := ai.NewRouter(ollamaClient)
+	aiRouter, err := ai.NewRouter(definitions, tools, providers...)
+	if err != nil {
+		log.Fatalf("Failed to create department agents: %v", err)
+	}
+	log.Printf("Loaded %d departments", len(definitions))
+	if cfg.AnswerFormat == "structured" {
+		aiRouter.EnableStructuredOutput()
+	}
+	aiRouter.SetTokenBudget(budget)
+	if cfg.RoutingMode == "classifier" {
+		aiRouter.EnableClassifier()
+		log.Printf("Routing queries with the %s classifier", cfg.OllamaModel)
+	}
+
+	// Agents cite store SOPs and manuals when a knowledge directory is set
+	if cfg.KnowledgeDir != "" {
+		embedder := ai.NewOllamaEmbedder(cfg.OllamaURL, cfg.EmbeddingModel)
+		index, err := knowledge.NewIndex(embedder, cfg.KnowledgeIndex)
+		if err != nil {
+			log.Fatalf("Failed to load knowledge index: %v", err)
+		}
+		aiRouter.SetRetriever(index)
+
+		// Embedding hundreds of pages takes a while; answer without
+		// documents until it's done
+		go func() {
+			if err := index.Ingest(context.Background(), cfg.KnowledgeDir, aiRouter.Departments()); err != nil {
+				log.Printf("Warning: failed to ingest documents from %s: %v", cfg.KnowledgeDir, err)
+			}
+		}()
+	}
+
+	// Conversations are kept in memory unless a database file is configured
+	var conversations conversation.Store
+	if cfg.ConversationDB != "" {
+		sqliteStore, err := conversation.NewSQLiteStore(cfg.ConversationDB)
+		if err != nil {
+			log.Fatalf("Failed to open conversation database: %v", err)
+		}
+		conversations = sqliteStore
+		log.Printf("Storing conversations in %s", cfg.ConversationDB)
+	} else {
+		conversations = conversation.NewMemoryStore()
+	}
+	defer conversations.Close()
+
+	// The chat pipeline is shared by every channel
+	chatService := chat.NewService(aiRouter, conversations)
 
 	// Initialize WebSocket gateway
-	gw := gateway.New(cfg)
+	gw := gateway.New(cfg, chatService)
+	alertEngine.Subscribe(gw.PublishAlert)
+
+	switch {
+	case cfg.JWTSecret == "" && cfg.AuthDisabled:
+		log.Println("WARNING: AUTH_DISABLED is set, every API request is treated as a signed-in admin. Never use this outside local development.")
+	case cfg.JWTSecret == "":
+		log.Println("Warning: JWT_SECRET is not set, API requests and WebSocket connections will be refused")
+	}
+
+	// Sign-in checks the local users file; tokens from another issuer
+	// sharing JWT_SECRET work without one
+	var users auth.Authenticator
+	var directory auth.Directory
+	if cfg.UsersFile != "" {
+		localUsers, err := auth.LoadLocalUsers(cfg.UsersFile)
+		if err != nil {
+			log.Fatalf("Failed to load users: %v", err)
+		}
+		users, directory = localUsers, localUsers
+		log.Printf("Loaded %d users", localUsers.Len())
+	}
+
+	// Slack is another channel into the chat pipeline, and alerts are
+	// posted to department channels
+	var slackAdapter *slack.Adapter
+	if cfg.SlackBotToken != "" {
+		slackAdapter = slack.New(slack.Config{
+			Token:         cfg.SlackBotToken,
+			SigningSecret: cfg.SlackSigningSecret,
+			APIURL:        cfg.SlackAPIURL,
+			Users:         cfg.SlackUsers,
+			AlertChannels: cfg.SlackAlertChannels,
+		}, chatService, directory)
+		if err := connectorRegistry.Register(slackAdapter); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		// With the notification router, alerts reach people by direct
+		// message when it picks Slack, not in the alert channels
+		if len(cfg.NotifyTiers) == 0 {
+			alertEngine.Subscribe(slackAdapter.PublishAlert)
+		}
+		log.Printf("Slack enabled for %d users", len(cfg.SlackUsers))
+	}
+
+	// Teams gets the same pipeline through the Bot Framework, with alerts
+	// posted as cards that can be acknowledged in place
+	var teamsAdapter *teams.Adapter
+	if cfg.TeamsAppID != "" {
+		teamsAdapter = teams.New(teams.Config{
+			AppID:         cfg.TeamsAppID,
+			AppPassword:   cfg.TeamsAppPassword,
+			OpenIDURL:     cfg.TeamsOpenIDURL,
+			TokenURL:      cfg.TeamsTokenURL,
+			ServiceURL:    cfg.TeamsServiceURL,
+			TenantID:      cfg.TeamsTenantID,
+			Users:         cfg.TeamsUsers,
+			AlertChannels: cfg.TeamsAlertChannels,
+		}, chatService, directory, alertEngine)
+		if err := connectorRegistry.Register(teamsAdapter); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		// With the notification router, alerts reach people by direct
+		// message when it picks Teams, not in the alert channels
+		if len(cfg.NotifyTiers) == 0 {
+			alertEngine.Subscribe(teamsAdapter.PublishAlert)
+		}
+		log.Printf("Teams enabled for %d users", len(cfg.TeamsUsers))
+	}
+
+	// SMS reaches managers on the floor, and pages them for critical
+	// alerts
+	var smsAdapter *sms.Adapter
+	if cfg.SMSAccountSID != "" {
+		smsAdapter = sms.New(sms.Config{
+			Provider: &sms.Twilio{
+				AccountSID: cfg.SMSAccountSID,
+				AuthToken:  cfg.SMSAuthToken,
+				From:       cfg.SMSFrom,
+				APIURL:     cfg.SMSAPIURL,
+				WebhookURL: cfg.SMSWebhookURL,
+			},
+			Users:     cfg.SMSUsers,
+			OnCall:    cfg.SMSOnCall,
+			MaxLength: cfg.SMSMaxLength,
+		}, chatService, directory, alertEngine)
+		if err := connectorRegistry.Register(smsAdapter); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		// With the notification router, pages are sent when it picks SMS
+		// for someone, so SMS_ONCALL isn't paged as well
+		if len(cfg.NotifyTiers) == 0 {
+			alertEngine.Subscribe(smsAdapter.PublishAlert)
+		}
+		log.Printf("SMS enabled for %d numbers", len(cfg.SMSUsers))
+	}
+
+	// Email sends the morning digest and alert notifications, as each
+	// user's preferences ask
+	var notifier *email.Notifier
+	if cfg.EmailSMTPHost != "" {
+		var emailStore email.Store
+		if cfg.EmailDB != "" {
+			sqliteStore, err := email.NewSQLiteStore(cfg.EmailDB)
+			if err != nil {
+				log.Fatalf("Failed to open email database: %v", err)
+			}
+			emailStore = sqliteStore
+		} else {
+			emailStore = email.NewMemoryStore()
+		}
+		defer emailStore.Close()
+
+		var departments []string
+		for _, d := range aiRouter.Departments() {
+			departments = append(departments, string(d))
+		}
+		notifier, err = email.New(email.Config{
+			SMTP: email.SMTPConfig{
+				Host:     cfg.EmailSMTPHost,
+				Port:     cfg.EmailSMTPPort,
+				Username: cfg.EmailSMTPUsername,
+				Password: cfg.EmailSMTPPassword,
+				TLS:      cfg.EmailSMTPTLS,
+			},
+			From:        cfg.EmailFrom,
+			DigestAt:    cfg.EmailDigestAt,
+			Departments: departments,
+			TemplateDir: cfg.EmailTemplates,
+			BaseURL:     cfg.EmailBaseURL,
+		}, emailStore, directory, alertEngine, items, schedules, plans)
+		if err != nil {
+			log.Fatalf("Failed to create email notifier: %v", err)
+		}
+		if err := connectorRegistry.Register(notifier); err != nil {
+			log.Fatalf("Failed to register connector: %v", err)
+		}
+		// With the notification router, alert emails are sent when it
+		// picks email for someone, and the notifier only sends digests
+		if len(cfg.NotifyTiers) == 0 {
+			alertEngine.Subscribe(notifier.PublishAlert)
+		}
+		log.Printf("Email enabled through %s:%d", cfg.EmailSMTPHost, cfg.EmailSMTPPort)
+	}
+
+	// The notification router sends each alert to the people who should
+	// handle it, on the channels they prefer, and escalates it through
+	// the tiers until someone acknowledges it
+	var notifyRouter *notify.Router
+	if len(cfg.NotifyTiers) > 0 {
+		var notifyStore notify.Store
+		if cfg.NotifyDB != "" {
+			sqliteStore, err := notify.NewSQLiteStore(cfg.NotifyDB)
+			if err != nil {
+				log.Fatalf("Failed to open notification database: %v", err)
+			}
+			notifyStore = sqliteStore
+		} else {
+			notifyStore = notify.NewMemoryStore()
+		}
+		defer notifyStore.Close()
+
+		// Channels are tried in this order unless NOTIFY_CHANNELS or a
+		// user's preferences say otherwise
+		channels := []notify.Channel{gw}
+		if slackAdapter != nil {
+			channels = append(channels, slackAdapter)
+		}
+		if teamsAdapter != nil {
+			channels = append(channels, teamsAdapter)
+		}
+		if smsAdapter != nil {
+			channels = append(channels, smsAdapter)
+		}
+		if notifier != nil {
+			channels = append(channels, notifier)
+		}
+
+		var tiers []notify.Tier
+		for _, t := range cfg.NotifyTiers {
+			tiers = append(tiers, notify.Tier{Name: t.Name, Wait: t.Wait})
+		}
+		notifyRouter, err = notify.New(notify.Config{
+			Tiers:       tiers,
+			Channels:    cfg.NotifyChannels,
+			MinSeverity: alerts.Severity(cfg.NotifyMinSeverity),
+			Store:       cfg.NotifyStore,
+			OnCall:      cfg.NotifyOnCall,
+		}, notifyStore, directory, alertEngine, schedules, channels...)
+		if err != nil {
+			log.Fatalf("Failed to create notification router: %v", err)
+		}
+		alertEngine.Subscribe(notifyRouter.PublishAlert)
+		log.Printf("Notification routing enabled through %d tiers", len(tiers))
+	}
+
+	// Alerts are evaluated once every channel has subscribed
+	alertCtx, stopAlerts := context.WithCancel(context.Background())
+	defer stopAlerts()
+	go alertEngine.Run(alertCtx, cfg.AlertInterval)
+	if notifyRouter != nil {
+		go notifyRouter.Run(alertCtx)
+	}
+
+	connectorRegistry.Start(context.Background())
+	log.Printf("Started %d connectors", connectorRegistry.Len())
 
 	// Initialize HTTP API
-	router := api.NewRouter(cfg, gw, aiRouter)
+	router := api.NewRouter(cfg, gw, chatService, users, items, schedules, lanes, plans, importRuns, alertEngine, connectorRegistry, notifier, notifyRouter)
+	if slackAdapter != nil {
+		router.Handle("POST /slack/events", slackAdapter.HandleEvents)
+		router.Handle("POST /slack/commands", slackAdapter.HandleCommand)
+	}
+	if teamsAdapter != nil {
+		router.Handle("POST /teams/messages", teamsAdapter.HandleActivities)
+	}
+	if smsAdapter != nil {
+		router.Handle("POST /sms/messages", smsAdapter.HandleMessage)
+	}
 
 	server := &http.Server{
 		Addr:         cfg.ServerAddr,
@@ -70,6 +485,7 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	connectorRegistry.Stop()
 
 	log.Println("Server stopped")
 }
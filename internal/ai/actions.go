@@ -0,0 +1,344 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ActionType is a kind of suggested action the manager can approve
+type ActionType string
+
+const (
+	ActionReorder          ActionType = "reorder"
+	ActionRequestCoverage  ActionType = "request_coverage"
+	ActionCreateTask       ActionType = "create_task"
+	ActionAcknowledgeAlert ActionType = "acknowledge_alert"
+)
+
+// maxFormatRetries is how many times a malformed structured answer is
+// sent back to the model for correction
+const maxFormatRetries = 2
+
+// maxActions bounds how many actions an answer may suggest
+const maxActions = 3
+
+// Action is a suggested next step rendered as a button. Which fields are
+// set depends on Type.
+type Action struct {
+	Type ActionType `json:"type"`
+	// Label is the button text, e.g. "Reorder 12 cases of 2% milk"
+	Label string `json:"label"`
+
+	// reorder
+	SKU      string `json:"sku,omitempty"`
+	Quantity int    `json:"quantity,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+
+	// request_coverage; Date is YYYY-MM-DD, Start and End are HH:MM
+	Date  string `json:"date,omitempty"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	Role  string `json:"role,omitempty"`
+
+	// create_task; Due is YYYY-MM-DD
+	Title   string `json:"title,omitempty"`
+	Details string `json:"details,omitempty"`
+	Due     string `json:"due,omitempty"`
+
+	// acknowledge_alert
+	AlertID string `json:"alertId,omitempty"`
+}
+
+// structuredAnswer is the JSON an agent is asked for in structured mode
+type structuredAnswer struct {
+	Answer  string   `json:"answer"`
+	Actions []Action `json:"actions"`
+}
+
+const structuredPrompt = `Reply with a single JSON object and nothing else, in this form:
+{"answer": "...", "actions": [...]}
+
+"answer" is your full reply to the manager as plain text, including any citation markers. "actions" lists up to 3 concrete next steps the manager can approve with one tap, or is empty. Each action has a "type", a short button "label" such as "Reorder 12 cases of 2% milk", and the fields for its type:
+- reorder: "sku", "quantity" (a whole number), "unit"
+- request_coverage: "date" (YYYY-MM-DD), "start" and "end" (HH:MM, 24-hour), "role"
+- create_task: "title", optional "details" and "due" (YYYY-MM-DD)
+- acknowledge_alert: "alertId"
+
+Only suggest actions backed by data you looked up, using the exact SKUs and alert IDs from it.`
+
+// answerSchema is the JSON schema structured answers must follow. The
+// answer comes first so it can be streamed before the actions arrive.
+var answerSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"answer": {"type": "string"},
+		"actions": {
+			"type": "array",
+			"maxItems": 3,
+			"items": {
+				"type": "object",
+				"properties": {
+					"type": {"type": "string", "enum": ["reorder", "request_coverage", "create_task", "acknowledge_alert"]},
+					"label": {"type": "string"},
+					"sku": {"type": "string"},
+					"quantity": {"type": "integer", "minimum": 1},
+					"unit": {"type": "string"},
+					"date": {"type": "string"},
+					"start": {"type": "string"},
+					"end": {"type": "string"},
+					"role": {"type": "string"},
+					"title": {"type": "string"},
+					"details": {"type": "string"},
+					"due": {"type": "string"},
+					"alertId": {"type": "string"}
+				},
+				"required": ["type", "label"]
+			}
+		}
+	},
+	"required": ["answer", "actions"]
+}`)
+
+// Validate checks that the action has the fields its type needs
+func (a Action) Validate() error {
+	if strings.TrimSpace(a.Label) == "" {
+		return errors.New("label is required")
+	}
+
+	switch a.Type {
+	case ActionReorder:
+		if a.SKU == "" {
+			return errors.New("reorder needs a sku")
+		}
+		if a.Quantity < 1 {
+			return errors.New("reorder needs a quantity of at least 1")
+		}
+	case ActionRequestCoverage:
+		if _, err := time.Parse("2006-01-02", a.Date); err != nil {
+			return fmt.Errorf("request_coverage needs a date as YYYY-MM-DD, got %q", a.Date)
+		}
+		start, err := time.Parse("15:04", a.Start)
+		if err != nil {
+			return fmt.Errorf("request_coverage needs a start time as HH:MM, got %q", a.Start)
+		}
+		end, err := time.Parse("15:04", a.End)
+		if err != nil {
+			return fmt.Errorf("request_coverage needs an end time as HH:MM, got %q", a.End)
+		}
+		if start.Equal(end) {
+			return errors.New("request_coverage start and end must differ")
+		}
+	case ActionCreateTask:
+		if strings.TrimSpace(a.Title) == "" {
+			return errors.New("create_task needs a title")
+		}
+		if a.Due != "" {
+			if _, err := time.Parse("2006-01-02", a.Due); err != nil {
+				return fmt.Errorf("create_task due must be YYYY-MM-DD, got %q", a.Due)
+			}
+		}
+	case ActionAcknowledgeAlert:
+		if a.AlertID == "" {
+			return errors.New("acknowledge_alert needs an alertId")
+		}
+	default:
+		return fmt.Errorf("unknown action type %q", a.Type)
+	}
+	return nil
+}
+
+// parseStructured decodes and validates a structured answer. On a
+// validation error the answer and the valid actions are still returned so
+// the caller can fall back to them.
+func parseStructured(content string) (*structuredAnswer, error) {
+	content = strings.TrimSpace(content)
+	// Some models wrap JSON in a Markdown code fence despite being told not to
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	var out structuredAnswer
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if strings.TrimSpace(out.Answer) == "" {
+		return nil, errors.New(`"answer" is empty`)
+	}
+
+	var valid []Action
+	var errs []error
+	for i, action := range out.Actions {
+		if err := action.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("action %d: %w", i+1, err))
+			continue
+		}
+		valid = append(valid, action)
+	}
+	if len(valid) > maxActions {
+		errs = append(errs, fmt.Errorf("at most %d actions are allowed", maxActions))
+		valid = valid[:maxActions]
+	}
+	out.Actions = valid
+
+	return &out, errors.Join(errs...)
+}
+
+// structure turns a structured-mode reply into the answer text and its
+// actions. Malformed replies are sent back to the model with the problem
+// described, up to maxFormatRetries times, using the provider's JSON
+// format enforcement. If every attempt fails the best answer so far is
+// returned as plain text rather than failing the query.
+func (a *Agent) structure(ctx context.Context, messages []Message, result *QueryResult) {
+	reply := result.Response
+	var best *structuredAnswer
+
+	for attempt := 0; ; attempt++ {
+		parsed, err := parseStructured(reply)
+		if err == nil {
+			result.Response, result.Actions = parsed.Answer, parsed.Actions
+			return
+		}
+		if parsed != nil {
+			best = parsed
+		}
+
+		if attempt == maxFormatRetries || ctx.Err() != nil {
+			log.Printf("Agent %s: structured answer still invalid after %d retries: %v", a.department, attempt, err)
+			break
+		}
+
+		retry := append(messages[:len(messages):len(messages)],
+			Message{Role: "assistant", Content: reply},
+			Message{Role: "user", Content: fmt.Sprintf("That reply was not valid: %v. Send the corrected JSON object only.", err)},
+		)
+		streamed := false
+		corrected, _, err := a.complete(ctx, &ChatRequest{Messages: retry, Format: answerSchema}, false, nil, &streamed)
+		if err != nil {
+			log.Printf("Agent %s: failed to correct structured answer: %v", a.department, err)
+			break
+		}
+		reply = corrected.Content
+	}
+
+	if best != nil {
+		result.Response, result.Actions = best.Answer, best.Actions
+	}
+}
+
+// answerKeyPattern finds where the answer string starts in a structured
+// reply
+var answerKeyPattern = regexp.MustCompile(`"answer"\s*:\s*"`)
+
+// Stages of answerStream
+const (
+	streamDetecting = iota
+	streamPlain
+	streamSeeking
+	streamAnswer
+	streamDone
+)
+
+// answerStream forwards only the "answer" string of a structured reply as
+// it streams in, so clients see prose rather than raw JSON. Replies that
+// aren't JSON are forwarded unchanged.
+type answerStream struct {
+	onDelta DeltaFunc
+	buf     string
+	pos     int
+	stage   int
+}
+
+func newAnswerStream(onDelta DeltaFunc) *answerStream {
+	return &answerStream{onDelta: onDelta}
+}
+
+func (s *answerStream) write(delta string) error {
+	if s.stage == streamPlain {
+		return s.onDelta(delta)
+	}
+	s.buf += delta
+
+	if s.stage == streamDetecting {
+		trimmed := strings.TrimLeft(s.buf, " \t\r\n")
+		if trimmed == "" {
+			return nil
+		}
+		if trimmed[0] != '{' && trimmed[0] != '`' {
+			s.stage = streamPlain
+			return s.onDelta(s.buf)
+		}
+		s.stage = streamSeeking
+	}
+
+	if s.stage == streamSeeking {
+		loc := answerKeyPattern.FindStringIndex(s.buf)
+		if loc == nil {
+			return nil
+		}
+		s.pos = loc[1]
+		s.stage = streamAnswer
+	}
+
+	if s.stage == streamAnswer {
+		if text := s.decode(); text != "" {
+			return s.onDelta(text)
+		}
+	}
+	return nil
+}
+
+// decode unescapes as much of the answer string as has arrived, stopping
+// at an escape sequence that is still incomplete
+func (s *answerStream) decode() string {
+	var out strings.Builder
+	for s.pos < len(s.buf) {
+		c := s.buf[s.pos]
+		if c == '"' {
+			s.stage = streamDone
+			break
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			s.pos++
+			continue
+		}
+
+		if s.pos+1 >= len(s.buf) {
+			break
+		}
+		if s.buf[s.pos+1] == 'u' {
+			if s.pos+6 > len(s.buf) {
+				break
+			}
+			r, err := strconv.ParseUint(s.buf[s.pos+2:s.pos+6], 16, 32)
+			if err == nil {
+				out.WriteRune(rune(r))
+			}
+			s.pos += 6
+			continue
+		}
+
+		switch esc := s.buf[s.pos+1]; esc {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'b', 'f':
+		default:
+			out.WriteByte(esc)
+		}
+		s.pos += 2
+	}
+	return out.String()
+}
@@ -2,13 +2,20 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Department represents a store department
 type Department string
 
+// The departments every store has. Others are added through department
+// definitions.
 const (
 	DeptDairy    Department = "dairy"
 	DeptProduce  Department = "produce"
@@ -19,108 +26,250 @@ const (
 	DeptFrontEnd Department = "frontend"
 )
 
+// maxToolRounds bounds how many rounds of tool calls the model may make
+// before it is asked to answer without tools
+const maxToolRounds = 5
+
+// fanOutTimeout is the shared deadline for department agents answering a
+// store-level question
+const fanOutTimeout = 60 * time.Second
+
 // Agent represents a department-specific AI agent
 type Agent struct {
-	department  Department
-	ollama      *OllamaClient
+	department   Department
+	providers    []Provider
+	tools        []*Tool
 	systemPrompt string
+	// retriever supplies reference passages from store documents; nil
+	// when no documents are indexed
+	retriever Retriever
+	// structured asks for JSON answers with suggested actions
+	structured bool
+
+	// toolsUnsupported records providers whose model rejected tool calls
+	// so later queries skip straight to a plain chat
+	toolsUnsupported sync.Map
 }
 
-// NewAgent creates a new department agent
-func NewAgent(dept Department, ollama *OllamaClient) *Agent {
-	return &Agent{
-		department:   dept,
-		ollama:       ollama,
-		systemPrompt: buildSystemPrompt(dept),
+// QueryResult is an agent's answer to a query
+type QueryResult struct {
+	Response   string
+	Department Department
+	// Model is the model that actually produced the answer, which differs
+	// from the primary model when a fallback provider was used
+	Model string
+	// Ambiguous is set when routing couldn't pick a department; Response
+	// then asks the user to choose one of Candidates
+	Ambiguous  bool
+	Candidates []Department
+	// Departments lists the agents consulted for a store-level answer
+	Departments []Department
+	// Citations are the document passages the answer cites
+	Citations []Citation
+	// Actions are suggested next steps, in structured output mode
+	Actions []Action
+}
+
+// NewAgent creates a new department agent from its definition, with the
+// tools from the registry that are scoped to the department and allowed by
+// the definition. Providers are tried in order, falling through to the next
+// only when one is unavailable.
+func NewAgent(def DepartmentDefinition, tools *ToolRegistry, providers ...Provider) (*Agent, error) {
+	prompt, err := def.SystemPrompt()
+	if err != nil {
+		return nil, err
 	}
+
+	available := tools.ForDepartment(def.ID)
+	agentTools := available
+	if len(def.Tools) > 0 {
+		agentTools = nil
+		for _, name := range def.Tools {
+			tool := findTool(available, name)
+			if tool == nil {
+				return nil, fmt.Errorf("tool %q is unknown or not available to the %s department", name, def.ID)
+			}
+			agentTools = append(agentTools, tool)
+		}
+	}
+
+	return &Agent{
+		department:   def.ID,
+		providers:    providers,
+		tools:        agentTools,
+		systemPrompt: prompt,
+	}, nil
 }
 
-func buildSystemPrompt(dept Department) string {
-	base := `You are Opus, an AI assistant for grocery store operations. You help department managers and assistant managers with their daily tasks.
+// ProcessQuery handles a user query through the department agent
+func (a *Agent) ProcessQuery(ctx context.Context, userQuery string, conversationHistory []Message) (*QueryResult, error) {
+	return a.answer(ctx, userQuery, conversationHistory, false, nil)
+}
+
+// ProcessQueryStream handles a user query like ProcessQuery, passing each
+// token chunk to onDelta as the model produces it
+func (a *Agent) ProcessQueryStream(ctx context.Context, userQuery string, conversationHistory []Message, onDelta DeltaFunc) (*QueryResult, error) {
+	return a.answer(ctx, userQuery, conversationHistory, true, onDelta)
+}
 
-You have access to:
-- Inventory data (stock levels, reorder points, expiration dates)
-- Schedule information (shifts, coverage, time-off requests)
-- Sales data and trends
-- Alert and monitoring systems
+// answer retrieves reference passages for the query, runs the model and
+// records which passages the answer cites
+func (a *Agent) answer(ctx context.Context, userQuery string, conversationHistory []Message, stream bool, onDelta DeltaFunc) (*QueryResult, error) {
+	passages := a.retrieve(ctx, userQuery)
+	messages := a.buildMessages(userQuery, conversationHistory, passages)
 
-Be concise, helpful, and action-oriented. When managers ask questions, provide direct answers and suggest next steps when appropriate.
+	if a.structured && onDelta != nil {
+		onDelta = newAnswerStream(onDelta).write
+	}
 
-Current context: You are assisting the %s department.
+	result, err := a.run(ctx, messages, stream, onDelta)
+	if err != nil {
+		return nil, err
+	}
 
-Department-specific knowledge:
-%s
+	if a.structured {
+		a.structure(ctx, messages, result)
+	}
+	result.Citations = citedPassages(result.Response, passages)
+	return result, nil
+}
 
-Always respond in a professional but friendly manner. If you don't have specific data, say so clearly and suggest how to get it.`
+// retrieve looks up reference passages. The agent still answers without
+// them if retrieval fails.
+func (a *Agent) retrieve(ctx context.Context, query string) []Passage {
+	if a.retriever == nil {
+		return nil
+	}
 
-	deptKnowledge := getDepartmentKnowledge(dept)
-	return fmt.Sprintf(base, dept, deptKnowledge)
+	passages, err := a.retriever.Retrieve(ctx, a.department, query)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Agent %s: document retrieval failed: %v", a.department, err)
+		}
+		return nil
+	}
+	return passages
 }
 
-func getDepartmentKnowledge(dept Department) string {
-	knowledge := map[Department]string{
-		DeptDairy: `- Monitor milk, cheese, yogurt, and egg inventory closely due to short shelf life
-- Pay attention to expiration dates and FIFO rotation
-- Temperature monitoring is critical (dairy coolers should be 35-38°F)
-- Common shrink issues: expired products, temperature abuse
-- Peak ordering: Sunday/Monday for weekend sales`,
+// run drives the tool-calling loop: the model is offered the agent's tools
+// and each round of tool calls is executed and fed back until it answers
+// in plain text
+func (a *Agent) run(ctx context.Context, messages []Message, stream bool, onDelta DeltaFunc) (*QueryResult, error) {
+	var definitions []ToolDefinition
+	for _, tool := range a.tools {
+		definitions = append(definitions, tool.Definition())
+	}
+
+	// Failover only happens before the first streamed chunk so the client
+	// never sees two partial answers
+	streamed := false
+	deltaFn := func(delta string) error {
+		streamed = true
+		if onDelta == nil {
+			return nil
+		}
+		return onDelta(delta)
+	}
+
+	for round := 0; ; round++ {
+		req := &ChatRequest{Messages: messages}
+		if round < maxToolRounds && len(definitions) > 0 {
+			req.Tools = definitions
+		} else {
+			req.Format = a.answerFormat()
+		}
+
+		reply, provider, err := a.complete(ctx, req, stream, deltaFn, &streamed)
+		if err != nil {
+			return nil, fmt.Errorf("agent query failed: %w", err)
+		}
 
-		DeptProduce: `- Fresh produce requires daily quality checks
-- Monitor ripeness levels for bananas, avocados, tomatoes
-- Wet rack items need frequent misting and rotation
-- Seasonal availability affects ordering
-- High shrink department - track waste carefully
-- Organic vs conventional inventory separation`,
+		if len(reply.ToolCalls) == 0 {
+			return &QueryResult{Response: reply.Content, Department: a.department, Model: provider.Model()}, nil
+		}
+
+		for i := range reply.ToolCalls {
+			// Ollama doesn't always assign call IDs, but Claude needs
+			// them to pair results with calls if we fail over mid-loop
+			if reply.ToolCalls[i].ID == "" {
+				reply.ToolCalls[i].ID = fmt.Sprintf("call_%d_%d", round, i)
+			}
+		}
+		messages = append(messages, *reply)
+
+		for _, call := range reply.ToolCalls {
+			log.Printf("Agent %s calling tool %s", a.department, call.Function.Name)
+			messages = append(messages, Message{
+				Role:       "tool",
+				Content:    runToolCall(ctx, a.department, a.tools, call),
+				ToolName:   call.Function.Name,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
 
-		DeptMeat: `- Temperature critical (meat cases 28-32°F)
-- Track sell-by dates carefully
-- Monitor grinding logs and food safety compliance
-- Marination and value-added prep scheduling
-- Weekend and holiday demand spikes
-- Special orders and custom cuts tracking`,
+// complete sends req to the first provider able to answer it, returning
+// the reply and the provider that produced it
+func (a *Agent) complete(ctx context.Context, req *ChatRequest, stream bool, onDelta DeltaFunc, streamed *bool) (*Message, Provider, error) {
+	for i, provider := range a.providers {
+		providerReq := req
+		if _, ok := a.toolsUnsupported.Load(provider.Name()); ok && len(req.Tools) > 0 {
+			providerReq = &ChatRequest{Messages: req.Messages, Format: a.answerFormat()}
+		}
 
-		DeptBakery: `- Production schedules based on sales patterns
-- Fresh-baked timing for peak traffic
-- Track ingredient inventory (flour, sugar, eggs)
-- Special order management (cakes, platters)
-- End-of-day markdown decisions
-- Seasonal and holiday production planning`,
+		reply, err := a.send(ctx, provider, providerReq, stream, onDelta)
+		if errors.Is(err, ErrToolsUnsupported) {
+			log.Printf("Provider %s model %s does not support tools, answering without them", provider.Name(), provider.Model())
+			a.toolsUnsupported.Store(provider.Name(), true)
+			reply, err = a.send(ctx, provider, &ChatRequest{Messages: req.Messages, Format: a.answerFormat()}, stream, onDelta)
+		}
+		if err != nil {
+			if !*streamed && i < len(a.providers)-1 && shouldFailover(ctx, err) {
+				log.Printf("Provider %s unavailable, falling back: %v", provider.Name(), err)
+				continue
+			}
+			return nil, nil, err
+		}
 
-		DeptDeli: `- Hot bar and salad bar temperature monitoring
-- Sliced meats and cheeses inventory
-- Prepared foods production scheduling
-- Catering and special orders
-- Food safety compliance (time/temp logs)
-- Peak lunch and dinner rush preparation`,
+		return reply, provider, nil
+	}
 
-		DeptGrocery: `- Center store inventory management
-- Shelf capacity and facing standards
-- Promotional display execution
-- Vendor deliveries and resets
-- Overstock management
-- Category planogram compliance`,
+	return nil, nil, fmt.Errorf("no providers configured")
+}
 
-		DeptFrontEnd: `- Register operations and cash management
-- Cashier scheduling for peak hours
-- Customer service issues escalation
-- Register/equipment status monitoring
-- Cart availability and lot maintenance
-- Checkout line management`,
+// answerFormat is the schema requests must follow when the model can only
+// answer, not call tools. Format and tools can't be combined, so rounds
+// offering tools rely on the structured prompt and structure's retries.
+func (a *Agent) answerFormat() json.RawMessage {
+	if !a.structured {
+		return nil
 	}
+	return answerSchema
+}
 
-	if k, ok := knowledge[dept]; ok {
-		return k
+func (a *Agent) send(ctx context.Context, provider Provider, req *ChatRequest, stream bool, onDelta DeltaFunc) (*Message, error) {
+	if stream {
+		return provider.ChatStream(ctx, req, onDelta)
 	}
-	return "General store operations support."
+	return provider.Chat(ctx, req)
 }
 
-// ProcessQuery handles a user query through the department agent
-func (a *Agent) ProcessQuery(ctx context.Context, userQuery string, conversationHistory []Message) (string, error) {
+func (a *Agent) buildMessages(userQuery string, conversationHistory []Message, passages []Passage) []Message {
 	// Build conversation with system prompt
 	messages := []Message{
 		{Role: "system", Content: a.systemPrompt},
 	}
 
+	// Add reference passages from store documents
+	if len(passages) > 0 {
+		messages = append(messages, referenceMessage(passages))
+	}
+
+	if a.structured {
+		messages = append(messages, Message{Role: "system", Content: structuredPrompt})
+	}
+
 	// Add conversation history
 	messages = append(messages, conversationHistory...)
 
@@ -130,76 +279,177 @@ func (a *Agent) ProcessQuery(ctx context.Context, userQuery string, conversation
 		Content: userQuery,
 	})
 
-	response, err := a.ollama.Chat(ctx, messages)
-	if err != nil {
-		return "", fmt.Errorf("agent query failed: %w", err)
-	}
-
-	return response, nil
+	return messages
 }
 
 // Router routes queries to the appropriate department agent
 type Router struct {
-	agents map[Department]*Agent
-	ollama *OllamaClient
+	agents       map[Department]*Agent
+	definitions  []DepartmentDefinition
+	providers    []Provider
+	matcher      *KeywordMatcher
+	classifier   *Classifier
+	orchestrator *Orchestrator
+	contexts     *ContextManager
 }
 
-// NewRouter creates a new agent router
-func NewRouter(ollama *OllamaClient) *Router {
+// NewRouter creates a new agent router with an agent per department
+// definition. Agents are given the tools scoped to their department. The
+// first provider is the primary; any others are fallbacks used when it is
+// unreachable. Definitions are in routing priority order.
+func NewRouter(definitions []DepartmentDefinition, tools *ToolRegistry, providers ...Provider) (*Router, error) {
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("no departments defined")
+	}
+
 	r := &Router{
-		agents: make(map[Department]*Agent),
-		ollama: ollama,
+		agents:      make(map[Department]*Agent),
+		definitions: definitions,
+		providers:   providers,
+	}
+
+	var rules []RoutingRule
+	for _, def := range definitions {
+		agent, err := NewAgent(def, tools, agentProviders(def, providers)...)
+		if err != nil {
+			return nil, fmt.Errorf("department %s: %w", def.ID, err)
+		}
+		r.agents[def.ID] = agent
+		rules = append(rules, RoutingRule{Department: def.ID, Keywords: def.Keywords})
 	}
 
-	// Initialize all department agents
-	departments := []Department{
-		DeptDairy, DeptProduce, DeptMeat, DeptBakery,
-		DeptDeli, DeptGrocery, DeptFrontEnd,
+	r.matcher = NewKeywordMatcher(rules)
+	r.orchestrator = NewOrchestrator(r, fanOutTimeout)
+	r.contexts = NewContextManager(NewTokenBudget(nil), providers...)
+
+	return r, nil
+}
+
+// agentProviders applies a department's model override to the primary
+// provider, keeping the fallbacks as they are
+func agentProviders(def DepartmentDefinition, providers []Provider) []Provider {
+	if def.Model == "" || len(providers) == 0 {
+		return providers
 	}
 
-	for _, dept := range departments {
-		r.agents[dept] = NewAgent(dept, ollama)
+	selector, ok := providers[0].(ModelSelector)
+	if !ok {
+		log.Printf("Department %s: provider %s can't switch models, ignoring override %s", def.ID, providers[0].Name(), def.Model)
+		return providers
 	}
 
-	return r
+	overridden := append([]Provider{selector.WithModel(def.Model)}, providers[1:]...)
+	return overridden
 }
 
-// Route determines which department should handle a query
-func (r *Router) Route(query string) Department {
-	query = strings.ToLower(query)
+// Definitions returns the department definitions, in routing order
+func (r *Router) Definitions() []DepartmentDefinition {
+	return r.definitions
+}
+
+// SetTokenBudget replaces the built-in model context windows used to keep
+// conversation history in bounds
+func (r *Router) SetTokenBudget(budget *TokenBudget) {
+	r.contexts = NewContextManager(budget, r.providers...)
+}
 
-	// Simple keyword-based routing (can be enhanced with AI classification)
-	routingRules := map[Department][]string{
-		DeptDairy:    {"milk", "cheese", "yogurt", "egg", "butter", "cream", "dairy"},
-		DeptProduce:  {"fruit", "vegetable", "produce", "banana", "apple", "lettuce", "organic"},
-		DeptMeat:     {"meat", "beef", "chicken", "pork", "steak", "ground", "butcher"},
-		DeptBakery:   {"bread", "cake", "pastry", "donut", "bakery", "fresh baked"},
-		DeptDeli:     {"deli", "sandwich", "sliced", "hot bar", "salad bar", "catering"},
-		DeptGrocery:  {"aisle", "shelf", "grocery", "canned", "cereal", "snack"},
-		DeptFrontEnd: {"register", "cashier", "checkout", "front end", "cart", "customer service"},
+// EnableStructuredOutput has department agents answer in JSON with
+// suggested actions alongside the text
+func (r *Router) EnableStructuredOutput() {
+	for _, agent := range r.agents {
+		agent.structured = true
 	}
+}
 
-	for dept, keywords := range routingRules {
-		for _, keyword := range keywords {
-			if strings.Contains(query, keyword) {
-				return dept
-			}
-		}
+// SetRetriever gives every department agent reference passages from the
+// store's documents
+func (r *Router) SetRetriever(retriever Retriever) {
+	for _, agent := range r.agents {
+		agent.retriever = retriever
 	}
+}
 
-	// Default to grocery for unmatched queries
-	return DeptGrocery
+// ContextManager returns the manager that fits conversation history into
+// the models' context windows
+func (r *Router) ContextManager() *ContextManager {
+	return r.contexts
 }
 
-// ProcessQuery routes and processes a query
-func (r *Router) ProcessQuery(ctx context.Context, query string, history []Message) (string, Department, error) {
-	dept := r.Route(query)
-	agent := r.agents[dept]
+// EnableClassifier switches routing from keyword matching to asking the
+// primary provider's model, with keyword matching kept as the fallback
+func (r *Router) EnableClassifier() {
+	if len(r.providers) > 0 {
+		r.classifier = NewClassifier(r.providers[0], r.matcher)
+	}
+}
 
-	response, err := agent.ProcessQuery(ctx, query, history)
-	if err != nil {
-		return "", dept, err
+// Classify decides which department should handle a query, reporting
+// ambiguity rather than guessing
+func (r *Router) Classify(ctx context.Context, query string) *RouteResult {
+	if r.classifier != nil {
+		return r.classifier.Classify(ctx, query)
+	}
+	return r.matcher.Match(query)
+}
+
+// Agent returns the agent for dept, for callers that already know which
+// department should answer
+func (r *Router) Agent(dept Department) (*Agent, bool) {
+	agent, ok := r.agents[dept]
+	return agent, ok
+}
+
+// ProcessQuery routes and processes a query. Store-wide questions fan out
+// to several agents. An ambiguous query is not sent to any agent; the
+// result asks the user to pick a department instead.
+func (r *Router) ProcessQuery(ctx context.Context, query string, history []Message) (*QueryResult, error) {
+	if depts := r.orchestrator.Departments(query); depts != nil {
+		return r.orchestrator.ProcessQuery(ctx, query, history, depts, nil)
+	}
+
+	route := r.Classify(ctx, query)
+	if route.Ambiguous {
+		return clarify(route), nil
+	}
+
+	return r.agents[route.Department].ProcessQuery(ctx, query, history)
+}
+
+// ProcessQueryStream routes a query and streams the agent's response
+// through onDelta
+func (r *Router) ProcessQueryStream(ctx context.Context, query string, history []Message, onDelta DeltaFunc) (*QueryResult, error) {
+	if depts := r.orchestrator.Departments(query); depts != nil {
+		return r.orchestrator.ProcessQuery(ctx, query, history, depts, onDelta)
 	}
 
-	return response, dept, nil
+	route := r.Classify(ctx, query)
+	if route.Ambiguous {
+		return clarify(route), nil
+	}
+
+	return r.agents[route.Department].ProcessQueryStream(ctx, query, history, onDelta)
+}
+
+// Departments returns the departments with agents, in routing order
+func (r *Router) Departments() []Department {
+	return r.matcher.Departments()
+}
+
+// Providers returns the configured providers, primary first
+func (r *Router) Providers() []Provider {
+	return r.providers
+}
+
+// clarify builds the reply for an ambiguous query
+func clarify(route *RouteResult) *QueryResult {
+	names := make([]string, len(route.Candidates))
+	for i, d := range route.Candidates {
+		names[i] = string(d)
+	}
+
+	return &QueryResult{
+		Response:   fmt.Sprintf("I'm not sure which department that's for. Which one do you mean: %s?", strings.Join(names, ", ")),
+		Ambiguous:  true,
+		Candidates: route.Candidates,
+	}
 }
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeClaude serves the Messages API, answering every request with text
+func fakeClaude(t *testing.T, text string) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "test-key" {
+			http.Error(w, `{"type": "error"}`, http.StatusUnauthorized)
+			return
+		}
+		var req ClaudeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(ClaudeResponse{
+			ID:         "msg_1",
+			Model:      req.Model,
+			Content:    []ClaudeContentBlock{{Type: "text", Text: text}},
+			StopReason: "end_turn",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// unreachableURL returns the URL of a server that has already shut down
+func unreachableURL() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func testAgent(t *testing.T, providers ...Provider) *Agent {
+	t.Helper()
+	agent, err := NewAgent(DepartmentDefinition{ID: DeptDairy, Name: "Dairy"}, NewToolRegistry(), providers...)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	return agent
+}
+
+func TestAgentFailsOverToClaudeWhenOllamaIsDown(t *testing.T) {
+	claude, calls := fakeClaude(t, "Milk is in aisle 3.")
+	agent := testAgent(t,
+		NewOllamaClient(unreachableURL(), "llama3"),
+		NewClaudeClient(claude.URL, "test-key", "claude-test"),
+	)
+
+	result, err := agent.ProcessQuery(context.Background(), "Where is the milk?", nil)
+	if err != nil {
+		t.Fatalf("ProcessQuery: %v", err)
+	}
+	if result.Response != "Milk is in aisle 3." {
+		t.Errorf("Response = %q", result.Response)
+	}
+	if result.Model != "claude-test" {
+		t.Errorf("Model = %q, want the fallback's model", result.Model)
+	}
+	if *calls != 1 {
+		t.Errorf("Claude called %d times, want 1", *calls)
+	}
+}
+
+func TestAgentReturnsErrorWhenEveryProviderIsDown(t *testing.T) {
+	agent := testAgent(t,
+		NewOllamaClient(unreachableURL(), "llama3"),
+		NewClaudeClient(unreachableURL(), "test-key", "claude-test"),
+	)
+
+	if _, err := agent.ProcessQuery(context.Background(), "Where is the milk?", nil); err == nil {
+		t.Fatal("ProcessQuery succeeded with no provider up")
+	}
+}
+
+// scriptedProvider streams deltas and then fails or answers
+type scriptedProvider struct {
+	name   string
+	deltas []string
+	err    error
+	calls  int
+}
+
+func (p *scriptedProvider) Name() string                         { return p.name }
+func (p *scriptedProvider) Model() string                        { return p.name + "-model" }
+func (p *scriptedProvider) IsAvailable(ctx context.Context) bool { return true }
+
+func (p *scriptedProvider) Chat(ctx context.Context, req *ChatRequest) (*Message, error) {
+	return p.ChatStream(ctx, req, func(string) error { return nil })
+}
+
+func (p *scriptedProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta DeltaFunc) (*Message, error) {
+	p.calls++
+	content := ""
+	for _, d := range p.deltas {
+		content += d
+		if err := onDelta(d); err != nil {
+			return nil, err
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Message{Role: "assistant", Content: content}, nil
+}
+
+func TestAgentDoesNotFailOverAfterStreaming(t *testing.T) {
+	primary := &scriptedProvider{name: "primary", deltas: []string{"Milk is "}, err: fmt.Errorf("%w: connection reset", ErrProviderUnavailable)}
+	fallback := &scriptedProvider{name: "fallback", deltas: []string{"Aisle 3."}}
+	agent := testAgent(t, primary, fallback)
+
+	var streamed string
+	_, err := agent.ProcessQueryStream(context.Background(), "Where is the milk?", nil, func(d string) error {
+		streamed += d
+		return nil
+	})
+	if err == nil {
+		t.Fatal("ProcessQueryStream succeeded after the primary failed mid-stream")
+	}
+	if fallback.calls != 0 {
+		t.Errorf("fallback called %d times after a partial answer was streamed", fallback.calls)
+	}
+	if streamed != "Milk is " {
+		t.Errorf("streamed %q, want only the primary's partial answer", streamed)
+	}
+}
+
+func TestAgentFailsOverBeforeStreaming(t *testing.T) {
+	primary := &scriptedProvider{name: "primary", err: fmt.Errorf("%w: status 503", ErrProviderUnavailable)}
+	fallback := &scriptedProvider{name: "fallback", deltas: []string{"Aisle ", "3."}}
+	agent := testAgent(t, primary, fallback)
+
+	var streamed string
+	result, err := agent.ProcessQueryStream(context.Background(), "Where is the milk?", nil, func(d string) error {
+		streamed += d
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessQueryStream: %v", err)
+	}
+	if result.Model != "fallback-model" || streamed != "Aisle 3." {
+		t.Errorf("got model %q streaming %q, want the fallback's answer", result.Model, streamed)
+	}
+}
+
+func TestShouldFailover(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"unavailable", context.Background(), fmt.Errorf("%w: timeout", ErrProviderUnavailable), true},
+		{"bad answer", context.Background(), fmt.Errorf("failed to decode response"), false},
+		{"tools unsupported", context.Background(), ErrToolsUnsupported, false},
+		{"caller cancelled", cancelled, fmt.Errorf("%w: timeout", ErrProviderUnavailable), false},
+	}
+	for _, tt := range tests {
+		if got := shouldFailover(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: shouldFailover = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// recordingProvider answers every request with reply, recording requests
+type recordingProvider struct {
+	scriptedProvider
+	requests []*ChatRequest
+	reply    string
+}
+
+func (p *recordingProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta DeltaFunc) (*Message, error) {
+	p.requests = append(p.requests, req)
+	if err := onDelta(p.reply); err != nil {
+		return nil, err
+	}
+	return &Message{Role: "assistant", Content: p.reply}, nil
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, req *ChatRequest) (*Message, error) {
+	return p.ChatStream(ctx, req, func(string) error { return nil })
+}
+
+func TestStructuredAgentSendsSchemaWithoutTools(t *testing.T) {
+	provider := &recordingProvider{
+		scriptedProvider: scriptedProvider{name: "primary"},
+		reply:            `{"answer": "Aisle 3.", "actions": []}`,
+	}
+	agent := testAgent(t, provider)
+	agent.structured = true
+
+	var streamed string
+	result, err := agent.ProcessQueryStream(context.Background(), "Where is the milk?", nil, func(d string) error {
+		streamed += d
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessQueryStream: %v", err)
+	}
+	if len(provider.requests) != 1 || len(provider.requests[0].Format) == 0 {
+		t.Fatal("the answer was requested without the answer schema")
+	}
+	if streamed != result.Response || result.Response != "Aisle 3." {
+		t.Errorf("streamed %q, answered %q", streamed, result.Response)
+	}
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ambiguousLabel is the department value the classifier returns when a
+// query does not clearly belong to one department
+const ambiguousLabel = "ambiguous"
+
+// Classifier asks the model which department should answer a query,
+// falling back to keyword matching when the model can't be reached or
+// returns something unusable
+type Classifier struct {
+	provider      Provider
+	matcher       *KeywordMatcher
+	minConfidence float64
+	timeout       time.Duration
+}
+
+// classification is the JSON the model is asked to produce
+type classification struct {
+	Department string   `json:"department"`
+	Confidence float64  `json:"confidence"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// NewClassifier creates a classifier that uses provider, with matcher as
+// the fallback
+func NewClassifier(provider Provider, matcher *KeywordMatcher) *Classifier {
+	return &Classifier{
+		provider:      provider,
+		matcher:       matcher,
+		minConfidence: 0.5,
+		timeout:       15 * time.Second,
+	}
+}
+
+// Classify decides which department should handle query
+func (c *Classifier) Classify(ctx context.Context, query string) *RouteResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	result, err := c.classify(ctx, query)
+	if err != nil {
+		log.Printf("Classifier unavailable, using keyword routing: %v", err)
+		return c.matcher.Match(query)
+	}
+	return result
+}
+
+func (c *Classifier) classify(ctx context.Context, query string) (*RouteResult, error) {
+	depts := c.matcher.Departments()
+
+	reply, err := c.provider.Chat(ctx, &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: c.prompt(depts)},
+			{Role: "user", Content: query},
+		},
+		Format: c.schema(depts),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out classification
+	if err := json.Unmarshal([]byte(reply.Content), &out); err != nil {
+		return nil, fmt.Errorf("malformed classification %q: %w", reply.Content, err)
+	}
+
+	known := make(map[Department]bool, len(depts))
+	for _, d := range depts {
+		known[d] = true
+	}
+
+	result := &RouteResult{Method: "classifier", Confidence: clamp(out.Confidence)}
+	for _, name := range out.Candidates {
+		if d := Department(name); known[d] && !containsDept(result.Candidates, d) {
+			result.Candidates = append(result.Candidates, d)
+		}
+	}
+
+	switch dept := Department(out.Department); {
+	case out.Department == ambiguousLabel:
+		result.Ambiguous = true
+	case !known[dept]:
+		return nil, fmt.Errorf("unknown department %q", out.Department)
+	case result.Confidence < c.minConfidence:
+		result.Department = dept
+		result.Ambiguous = true
+		if !containsDept(result.Candidates, dept) {
+			result.Candidates = append([]Department{dept}, result.Candidates...)
+		}
+	default:
+		result.Department = dept
+		result.Candidates = nil
+	}
+
+	if result.Ambiguous && len(result.Candidates) == 0 {
+		result.Candidates = depts
+	}
+	return result, nil
+}
+
+func (c *Classifier) prompt(depts []Department) string {
+	var b strings.Builder
+	b.WriteString("You route grocery store operations questions to the department that should answer them. The departments are:\n")
+	for _, d := range depts {
+		fmt.Fprintf(&b, "- %s (e.g. %s)\n", d, strings.Join(c.matcher.Keywords(d), ", "))
+	}
+	b.WriteString(`
+Reply with JSON only. Set "department" to the single best department and "confidence" to how sure you are, from 0 to 1. If the question fits several departments equally well, or none, set "department" to "ambiguous" and list the plausible departments in "candidates".`)
+	return b.String()
+}
+
+func (c *Classifier) schema(depts []Department) json.RawMessage {
+	names := make([]string, 0, len(depts)+1)
+	for _, d := range depts {
+		names = append(names, string(d))
+	}
+	candidates := append([]string(nil), names...)
+	names = append(names, ambiguousLabel)
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"department": map[string]interface{}{"type": "string", "enum": names},
+			"confidence": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+			"candidates": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string", "enum": candidates},
+			},
+		},
+		"required": []string{"department", "confidence"},
+	}
+
+	data, _ := json.Marshal(schema)
+	return data
+}
+
+func clamp(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func containsDept(depts []Department, dept Department) bool {
+	for _, d := range depts {
+		if d == dept {
+			return true
+		}
+	}
+	return false
+}
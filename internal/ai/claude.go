@@ -0,0 +1,401 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const claudeAPIVersion = "2023-06-01"
+
+// claudeFormatTool is the tool Claude is forced to call when a request
+// asks for a JSON format; its input is the structured reply
+const claudeFormatTool = "respond"
+
+// ClaudeClient handles communication with the Anthropic Messages API
+type ClaudeClient struct {
+	baseURL      string
+	apiKey       string
+	model        string
+	maxTokens    int
+	httpClient   *http.Client
+	streamClient *http.Client
+}
+
+// ClaudeRequest represents a request to the Messages API
+type ClaudeRequest struct {
+	Model      string            `json:"model"`
+	System     string            `json:"system,omitempty"`
+	Messages   []ClaudeMessage   `json:"messages"`
+	Tools      []ClaudeTool      `json:"tools,omitempty"`
+	ToolChoice *ClaudeToolChoice `json:"tool_choice,omitempty"`
+	MaxTokens  int               `json:"max_tokens"`
+	Stream     bool              `json:"stream,omitempty"`
+}
+
+// ClaudeMessage is a single turn in a Messages API conversation
+type ClaudeMessage struct {
+	Role    string               `json:"role"`
+	Content []ClaudeContentBlock `json:"content"`
+}
+
+// ClaudeTool describes a callable tool to the Messages API
+type ClaudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ClaudeToolChoice controls whether and which tool Claude must call
+type ClaudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// ClaudeResponse represents a non-streaming Messages API response
+type ClaudeResponse struct {
+	ID         string               `json:"id"`
+	Model      string               `json:"model"`
+	Content    []ClaudeContentBlock `json:"content"`
+	StopReason string               `json:"stop_reason"`
+}
+
+// ClaudeContentBlock is one block of message content: text, a tool_use
+// request from the model, or a tool_result answering one
+type ClaudeContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// claudeStreamEvent covers the fields used from Messages API stream events
+type claudeStreamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	ContentBlock ClaudeContentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewClaudeClient creates a new Claude client. baseURL is normally
+// https://api.anthropic.com but can point at a local stand-in.
+func NewClaudeClient(baseURL, apiKey, model string) *ClaudeClient {
+	return &ClaudeClient{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		maxTokens: 2048,
+		httpClient: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+		streamClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 120 * time.Second,
+			},
+		},
+	}
+}
+
+// Name returns the provider name
+func (c *ClaudeClient) Name() string {
+	return "claude"
+}
+
+// Model returns the Claude model used for requests
+func (c *ClaudeClient) Model() string {
+	return c.model
+}
+
+// Chat sends a chat conversation to Claude and returns the assistant's
+// reply, which may contain tool calls when tools were offered
+func (c *ClaudeClient) Chat(ctx context.Context, chatReq *ChatRequest) (*Message, error) {
+	req := c.buildRequest(chatReq, false)
+
+	resp, err := c.do(ctx, c.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var claudeResp ClaudeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return formatReply(chatReq, messageFromBlocks(claudeResp.Content)), nil
+}
+
+// ChatStream sends a chat conversation to Claude with streaming enabled,
+// calling onDelta for every text delta
+func (c *ClaudeClient) ChatStream(ctx context.Context, chatReq *ChatRequest, onDelta DeltaFunc) (*Message, error) {
+	req := c.buildRequest(chatReq, true)
+
+	resp, err := c.do(ctx, c.streamClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// The Messages API streams Server-Sent Events; only the data lines
+	// matter since each payload repeats its event type. Blocks are built
+	// up by index, with tool inputs arriving as partial JSON.
+	var blocks []ClaudeContentBlock
+	var inputs []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event claudeStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "content_block_start":
+			for len(blocks) <= event.Index {
+				blocks = append(blocks, ClaudeContentBlock{})
+				inputs = append(inputs, "")
+			}
+			blocks[event.Index] = event.ContentBlock
+		case "content_block_delta":
+			if event.Index >= len(blocks) {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				blocks[event.Index].Text += event.Delta.Text
+				if onDelta != nil && event.Delta.Text != "" {
+					if err := onDelta(event.Delta.Text); err != nil {
+						return nil, err
+					}
+				}
+			case "input_json_delta":
+				inputs[event.Index] += event.Delta.PartialJSON
+				// A forced format call is the reply itself, so its JSON
+				// streams like text would
+				if onDelta != nil && len(chatReq.Format) > 0 && blocks[event.Index].Name == claudeFormatTool && event.Delta.PartialJSON != "" {
+					if err := onDelta(event.Delta.PartialJSON); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case "message_stop":
+			for i := range blocks {
+				if blocks[i].Type == "tool_use" && inputs[i] != "" {
+					blocks[i].Input = json.RawMessage(inputs[i])
+				}
+			}
+			return formatReply(chatReq, messageFromBlocks(blocks)), nil
+		case "error":
+			return nil, fmt.Errorf("claude stream error: %s: %s", event.Error.Type, event.Error.Message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil, fmt.Errorf("stream ended before completion")
+}
+
+// IsAvailable checks if the API key is accepted by the Messages API
+func (c *ClaudeClient) IsAvailable(ctx context.Context) bool {
+	if c.apiKey == "" {
+		return false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v1/models", nil)
+	if err != nil {
+		return false
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// buildRequest converts Opus messages to the Messages API shape: system
+// prompts move to the top-level system field, tool results become
+// tool_result blocks in a user turn, and consecutive turns from the same
+// role are merged since the API requires alternating roles.
+func (c *ClaudeClient) buildRequest(chatReq *ChatRequest, stream bool) ClaudeRequest {
+	req := ClaudeRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		Stream:    stream,
+	}
+
+	for _, tool := range chatReq.Tools {
+		req.Tools = append(req.Tools, ClaudeTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+
+	// The Messages API has no JSON mode, so a required format is expressed
+	// as a tool whose input schema is the format, and Claude is made to
+	// call it
+	if len(chatReq.Format) > 0 {
+		req.Tools = append(req.Tools, ClaudeTool{
+			Name:        claudeFormatTool,
+			Description: "Respond to the user with output matching this schema.",
+			InputSchema: chatReq.Format,
+		})
+		req.ToolChoice = &ClaudeToolChoice{Type: "tool", Name: claudeFormatTool}
+	}
+
+	var system []string
+	for _, msg := range chatReq.Messages {
+		role := "user"
+		var blocks []ClaudeContentBlock
+
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+			continue
+		case "tool":
+			blocks = append(blocks, ClaudeContentBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			})
+		case "assistant":
+			role = "assistant"
+			if msg.Content != "" {
+				blocks = append(blocks, ClaudeContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := call.Function.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, ClaudeContentBlock{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: input,
+				})
+			}
+		default:
+			blocks = append(blocks, ClaudeContentBlock{Type: "text", Text: msg.Content})
+		}
+
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			continue
+		}
+		req.Messages = append(req.Messages, ClaudeMessage{Role: role, Content: blocks})
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	return req
+}
+
+// formatReply unwraps the forced format tool call into the reply's Content
+// so callers see the same JSON document Ollama would return
+func formatReply(chatReq *ChatRequest, msg *Message) *Message {
+	if len(chatReq.Format) == 0 {
+		return msg
+	}
+
+	for _, call := range msg.ToolCalls {
+		if call.Function.Name == claudeFormatTool {
+			return &Message{Role: "assistant", Content: string(call.Function.Arguments)}
+		}
+	}
+	return msg
+}
+
+// messageFromBlocks converts response content blocks to an assistant
+// Message, mapping tool_use blocks to tool calls
+func messageFromBlocks(blocks []ClaudeContentBlock) *Message {
+	msg := &Message{Role: "assistant"}
+
+	var text strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			args := block.Input
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				ID:       block.ID,
+				Function: ToolCallFunction{Name: block.Name, Arguments: args},
+			})
+		}
+	}
+	msg.Content = text.String()
+
+	return msg
+}
+
+func (c *ClaudeClient) setHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", claudeAPIVersion)
+}
+
+// do sends a Messages API request and returns the response once it has a
+// 200 status; the caller must close the body
+func (c *ClaudeClient) do(ctx context.Context, client *http.Client, req ClaudeRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.setHeaders(httpReq)
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: claude request failed: %v", ErrProviderUnavailable, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: claude error (status %d): %s", ErrProviderUnavailable, resp.StatusCode, string(bodyBytes))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultPromptTemplate is the system prompt for departments that don't
+// define their own. It is a text/template executed with the
+// DepartmentDefinition.
+const DefaultPromptTemplate = `You are Opus, an AI assistant for grocery store operations. You help department managers and assistant managers with their daily tasks.
+
+You have access to:
+- Inventory data (stock levels, reorder points, expiration dates)
+- Schedule information (shifts, coverage, time-off requests)
+- Sales data and trends
+- Alert and monitoring systems
+
+When tools are available, use them to look up current inventory, schedules, alerts and lane status before answering. Never invent stock levels, shift times, alert details or sales figures.
+
+Be concise, helpful, and action-oriented. When managers ask questions, provide direct answers and suggest next steps when appropriate.
+
+Current context: You are assisting the {{.Name}} department.
+
+Department-specific knowledge:
+{{.Knowledge}}
+
+Always respond in a professional but friendly manner. If you don't have specific data, say so clearly and suggest how to get it.`
+
+// DepartmentDefinition describes a department and how its agent behaves
+type DepartmentDefinition struct {
+	ID   Department
+	Name string
+	// Version is bumped whenever the definition changes so answers can be
+	// traced back to the prompt that produced them
+	Version int
+	// PromptTemplate overrides DefaultPromptTemplate
+	PromptTemplate string
+	Knowledge      string
+	// Keywords route queries to the department
+	Keywords []string
+	// Model overrides the primary provider's model for this department
+	Model string
+	// Tools lists the tools the agent may call; empty allows all of them
+	Tools []string
+}
+
+// SystemPrompt renders the department's prompt template
+func (d DepartmentDefinition) SystemPrompt() (string, error) {
+	text := d.PromptTemplate
+	if text == "" {
+		text = DefaultPromptTemplate
+	}
+
+	tmpl, err := template.New(string(d.ID)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// ModelSelector is implemented by providers that can serve a different
+// model from the same backend, for departments with a model override
+type ModelSelector interface {
+	WithModel(model string) Provider
+}
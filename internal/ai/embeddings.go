@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// OllamaEmbedder turns text into vectors with an Ollama embedding model
+// such as nomic-embed-text
+type OllamaEmbedder struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// ollamaEmbedRequest is the body of POST /api/embed
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// ollamaEmbedResponse is the reply from POST /api/embed
+type ollamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// NewOllamaEmbedder creates an embedder for model on the Ollama server at
+// baseURL
+func NewOllamaEmbedder(baseURL, model string) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		baseURL: baseURL,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+	}
+}
+
+// Model returns the embedding model name
+func (e *OllamaEmbedder) Model() string {
+	return e.model
+}
+
+// Embed returns one vector per input text, in order
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(ollamaEmbedRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ollama request failed: %v", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama embed error (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var embedResp ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if embedResp.Error != "" {
+		return nil, fmt.Errorf("ollama embed error: %s", embedResp.Error)
+	}
+	if len(embedResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(embedResp.Embeddings), len(texts))
+	}
+
+	return embedResp.Embeddings, nil
+}
@@ -1,35 +1,73 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 // OllamaClient handles communication with local Ollama instance
 type OllamaClient struct {
-	baseURL    string
-	model      string
-	httpClient *http.Client
+	baseURL      string
+	model        string
+	httpClient   *http.Client
+	streamClient *http.Client
+	// contextWindow is sent as num_ctx so Ollama doesn't truncate the
+	// prompt at its own, smaller default
+	contextWindow int
 }
 
 // OllamaRequest represents a request to Ollama
 type OllamaRequest struct {
-	Model    string    `json:"model"`
-	Prompt   string    `json:"prompt,omitempty"`
-	Messages []Message `json:"messages,omitempty"`
-	Stream   bool      `json:"stream"`
-	Options  *Options  `json:"options,omitempty"`
+	Model    string          `json:"model"`
+	Prompt   string          `json:"prompt,omitempty"`
+	Messages []Message       `json:"messages,omitempty"`
+	Tools    []OllamaTool    `json:"tools,omitempty"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  *Options        `json:"options,omitempty"`
 }
 
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolName and ToolCallID identify the call a "tool" message answers
+	ToolName   string `json:"tool_name,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is a model's request to run a tool. The shape matches Ollama's
+// wire format; other providers convert to and from it.
+type ToolCall struct {
+	ID       string           `json:"id,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the tool being called and its JSON arguments
+type ToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// OllamaTool describes a callable function to Ollama
+type OllamaTool struct {
+	Type     string             `json:"type"`
+	Function OllamaToolFunction `json:"function"`
+}
+
+// OllamaToolFunction is the function half of an OllamaTool
+type OllamaToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
 }
 
 // Options represents Ollama model options
@@ -37,17 +75,23 @@ type Options struct {
 	Temperature float64 `json:"temperature,omitempty"`
 	TopP        float64 `json:"top_p,omitempty"`
 	MaxTokens   int     `json:"num_predict,omitempty"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
 }
 
 // OllamaResponse represents a response from Ollama
 type OllamaResponse struct {
-	Model     string  `json:"model"`
-	Response  string  `json:"response"`
-	Message   Message `json:"message,omitempty"`
-	Done      bool    `json:"done"`
-	TotalDuration int64 `json:"total_duration,omitempty"`
+	Model         string  `json:"model"`
+	Response      string  `json:"response"`
+	Message       Message `json:"message,omitempty"`
+	Done          bool    `json:"done"`
+	TotalDuration int64   `json:"total_duration,omitempty"`
+	Error         string  `json:"error,omitempty"`
 }
 
+// DeltaFunc receives each chunk of a streamed response as it arrives.
+// Returning an error aborts the stream.
+type DeltaFunc func(delta string) error
+
 // NewOllamaClient creates a new Ollama client
 func NewOllamaClient(baseURL, model string) *OllamaClient {
 	return &OllamaClient{
@@ -56,9 +100,40 @@ func NewOllamaClient(baseURL, model string) *OllamaClient {
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		// Streams can legitimately outlive a fixed client timeout, so only
+		// bound the wait for the first byte and rely on ctx for the rest.
+		streamClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 120 * time.Second,
+			},
+		},
 	}
 }
 
+// Name returns the provider name
+func (c *OllamaClient) Name() string {
+	return "ollama"
+}
+
+// Model returns the Ollama model used for requests
+func (c *OllamaClient) Model() string {
+	return c.model
+}
+
+// SetContextWindow sets the context size, in tokens, Ollama loads the
+// model with
+func (c *OllamaClient) SetContextWindow(tokens int) {
+	c.contextWindow = tokens
+}
+
+// WithModel returns a client for another model on the same Ollama server
+func (c *OllamaClient) WithModel(model string) Provider {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
 // Generate sends a prompt to Ollama and returns the response
 func (c *OllamaClient) Generate(ctx context.Context, prompt string) (string, error) {
 	req := OllamaRequest{
@@ -68,87 +143,164 @@ func (c *OllamaClient) Generate(ctx context.Context, prompt string) (string, err
 		Options: &Options{
 			Temperature: 0.7,
 			MaxTokens:   2048,
+			NumCtx:      c.contextWindow,
 		},
 	}
 
 	return c.doRequest(ctx, "/api/generate", req)
 }
 
-// Chat sends a chat conversation to Ollama
-func (c *OllamaClient) Chat(ctx context.Context, messages []Message) (string, error) {
+// Chat sends a chat conversation to Ollama and returns the assistant's
+// reply, which may contain tool calls when tools were offered
+func (c *OllamaClient) Chat(ctx context.Context, chatReq *ChatRequest) (*Message, error) {
+	return c.doChatRequest(ctx, "/api/chat", c.buildChatRequest(chatReq, false))
+}
+
+// ChatStream sends a chat conversation to Ollama with streaming enabled,
+// calling onDelta for every token chunk. It returns the full reply once
+// Ollama reports done.
+func (c *OllamaClient) ChatStream(ctx context.Context, chatReq *ChatRequest, onDelta DeltaFunc) (*Message, error) {
+	return c.doChatStreamRequest(ctx, "/api/chat", c.buildChatRequest(chatReq, true), onDelta)
+}
+
+func (c *OllamaClient) buildChatRequest(chatReq *ChatRequest, stream bool) OllamaRequest {
 	req := OllamaRequest{
 		Model:    c.model,
-		Messages: messages,
-		Stream:   false,
+		Messages: chatReq.Messages,
+		Format:   chatReq.Format,
+		Stream:   stream,
 		Options: &Options{
 			Temperature: 0.7,
 			MaxTokens:   2048,
+			NumCtx:      c.contextWindow,
 		},
 	}
 
-	return c.doChatRequest(ctx, "/api/chat", req)
+	for _, tool := range chatReq.Tools {
+		req.Tools = append(req.Tools, OllamaTool{
+			Type: "function",
+			Function: OllamaToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	return req
 }
 
 func (c *OllamaClient) doRequest(ctx context.Context, endpoint string, req OllamaRequest) (string, error) {
-	body, err := json.Marshal(req)
+	resp, err := c.post(ctx, c.httpClient, endpoint, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+endpoint, bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+	var ollamaResp OllamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
+	return ollamaResp.Response, nil
+}
+
+func (c *OllamaClient) doChatRequest(ctx context.Context, endpoint string, req OllamaRequest) (*Message, error) {
+	resp, err := c.post(ctx, c.httpClient, endpoint, req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
 	var ollamaResp OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return ollamaResp.Response, nil
+	return &ollamaResp.Message, nil
 }
 
-func (c *OllamaClient) doChatRequest(ctx context.Context, endpoint string, req OllamaRequest) (string, error) {
+func (c *OllamaClient) doChatStreamRequest(ctx context.Context, endpoint string, req OllamaRequest, onDelta DeltaFunc) (*Message, error) {
+	resp, err := c.post(ctx, c.streamClient, endpoint, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Ollama streams newline-delimited JSON objects, one per chunk. Tool
+	// calls arrive whole in a chunk rather than as deltas.
+	reply := &Message{Role: "assistant"}
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk OllamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+
+		reply.ToolCalls = append(reply.ToolCalls, chunk.Message.ToolCalls...)
+		if delta := chunk.Message.Content; delta != "" {
+			full.WriteString(delta)
+			if onDelta != nil {
+				if err := onDelta(delta); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if chunk.Done {
+			reply.Content = full.String()
+			return reply, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil, fmt.Errorf("stream ended before completion")
+}
+
+// post sends a JSON request to Ollama and returns the response once it has
+// a 200 status; the caller must close the body
+func (c *OllamaClient) post(ctx context.Context, client *http.Client, endpoint string, req OllamaRequest) (*http.Response, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+endpoint, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("%w: ollama request failed: %v", ErrProviderUnavailable, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
 
-	var ollamaResp OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		// Models without tool support (e.g. llama3) reject the tools field
+		// outright; that is a capability gap, not an outage
+		if resp.StatusCode == http.StatusBadRequest && len(req.Tools) > 0 && bytes.Contains(bodyBytes, []byte("does not support tools")) {
+			return nil, fmt.Errorf("%w: %s", ErrToolsUnsupported, string(bodyBytes))
+		}
+		return nil, fmt.Errorf("%w: ollama error (status %d): %s", ErrProviderUnavailable, resp.StatusCode, string(bodyBytes))
 	}
 
-	return ollamaResp.Message.Content, nil
+	return resp, nil
 }
 
 // IsAvailable checks if Ollama is running and responsive
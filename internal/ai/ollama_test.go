@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeOllama streams body as the reply to every chat request
+func fakeOllama(t *testing.T, body string) *OllamaClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		if r.URL.Path != "/api/chat" || json.NewDecoder(r.Body).Decode(&req) != nil || !req.Stream {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return NewOllamaClient(srv.URL, "llama3")
+}
+
+func TestOllamaChatStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		deltas  []string
+		content string
+		tool    string
+		err     string
+	}{
+		{
+			name: "chunks",
+			body: `{"message": {"role": "assistant", "content": "Milk is "}, "done": false}` + "\n\n" +
+				`{"message": {"role": "assistant", "content": "in aisle 3."}, "done": false}` + "\n" +
+				`{"message": {"role": "assistant", "content": ""}, "done": true}` + "\n",
+			deltas:  []string{"Milk is ", "in aisle 3."},
+			content: "Milk is in aisle 3.",
+		},
+		{
+			name: "tool call",
+			body: `{"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "get_inventory", "arguments": {"sku": "milk"}}}]}, "done": false}` + "\n" +
+				`{"done": true}`,
+			tool: "get_inventory",
+		},
+		{
+			name:   "stream error",
+			body:   `{"message": {"content": "Milk"}, "done": false}` + "\n" + `{"error": "model crashed"}` + "\n",
+			deltas: []string{"Milk"},
+			err:    "model crashed",
+		},
+		{
+			name:   "cut off",
+			body:   `{"message": {"content": "Milk"}, "done": false}` + "\n",
+			deltas: []string{"Milk"},
+			err:    "stream ended before completion",
+		},
+		{
+			name: "bad chunk",
+			body: "not json\n",
+			err:  "failed to decode stream chunk",
+		},
+	}
+	for _, tt := range tests {
+		client := fakeOllama(t, tt.body)
+		var deltas []string
+		reply, err := client.ChatStream(context.Background(), &ChatRequest{}, func(d string) error {
+			deltas = append(deltas, d)
+			return nil
+		})
+
+		if strings.Join(deltas, "|") != strings.Join(tt.deltas, "|") {
+			t.Errorf("%s: deltas = %q, want %q", tt.name, deltas, tt.deltas)
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ChatStream: %v", tt.name, err)
+			continue
+		}
+		if reply.Content != tt.content {
+			t.Errorf("%s: Content = %q, want %q", tt.name, reply.Content, tt.content)
+		}
+		if tt.tool != "" && (len(reply.ToolCalls) != 1 || reply.ToolCalls[0].Function.Name != tt.tool) {
+			t.Errorf("%s: ToolCalls = %+v, want one %s call", tt.name, reply.ToolCalls, tt.tool)
+		}
+	}
+}
+
+func TestOllamaChatStreamStopsWhenDeltaFails(t *testing.T) {
+	client := fakeOllama(t, `{"message": {"content": "Milk"}, "done": false}`+"\n"+`{"message": {"content": " is"}, "done": false}`+"\n"+`{"done": true}`)
+	stop := errors.New("client went away")
+
+	calls := 0
+	_, err := client.ChatStream(context.Background(), &ChatRequest{}, func(string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("got %v after %d deltas, want the delta error after 1", err, calls)
+	}
+}
@@ -0,0 +1,226 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeptStore is reported as the department for answers the orchestrator
+// assembled from several department agents
+const DeptStore Department = "store"
+
+// storeWidePhrases signal a question about every department
+var storeWidePhrases = []string{
+	"across the store", "all departments", "every department", "each department",
+	"store-wide", "storewide", "whole store", "entire store", "all over the store",
+}
+
+const synthesisPrompt = `You are Opus, an AI assistant for grocery store operations, answering a store manager's question that spans several departments.
+
+You are given reports from each department's assistant. Combine them into one concise answer. Attribute every fact to its department by starting the relevant line or section with the department name in bold, e.g. **Dairy:**. Keep the departments' numbers and details exactly as reported, along with citation markers such as [3] next to the facts they support. If a department had no relevant information or could not be reached, say so briefly. Finish with the most important next steps across the store, if any.`
+
+// Orchestrator answers store-level questions by consulting several
+// department agents concurrently and merging their answers
+type Orchestrator struct {
+	router      *Router
+	synthesizer *Agent
+	mentions    map[Department]*regexp.Regexp
+	timeout     time.Duration
+}
+
+// departmentReport is one department's contribution to a fan-out answer
+type departmentReport struct {
+	dept   Department
+	result *QueryResult
+	err    error
+}
+
+// NewOrchestrator creates an orchestrator over the router's agents.
+// timeout is the shared deadline for all department agents.
+func NewOrchestrator(router *Router, timeout time.Duration) *Orchestrator {
+	o := &Orchestrator{
+		router: router,
+		synthesizer: &Agent{
+			department:   DeptStore,
+			providers:    router.providers,
+			systemPrompt: synthesisPrompt,
+		},
+		mentions: make(map[Department]*regexp.Regexp),
+		timeout:  timeout,
+	}
+
+	// A department is mentioned by its ID or display name, so "front end"
+	// and "front-end" both name the frontend department
+	for _, def := range router.definitions {
+		names := []string{regexp.QuoteMeta(string(def.ID))}
+		if name := strings.ToLower(def.Name); name != string(def.ID) {
+			words := strings.Fields(name)
+			for i, word := range words {
+				words[i] = regexp.QuoteMeta(word)
+			}
+			names = append(names, strings.Join(words, `[ -]`))
+		}
+		o.mentions[def.ID] = regexp.MustCompile(`\b(` + strings.Join(names, "|") + `)\b`)
+	}
+
+	return o
+}
+
+// Departments returns the departments a query should fan out to, or nil
+// when it is a single-department question. Store-wide phrasing fans out
+// to every department; naming two or more departments fans out to those.
+func (o *Orchestrator) Departments(query string) []Department {
+	query = strings.ToLower(query)
+
+	for _, phrase := range storeWidePhrases {
+		if strings.Contains(query, phrase) {
+			return o.router.matcher.Departments()
+		}
+	}
+
+	var depts []Department
+	for _, dept := range o.router.matcher.Departments() {
+		if o.mentions[dept].MatchString(query) {
+			depts = append(depts, dept)
+		}
+	}
+	if len(depts) < 2 {
+		return nil
+	}
+	return depts
+}
+
+// ProcessQuery asks each department's agent concurrently, then has the
+// model merge their answers. Departments that fail or miss the deadline
+// are reported as unavailable rather than failing the whole answer.
+func (o *Orchestrator) ProcessQuery(ctx context.Context, query string, history []Message, depts []Department, onDelta DeltaFunc) (*QueryResult, error) {
+	reports := o.consult(ctx, query, history, depts)
+
+	var sections []string
+	var consulted []Department
+	var passages []Passage
+	var actions []Action
+	var model string
+	for i := range reports {
+		report := &reports[i]
+		if report.err != nil {
+			log.Printf("Fan-out: %s agent failed: %v", report.dept, report.err)
+			sections = append(sections, fmt.Sprintf("[%s]\n(unavailable)", report.dept))
+			continue
+		}
+		if model == "" {
+			model = report.result.Model
+		}
+		report.result.Response, passages = renumberCitations(report.result, passages)
+		actions = append(actions, report.result.Actions...)
+		consulted = append(consulted, report.dept)
+		sections = append(sections, fmt.Sprintf("[%s]\n%s", report.dept, report.result.Response))
+	}
+
+	if len(consulted) == 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("no department could answer: %w", reports[0].err)
+	}
+
+	messages := []Message{
+		{Role: "system", Content: o.synthesizer.systemPrompt},
+		{Role: "user", Content: fmt.Sprintf("Question: %s\n\nDepartment reports:\n\n%s", query, strings.Join(sections, "\n\n"))},
+	}
+
+	result, err := o.synthesizer.run(ctx, messages, onDelta != nil, onDelta)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		// Still give the manager the department answers, just unmerged
+		log.Printf("Fan-out synthesis failed, returning department reports: %v", err)
+		result = &QueryResult{Response: mergeReports(reports), Model: model}
+		if onDelta != nil {
+			if err := onDelta(result.Response); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Department = DeptStore
+	result.Departments = consulted
+	result.Citations = citedPassages(result.Response, passages)
+	result.Actions = actions
+	return result, nil
+}
+
+// consult runs every department's agent concurrently under one deadline
+// and returns their reports in department order
+func (o *Orchestrator) consult(ctx context.Context, query string, history []Message, depts []Department) []departmentReport {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	reports := make([]departmentReport, len(depts))
+	var wg sync.WaitGroup
+	for i, dept := range depts {
+		reports[i].dept = dept
+
+		agent, ok := o.router.Agent(dept)
+		if !ok {
+			reports[i].err = fmt.Errorf("no agent for department %s", dept)
+			continue
+		}
+
+		wg.Add(1)
+		go func(report *departmentReport, agent *Agent) {
+			defer wg.Done()
+			report.result, report.err = agent.ProcessQuery(ctx, query, history)
+		}(&reports[i], agent)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+// renumberCitations rewrites the citations in a department's answer to
+// their position in passages, the combined list for the whole fan-out, so
+// numbers from different departments don't collide
+func renumberCitations(result *QueryResult, passages []Passage) (string, []Passage) {
+	numbers := make(map[string]string)
+	for _, c := range result.Citations {
+		passages = append(passages, c.Passage)
+		numbers[strconv.Itoa(c.Number)] = strconv.Itoa(len(passages))
+	}
+
+	response := citationPattern.ReplaceAllStringFunc(result.Response, func(match string) string {
+		var renumbered []string
+		for _, field := range strings.Split(strings.Trim(match, "[]"), ",") {
+			if n, ok := numbers[strings.TrimSpace(field)]; ok {
+				renumbered = append(renumbered, n)
+			}
+		}
+		if len(renumbered) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(renumbered, ", ") + "]"
+	})
+
+	return response, passages
+}
+
+// mergeReports joins department answers under bold department headings
+func mergeReports(reports []departmentReport) string {
+	var parts []string
+	for _, report := range reports {
+		if report.err != nil {
+			parts = append(parts, fmt.Sprintf("**%s:** unavailable right now.", report.dept))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("**%s:** %s", report.dept, report.result.Response))
+	}
+	return strings.Join(parts, "\n\n")
+}
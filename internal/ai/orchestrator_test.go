@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testDefinitions = []DepartmentDefinition{
+	{ID: DeptDairy, Name: "Dairy", Keywords: []string{"milk"}},
+	{ID: DeptMeat, Name: "Meat", Keywords: []string{"beef"}},
+	{ID: DeptFrontEnd, Name: "Front End", Keywords: []string{"register"}},
+}
+
+// fanOutProvider answers each department from answers, keyed by the
+// department name in the system prompt, and stalls on departments not in
+// it until the request is cancelled. The synthesis request fails with
+// failSynthesis if set, and its department reports are kept in reports.
+type fanOutProvider struct {
+	scriptedProvider
+	answers       map[string]string
+	failSynthesis error
+
+	mu      sync.Mutex
+	reports string
+}
+
+func (p *fanOutProvider) Chat(ctx context.Context, req *ChatRequest) (*Message, error) {
+	system := req.Messages[0].Content
+	if system == synthesisPrompt {
+		p.mu.Lock()
+		p.reports = req.Messages[1].Content
+		p.mu.Unlock()
+		if p.failSynthesis != nil {
+			return nil, p.failSynthesis
+		}
+		return &Message{Role: "assistant", Content: "Merged."}, nil
+	}
+
+	for name, answer := range p.answers {
+		if strings.Contains(system, "assisting the "+name+" department") {
+			return &Message{Role: "assistant", Content: answer}, nil
+		}
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *fanOutProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta DeltaFunc) (*Message, error) {
+	reply, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return reply, onDelta(reply.Content)
+}
+
+func TestOrchestratorDepartments(t *testing.T) {
+	router, err := NewRouter(testDefinitions, NewToolRegistry(), &scriptedProvider{name: "primary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []Department
+	}{
+		{"How are sales across the store?", []Department{DeptDairy, DeptMeat, DeptFrontEnd}},
+		{"Compare dairy and meat shrink", []Department{DeptDairy, DeptMeat}},
+		{"Who covers the front end and the meat counter?", []Department{DeptMeat, DeptFrontEnd}},
+		{"Is the front-end short for dairy?", []Department{DeptDairy, DeptFrontEnd}},
+		{"How much milk is in dairy?", nil},
+		{"Where is the beef?", nil},
+	}
+	for _, tt := range tests {
+		if got := router.orchestrator.Departments(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Departments(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFanOutReportsDepartmentsThatMissTheDeadline(t *testing.T) {
+	provider := &fanOutProvider{
+		scriptedProvider: scriptedProvider{name: "primary"},
+		answers:          map[string]string{"Dairy": "Milk is low.", "Front End": "Two lanes open."},
+	}
+	router, err := NewRouter(testDefinitions, NewToolRegistry(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orchestrator := NewOrchestrator(router, 50*time.Millisecond)
+
+	start := time.Now()
+	result, err := orchestrator.ProcessQuery(context.Background(), "How is every department doing?", nil, router.matcher.Departments(), nil)
+	if err != nil {
+		t.Fatalf("ProcessQuery: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fan-out took %s, want the stalled department cut off at the deadline", elapsed)
+	}
+
+	if result.Response != "Merged." || result.Department != DeptStore {
+		t.Errorf("got %q from %q, want the synthesis from store", result.Response, result.Department)
+	}
+	if !slices.Equal(result.Departments, []Department{DeptDairy, DeptFrontEnd}) {
+		t.Errorf("Departments = %v, want dairy and frontend", result.Departments)
+	}
+	for _, want := range []string{"[dairy]\nMilk is low.", "[meat]\n(unavailable)", "[frontend]\nTwo lanes open."} {
+		if !strings.Contains(provider.reports, want) {
+			t.Errorf("synthesis input is missing %q:\n%s", want, provider.reports)
+		}
+	}
+}
+
+func TestFanOutFallsBackToReportsWhenSynthesisFails(t *testing.T) {
+	provider := &fanOutProvider{
+		scriptedProvider: scriptedProvider{name: "primary"},
+		answers:          map[string]string{"Dairy": "Milk is low.", "Meat": "Beef is fine.", "Front End": "Two lanes open."},
+		failSynthesis:    errors.New("bad answer"),
+	}
+	router, err := NewRouter(testDefinitions, NewToolRegistry(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var streamed string
+	result, err := router.orchestrator.ProcessQuery(context.Background(), "Compare dairy and meat", nil, []Department{DeptDairy, DeptMeat}, func(d string) error {
+		streamed += d
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessQuery: %v", err)
+	}
+	want := "**dairy:** Milk is low.\n\n**meat:** Beef is fine."
+	if result.Response != want || streamed != want {
+		t.Errorf("got %q streaming %q, want the merged reports", result.Response, streamed)
+	}
+}
+
+func TestFanOutFailsWhenNoDepartmentAnswers(t *testing.T) {
+	provider := &fanOutProvider{scriptedProvider: scriptedProvider{name: "primary"}}
+	router, err := NewRouter(testDefinitions, NewToolRegistry(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orchestrator := NewOrchestrator(router, 10*time.Millisecond)
+
+	if _, err := orchestrator.ProcessQuery(context.Background(), "Compare dairy and meat", nil, []Department{DeptDairy, DeptMeat}, nil); err == nil {
+		t.Fatal("ProcessQuery succeeded with every department timed out")
+	}
+}
+
+func TestRenumberCitations(t *testing.T) {
+	earlier := []Passage{{Source: "Cooler SOP"}}
+	result := &QueryResult{
+		Response: "Rotate stock [1] and log temps [2, 1]. See also [9].",
+		Citations: []Citation{
+			{Number: 1, Passage: Passage{Source: "Rotation guide"}},
+			{Number: 2, Passage: Passage{Source: "Temperature log"}},
+		},
+	}
+
+	response, passages := renumberCitations(result, earlier)
+	if response != "Rotate stock [2] and log temps [3, 2]. See also ." {
+		t.Errorf("response = %q", response)
+	}
+	if len(passages) != 3 || passages[1].Source != "Rotation guide" || passages[2].Source != "Temperature log" {
+		t.Errorf("passages = %+v", passages)
+	}
+}
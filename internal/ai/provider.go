@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrProviderUnavailable marks failures that mean the provider could not
+// answer at all (unreachable, timed out, non-200), as opposed to a bad
+// answer. Only these trigger failover to the next provider.
+var ErrProviderUnavailable = errors.New("provider unavailable")
+
+// ErrToolsUnsupported is returned when the provider's model cannot do tool
+// calling. Callers should retry the same provider without tools.
+var ErrToolsUnsupported = errors.New("model does not support tools")
+
+// ChatRequest is a provider-neutral chat completion request
+type ChatRequest struct {
+	Messages []Message
+	Tools    []ToolDefinition
+	// Format is a JSON schema the reply must conform to. When set, the
+	// reply's Content is the JSON document.
+	Format json.RawMessage
+}
+
+// ToolDefinition describes a tool to the model
+type ToolDefinition struct {
+	Name        string
+	Description string
+	// Parameters is a JSON schema object for the tool's arguments
+	Parameters json.RawMessage
+}
+
+// Provider is an LLM backend that department agents can send chats to
+type Provider interface {
+	// Name identifies the backend, e.g. "ollama" or "claude"
+	Name() string
+	// Model is the model the provider answers with
+	Model() string
+	Chat(ctx context.Context, req *ChatRequest) (*Message, error)
+	ChatStream(ctx context.Context, req *ChatRequest, onDelta DeltaFunc) (*Message, error)
+	IsAvailable(ctx context.Context) bool
+}
+
+// shouldFailover reports whether err from a provider call means the next
+// provider should be tried. A cancelled caller never fails over.
+func shouldFailover(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && errors.Is(err, ErrProviderUnavailable)
+}
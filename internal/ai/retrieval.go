@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Passage is an excerpt from a store document, such as an SOP or HACCP
+// plan, retrieved as reference material for a query
+type Passage struct {
+	// Source is the document the passage came from, e.g. its title
+	Source  string
+	Section string
+	// Page is set for documents extracted from PDFs
+	Page  int
+	Text  string
+	Score float64
+}
+
+// Retriever finds the passages most relevant to a query for a department
+type Retriever interface {
+	Retrieve(ctx context.Context, dept Department, query string) ([]Passage, error)
+}
+
+// citationPattern matches citations like [2] or [1, 3]
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+const referencePrompt = `Reference passages from the store's documents:
+
+%s
+
+Use these passages when they answer the question. Cite each passage you rely on by its number in square brackets, e.g. [1]. Don't cite passages you didn't use, and don't quote procedures the passages don't contain.`
+
+// referenceMessage formats passages as a numbered list for the model
+func referenceMessage(passages []Passage) Message {
+	var b strings.Builder
+	for i, p := range passages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[%d] %s\n%s", i+1, p.Citation(), strings.TrimSpace(p.Text))
+	}
+	return Message{Role: "system", Content: fmt.Sprintf(referencePrompt, b.String())}
+}
+
+// Citation describes where the passage came from, e.g.
+// "Dairy Cooler SOP, Temperature Checks, p. 3"
+func (p Passage) Citation() string {
+	parts := []string{p.Source}
+	if p.Section != "" {
+		parts = append(parts, p.Section)
+	}
+	if p.Page > 0 {
+		parts = append(parts, fmt.Sprintf("p. %d", p.Page))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// citedPassages returns the passages a response cites, keyed by the number
+// the model used, in citation order
+func citedPassages(response string, passages []Passage) []Citation {
+	var cited []Citation
+	seen := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(response, -1) {
+		for _, field := range strings.Split(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || n < 1 || n > len(passages) || seen[n] {
+				continue
+			}
+			seen[n] = true
+			cited = append(cited, Citation{Number: n, Passage: passages[n-1]})
+		}
+	}
+	return cited
+}
+
+// Citation is a passage an answer refers to as [Number]
+type Citation struct {
+	Number int
+	Passage
+}
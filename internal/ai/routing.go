@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RouteResult is the outcome of deciding which department handles a query
+type RouteResult struct {
+	Department Department
+	// Confidence is between 0 and 1
+	Confidence float64
+	// Ambiguous is set when no single department clearly fits; Candidates
+	// then lists the departments the user should choose between
+	Ambiguous  bool
+	Candidates []Department
+	// Method is how the decision was made: "classifier" or "keyword"
+	Method string
+}
+
+// RoutingRule lists the keywords that send a query to a department
+type RoutingRule struct {
+	Department Department
+	Keywords   []string
+}
+
+// KeywordMatcher scores queries against routing rules. Unlike iterating a
+// map it always gives the same answer for the same query, and it reports
+// ties instead of picking one arbitrarily.
+type KeywordMatcher struct {
+	rules    []RoutingRule
+	patterns [][]*regexp.Regexp
+}
+
+// NewKeywordMatcher creates a matcher for rules
+func NewKeywordMatcher(rules []RoutingRule) *KeywordMatcher {
+	m := &KeywordMatcher{rules: rules}
+	for _, rule := range rules {
+		var patterns []*regexp.Regexp
+		for _, keyword := range rule.Keywords {
+			// Whole words only, allowing simple plurals, so "cart" doesn't
+			// match "cartons" but "eggs" still matches "egg"
+			pattern := `\b` + regexp.QuoteMeta(strings.ToLower(keyword)) + `(s|es)?\b`
+			patterns = append(patterns, regexp.MustCompile(pattern))
+		}
+		m.patterns = append(m.patterns, patterns)
+	}
+	return m
+}
+
+// Departments returns the departments the matcher knows about, in rule order
+func (m *KeywordMatcher) Departments() []Department {
+	depts := make([]Department, len(m.rules))
+	for i, rule := range m.rules {
+		depts[i] = rule.Department
+	}
+	return depts
+}
+
+// Keywords returns the routing keywords for dept
+func (m *KeywordMatcher) Keywords(dept Department) []string {
+	for _, rule := range m.rules {
+		if rule.Department == dept {
+			return rule.Keywords
+		}
+	}
+	return nil
+}
+
+// Match scores every department against query. Multi-word keywords count
+// once per word since they are more specific. The result is ambiguous when
+// nothing matches or the best score is tied.
+func (m *KeywordMatcher) Match(query string) *RouteResult {
+	query = strings.ToLower(query)
+
+	type score struct {
+		dept  Department
+		order int
+		value int
+	}
+
+	var scores []score
+	total := 0
+	for i, rule := range m.rules {
+		value := 0
+		for j, pattern := range m.patterns[i] {
+			if pattern.MatchString(query) {
+				value += len(strings.Fields(rule.Keywords[j]))
+			}
+		}
+		if value > 0 {
+			scores = append(scores, score{rule.Department, i, value})
+			total += value
+		}
+	}
+
+	result := &RouteResult{Method: "keyword"}
+	if len(scores) == 0 {
+		result.Ambiguous = true
+		result.Candidates = m.Departments()
+		return result
+	}
+
+	sort.SliceStable(scores, func(a, b int) bool {
+		if scores[a].value != scores[b].value {
+			return scores[a].value > scores[b].value
+		}
+		return scores[a].order < scores[b].order
+	})
+
+	best := scores[0]
+	result.Department = best.dept
+	result.Confidence = float64(best.value) / float64(total)
+
+	for _, s := range scores {
+		if s.value == best.value {
+			result.Candidates = append(result.Candidates, s.dept)
+		}
+	}
+	if len(result.Candidates) > 1 {
+		result.Ambiguous = true
+	} else {
+		result.Candidates = nil
+	}
+
+	return result
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StoreData is the store information department tools look up. Results
+// are marshaled to JSON for the model, so implementations can return
+// whatever their domain types are.
+type StoreData interface {
+	// Inventory returns the department's items, or just the one matching
+	// sku when it is set
+	Inventory(ctx context.Context, dept Department, sku string) (interface{}, error)
+	// Schedule returns the department's shifts and coverage on date
+	Schedule(ctx context.Context, dept Department, date time.Time) (interface{}, error)
+	// Alerts returns the department's active alerts
+	Alerts(ctx context.Context, dept Department) (interface{}, error)
+	// Lanes returns checkout lane status and today's sales, for the
+	// department that runs the registers
+	Lanes(ctx context.Context, dept Department) (interface{}, error)
+}
+
+type getInventoryArgs struct {
+	Dept string `json:"dept"`
+	SKU  string `json:"sku"`
+}
+
+type getScheduleArgs struct {
+	Dept string `json:"dept"`
+	Date string `json:"date"`
+}
+
+type listAlertsArgs struct {
+	Dept string `json:"dept"`
+}
+
+type getLanesArgs struct {
+	Dept string `json:"dept"`
+}
+
+// RegisterStoreTools adds the get_inventory, get_schedule, list_alerts and
+// get_lanes tools backed by data. Each agent may only read its own department.
+func RegisterStoreTools(reg *ToolRegistry, data StoreData) {
+	reg.Register(&Tool{
+		Name:        "get_inventory",
+		Description: "Look up current inventory for a department: on-hand quantity, reorder point, par level and lot expiration dates. Pass a SKU to get a single item.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"dept": {"type": "string", "description": "Department ID, e.g. dairy"},
+				"sku": {"type": "string", "description": "Optional SKU to look up a single item"}
+			},
+			"required": ["dept"]
+		}`),
+		Handler: func(ctx context.Context, dept Department, raw json.RawMessage) (interface{}, error) {
+			var args getInventoryArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if err := checkToolDepartment(dept, args.Dept); err != nil {
+				return nil, err
+			}
+			return data.Inventory(ctx, dept, args.SKU)
+		},
+	})
+
+	reg.Register(&Tool{
+		Name:        "get_schedule",
+		Description: "Look up a department's schedule for a date: shifts with breaks, time-off requests, and hourly coverage against staffing targets. Hours in \"gaps\" are short-staffed.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"dept": {"type": "string", "description": "Department ID, e.g. dairy"},
+				"date": {"type": "string", "description": "Date as YYYY-MM-DD; defaults to today"}
+			},
+			"required": ["dept"]
+		}`),
+		Handler: func(ctx context.Context, dept Department, raw json.RawMessage) (interface{}, error) {
+			var args getScheduleArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if err := checkToolDepartment(dept, args.Dept); err != nil {
+				return nil, err
+			}
+
+			date := time.Now()
+			if args.Date != "" {
+				parsed, err := time.ParseInLocation("2006-01-02", args.Date, time.Local)
+				if err != nil {
+					return nil, fmt.Errorf("date must be YYYY-MM-DD")
+				}
+				date = parsed
+			}
+			return data.Schedule(ctx, dept, date)
+		},
+	})
+
+	reg.Register(&Tool{
+		Name:        "list_alerts",
+		Description: "List the open alerts for a department: low or out of stock items, expiring or expired product, cooler and freezer temperatures, and staffing gaps. Each has an ID, severity (info, warning, critical) and status (active, acknowledged, snoozed).",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"dept": {"type": "string", "description": "Department ID, e.g. dairy"}
+			},
+			"required": ["dept"]
+		}`),
+		Handler: func(ctx context.Context, dept Department, raw json.RawMessage) (interface{}, error) {
+			var args listAlertsArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if err := checkToolDepartment(dept, args.Dept); err != nil {
+				return nil, err
+			}
+			return data.Alerts(ctx, dept)
+		},
+	})
+
+	reg.Register(&Tool{
+		Name:        "get_lanes",
+		Description: "Look up checkout lanes: each lane's status (open, closed, offline, needs_assistance), cashier, and transactions and sales today, plus today's sales per hour and best-selling items.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"dept": {"type": "string", "description": "Department ID, e.g. frontend"}
+			},
+			"required": ["dept"]
+		}`),
+		Handler: func(ctx context.Context, dept Department, raw json.RawMessage) (interface{}, error) {
+			var args getLanesArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if err := checkToolDepartment(dept, args.Dept); err != nil {
+				return nil, err
+			}
+			return data.Lanes(ctx, dept)
+		},
+	})
+}
+
+// checkToolDepartment keeps an agent from reading other departments' data.
+// An empty requested department means the agent's own.
+func checkToolDepartment(agentDept Department, requested string) error {
+	if requested == "" || Department(requested) == agentDept {
+		return nil
+	}
+	return fmt.Errorf("the %s agent can only access %s data", agentDept, agentDept)
+}
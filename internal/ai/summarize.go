@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const summaryPrompt = `You keep a running summary of a conversation between a grocery store manager and Opus, the store's AI assistant.
+
+You are given the summary so far, if any, and the messages that followed it. Write an updated summary that replaces both. Keep every fact the conversation may need later: products, SKUs, quantities, times, people, decisions made, tasks promised and questions still open, and which department each concerns. Drop greetings and small talk. Write terse notes of at most 200 words.`
+
+// summaryHeading introduces the summary message placed before the recent
+// history
+const summaryHeading = "Summary of the conversation so far:\n"
+
+// ContextManager keeps conversation history within the models' context
+// windows. When history grows past its share of the window, the oldest
+// messages are folded into a running summary.
+type ContextManager struct {
+	budget     *TokenBudget
+	providers  []Provider
+	summarizer *Agent
+}
+
+// Compaction is the history to send to an agent after fitting it into the
+// context window
+type Compaction struct {
+	// History is the summary message, if any, followed by the most recent
+	// messages
+	History []Message
+	// Summary covers every message no longer in History
+	Summary string
+	// Summarized is how many of the given messages were folded into
+	// Summary. It is zero when the summary didn't change.
+	Summarized int
+}
+
+// NewContextManager creates a context manager for providers. The summary
+// is written by the first provider able to answer.
+func NewContextManager(budget *TokenBudget, providers ...Provider) *ContextManager {
+	return &ContextManager{
+		budget:    budget,
+		providers: providers,
+		summarizer: &Agent{
+			providers:    providers,
+			systemPrompt: summaryPrompt,
+		},
+	}
+}
+
+// Window returns the smallest context window among the providers, since
+// any of them may end up answering
+func (m *ContextManager) Window() int {
+	window := 0
+	for _, provider := range m.providers {
+		if w := m.budget.Window(provider.Model()); window == 0 || w < window {
+			window = w
+		}
+	}
+	if window == 0 {
+		return defaultContextWindow
+	}
+	return window
+}
+
+// HistoryLimit is the share of the window history may use. The rest is
+// left for the system prompt, tool definitions and results, and the answer.
+func (m *ContextManager) HistoryLimit() int {
+	return m.Window() / 2
+}
+
+// Compact fits history, which follows summary, into the history limit.
+// When it doesn't fit, the oldest messages are summarized so the recent
+// ones take up no more than half the limit, leaving room for the
+// conversation to grow before the next summary. If the model can't write
+// the summary, the oldest messages are dropped for this request only.
+func (m *ContextManager) Compact(ctx context.Context, summary string, history []Message) *Compaction {
+	limit := m.HistoryLimit()
+	if EstimateHistoryTokens(withSummary(summary, history)) <= limit {
+		return &Compaction{History: withSummary(summary, history), Summary: summary}
+	}
+
+	cut := splitPoint(history, limit/2)
+	if cut == 0 {
+		return &Compaction{History: withSummary(summary, history), Summary: summary}
+	}
+
+	updated, err := m.summarize(ctx, summary, history[:cut])
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to summarize conversation, dropping %d older messages: %v", cut, err)
+		}
+		return &Compaction{History: withSummary(summary, history[cut:]), Summary: summary}
+	}
+
+	return &Compaction{
+		History:    withSummary(updated, history[cut:]),
+		Summary:    updated,
+		Summarized: cut,
+	}
+}
+
+func (m *ContextManager) summarize(ctx context.Context, summary string, messages []Message) (string, error) {
+	var b strings.Builder
+	if summary != "" {
+		fmt.Fprintf(&b, "Summary so far:\n%s\n\n", summary)
+	}
+	b.WriteString("Messages that followed:\n")
+	for _, msg := range messages {
+		switch msg.Role {
+		case "user":
+			fmt.Fprintf(&b, "\nManager: %s\n", msg.Content)
+		case "assistant":
+			fmt.Fprintf(&b, "\nOpus: %s\n", msg.Content)
+		}
+	}
+
+	result, err := m.summarizer.run(ctx, []Message{
+		{Role: "system", Content: m.summarizer.systemPrompt},
+		{Role: "user", Content: b.String()},
+	}, false, nil)
+	if err != nil {
+		return "", err
+	}
+
+	updated := strings.TrimSpace(result.Response)
+	if updated == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	return updated, nil
+}
+
+// splitPoint returns the index of the first message to keep so the kept
+// messages use at most keep tokens. The kept history always starts with a
+// user message so the model never sees an answer without its question.
+func splitPoint(history []Message, keep int) int {
+	cut := len(history)
+	used := 0
+	for cut > 0 {
+		used += EstimateTokens(history[cut-1])
+		if used > keep {
+			break
+		}
+		cut--
+	}
+
+	for cut < len(history) && history[cut].Role != "user" {
+		cut++
+	}
+	return cut
+}
+
+// withSummary puts the summary, if any, in front of history
+func withSummary(summary string, history []Message) []Message {
+	if summary == "" {
+		return history
+	}
+	messages := make([]Message, 0, len(history)+1)
+	messages = append(messages, Message{Role: "system", Content: summaryHeading + summary})
+	return append(messages, history...)
+}
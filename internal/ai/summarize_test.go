@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenBudgetWindow(t *testing.T) {
+	budget := NewTokenBudget(map[string]int{"Mistral-Small": 16000})
+
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"llama3", 8192},
+		{"llama3:8b", 8192},
+		{"llama3.1:70b", 131072},
+		{"claude-sonnet-4", 200000},
+		{"mistral-small:22b", 16000},
+		{"phi3", defaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := budget.Window(tt.model); got != tt.want {
+			t.Errorf("Window(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+// chatter returns n alternating user and assistant messages of 12 tokens
+// each
+func chatter(n int) []Message {
+	history := make([]Message, n)
+	for i := range history {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		history[i] = Message{Role: role, Content: fmt.Sprintf("message %02d about the dairy order", i)}
+	}
+	return history
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name       string
+		summary    string
+		history    []Message
+		reply      string
+		summarized int
+		kept       int
+		want       string
+	}{
+		{"fits", "", chatter(4), "unused", 0, 4, ""},
+		{"fits with a summary", "Ordered milk.", chatter(4), "unused", 0, 4, "Ordered milk."},
+		{"summarized", "", chatter(10), "Dairy order discussed.", 6, 4, "Dairy order discussed."},
+		{"summary failed", "Ordered milk.", chatter(10), "", 0, 4, "Ordered milk."},
+	}
+	for _, tt := range tests {
+		provider := &recordingProvider{scriptedProvider: scriptedProvider{name: "primary"}, reply: tt.reply}
+		// A 200 token window leaves 100 for history, compacted down to 50
+		manager := NewContextManager(NewTokenBudget(map[string]int{"primary-model": 200}), provider)
+
+		got := manager.Compact(context.Background(), tt.summary, tt.history)
+		if got.Summarized != tt.summarized || got.Summary != tt.want {
+			t.Errorf("%s: summarized %d into %q, want %d into %q", tt.name, got.Summarized, got.Summary, tt.summarized, tt.want)
+		}
+
+		history := got.History
+		if tt.want != "" {
+			if history[0].Role != "system" || history[0].Content != summaryHeading+tt.want {
+				t.Errorf("%s: first message %+v, want the summary", tt.name, history[0])
+				continue
+			}
+			history = history[1:]
+		}
+		if len(history) != tt.kept || history[0].Role != "user" {
+			t.Errorf("%s: kept %d messages starting with %q, want %d starting with a question", tt.name, len(history), history[0].Role, tt.kept)
+		}
+		if tt.summarized > 0 && !strings.Contains(provider.requests[0].Messages[1].Content, "Manager: message 00") {
+			t.Errorf("%s: the summarizer wasn't given the oldest messages", tt.name)
+		}
+	}
+}
+
+func TestSplitPointKeepsAQuestionFirst(t *testing.T) {
+	history := chatter(6)
+	perMessage := EstimateTokens(history[0])
+
+	tests := []struct {
+		name string
+		keep int
+		want int
+	}{
+		{"room for everything", 6 * perMessage, 0},
+		{"room for two", 2 * perMessage, 4},
+		{"room for three starts at an answer", 3 * perMessage, 4},
+		{"room for nothing", 0, 6},
+	}
+	for _, tt := range tests {
+		if got := splitPoint(history, tt.keep); got != tt.want {
+			t.Errorf("%s: splitPoint = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
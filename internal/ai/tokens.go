@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultContextWindows are the context sizes, in tokens, of the models Opus
+// is usually run with. Keys match a model name exactly or as a prefix, so
+// "llama3" also covers "llama3:8b" and "llama3:latest".
+var DefaultContextWindows = map[string]int{
+	"llama3":   8192,
+	"llama3.1": 131072,
+	"llama3.2": 131072,
+	"llama3.3": 131072,
+	"mistral":  32768,
+	"qwen2.5":  32768,
+	"claude":   200000,
+}
+
+// defaultContextWindow is used for models with no configured window. It is
+// deliberately small so unknown models are never overrun.
+const defaultContextWindow = 8192
+
+// messageOverhead approximates the tokens a chat template adds around each
+// message for its role markers
+const messageOverhead = 4
+
+// EstimateTokens approximates how many tokens a message uses. It assumes
+// about four characters per token, which is close for English text with
+// the Llama 3 and Claude tokenizers, and errs on the high side.
+func EstimateTokens(msg Message) int {
+	chars := len(msg.Content)
+	for _, call := range msg.ToolCalls {
+		chars += len(call.Function.Name) + len(call.Function.Arguments)
+	}
+	return (chars+3)/4 + messageOverhead
+}
+
+// EstimateHistoryTokens approximates the tokens used by messages
+func EstimateHistoryTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg)
+	}
+	return total
+}
+
+// TokenBudget knows the context window of each model
+type TokenBudget struct {
+	windows map[string]int
+	// prefixes holds the keys of windows, longest first, so "llama3.1"
+	// wins over "llama3"
+	prefixes []string
+}
+
+// NewTokenBudget creates a budget from DefaultContextWindows with
+// overrides applied on top
+func NewTokenBudget(overrides map[string]int) *TokenBudget {
+	b := &TokenBudget{windows: make(map[string]int)}
+	for model, tokens := range DefaultContextWindows {
+		b.windows[model] = tokens
+	}
+	for model, tokens := range overrides {
+		b.windows[strings.ToLower(model)] = tokens
+	}
+
+	for model := range b.windows {
+		b.prefixes = append(b.prefixes, model)
+	}
+	sort.Slice(b.prefixes, func(i, j int) bool {
+		if len(b.prefixes[i]) != len(b.prefixes[j]) {
+			return len(b.prefixes[i]) > len(b.prefixes[j])
+		}
+		return b.prefixes[i] < b.prefixes[j]
+	})
+
+	return b
+}
+
+// Window returns the context window for model in tokens
+func (b *TokenBudget) Window(model string) int {
+	model = strings.ToLower(model)
+	if tokens, ok := b.windows[model]; ok {
+		return tokens
+	}
+	for _, prefix := range b.prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return b.windows[prefix]
+		}
+	}
+	return defaultContextWindow
+}
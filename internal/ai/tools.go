@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ToolHandler runs a tool call on behalf of the agent for dept. The result
+// is marshaled to JSON and handed back to the model.
+type ToolHandler func(ctx context.Context, dept Department, args json.RawMessage) (interface{}, error)
+
+// Tool is a function the model may call while answering a query
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is a JSON schema object for the tool's arguments
+	Parameters json.RawMessage
+	// Departments limits which agents are offered the tool; empty means all
+	Departments []Department
+	Handler     ToolHandler
+}
+
+// Definition returns the description of the tool sent to providers
+func (t *Tool) Definition() ToolDefinition {
+	return ToolDefinition{
+		Name:        t.Name,
+		Description: t.Description,
+		Parameters:  t.Parameters,
+	}
+}
+
+// availableTo reports whether the agent for dept may use the tool
+func (t *Tool) availableTo(dept Department) bool {
+	if len(t.Departments) == 0 {
+		return true
+	}
+	for _, d := range t.Departments {
+		if d == dept {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolRegistry holds the tools department agents can call
+type ToolRegistry struct {
+	tools []*Tool
+	mu    sync.RWMutex
+}
+
+// NewToolRegistry creates an empty tool registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{}
+}
+
+// Register adds a tool, replacing any existing tool with the same name
+func (r *ToolRegistry) Register(tool *Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.tools {
+		if existing.Name == tool.Name {
+			r.tools[i] = tool
+			return
+		}
+	}
+	r.tools = append(r.tools, tool)
+}
+
+// ForDepartment returns the tools offered to the agent for dept
+func (r *ToolRegistry) ForDepartment(dept Department) []*Tool {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tools []*Tool
+	for _, tool := range r.tools {
+		if tool.availableTo(dept) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// findTool returns the tool named name, or nil
+func findTool(tools []*Tool, name string) *Tool {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+// runToolCall executes call against tools and returns the content of the
+// "tool" message to send back. Failures are reported to the model as an
+// error object so it can recover instead of the whole query failing.
+func runToolCall(ctx context.Context, dept Department, tools []*Tool, call ToolCall) string {
+	tool := findTool(tools, call.Function.Name)
+	if tool == nil {
+		return toolError(fmt.Errorf("unknown tool %q", call.Function.Name))
+	}
+
+	args := call.Function.Arguments
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	result, err := tool.Handler(ctx, dept, args)
+	if err != nil {
+		return toolError(err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return toolError(fmt.Errorf("failed to marshal result: %w", err))
+	}
+	return string(data)
+}
+
+func toolError(err error) string {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// toolProvider answers each request with reply(req), recording requests
+type toolProvider struct {
+	scriptedProvider
+	requests []*ChatRequest
+	reply    func(req *ChatRequest) *Message
+}
+
+func (p *toolProvider) Chat(ctx context.Context, req *ChatRequest) (*Message, error) {
+	p.requests = append(p.requests, req)
+	return p.reply(req), nil
+}
+
+func (p *toolProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta DeltaFunc) (*Message, error) {
+	return p.Chat(ctx, req)
+}
+
+func stockTool(calls *[]string) *Tool {
+	return &Tool{
+		Name:       "get_stock",
+		Parameters: json.RawMessage(`{"type": "object"}`),
+		Handler: func(ctx context.Context, dept Department, args json.RawMessage) (interface{}, error) {
+			*calls = append(*calls, string(dept)+" "+string(args))
+			return map[string]int{"onHand": 12}, nil
+		},
+	}
+}
+
+func toolAgent(t *testing.T, provider Provider, tools ...*Tool) *Agent {
+	t.Helper()
+	registry := NewToolRegistry()
+	for _, tool := range tools {
+		registry.Register(tool)
+	}
+	agent, err := NewAgent(DepartmentDefinition{ID: DeptDairy, Name: "Dairy"}, registry, provider)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	return agent
+}
+
+func TestAgentRunsToolCallsUntilAnswered(t *testing.T) {
+	var calls []string
+	provider := &toolProvider{scriptedProvider: scriptedProvider{name: "primary"}}
+	provider.reply = func(req *ChatRequest) *Message {
+		if len(provider.requests) == 1 {
+			return &Message{Role: "assistant", ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: "get_stock", Arguments: json.RawMessage(`{"sku": "milk"}`)}}}}
+		}
+		return &Message{Role: "assistant", Content: "12 gallons on hand."}
+	}
+	agent := toolAgent(t, provider, stockTool(&calls))
+
+	result, err := agent.ProcessQuery(context.Background(), "How much milk do we have?", nil)
+	if err != nil {
+		t.Fatalf("ProcessQuery: %v", err)
+	}
+	if result.Response != "12 gallons on hand." {
+		t.Errorf("Response = %q", result.Response)
+	}
+	if len(calls) != 1 || calls[0] != `dairy {"sku": "milk"}` {
+		t.Errorf("tool calls = %q, want one for dairy with the model's arguments", calls)
+	}
+
+	if len(provider.requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(provider.requests))
+	}
+	messages := provider.requests[1].Messages
+	call, reply := messages[len(messages)-2], messages[len(messages)-1]
+	if call.ToolCalls[0].ID == "" || reply.Role != "tool" || reply.ToolCallID != call.ToolCalls[0].ID {
+		t.Errorf("tool result %+v doesn't answer call %+v", reply, call.ToolCalls[0])
+	}
+	if reply.Content != `{"onHand":12}` {
+		t.Errorf("tool result content = %q", reply.Content)
+	}
+}
+
+func TestAgentStopsOfferingToolsAfterMaxRounds(t *testing.T) {
+	var calls []string
+	provider := &toolProvider{scriptedProvider: scriptedProvider{name: "primary"}}
+	provider.reply = func(req *ChatRequest) *Message {
+		if len(req.Tools) > 0 {
+			return &Message{Role: "assistant", ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: "get_stock"}}}}
+		}
+		return &Message{Role: "assistant", Content: "About 12."}
+	}
+	agent := toolAgent(t, provider, stockTool(&calls))
+
+	result, err := agent.ProcessQuery(context.Background(), "How much milk do we have?", nil)
+	if err != nil {
+		t.Fatalf("ProcessQuery: %v", err)
+	}
+	if result.Response != "About 12." {
+		t.Errorf("Response = %q", result.Response)
+	}
+	if len(provider.requests) != maxToolRounds+1 || len(calls) != maxToolRounds {
+		t.Errorf("%d requests and %d tool calls, want %d and %d", len(provider.requests), len(calls), maxToolRounds+1, maxToolRounds)
+	}
+	if last := provider.requests[len(provider.requests)-1]; len(last.Tools) != 0 {
+		t.Error("the final round still offered tools")
+	}
+}
+
+func TestRunToolCall(t *testing.T) {
+	var calls []string
+	failing := &Tool{
+		Name: "get_schedule",
+		Handler: func(ctx context.Context, dept Department, args json.RawMessage) (interface{}, error) {
+			return nil, errors.New("schedule store offline")
+		},
+	}
+	tools := []*Tool{stockTool(&calls), failing}
+
+	tests := []struct {
+		name string
+		call ToolCall
+		want string
+	}{
+		{"result", ToolCall{Function: ToolCallFunction{Name: "get_stock", Arguments: json.RawMessage(`{"sku": "milk"}`)}}, `{"onHand":12}`},
+		{"unknown tool", ToolCall{Function: ToolCallFunction{Name: "drop_tables"}}, `{"error":"unknown tool \"drop_tables\""}`},
+		{"handler error", ToolCall{Function: ToolCallFunction{Name: "get_schedule"}}, `{"error":"schedule store offline"}`},
+	}
+	for _, tt := range tests {
+		if got := runToolCall(context.Background(), DeptDairy, tools, tt.call); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	runToolCall(context.Background(), DeptDairy, tools, ToolCall{Function: ToolCallFunction{Name: "get_stock"}})
+	if calls[len(calls)-1] != "dairy {}" {
+		t.Errorf("a call without arguments got %q, want an empty object", calls[len(calls)-1])
+	}
+}
+
+func TestAgentToolsAreScopedToItsDepartment(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&Tool{Name: "get_stock"})
+	registry.Register(&Tool{Name: "get_cut_list", Departments: []Department{DeptMeat}})
+
+	tests := []struct {
+		name  string
+		tools []string
+		want  []string
+		err   string
+	}{
+		{"every available tool", nil, []string{"get_stock"}, ""},
+		{"named tool", []string{"get_stock"}, []string{"get_stock"}, ""},
+		{"another department's tool", []string{"get_cut_list"}, nil, "not available to the dairy department"},
+		{"unknown tool", []string{"get_weather"}, nil, "is unknown"},
+	}
+	for _, tt := range tests {
+		agent, err := NewAgent(DepartmentDefinition{ID: DeptDairy, Name: "Dairy", Tools: tt.tools}, registry)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NewAgent: %v", tt.name, err)
+			continue
+		}
+		var names []string
+		for _, tool := range agent.tools {
+			names = append(names, tool.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: tools = %v, want %v", tt.name, names, tt.want)
+		}
+	}
+}
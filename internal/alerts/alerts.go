@@ -0,0 +1,188 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for an unknown alert ID
+	ErrNotFound = errors.New("alert not found")
+	// ErrInvalidTransition is returned for a lifecycle change the alert's
+	// status doesn't allow, e.g. acknowledging a resolved alert
+	ErrInvalidTransition = errors.New("alert can't change to that status")
+)
+
+// Severity is how urgently an alert needs attention
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRank = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+// Valid reports whether s is a known severity
+func (s Severity) Valid() bool {
+	return severityRank[s] > 0
+}
+
+// AtLeast reports whether s is as severe as min
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRank[s] >= severityRank[min]
+}
+
+// raise returns the next severity up, staying at critical
+func (s Severity) raise() Severity {
+	switch s {
+	case SeverityInfo:
+		return SeverityWarning
+	default:
+		return SeverityCritical
+	}
+}
+
+// Status is where an alert is in its lifecycle
+type Status string
+
+const (
+	StatusActive       Status = "active"
+	StatusAcknowledged Status = "acknowledged"
+	StatusSnoozed      Status = "snoozed"
+	StatusResolved     Status = "resolved"
+)
+
+// Open reports whether the alert still needs handling
+func (s Status) Open() bool {
+	return s != StatusResolved
+}
+
+// Lifecycle actions recorded in an alert's history
+const (
+	ActionRaised       = "raised"
+	ActionChanged      = "changed"
+	ActionAcknowledged = "acknowledged"
+	ActionSnoozed      = "snoozed"
+	ActionWoke         = "woke"
+	ActionEscalated    = "escalated"
+	ActionResolved     = "resolved"
+	// ActionCleared is a resolution by the engine after the condition
+	// went away
+	ActionCleared = "cleared"
+)
+
+// Change is one entry in an alert's history. By is empty for changes made
+// by the engine.
+type Change struct {
+	At     time.Time `json:"at"`
+	Action string    `json:"action"`
+	By     string    `json:"by,omitempty"`
+	Note   string    `json:"note,omitempty"`
+}
+
+// Alert is a condition in the store that someone should look at
+type Alert struct {
+	ID string `json:"id"`
+	// Key identifies the condition, e.g. "low_stock:DAI-001", so it raises
+	// one alert while it lasts however often rules see it
+	Key        string `json:"key"`
+	Department string `json:"department"`
+	// Source is the rule that raised the alert
+	Source   string   `json:"source"`
+	Severity Severity `json:"severity"`
+	Status   Status   `json:"status"`
+	Title    string   `json:"title"`
+	Detail   string   `json:"detail,omitempty"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+
+	AcknowledgedBy string     `json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	SnoozedUntil   *time.Time `json:"snoozedUntil,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	// EscalationLevel counts escalations, by hand or by the notification
+	// router
+	EscalationLevel int `json:"escalationLevel,omitempty"`
+	// SeverityRaised is set once an escalation by hand raised the severity
+	SeverityRaised bool `json:"severityRaised,omitempty"`
+
+	History []Change `json:"history"`
+}
+
+func (a *Alert) record(at time.Time, action, by, note string) {
+	a.UpdatedAt = at
+	a.History = append(a.History, Change{At: at, Action: action, By: by, Note: note})
+}
+
+func cloneAlert(a Alert) Alert {
+	a.History = append([]Change(nil), a.History...)
+	return a
+}
+
+// Filter selects alerts. The zero Filter matches open alerts of every
+// department.
+type Filter struct {
+	Department string
+	// Statuses to match; empty matches every open status
+	Statuses    []Status
+	MinSeverity Severity
+	Source      string
+}
+
+func (f Filter) matches(a *Alert) bool {
+	if f.Department != "" && a.Department != f.Department {
+		return false
+	}
+	if f.Source != "" && a.Source != f.Source {
+		return false
+	}
+	if f.MinSeverity != "" && !a.Severity.AtLeast(f.MinSeverity) {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return a.Status.Open()
+	}
+	for _, s := range f.Statuses {
+		if a.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository stores alerts
+type Repository interface {
+	// List returns the alerts matching f, most severe and then newest
+	// first
+	List(ctx context.Context, f Filter) ([]Alert, error)
+	Get(ctx context.Context, id string) (*Alert, error)
+	// Open returns the unresolved alert with key, or ErrNotFound
+	Open(ctx context.Context, key string) (*Alert, error)
+	// Create stores a new alert and sets its ID
+	Create(ctx context.Context, a *Alert) error
+	Update(ctx context.Context, a *Alert) error
+}
+
+// Event types sent to subscribers
+const (
+	EventCreated  = "alert.created"
+	EventUpdated  = "alert.updated"
+	EventResolved = "alert.resolved"
+	// EventEscalated is sent when someone escalates an alert
+	EventEscalated = "alert.escalated"
+)
+
+// Event tells subscribers that an alert was raised or changed
+type Event struct {
+	Type  string `json:"type"`
+	Alert Alert  `json:"alert"`
+}
@@ -0,0 +1,334 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// MaxSnooze is the longest an alert can be snoozed
+const MaxSnooze = 24 * time.Hour
+
+// ErrInvalidSnooze is returned for a snooze that isn't between a minute
+// and MaxSnooze
+var ErrInvalidSnooze = errors.New("snooze must be between 1m and 24h")
+
+// Finding is a condition a rule found. Findings with the same Key are the
+// same alert.
+type Finding struct {
+	Key        string
+	Department string
+	Severity   Severity
+	Title      string
+	Detail     string
+}
+
+// Rule checks one kind of store data for conditions to alert on
+type Rule interface {
+	// Name is recorded as the Source of the rule's alerts
+	Name() string
+	// Evaluate returns the conditions present at now. Alerts the rule
+	// raised earlier that it no longer finds are cleared.
+	Evaluate(ctx context.Context, now time.Time) ([]Finding, error)
+}
+
+// Engine evaluates rules into deduplicated alerts, moves alerts through
+// their lifecycle and tells subscribers about every change
+type Engine struct {
+	repo        Repository
+	rules       []Rule
+	subscribers []func(Event)
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+// NewEngine creates an engine storing alerts in repo
+func NewEngine(repo Repository, rules ...Rule) *Engine {
+	return &Engine{
+		repo:  repo,
+		rules: rules,
+		now:   time.Now,
+	}
+}
+
+// Subscribe calls fn for every alert event. fn runs while the engine is
+// busy and must not block.
+func (e *Engine) Subscribe(fn func(Event)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscribers = append(e.subscribers, fn)
+}
+
+// Run evaluates the rules every interval until ctx is cancelled
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Evaluate(ctx); err != nil {
+			log.Printf("Alert evaluation failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluate runs every rule once, raising, updating and clearing alerts,
+// and wakes snoozed alerts whose snooze is over. A failing rule leaves
+// its alerts as they are.
+func (e *Engine) Evaluate(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	var errs []error
+	for _, rule := range e.rules {
+		findings, err := rule.Evaluate(ctx, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name(), err))
+			continue
+		}
+
+		seen := make(map[string]bool, len(findings))
+		for _, f := range findings {
+			seen[f.Key] = true
+			if err := e.raise(ctx, rule.Name(), f, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := e.clear(ctx, rule.Name(), seen, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := e.wake(ctx, now); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// raise creates an alert for a new finding or updates the open alert with
+// the same key. A snoozed alert wakes early if it gets more severe.
+func (e *Engine) raise(ctx context.Context, source string, f Finding, now time.Time) error {
+	a, err := e.repo.Open(ctx, f.Key)
+	if errors.Is(err, ErrNotFound) {
+		a = &Alert{
+			Key:        f.Key,
+			Department: f.Department,
+			Source:     source,
+			Severity:   f.Severity,
+			Status:     StatusActive,
+			Title:      f.Title,
+			Detail:     f.Detail,
+			CreatedAt:  now,
+			LastSeenAt: now,
+		}
+		a.record(now, ActionRaised, "", "")
+		if err := e.repo.Create(ctx, a); err != nil {
+			return fmt.Errorf("failed to create alert %s: %w", f.Key, err)
+		}
+		e.publish(EventCreated, a)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load alert %s: %w", f.Key, err)
+	}
+
+	a.LastSeenAt = now
+	severity := f.Severity
+	if a.SeverityRaised && !severity.AtLeast(a.Severity) {
+		// Escalation raised the severity by hand; keep it
+		severity = a.Severity
+	}
+
+	changed := a.Title != f.Title || a.Detail != f.Detail || a.Severity != severity
+	if changed {
+		note := ""
+		if severity != a.Severity {
+			note = fmt.Sprintf("severity %s to %s", a.Severity, severity)
+			if a.Status == StatusSnoozed && !a.Severity.AtLeast(severity) {
+				a.Status = StatusActive
+				a.SnoozedUntil = nil
+			}
+		}
+		a.Severity, a.Title, a.Detail = severity, f.Title, f.Detail
+		a.record(now, ActionChanged, "", note)
+	}
+
+	if err := e.repo.Update(ctx, a); err != nil {
+		return fmt.Errorf("failed to update alert %s: %w", a.ID, err)
+	}
+	if changed {
+		e.publish(EventUpdated, a)
+	}
+	return nil
+}
+
+// clear resolves the open alerts from source that it no longer finds
+func (e *Engine) clear(ctx context.Context, source string, seen map[string]bool, now time.Time) error {
+	open, err := e.repo.List(ctx, Filter{Source: source})
+	if err != nil {
+		return fmt.Errorf("failed to list %s alerts: %w", source, err)
+	}
+
+	for i := range open {
+		a := &open[i]
+		if seen[a.Key] {
+			continue
+		}
+		a.Status = StatusResolved
+		a.SnoozedUntil = nil
+		a.ResolvedAt = &now
+		a.record(now, ActionCleared, "", "")
+		if err := e.repo.Update(ctx, a); err != nil {
+			return fmt.Errorf("failed to clear alert %s: %w", a.ID, err)
+		}
+		e.publish(EventResolved, a)
+	}
+	return nil
+}
+
+// wake reactivates snoozed alerts whose snooze is over
+func (e *Engine) wake(ctx context.Context, now time.Time) error {
+	snoozed, err := e.repo.List(ctx, Filter{Statuses: []Status{StatusSnoozed}})
+	if err != nil {
+		return fmt.Errorf("failed to list snoozed alerts: %w", err)
+	}
+
+	for i := range snoozed {
+		a := &snoozed[i]
+		if a.SnoozedUntil != nil && a.SnoozedUntil.After(now) {
+			continue
+		}
+		a.Status = StatusActive
+		a.SnoozedUntil = nil
+		a.record(now, ActionWoke, "", "")
+		if err := e.repo.Update(ctx, a); err != nil {
+			return fmt.Errorf("failed to wake alert %s: %w", a.ID, err)
+		}
+		e.publish(EventUpdated, a)
+	}
+	return nil
+}
+
+// List returns the alerts matching f
+func (e *Engine) List(ctx context.Context, f Filter) ([]Alert, error) {
+	return e.repo.List(ctx, f)
+}
+
+// Get returns the alert with id
+func (e *Engine) Get(ctx context.Context, id string) (*Alert, error) {
+	return e.repo.Get(ctx, id)
+}
+
+// Acknowledge records that by is handling the alert. Acknowledging an
+// acknowledged alert changes nothing.
+func (e *Engine) Acknowledge(ctx context.Context, id, by string) (*Alert, error) {
+	return e.change(ctx, id, func(a *Alert, now time.Time) string {
+		if a.Status == StatusAcknowledged {
+			return ""
+		}
+		a.Status = StatusAcknowledged
+		a.SnoozedUntil = nil
+		a.AcknowledgedBy = by
+		a.AcknowledgedAt = &now
+		a.record(now, ActionAcknowledged, by, "")
+		return EventUpdated
+	})
+}
+
+// Snooze hides the alert for d, after which it becomes active again if
+// its condition is still present
+func (e *Engine) Snooze(ctx context.Context, id, by string, d time.Duration, note string) (*Alert, error) {
+	if d < time.Minute || d > MaxSnooze {
+		return nil, ErrInvalidSnooze
+	}
+	return e.change(ctx, id, func(a *Alert, now time.Time) string {
+		until := now.Add(d)
+		a.Status = StatusSnoozed
+		a.SnoozedUntil = &until
+		a.record(now, ActionSnoozed, by, note)
+		return EventUpdated
+	})
+}
+
+// Resolve closes the alert. If its condition is still present the next
+// evaluation raises a new alert.
+func (e *Engine) Resolve(ctx context.Context, id, by, note string) (*Alert, error) {
+	return e.change(ctx, id, func(a *Alert, now time.Time) string {
+		a.Status = StatusResolved
+		a.SnoozedUntil = nil
+		a.ResolvedAt = &now
+		a.record(now, ActionResolved, by, note)
+		return EventResolved
+	})
+}
+
+// Escalate raises the alert's severity and escalation level and makes it
+// active again
+func (e *Engine) Escalate(ctx context.Context, id, by, note string) (*Alert, error) {
+	return e.change(ctx, id, func(a *Alert, now time.Time) string {
+		a.EscalationLevel++
+		a.Severity = a.Severity.raise()
+		a.SeverityRaised = true
+		a.Status = StatusActive
+		a.SnoozedUntil = nil
+		a.record(now, ActionEscalated, by, note)
+		return EventEscalated
+	})
+}
+
+// Advance moves the alert to the next escalation level without changing
+// its severity, so it goes to more people without becoming more urgent.
+// Only active alerts advance; anything else returns ErrInvalidTransition.
+func (e *Engine) Advance(ctx context.Context, id, by, note string) (*Alert, error) {
+	active := func(s Status) bool { return s == StatusActive }
+	return e.changeIf(ctx, id, active, func(a *Alert, now time.Time) string {
+		a.EscalationLevel++
+		a.record(now, ActionEscalated, by, note)
+		return EventEscalated
+	})
+}
+
+// change applies fn to an open alert and publishes the event it returns,
+// if any
+func (e *Engine) change(ctx context.Context, id string, fn func(a *Alert, now time.Time) string) (*Alert, error) {
+	return e.changeIf(ctx, id, Status.Open, fn)
+}
+
+// changeIf is change for alerts whose status passes allowed
+func (e *Engine) changeIf(ctx context.Context, id string, allowed func(Status) bool, fn func(a *Alert, now time.Time) string) (*Alert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	a, err := e.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed(a.Status) {
+		return nil, ErrInvalidTransition
+	}
+
+	event := fn(a, e.now())
+	if event == "" {
+		return a, nil
+	}
+	if err := e.repo.Update(ctx, a); err != nil {
+		return nil, fmt.Errorf("failed to update alert %s: %w", id, err)
+	}
+	e.publish(event, a)
+	return a, nil
+}
+
+func (e *Engine) publish(eventType string, a *Alert) {
+	event := Event{Type: eventType, Alert: cloneAlert(*a)}
+	for _, fn := range e.subscribers {
+		fn(event)
+	}
+}
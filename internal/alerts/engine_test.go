@@ -0,0 +1,101 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// staticRule finds the same conditions on every run
+type staticRule struct {
+	findings []Finding
+}
+
+func (r *staticRule) Name() string { return "static" }
+
+func (r *staticRule) Evaluate(ctx context.Context, now time.Time) ([]Finding, error) {
+	return r.findings, nil
+}
+
+// raiseWarning returns an engine with one active warning alert
+func raiseWarning(t *testing.T) (*Engine, *staticRule, string) {
+	t.Helper()
+	rule := &staticRule{findings: []Finding{{Key: "dairy-temp", Department: "dairy", Severity: SeverityWarning, Title: "Cooler warm"}}}
+	engine := NewEngine(NewMemoryRepository(), rule)
+	if err := engine.Evaluate(context.Background()); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	open, err := engine.List(context.Background(), Filter{})
+	if err != nil || len(open) != 1 {
+		t.Fatalf("List = %d alerts, %v", len(open), err)
+	}
+	return engine, rule, open[0].ID
+}
+
+func TestEscalateRaisesSeverity(t *testing.T) {
+	engine, _, id := raiseWarning(t)
+
+	a, err := engine.Escalate(context.Background(), id, "jsmith", "")
+	if err != nil {
+		t.Fatalf("Escalate: %v", err)
+	}
+	if a.Severity != SeverityCritical || a.EscalationLevel != 1 {
+		t.Errorf("got %s at level %d, want critical at level 1", a.Severity, a.EscalationLevel)
+	}
+	if err := engine.Evaluate(context.Background()); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if a, _ := engine.Get(context.Background(), id); a.Severity != SeverityCritical {
+		t.Errorf("severity %s after the next run, want the escalated critical", a.Severity)
+	}
+}
+
+func TestAdvanceKeepsSeverity(t *testing.T) {
+	engine, rule, id := raiseWarning(t)
+	var events []Event
+	engine.Subscribe(func(e Event) { events = append(events, e) })
+
+	a, err := engine.Advance(context.Background(), id, "", "Not acknowledged")
+	if err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	if a.Severity != SeverityWarning || a.EscalationLevel != 1 {
+		t.Errorf("got %s at level %d, want warning at level 1", a.Severity, a.EscalationLevel)
+	}
+	if last := a.History[len(a.History)-1]; last.Action != ActionEscalated {
+		t.Errorf("last change %q, want %q", last.Action, ActionEscalated)
+	}
+	if len(events) != 1 || events[0].Type != EventEscalated {
+		t.Errorf("events = %v, want one %s", events, EventEscalated)
+	}
+
+	// The condition easing still lowers the severity
+	rule.findings[0].Severity = SeverityInfo
+	if err := engine.Evaluate(context.Background()); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if a, _ := engine.Get(context.Background(), id); a.Severity != SeverityInfo {
+		t.Errorf("severity %s after the condition eased, want info", a.Severity)
+	}
+}
+
+func TestAdvanceSkipsAlertsNoLongerActive(t *testing.T) {
+	engine, _, id := raiseWarning(t)
+	if _, err := engine.Acknowledge(context.Background(), id, "jsmith"); err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	var events []Event
+	engine.Subscribe(func(e Event) { events = append(events, e) })
+
+	if _, err := engine.Advance(context.Background(), id, "", "Not acknowledged"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Advance on an acknowledged alert = %v, want ErrInvalidTransition", err)
+	}
+	a, _ := engine.Get(context.Background(), id)
+	if a.EscalationLevel != 0 || a.History[len(a.History)-1].Action != ActionAcknowledged {
+		t.Errorf("acknowledged alert changed: level %d, last change %q", a.EscalationLevel, a.History[len(a.History)-1].Action)
+	}
+	if len(events) != 0 {
+		t.Errorf("events = %v, want none", events)
+	}
+}
@@ -0,0 +1,95 @@
+package alerts
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// MemoryRepository keeps alerts in memory. IDs are sequential numbers so
+// they are easy to type, e.g. in an SMS reply.
+type MemoryRepository struct {
+	alerts map[string]*Alert
+	nextID int
+	mu     sync.RWMutex
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{alerts: make(map[string]*Alert)}
+}
+
+// List returns the alerts matching f, most severe and then newest first
+func (m *MemoryRepository) List(ctx context.Context, f Filter) ([]Alert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	alerts := []Alert{}
+	for _, a := range m.alerts {
+		if f.matches(a) {
+			alerts = append(alerts, cloneAlert(*a))
+		}
+	}
+	slices.SortFunc(alerts, func(a, b Alert) int {
+		return cmp.Or(
+			cmp.Compare(severityRank[b.Severity], severityRank[a.Severity]),
+			b.CreatedAt.Compare(a.CreatedAt),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+	return alerts, nil
+}
+
+// Get returns the alert with id
+func (m *MemoryRepository) Get(ctx context.Context, id string) (*Alert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.alerts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	clone := cloneAlert(*a)
+	return &clone, nil
+}
+
+// Open returns the unresolved alert with key
+func (m *MemoryRepository) Open(ctx context.Context, key string) (*Alert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.alerts {
+		if a.Key == key && a.Status.Open() {
+			clone := cloneAlert(*a)
+			return &clone, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Create stores a new alert and sets its ID
+func (m *MemoryRepository) Create(ctx context.Context, a *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	a.ID = strconv.Itoa(m.nextID)
+	clone := cloneAlert(*a)
+	m.alerts[a.ID] = &clone
+	return nil
+}
+
+// Update replaces a stored alert
+func (m *MemoryRepository) Update(ctx context.Context, a *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.alerts[a.ID]; !ok {
+		return ErrNotFound
+	}
+	clone := cloneAlert(*a)
+	m.alerts[a.ID] = &clone
+	return nil
+}
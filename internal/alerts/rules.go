@@ -0,0 +1,292 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// allItems pages through every item matching q
+func allItems(ctx context.Context, repo inventory.Repository, q inventory.Query) ([]inventory.Item, error) {
+	q.Limit = inventory.MaxLimit
+	var items []inventory.Item
+	for {
+		page, err := repo.List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		q.Offset += len(page.Items)
+		if len(page.Items) == 0 || q.Offset >= page.Total {
+			return items, nil
+		}
+	}
+}
+
+// LowStock alerts on items at or below their reorder point, critical once
+// they are out of stock
+type LowStock struct {
+	Items inventory.Repository
+}
+
+func (r *LowStock) Name() string { return "low_stock" }
+
+func (r *LowStock) Evaluate(ctx context.Context, now time.Time) ([]Finding, error) {
+	items, err := allItems(ctx, r.Items, inventory.Query{BelowReorder: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, item := range items {
+		f := Finding{
+			Key:        "low_stock:" + item.SKU,
+			Department: item.Department,
+			Severity:   SeverityWarning,
+			Title:      "Low stock: " + item.Description,
+			Detail: fmt.Sprintf("%s %s on hand, reorder point %s, par %s",
+				quantity(item.OnHand), item.Unit, quantity(item.ReorderPoint), quantity(item.ParLevel)),
+		}
+		if item.OnHand <= 0 {
+			f.Severity = SeverityCritical
+			f.Title = "Out of stock: " + item.Description
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// Expiring alerts on items with lots expiring within Within, critical for
+// lots already past their date
+type Expiring struct {
+	Items  inventory.Repository
+	Within time.Duration
+}
+
+func (r *Expiring) Name() string { return "expiring" }
+
+func (r *Expiring) Evaluate(ctx context.Context, now time.Time) ([]Finding, error) {
+	items, err := allItems(ctx, r.Items, inventory.Query{ExpiringWithin: r.Within})
+	if err != nil {
+		return nil, err
+	}
+
+	today := now.Format(time.DateOnly)
+	cutoff := now.Add(r.Within).Format(time.DateOnly)
+
+	var findings []Finding
+	for _, item := range items {
+		var expired, expiring float64
+		for _, lot := range item.Lots {
+			switch {
+			case lot.ExpiresOn == "" || lot.ExpiresOn > cutoff:
+			case lot.ExpiresOn < today:
+				expired += lot.Quantity
+			default:
+				expiring += lot.Quantity
+			}
+		}
+
+		f := Finding{
+			Key:        "expiring:" + item.SKU,
+			Department: item.Department,
+			Severity:   SeverityWarning,
+			Title:      fmt.Sprintf("Expiring: %s (%s %s)", item.Description, quantity(expiring), item.Unit),
+			Detail:     "Earliest lot expires " + item.ExpiresOn,
+		}
+		if expired > 0 {
+			f.Severity = SeverityCritical
+			f.Title = fmt.Sprintf("Expired: %s (%s %s)", item.Description, quantity(expired), item.Unit)
+			if expiring > 0 {
+				f.Detail += fmt.Sprintf("; another %s %s expires by %s", quantity(expiring), item.Unit, cutoff)
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// quantity formats a stock quantity without trailing zeros
+func quantity(q float64) string {
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", q), ".0")
+}
+
+// TemperatureReading is the latest reading of a cooler, freezer or case
+// sensor with the range its product must be kept in
+type TemperatureReading struct {
+	SensorID   string    `json:"sensorId"`
+	Name       string    `json:"name"`
+	Department string    `json:"department"`
+	Fahrenheit float64   `json:"fahrenheit"`
+	MinF       float64   `json:"minF"`
+	MaxF       float64   `json:"maxF"`
+	ReadAt     time.Time `json:"readAt"`
+}
+
+// TemperatureSource provides the latest reading of every sensor
+type TemperatureSource interface {
+	Temperatures(ctx context.Context) ([]TemperatureReading, error)
+}
+
+// criticalDeviation is how far out of range, in °F, a reading turns
+// critical
+const criticalDeviation = 5
+
+// Temperature alerts on sensors outside their range, critical when more
+// than 5°F out, and on sensors that haven't reported within Stale
+type Temperature struct {
+	Sensors TemperatureSource
+	Stale   time.Duration
+}
+
+func (r *Temperature) Name() string { return "temperature" }
+
+func (r *Temperature) Evaluate(ctx context.Context, now time.Time) ([]Finding, error) {
+	readings, err := r.Sensors.Temperatures(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, reading := range readings {
+		f := Finding{
+			Key:        "temperature:" + reading.SensorID,
+			Department: reading.Department,
+			Severity:   SeverityWarning,
+			Detail: fmt.Sprintf("Safe range %.0f-%.0f°F, last read %s",
+				reading.MinF, reading.MaxF, reading.ReadAt.Format("Jan 2 15:04")),
+		}
+
+		deviation := math.Max(reading.MinF-reading.Fahrenheit, reading.Fahrenheit-reading.MaxF)
+		switch {
+		case r.Stale > 0 && now.Sub(reading.ReadAt) > r.Stale:
+			f.Title = "No reading from " + reading.Name
+		case deviation > 0:
+			f.Title = fmt.Sprintf("%s at %.1f°F", reading.Name, reading.Fahrenheit)
+			if deviation > criticalDeviation {
+				f.Severity = SeverityCritical
+			}
+		default:
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// imminent is how soon a coverage gap must start to be critical
+const imminent = 4 * time.Hour
+
+// Coverage alerts on short-staffed hours within Lookahead, one alert per
+// department, role and day. Gaps starting within four hours are critical.
+type Coverage struct {
+	Schedules schedule.Repository
+	Lookahead time.Duration
+}
+
+func (r *Coverage) Name() string { return "coverage" }
+
+func (r *Coverage) Evaluate(ctx context.Context, now time.Time) ([]Finding, error) {
+	from := now.Truncate(time.Hour)
+	period, err := schedule.Load(ctx, r.Schedules, "", from, now.Add(r.Lookahead))
+	if err != nil {
+		return nil, err
+	}
+
+	type gapDay struct {
+		first, last schedule.HourCoverage
+		short       int
+	}
+	var keys []string
+	days := make(map[string]*gapDay)
+	for _, gap := range period.Gaps {
+		key := fmt.Sprintf("coverage:%s:%s:%s", gap.Department, gap.Role, gap.Start.Format(time.DateOnly))
+		day, ok := days[key]
+		if !ok {
+			day = &gapDay{first: gap}
+			days[key] = day
+			keys = append(keys, key)
+		}
+		day.last = gap
+		day.short = max(day.short, gap.Short)
+	}
+
+	var findings []Finding
+	for _, key := range keys {
+		day := days[key]
+		who := day.first.Role
+		if who == "" {
+			who = "staff"
+		}
+		f := Finding{
+			Key:        key,
+			Department: day.first.Department,
+			Severity:   SeverityWarning,
+			Title: fmt.Sprintf("Short %d %s %s %s-%s", day.short, who,
+				day.first.Start.Format("Mon"), day.first.Start.Format("15:04"),
+				day.last.Start.Add(time.Hour).Format("15:04")),
+			Detail: fmt.Sprintf("Scheduled %d of %d needed at %s",
+				day.first.Scheduled, day.first.Required, day.first.Start.Format("Jan 2 15:04")),
+		}
+		if day.first.Start.Sub(now) < imminent {
+			f.Severity = SeverityCritical
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// Lanes alerts on checkout lanes that need assistance or are offline.
+// A call for help turns critical after Assistance, an offline lane after
+// Offline.
+type Lanes struct {
+	Lanes      checkout.Repository
+	Assistance time.Duration
+	Offline    time.Duration
+}
+
+func (r *Lanes) Name() string { return "lanes" }
+
+func (r *Lanes) Evaluate(ctx context.Context, now time.Time) ([]Finding, error) {
+	lanes, err := r.Lanes.Lanes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, lane := range lanes {
+		waiting := now.Sub(lane.StatusSince)
+		f := Finding{
+			Key:        "lane:" + lane.ID,
+			Department: checkout.Department,
+			Severity:   SeverityWarning,
+			Detail:     "Since " + lane.StatusSince.Format("15:04"),
+		}
+		if lane.Cashier != "" {
+			f.Detail += ", cashier " + lane.Cashier
+		}
+
+		switch lane.Status {
+		case checkout.LaneNeedsAssistance:
+			f.Title = lane.Name + " needs assistance"
+			if waiting >= r.Assistance {
+				f.Severity = SeverityCritical
+			}
+		case checkout.LaneOffline:
+			f.Title = lane.Name + " is offline"
+			if waiting >= r.Offline {
+				f.Severity = SeverityCritical
+			}
+		default:
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+)
+
+// AlertActionRequest is the optional body of an alert lifecycle request
+type AlertActionRequest struct {
+	Note string `json:"note,omitempty"`
+	// Duration is how long to snooze for, e.g. 30m or 2h
+	Duration string `json:"duration,omitempty"`
+}
+
+// getAlerts lists alerts, most severe first. Query parameters:
+//
+//	department   defaults to the user's own for department managers
+//	status       comma-separated statuses, or "all"; defaults to open ones
+//	severity     minimum severity: info, warning or critical
+//	source       the rule that raised them, e.g. low_stock
+func (r *Router) getAlerts(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	values := req.URL.Query()
+	filter := alerts.Filter{
+		Department:  values.Get("department"),
+		MinSeverity: alerts.Severity(values.Get("severity")),
+		Source:      values.Get("source"),
+	}
+
+	// Department managers only see their own department's alerts
+	if filter.Department == "" {
+		filter.Department = departmentScope(req)
+	}
+	if filter.Department != "" && !requireDepartment(w, req, filter.Department) {
+		return
+	}
+
+	if filter.MinSeverity != "" && !filter.MinSeverity.Valid() {
+		http.Error(w, `{"error": "severity must be info, warning or critical"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch status := values.Get("status"); status {
+	case "":
+	case "all":
+		filter.Statuses = []alerts.Status{alerts.StatusActive, alerts.StatusAcknowledged, alerts.StatusSnoozed, alerts.StatusResolved}
+	default:
+		for _, s := range strings.Split(status, ",") {
+			switch s := alerts.Status(strings.TrimSpace(s)); s {
+			case alerts.StatusActive, alerts.StatusAcknowledged, alerts.StatusSnoozed, alerts.StatusResolved:
+				filter.Statuses = append(filter.Statuses, s)
+			default:
+				http.Error(w, `{"error": "status must be active, acknowledged, snoozed, resolved or all"}`, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	list, err := r.alerts.List(req.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to list alerts: %v", err)
+		http.Error(w, `{"error": "Failed to load alerts"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// getAlert returns one alert with its history
+func (r *Router) getAlert(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	alert, ok := r.loadAlert(w, req)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(alert)
+}
+
+func (r *Router) acknowledgeAlert(w http.ResponseWriter, req *http.Request) {
+	r.changeAlert(w, req, func(id, by string, body AlertActionRequest) (*alerts.Alert, error) {
+		return r.alerts.Acknowledge(req.Context(), id, by)
+	})
+}
+
+func (r *Router) snoozeAlert(w http.ResponseWriter, req *http.Request) {
+	r.changeAlert(w, req, func(id, by string, body AlertActionRequest) (*alerts.Alert, error) {
+		d, err := time.ParseDuration(body.Duration)
+		if err != nil {
+			return nil, alerts.ErrInvalidSnooze
+		}
+		return r.alerts.Snooze(req.Context(), id, by, d, body.Note)
+	})
+}
+
+func (r *Router) resolveAlert(w http.ResponseWriter, req *http.Request) {
+	r.changeAlert(w, req, func(id, by string, body AlertActionRequest) (*alerts.Alert, error) {
+		return r.alerts.Resolve(req.Context(), id, by, body.Note)
+	})
+}
+
+func (r *Router) escalateAlert(w http.ResponseWriter, req *http.Request) {
+	r.changeAlert(w, req, func(id, by string, body AlertActionRequest) (*alerts.Alert, error) {
+		return r.alerts.Escalate(req.Context(), id, by, body.Note)
+	})
+}
+
+// loadAlert returns the alert named in the path, writing a 404 if it
+// doesn't exist or a 403 if it belongs to another department
+func (r *Router) loadAlert(w http.ResponseWriter, req *http.Request) (*alerts.Alert, bool) {
+	alert, err := r.alerts.Get(req.Context(), req.PathValue("id"))
+	if errors.Is(err, alerts.ErrNotFound) {
+		http.Error(w, `{"error": "Alert not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Failed to load alert: %v", err)
+		http.Error(w, `{"error": "Failed to load alert"}`, http.StatusInternalServerError)
+		return nil, false
+	}
+	if !requireDepartment(w, req, alert.Department) {
+		return nil, false
+	}
+	return alert, true
+}
+
+// changeAlert runs a lifecycle change on the alert named in the path on
+// behalf of the signed-in user and returns the updated alert. Associates
+// may only look at alerts.
+func (r *Router) changeAlert(w http.ResponseWriter, req *http.Request, change func(id, by string, body AlertActionRequest) (*alerts.Alert, error)) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireRole(w, req, auth.RoleAssistantManager) {
+		return
+	}
+
+	var body AlertActionRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	alert, ok := r.loadAlert(w, req)
+	if !ok {
+		return
+	}
+
+	alert, err := change(alert.ID, claims(req).Subject, body)
+	switch {
+	case errors.Is(err, alerts.ErrInvalidSnooze):
+		http.Error(w, `{"error": "duration must be between 1m and 24h"}`, http.StatusBadRequest)
+	case errors.Is(err, alerts.ErrInvalidTransition):
+		http.Error(w, `{"error": "Alert is already resolved"}`, http.StatusConflict)
+	case errors.Is(err, alerts.ErrNotFound):
+		http.Error(w, `{"error": "Alert not found"}`, http.StatusNotFound)
+	case err != nil:
+		log.Printf("Failed to update alert: %v", err)
+		http.Error(w, `{"error": "Failed to update alert"}`, http.StatusInternalServerError)
+	default:
+		json.NewEncoder(w).Encode(alert)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dokk-dev/opus/internal/auth"
+)
+
+// publicPaths are the API routes that don't need a token
+var publicPaths = map[string]bool{
+	"/api/v1/auth/login": true,
+}
+
+// anonymous stands in for a signed-in user when AUTH_DISABLED is set
+// without JWT_SECRET, so a development server works without a users file
+var anonymous = &auth.Claims{Role: auth.RoleAdmin}
+
+// authenticate verifies the request's bearer token and returns its claims,
+// writing a 401 response if it is missing or invalid and a 403 if it is
+// another store's. Without JWT_SECRET every request is refused with a 503
+// unless AUTH_DISABLED is set.
+func (r *Router) authenticate(w http.ResponseWriter, req *http.Request) (*auth.Claims, bool) {
+	if r.config.JWTSecret == "" {
+		if r.config.AuthDisabled {
+			return anonymous, true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "Authentication is not configured"}`, http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	token, err := auth.TokenFromRequest(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, `{"error": "Authentication required"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	claims, err := auth.ParseToken([]byte(r.config.JWTSecret), token)
+	if err != nil {
+		log.Printf("API auth failed from %s: %v", req.RemoteAddr, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, `{"error": "Invalid token"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+	if !servesStore(claims, r.config.StoreID) {
+		log.Printf("API auth refused %s from store %q at %s", claims.Subject, claims.Store, req.RemoteAddr)
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "This server doesn't serve your store"}`, http.StatusForbidden)
+		return nil, false
+	}
+	return claims, true
+}
+
+// servesStore reports whether a server for store may serve the user.
+// Admins are served everywhere; without STORE_ID every store is.
+func servesStore(c *auth.Claims, store string) bool {
+	return store == "" || c.Role == auth.RoleAdmin || c.Store == store
+}
+
+// claims returns the signed-in user's claims for an authenticated request
+func claims(req *http.Request) *auth.Claims {
+	if c, ok := auth.FromContext(req.Context()); ok {
+		return c
+	}
+	return anonymous
+}
+
+// requireDepartment writes a 403 response and returns false unless the
+// user may access dept
+func requireDepartment(w http.ResponseWriter, req *http.Request, dept string) bool {
+	if claims(req).CanAccessDepartment(dept) {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.Error(w, `{"error": "You don't have access to this department"}`, http.StatusForbidden)
+	return false
+}
+
+// requireRole writes a 403 response and returns false unless the user's
+// role is at least min
+func requireRole(w http.ResponseWriter, req *http.Request, min auth.Role) bool {
+	if claims(req).Role.AtLeast(min) {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.Error(w, `{"error": "Your role doesn't allow this"}`, http.StatusForbidden)
+	return false
+}
+
+// requireStoreLevel writes a 403 response and returns false unless the
+// user is a manager or above who can see every department
+func requireStoreLevel(w http.ResponseWriter, req *http.Request) bool {
+	if !requireRole(w, req, auth.RoleManager) {
+		return false
+	}
+	if claims(req).StoreLevel() {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.Error(w, `{"error": "Only store-level users can access this"}`, http.StatusForbidden)
+	return false
+}
+
+// departmentScope is the department a user is limited to, if any
+func departmentScope(req *http.Request) string {
+	if c := claims(req); !c.StoreLevel() {
+		return c.Department
+	}
+	return ""
+}
+
+// LoginRequest is the body of POST /api/v1/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse carries a token to send as "Authorization: Bearer <token>"
+type LoginResponse struct {
+	Token     string     `json:"token"`
+	ExpiresAt int64      `json:"expiresAt"`
+	User      *auth.User `json:"user"`
+}
+
+func (r *Router) login(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.users == nil || r.config.JWTSecret == "" {
+		http.Error(w, `{"error": "Sign-in is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	var loginReq LoginRequest
+	if err := json.NewDecoder(req.Body).Decode(&loginReq); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(loginReq.Username) == "" || loginReq.Password == "" {
+		http.Error(w, `{"error": "Username and password are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := r.users.Authenticate(req.Context(), loginReq.Username, loginReq.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		log.Printf("Failed sign-in from %s", req.RemoteAddr)
+		http.Error(w, `{"error": "Invalid username or password"}`, http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Sign-in error: %v", err)
+		http.Error(w, `{"error": "Sign-in failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	token, expires, err := auth.IssueToken([]byte(r.config.JWTSecret), user, r.config.TokenTTL)
+	if err != nil {
+		log.Printf("Sign-in error: %v", err)
+		http.Error(w, `{"error": "Sign-in failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(LoginResponse{
+		Token:     token,
+		ExpiresAt: expires.Unix(),
+		User:      user,
+	})
+}
+
+func (r *Router) getCurrentUser(w http.ResponseWriter, req *http.Request) {
+	c := claims(req)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.User{
+		ID:         c.Subject,
+		Name:       c.Name,
+		Role:       c.Role,
+		Store:      c.Store,
+		Department: c.Department,
+	})
+}
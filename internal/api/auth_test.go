@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/config"
+	"github.com/dokk-dev/opus/internal/connectors"
+)
+
+const testSecret = "test-secret"
+
+// milkRule finds one warm dairy cooler
+type milkRule struct{}
+
+func (milkRule) Name() string { return "temperature" }
+
+func (milkRule) Evaluate(ctx context.Context, now time.Time) ([]alerts.Finding, error) {
+	return []alerts.Finding{{
+		Key:        "temperature:cooler-1",
+		Department: "dairy",
+		Severity:   alerts.SeverityWarning,
+		Title:      "Cooler 1 is warm",
+	}}, nil
+}
+
+// testRouter returns a router with one active dairy alert
+func testRouter(t *testing.T, cfg *config.Config) (*Router, *alerts.Alert) {
+	t.Helper()
+	engine := alerts.NewEngine(alerts.NewMemoryRepository(), milkRule{})
+	if err := engine.Evaluate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	list, err := engine.List(context.Background(), alerts.Filter{})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	registry := connectors.NewRegistry(connectors.Options{})
+	return NewRouter(cfg, nil, nil, nil, nil, nil, nil, nil, nil, engine, registry, nil, nil), &list[0]
+}
+
+func token(t *testing.T, user *auth.User) string {
+	t.Helper()
+	token, _, err := auth.IssueToken([]byte(testSecret), user, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func serve(r *Router, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate(t *testing.T) {
+	r, _ := testRouter(t, &config.Config{JWTSecret: testSecret})
+	manager := token(t, &auth.User{ID: "jsmith", Role: auth.RoleManager, Store: "42", Department: "dairy"})
+	forged, _, _ := auth.IssueToken([]byte("other-secret"), &auth.User{ID: "jsmith", Role: auth.RoleAdmin}, time.Hour)
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"no token", "/api/v1/auth/me", "", http.StatusUnauthorized},
+		{"forged token", "/api/v1/auth/me", forged, http.StatusUnauthorized},
+		{"valid token", "/api/v1/auth/me", manager, http.StatusOK},
+		{"query token", "/api/v1/auth/me?token=" + manager, "", http.StatusUnauthorized},
+		{"sign-in is public", "/api/v1/auth/login", "", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		method := "GET"
+		if strings.HasSuffix(tt.path, "/login") {
+			method = "POST"
+		}
+		if w := serve(r, method, tt.path, tt.token); w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	w := serve(r, "GET", "/api/v1/auth/me", manager)
+	var user auth.User
+	if err := json.NewDecoder(w.Body).Decode(&user); err != nil || user.ID != "jsmith" || user.Department != "dairy" {
+		t.Errorf("me = %+v, %v", user, err)
+	}
+}
+
+func TestAuthenticateChecksStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		store string
+		user  *auth.User
+		want  int
+	}{
+		{"own store", "42", &auth.User{ID: "jsmith", Role: auth.RoleManager, Store: "42"}, http.StatusOK},
+		{"other store", "42", &auth.User{ID: "kim", Role: auth.RoleManager, Store: "7"}, http.StatusForbidden},
+		{"no store claim", "42", &auth.User{ID: "kim", Role: auth.RoleManager}, http.StatusForbidden},
+		{"admin from elsewhere", "42", &auth.User{ID: "root", Role: auth.RoleAdmin, Store: "7"}, http.StatusOK},
+		{"no STORE_ID", "", &auth.User{ID: "kim", Role: auth.RoleManager, Store: "7"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r, _ := testRouter(t, &config.Config{JWTSecret: testSecret, StoreID: tt.store})
+		if w := serve(r, "GET", "/api/v1/alerts", token(t, tt.user)); w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthenticateWithoutSecret(t *testing.T) {
+	r, _ := testRouter(t, &config.Config{})
+	if w := serve(r, "GET", "/api/v1/auth/me", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without JWT_SECRET got %d, want 503", w.Code)
+	}
+
+	r, _ = testRouter(t, &config.Config{AuthDisabled: true})
+	w := serve(r, "GET", "/api/v1/auth/me", "")
+	var user auth.User
+	json.NewDecoder(w.Body).Decode(&user)
+	if w.Code != http.StatusOK || user.Role != auth.RoleAdmin {
+		t.Errorf("with AUTH_DISABLED got %d as %q, want an admin", w.Code, user.Role)
+	}
+}
+
+func TestAssociatesCantChangeAlerts(t *testing.T) {
+	r, alert := testRouter(t, &config.Config{JWTSecret: testSecret})
+	associate := token(t, &auth.User{ID: "pat", Role: auth.RoleAssociate, Store: "42", Department: "dairy"})
+	assistant := token(t, &auth.User{ID: "amy", Role: auth.RoleAssistantManager, Store: "42", Department: "dairy"})
+
+	if w := serve(r, "GET", "/api/v1/alerts/"+alert.ID, associate); w.Code != http.StatusOK {
+		t.Errorf("associate viewing the alert got %d, want 200", w.Code)
+	}
+	for _, action := range []string{"acknowledge", "snooze", "resolve", "escalate"} {
+		if w := serve(r, "POST", "/api/v1/alerts/"+alert.ID+"/"+action, associate); w.Code != http.StatusForbidden {
+			t.Errorf("associate %s got %d, want 403", action, w.Code)
+		}
+	}
+	if w := serve(r, "POST", "/api/v1/alerts/"+alert.ID+"/acknowledge", assistant); w.Code != http.StatusOK {
+		t.Errorf("assistant manager acknowledge got %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestStoreLevelReadsNeedAManager(t *testing.T) {
+	r, _ := testRouter(t, &config.Config{JWTSecret: testSecret})
+
+	tests := []struct {
+		name string
+		user *auth.User
+		want int
+	}{
+		{"store-level associate", &auth.User{ID: "pat", Role: auth.RoleAssociate, Store: "42"}, http.StatusForbidden},
+		{"store-level assistant manager", &auth.User{ID: "amy", Role: auth.RoleAssistantManager, Store: "42"}, http.StatusForbidden},
+		{"department manager", &auth.User{ID: "jsmith", Role: auth.RoleManager, Store: "42", Department: "dairy"}, http.StatusForbidden},
+		{"store manager", &auth.User{ID: "boss", Role: auth.RoleManager, Store: "42"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := serve(r, "GET", "/api/v1/connectors", token(t, tt.user)); w.Code != tt.want {
+			t.Errorf("%s: connectors got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/config"
+	"github.com/dokk-dev/opus/internal/conversation"
+)
+
+// chatRouter returns a router whose dairy agent answers through an Ollama
+// server streaming body
+func chatRouter(t *testing.T, body string) *Router {
+	t.Helper()
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ollama.Close)
+
+	definitions := []ai.DepartmentDefinition{{ID: ai.DeptDairy, Name: "Dairy", Keywords: []string{"milk"}}}
+	aiRouter, err := ai.NewRouter(definitions, ai.NewToolRegistry(), ai.NewOllamaClient(ollama.URL, "llama3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := chat.NewService(aiRouter, conversation.NewMemoryStore())
+	return NewRouter(&config.Config{AuthDisabled: true}, nil, service, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+// event is one Server-Sent Event
+type event struct {
+	name string
+	data string
+}
+
+func readEvents(t *testing.T, body string) []event {
+	t.Helper()
+	var events []event
+	var current event
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = event{}
+		}
+	}
+	return events
+}
+
+func TestStreamChat(t *testing.T) {
+	tests := []struct {
+		name   string
+		ollama string
+		want   []string
+		done   string
+	}{
+		{
+			name: "answer",
+			ollama: `{"message": {"content": "Aisle "}, "done": false}` + "\n" +
+				`{"message": {"content": "3."}, "done": false}` + "\n" +
+				`{"done": true}` + "\n",
+			want: []string{"delta", "delta", "done"},
+			done: "Aisle 3.",
+		},
+		{
+			name:   "model fails mid-stream",
+			ollama: `{"message": {"content": "Aisle "}, "done": false}` + "\n" + `{"error": "out of memory"}` + "\n",
+			want:   []string{"delta", "error"},
+		},
+	}
+	for _, tt := range tests {
+		r := chatRouter(t, tt.ollama)
+		req := httptest.NewRequest("POST", "/api/v1/chat", strings.NewReader(`{"message": "Where is the milk?", "stream": true}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("%s: Content-Type = %q", tt.name, ct)
+		}
+		events := readEvents(t, w.Body.String())
+		var names []string
+		for _, e := range events {
+			names = append(names, e.name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: events %v, want %v", tt.name, names, tt.want)
+			continue
+		}
+
+		last := events[len(events)-1]
+		if tt.done == "" {
+			if strings.Contains(last.data, "out of memory") {
+				t.Errorf("%s: error event leaks the model error: %s", tt.name, last.data)
+			}
+			continue
+		}
+		var resp ChatResponse
+		if err := json.Unmarshal([]byte(last.data), &resp); err != nil {
+			t.Fatalf("%s: done event %q: %v", tt.name, last.data, err)
+		}
+		if resp.Response != tt.done || resp.Department != "dairy" {
+			t.Errorf("%s: done with %q from %q, want %q from dairy", tt.name, resp.Response, resp.Department, tt.done)
+		}
+	}
+}
+
+func TestChatWithoutStreamAnswersJSON(t *testing.T) {
+	r := chatRouter(t, `{"message": {"content": "Aisle 3."}, "done": true}`)
+	req := httptest.NewRequest("POST", "/api/v1/chat", strings.NewReader(`{"message": "Where is the milk?"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp ChatResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("got %d: %v", w.Code, err)
+	}
+	if resp.Response != "Aisle 3." {
+		t.Errorf("Response = %q", resp.Response)
+	}
+}
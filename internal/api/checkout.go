@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+)
+
+// topItems is how many best sellers the sales endpoint returns
+const topItems = 20
+
+// getLanes returns every checkout lane's status with today's totals
+func (r *Router) getLanes(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireDepartment(w, req, checkout.Department) {
+		return
+	}
+
+	lanes, err := r.checkout.Lanes(req.Context())
+	if err != nil {
+		log.Printf("Failed to load lanes: %v", err)
+		http.Error(w, `{"error": "Failed to load lanes"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(lanes)
+}
+
+// getSales returns sales per hour and best sellers for ?date=YYYY-MM-DD,
+// defaulting to today
+func (r *Router) getSales(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireDepartment(w, req, checkout.Department) {
+		return
+	}
+
+	date := time.Now()
+	if v := req.URL.Query().Get("date"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, `{"error": "date must be YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	day, err := checkout.LoadDay(req.Context(), r.checkout, date, topItems)
+	if err != nil {
+		log.Printf("Failed to load sales: %v", err)
+		http.Error(w, `{"error": "Failed to load sales"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(day)
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// getConnectors reports each connector's state, latest health probe and
+// reconnect progress
+func (r *Router) getConnectors(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireStoreLevel(w, req) {
+		return
+	}
+	json.NewEncoder(w).Encode(r.connectors.Status())
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dokk-dev/opus/internal/imports"
+)
+
+// getImports lists import runs, newest first, filtered by ?source=, ?kind=
+// and ?status=, up to ?limit= (default 50). Row errors are left out; get a
+// single run to see them.
+func (r *Router) getImports(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireStoreLevel(w, req) {
+		return
+	}
+
+	values := req.URL.Query()
+	filter := imports.Filter{
+		Source: values.Get("source"),
+		Kind:   values.Get("kind"),
+		Status: imports.Status(values.Get("status")),
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, `{"error": "limit must be a positive number"}`, http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	runs, err := r.imports.List(req.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to list import runs: %v", err)
+		http.Error(w, `{"error": "Failed to list import runs"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(runs)
+}
+
+// getImport returns an import run with its row errors
+func (r *Router) getImport(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !requireStoreLevel(w, req) {
+		return
+	}
+
+	run, err := r.imports.Get(req.Context(), req.PathValue("id"))
+	if errors.Is(err, imports.ErrNotFound) {
+		http.Error(w, `{"error": "Import run not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load import run: %v", err)
+		http.Error(w, `{"error": "Failed to load import run"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(run)
+}
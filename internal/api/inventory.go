@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/inventory"
+)
+
+// InventoryResponse is one page of a department's inventory
+type InventoryResponse struct {
+	Department string `json:"department"`
+	*inventory.Page
+	// LastUpdated is the most recent update among the returned items
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+// getDepartmentInventory lists a department's items. Query parameters:
+//
+//	q                 search SKU, UPC or description
+//	below_reorder     true for items at or below their reorder point
+//	expiring_within   e.g. 48h or 3d, for items with a lot expiring by then
+//	sort              sku, description, onHand, reorderPoint, expiresOn or
+//	                  updatedAt; prefix with - for descending
+//	limit, offset     pagination
+func (r *Router) getDepartmentInventory(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dept := req.PathValue("dept")
+	if !requireDepartment(w, req, dept) {
+		return
+	}
+	if _, ok := r.chat.Router().Agent(ai.Department(dept)); !ok {
+		http.Error(w, `{"error": "Unknown department"}`, http.StatusNotFound)
+		return
+	}
+
+	query, errBody := parseInventoryQuery(req.URL.Query())
+	if errBody != "" {
+		http.Error(w, errBody, http.StatusBadRequest)
+		return
+	}
+	query.Department = dept
+
+	page, err := r.inventory.List(req.Context(), query)
+	if errors.Is(err, inventory.ErrInvalidQuery) {
+		http.Error(w, `{"error": "Invalid sort field"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to list inventory: %v", err)
+		http.Error(w, `{"error": "Failed to load inventory"}`, http.StatusInternalServerError)
+		return
+	}
+
+	resp := InventoryResponse{Department: dept, Page: page}
+	for _, item := range page.Items {
+		if resp.LastUpdated == nil || item.UpdatedAt.After(*resp.LastUpdated) {
+			updated := item.UpdatedAt
+			resp.LastUpdated = &updated
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseInventoryQuery reads inventory filters from query parameters,
+// returning a JSON error body if one is invalid
+func parseInventoryQuery(values url.Values) (inventory.Query, string) {
+	query := inventory.Query{Search: strings.TrimSpace(values.Get("q"))}
+
+	if v := values.Get("below_reorder"); v != "" {
+		below, err := strconv.ParseBool(v)
+		if err != nil {
+			return query, `{"error": "below_reorder must be true or false"}`
+		}
+		query.BelowReorder = below
+	}
+
+	if v := values.Get("expiring_within"); v != "" {
+		within, err := parseWindow(v)
+		if err != nil || within <= 0 {
+			return query, `{"error": "expiring_within must be a duration such as 48h or 3d"}`
+		}
+		query.ExpiringWithin = within
+	}
+
+	if v := values.Get("sort"); v != "" {
+		query.Sort, query.Desc = strings.TrimPrefix(v, "-"), strings.HasPrefix(v, "-")
+	}
+
+	var err error
+	if query.Limit, err = intParam(values, "limit"); err != nil || query.Limit < 0 {
+		return query, `{"error": "limit must be a positive number"}`
+	}
+	if query.Offset, err = intParam(values, "offset"); err != nil || query.Offset < 0 {
+		return query, `{"error": "offset must be zero or more"}`
+	}
+	return query, ""
+}
+
+// parseWindow parses a Go duration, also accepting whole days such as 3d
+func parseWindow(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// intParam reads an optional integer query parameter
+func intParam(values url.Values, name string) (int, error) {
+	v := values.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
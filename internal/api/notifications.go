@@ -0,0 +1,314 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/channels/email"
+	"github.com/dokk-dev/opus/internal/notify"
+)
+
+// requireNotifier writes a 503 response and returns false unless email
+// is configured
+func (r *Router) requireNotifier(w http.ResponseWriter) bool {
+	if r.notifier != nil {
+		return true
+	}
+	http.Error(w, `{"error": "Email notifications are not configured"}`, http.StatusServiceUnavailable)
+	return false
+}
+
+// notificationUser returns the signed-in user's ID, writing a 400 if
+// they have none because the API is open
+func notificationUser(w http.ResponseWriter, req *http.Request) (string, bool) {
+	if id := claims(req).Subject; id != "" {
+		return id, true
+	}
+	http.Error(w, `{"error": "Sign in to manage notifications"}`, http.StatusBadRequest)
+	return "", false
+}
+
+// getNotificationPreferences returns what the signed-in user has emailed
+func (r *Router) getNotificationPreferences(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireNotifier(w) {
+		return
+	}
+	userID, ok := notificationUser(w, req)
+	if !ok {
+		return
+	}
+
+	p, err := r.notifier.Preferences(req.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load notification preferences: %v", err)
+		http.Error(w, `{"error": "Failed to load preferences"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// putNotificationPreferences replaces the signed-in user's preferences
+func (r *Router) putNotificationPreferences(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireNotifier(w) {
+		return
+	}
+	userID, ok := notificationUser(w, req)
+	if !ok {
+		return
+	}
+
+	var p email.Preferences
+	if err := json.NewDecoder(req.Body).Decode(&p); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	p.UserID = userID
+
+	err := r.notifier.SavePreferences(req.Context(), &p)
+	if errors.Is(err, email.ErrInvalidPreferences) {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save notification preferences: %v", err)
+		http.Error(w, `{"error": "Failed to save preferences"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// sendDigest emails the signed-in user today's digest now, whatever
+// their preferences, and returns the delivery
+func (r *Router) sendDigest(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireNotifier(w) {
+		return
+	}
+	userID, ok := notificationUser(w, req)
+	if !ok {
+		return
+	}
+
+	d, err := r.notifier.SendDigest(req.Context(), userID)
+	if errors.Is(err, auth.ErrUnknownUser) || errors.Is(err, email.ErrNoAddress) {
+		http.Error(w, `{"error": "You don't have an email address"}`, http.StatusBadRequest)
+		return
+	}
+	if d == nil {
+		log.Printf("Failed to send digest: %v", err)
+		http.Error(w, `{"error": "Failed to send digest"}`, http.StatusInternalServerError)
+		return
+	}
+	// A delivery is recorded even if the SMTP server refused it
+	if d.Status == email.DeliveryFailed {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(d)
+}
+
+// getDeliveries lists recorded emails, newest first. Users see their
+// own; store-level users see everyone's. Query parameters:
+//
+//	user     only this user's, for store-level users
+//	kind     digest or alert
+//	status   sent or failed
+//	since    an RFC 3339 time
+//	limit    at most this many
+func (r *Router) getDeliveries(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireNotifier(w) {
+		return
+	}
+
+	values := req.URL.Query()
+	filter := email.DeliveryFilter{
+		UserID: values.Get("user"),
+		Kind:   email.Kind(values.Get("kind")),
+		Status: email.DeliveryStatus(values.Get("status")),
+	}
+	if c := claims(req); !c.StoreLevel() || !c.Role.AtLeast(auth.RoleManager) {
+		if filter.UserID != "" && filter.UserID != c.Subject {
+			requireStoreLevel(w, req)
+			return
+		}
+		filter.UserID = c.Subject
+	}
+
+	switch filter.Kind {
+	case "", email.KindDigest, email.KindAlert:
+	default:
+		http.Error(w, `{"error": "kind must be digest or alert"}`, http.StatusBadRequest)
+		return
+	}
+	switch filter.Status {
+	case "", email.DeliverySent, email.DeliveryFailed:
+	default:
+		http.Error(w, `{"error": "status must be sent or failed"}`, http.StatusBadRequest)
+		return
+	}
+	if v := values.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, `{"error": "since must be an RFC 3339 time"}`, http.StatusBadRequest)
+			return
+		}
+		filter.Since = since
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, `{"error": "limit must be a positive number"}`, http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	deliveries, err := r.notifier.Deliveries(req.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to list email deliveries: %v", err)
+		http.Error(w, `{"error": "Failed to list deliveries"}`, http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []email.Delivery{}
+	}
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// requireRouting writes a 503 response and returns false unless
+// notification routing is configured
+func (r *Router) requireRouting(w http.ResponseWriter) bool {
+	if r.routing != nil {
+		return true
+	}
+	http.Error(w, `{"error": "Notification routing is not configured"}`, http.StatusServiceUnavailable)
+	return false
+}
+
+// EscalationTier is a tier of the notification policy
+type EscalationTier struct {
+	Name string `json:"name"`
+	// Wait is how long the tier has to acknowledge an alert, e.g. "10m0s";
+	// empty for the last tier
+	Wait string `json:"wait,omitempty"`
+}
+
+type NotificationPolicyResponse struct {
+	Tiers []EscalationTier `json:"tiers"`
+	// DefaultChannels are tried in order for users without preferences
+	DefaultChannels []string `json:"defaultChannels"`
+	// Channels are the channels users can choose from
+	Channels    []string        `json:"channels"`
+	MinSeverity alerts.Severity `json:"minSeverity"`
+}
+
+// getNotificationPolicy returns the escalation tiers and the channels
+// alerts are routed through
+func (r *Router) getNotificationPolicy(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireRouting(w) {
+		return
+	}
+
+	tiers, defaults, minSeverity := r.routing.Policy()
+	resp := NotificationPolicyResponse{
+		Tiers:           []EscalationTier{},
+		DefaultChannels: defaults,
+		Channels:        r.routing.Channels(),
+		MinSeverity:     minSeverity,
+	}
+	for _, t := range tiers {
+		tier := EscalationTier{Name: t.Name}
+		if t.Wait > 0 {
+			tier.Wait = t.Wait.String()
+		}
+		resp.Tiers = append(resp.Tiers, tier)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// getChannelPreferences returns the channels the signed-in user is
+// reached on and their quiet hours
+func (r *Router) getChannelPreferences(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireRouting(w) {
+		return
+	}
+	userID, ok := notificationUser(w, req)
+	if !ok {
+		return
+	}
+
+	p, err := r.routing.Preferences(req.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load channel preferences: %v", err)
+		http.Error(w, `{"error": "Failed to load preferences"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// putChannelPreferences replaces the signed-in user's channels and quiet
+// hours
+func (r *Router) putChannelPreferences(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireRouting(w) {
+		return
+	}
+	userID, ok := notificationUser(w, req)
+	if !ok {
+		return
+	}
+
+	var p notify.Preferences
+	if err := json.NewDecoder(req.Body).Decode(&p); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	p.UserID = userID
+
+	err := r.routing.SavePreferences(req.Context(), &p)
+	if errors.Is(err, notify.ErrInvalidPreferences) {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save channel preferences: %v", err)
+		http.Error(w, `{"error": "Failed to save preferences"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// getAlertNotifications lists who an alert was routed to and how, newest
+// first
+func (r *Router) getAlertNotifications(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !r.requireRouting(w) {
+		return
+	}
+	alert, ok := r.loadAlert(w, req)
+	if !ok {
+		return
+	}
+
+	records, err := r.routing.Records(req.Context(), notify.RecordFilter{AlertID: alert.ID, Limit: 200})
+	if err != nil {
+		log.Printf("Failed to list alert notifications: %v", err)
+		http.Error(w, `{"error": "Failed to list notifications"}`, http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []notify.Record{}
+	}
+	json.NewEncoder(w).Encode(records)
+}
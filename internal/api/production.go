@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/production"
+)
+
+// ProductionResponse is a department's production plans and shrink for a
+// day
+type ProductionResponse struct {
+	Date   string              `json:"date"`
+	Plans  []production.Plan   `json:"plans"`
+	Shrink []production.Shrink `json:"shrink"`
+}
+
+// getDepartmentProduction returns a department's production plans and
+// shrink for ?date=YYYY-MM-DD, defaulting to today
+func (r *Router) getDepartmentProduction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dept := req.PathValue("dept")
+	if !requireDepartment(w, req, dept) {
+		return
+	}
+	if _, ok := r.chat.Router().Agent(ai.Department(dept)); !ok {
+		http.Error(w, `{"error": "Unknown department"}`, http.StatusNotFound)
+		return
+	}
+
+	date := time.Now().Format(time.DateOnly)
+	if v := req.URL.Query().Get("date"); v != "" {
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			http.Error(w, `{"error": "date must be YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		date = v
+	}
+
+	plans, err := r.production.Plans(req.Context(), dept, date, date)
+	if err != nil {
+		log.Printf("Failed to load production plans: %v", err)
+		http.Error(w, `{"error": "Failed to load production"}`, http.StatusInternalServerError)
+		return
+	}
+	shrink, err := r.production.Shrink(req.Context(), dept, date, date)
+	if err != nil {
+		log.Printf("Failed to load shrink: %v", err)
+		http.Error(w, `{"error": "Failed to load production"}`, http.StatusInternalServerError)
+		return
+	}
+
+	resp := ProductionResponse{Date: date, Plans: plans, Shrink: shrink}
+	if resp.Plans == nil {
+		resp.Plans = []production.Plan{}
+	}
+	if resp.Shrink == nil {
+		resp.Shrink = []production.Shrink{}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
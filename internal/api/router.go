@@ -2,26 +2,66 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/channels/email"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/checkout"
 	"github.com/dokk-dev/opus/internal/config"
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/conversation"
 	"github.com/dokk-dev/opus/internal/gateway"
+	"github.com/dokk-dev/opus/internal/imports"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/notify"
+	"github.com/dokk-dev/opus/internal/production"
+	"github.com/dokk-dev/opus/internal/schedule"
 )
 
 type Router struct {
-	mux      *http.ServeMux
-	config   *config.Config
-	gateway  *gateway.Gateway
-	aiRouter *ai.Router
+	mux        *http.ServeMux
+	config     *config.Config
+	gateway    *gateway.Gateway
+	chat       *chat.Service
+	users      auth.Authenticator
+	inventory  inventory.Repository
+	schedules  schedule.Repository
+	checkout   checkout.Repository
+	production production.Repository
+	imports    imports.Repository
+	alerts     *alerts.Engine
+	connectors *connectors.Registry
+	notifier   *email.Notifier
+	routing    *notify.Router
 }
 
-func NewRouter(cfg *config.Config, gw *gateway.Gateway, aiRouter *ai.Router) *Router {
+// NewRouter creates the HTTP API. users checks sign-in credentials and
+// may be nil if tokens are issued elsewhere, notifier is nil unless
+// email is configured, and routing is nil unless notification routing
+// is.
+func NewRouter(cfg *config.Config, gw *gateway.Gateway, chatService *chat.Service, users auth.Authenticator, items inventory.Repository, schedules schedule.Repository, lanes checkout.Repository, plans production.Repository, importRuns imports.Repository, alertEngine *alerts.Engine, connectorRegistry *connectors.Registry, notifier *email.Notifier, routing *notify.Router) *Router {
 	r := &Router{
-		mux:      http.NewServeMux(),
-		config:   cfg,
-		gateway:  gw,
-		aiRouter: aiRouter,
+		mux:        http.NewServeMux(),
+		config:     cfg,
+		gateway:    gw,
+		chat:       chatService,
+		users:      users,
+		inventory:  items,
+		schedules:  schedules,
+		checkout:   lanes,
+		production: plans,
+		imports:    importRuns,
+		alerts:     alertEngine,
+		connectors: connectorRegistry,
+		notifier:   notifier,
+		routing:    routing,
 	}
 
 	r.setupRoutes()
@@ -30,7 +70,10 @@ func NewRouter(cfg *config.Config, gw *gateway.Gateway, aiRouter *ai.Router) *Ro
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// CORS middleware
-	w.Header().Set("Access-Control-Allow-Origin", r.config.CORSOrigins[0])
+	if origin := req.Header.Get("Origin"); origin != "" && r.config.AllowsOrigin(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
@@ -39,9 +82,24 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Every API route needs a signed-in user except sign-in itself
+	if strings.HasPrefix(req.URL.Path, "/api/") && !publicPaths[req.URL.Path] {
+		claims, ok := r.authenticate(w, req)
+		if !ok {
+			return
+		}
+		req = req.WithContext(auth.WithClaims(req.Context(), claims))
+	}
+
 	r.mux.ServeHTTP(w, req)
 }
 
+// Handle registers a handler outside the authenticated API, for channel
+// webhooks that verify their requests themselves
+func (r *Router) Handle(pattern string, handler http.HandlerFunc) {
+	r.mux.HandleFunc(pattern, handler)
+}
+
 func (r *Router) setupRoutes() {
 	// Health check
 	r.mux.HandleFunc("GET /health", r.healthCheck)
@@ -49,17 +107,55 @@ func (r *Router) setupRoutes() {
 	// WebSocket endpoint
 	r.mux.HandleFunc("GET /ws", r.gateway.HandleWebSocket)
 
+	// Authentication
+	r.mux.HandleFunc("POST /api/v1/auth/login", r.login)
+	r.mux.HandleFunc("GET /api/v1/auth/me", r.getCurrentUser)
+
 	// API v1
 	r.mux.HandleFunc("GET /api/v1/status", r.getStatus)
 	r.mux.HandleFunc("POST /api/v1/chat", r.handleChat)
 
+	// Conversations
+	r.mux.HandleFunc("POST /api/v1/conversations", r.createConversation)
+	r.mux.HandleFunc("GET /api/v1/conversations/{id}", r.getConversation)
+
 	// Department endpoints
 	r.mux.HandleFunc("GET /api/v1/departments", r.getDepartments)
 	r.mux.HandleFunc("GET /api/v1/departments/{dept}/inventory", r.getDepartmentInventory)
 	r.mux.HandleFunc("GET /api/v1/departments/{dept}/schedule", r.getDepartmentSchedule)
+	r.mux.HandleFunc("GET /api/v1/departments/{dept}/production", r.getDepartmentProduction)
+
+	// Schedule
+	r.mux.HandleFunc("GET /api/v1/schedule/gaps", r.getCoverageGaps)
+
+	// Checkout lanes and sales
+	r.mux.HandleFunc("GET /api/v1/lanes", r.getLanes)
+	r.mux.HandleFunc("GET /api/v1/sales", r.getSales)
 
 	// Alerts
 	r.mux.HandleFunc("GET /api/v1/alerts", r.getAlerts)
+	r.mux.HandleFunc("GET /api/v1/alerts/{id}", r.getAlert)
+	r.mux.HandleFunc("POST /api/v1/alerts/{id}/acknowledge", r.acknowledgeAlert)
+	r.mux.HandleFunc("POST /api/v1/alerts/{id}/snooze", r.snoozeAlert)
+	r.mux.HandleFunc("POST /api/v1/alerts/{id}/resolve", r.resolveAlert)
+	r.mux.HandleFunc("POST /api/v1/alerts/{id}/escalate", r.escalateAlert)
+	r.mux.HandleFunc("GET /api/v1/alerts/{id}/notifications", r.getAlertNotifications)
+
+	// Connectors
+	r.mux.HandleFunc("GET /api/v1/connectors", r.getConnectors)
+	r.mux.HandleFunc("GET /api/v1/imports", r.getImports)
+	r.mux.HandleFunc("GET /api/v1/imports/{id}", r.getImport)
+
+	// Email notifications
+	r.mux.HandleFunc("GET /api/v1/notifications/preferences", r.getNotificationPreferences)
+	r.mux.HandleFunc("PUT /api/v1/notifications/preferences", r.putNotificationPreferences)
+	r.mux.HandleFunc("POST /api/v1/notifications/digest", r.sendDigest)
+	r.mux.HandleFunc("GET /api/v1/notifications/deliveries", r.getDeliveries)
+
+	// Notification routing
+	r.mux.HandleFunc("GET /api/v1/notifications/policy", r.getNotificationPolicy)
+	r.mux.HandleFunc("GET /api/v1/notifications/channels", r.getChannelPreferences)
+	r.mux.HandleFunc("PUT /api/v1/notifications/channels", r.putChannelPreferences)
 }
 
 func (r *Router) healthCheck(w http.ResponseWriter, req *http.Request) {
@@ -73,6 +169,10 @@ func (r *Router) getStatus(w http.ResponseWriter, req *http.Request) {
 		"model":   r.config.OllamaModel,
 		"version": "0.1.0",
 	}
+	if providers := r.chat.Router().Providers(); len(providers) > 1 {
+		status["fallback"] = providers[1].Name()
+		status["fallbackModel"] = providers[1].Model()
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
@@ -81,6 +181,13 @@ func (r *Router) getStatus(w http.ResponseWriter, req *http.Request) {
 type ChatRequest struct {
 	Message string       `json:"message"`
 	History []ai.Message `json:"history,omitempty"`
+	Stream  bool         `json:"stream,omitempty"`
+	// ConversationID continues a server-side conversation; its history is
+	// loaded by the server, so History must be empty
+	ConversationID string `json:"conversationId,omitempty"`
+	// Department skips routing, e.g. after the user picks one of the
+	// candidates from an ambiguous response
+	Department string `json:"department,omitempty"`
 }
 
 // ChatResponse represents the AI response
@@ -88,6 +195,54 @@ type ChatResponse struct {
 	Response   string `json:"response"`
 	Department string `json:"department"`
 	Model      string `json:"model"`
+	// Ambiguous is set when the question could belong to several
+	// departments; the client should ask the user to pick from Candidates
+	// and resend with Department set
+	Ambiguous  bool     `json:"ambiguous,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
+	// Departments lists the departments consulted for a store-wide answer
+	Departments    []string `json:"departments,omitempty"`
+	ConversationID string   `json:"conversationId,omitempty"`
+	// Actions are suggested next steps to show as buttons
+	Actions []ai.Action `json:"actions,omitempty"`
+	// Citations are the document passages the response cites as [n]
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation is a passage from a store document
+type Citation struct {
+	Number  int    `json:"number"`
+	Source  string `json:"source"`
+	Section string `json:"section,omitempty"`
+	Page    int    `json:"page,omitempty"`
+	Text    string `json:"text"`
+}
+
+func newChatResponse(result *chat.Result) ChatResponse {
+	resp := ChatResponse{
+		Response:       result.Response,
+		Department:     string(result.Department),
+		Model:          result.Model,
+		Ambiguous:      result.Ambiguous,
+		ConversationID: result.ConversationID,
+	}
+	for _, d := range result.Candidates {
+		resp.Candidates = append(resp.Candidates, string(d))
+	}
+	for _, d := range result.Departments {
+		resp.Departments = append(resp.Departments, string(d))
+	}
+	resp.Actions = result.Actions
+	for _, c := range result.Citations {
+		resp.Citations = append(resp.Citations, Citation{
+			Number:  c.Number,
+			Source:  c.Source,
+			Section: c.Section,
+			Page:    c.Page,
+			Text:    c.Text,
+		})
+	}
+	return resp
 }
 
 func (r *Router) handleChat(w http.ResponseWriter, req *http.Request) {
@@ -104,63 +259,145 @@ func (r *Router) handleChat(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Process through AI router
-	response, dept, err := r.aiRouter.ProcessQuery(req.Context(), chatReq.Message, chatReq.History)
+	// Reject a bad department before an event stream is started
+	if chatReq.Department != "" {
+		if _, ok := r.chat.Router().Agent(ai.Department(chatReq.Department)); !ok {
+			http.Error(w, `{"error": "Unknown department"}`, http.StatusBadRequest)
+			return
+		}
+		if !requireDepartment(w, req, chatReq.Department) {
+			return
+		}
+	}
+
+	if chatReq.Stream || strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		r.streamChat(w, req, chatReq)
+		return
+	}
+
+	// A store-wide answer waits on every department's agent, which can
+	// take longer than the server's WriteTimeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Process through the chat pipeline
+	result, err := r.chat.Process(req.Context(), newChatServiceRequest(req, chatReq), nil)
 	if err != nil {
-		// Return error but don't expose internal details
-		http.Error(w, `{"error": "Failed to process message. Is Ollama running?"}`, http.StatusServiceUnavailable)
+		status, body := chatError(err)
+		http.Error(w, body, status)
 		return
 	}
 
-	json.NewEncoder(w).Encode(ChatResponse{
-		Response:   response,
-		Department: string(dept),
-		Model:      r.config.OllamaModel,
-	})
+	json.NewEncoder(w).Encode(newChatResponse(result))
 }
 
-func (r *Router) getDepartments(w http.ResponseWriter, req *http.Request) {
-	// Demo data
-	departments := []map[string]interface{}{
-		{"id": "dairy", "name": "Dairy", "manager": "Demo Manager"},
-		{"id": "produce", "name": "Produce", "manager": "Demo Manager"},
-		{"id": "meat", "name": "Meat", "manager": "Demo Manager"},
-		{"id": "bakery", "name": "Bakery", "manager": "Demo Manager"},
-		{"id": "deli", "name": "Deli", "manager": "Demo Manager"},
-		{"id": "grocery", "name": "Grocery", "manager": "Demo Manager"},
-		{"id": "frontend", "name": "Front End", "manager": "Demo Manager"},
+func newChatServiceRequest(req *http.Request, chatReq ChatRequest) chat.Request {
+	return chat.Request{
+		Message:        chatReq.Message,
+		History:        chatReq.History,
+		ConversationID: chatReq.ConversationID,
+		UserID:         claims(req).Subject,
+		Department:     ai.Department(chatReq.Department),
+		Scope:          ai.Department(departmentScope(req)),
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(departments)
 }
 
-func (r *Router) getDepartmentInventory(w http.ResponseWriter, req *http.Request) {
-	dept := req.PathValue("dept")
-	// Demo data
-	inventory := map[string]interface{}{
-		"department": dept,
-		"items":      []map[string]interface{}{},
-		"lastUpdated": "2024-01-01T00:00:00Z",
+// chatError maps a chat pipeline error to a status code and JSON body
+// without exposing internal details
+func chatError(err error) (int, string) {
+	switch {
+	case errors.Is(err, chat.ErrEmptyMessage):
+		return http.StatusBadRequest, `{"error": "Message is required"}`
+	case errors.Is(err, chat.ErrUnknownDepartment):
+		return http.StatusBadRequest, `{"error": "Unknown department"}`
+	case errors.Is(err, chat.ErrHistoryConflict):
+		return http.StatusBadRequest, `{"error": "History can't be sent with a conversation ID"}`
+	case errors.Is(err, chat.ErrOutOfScope):
+		return http.StatusForbidden, `{"error": "You don't have access to this department"}`
+	case errors.Is(err, conversation.ErrNotFound):
+		return http.StatusNotFound, `{"error": "Conversation not found"}`
+	default:
+		log.Printf("Chat error: %v", err)
+		return http.StatusServiceUnavailable, `{"error": "Failed to process message. Is Ollama running?"}`
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(inventory)
 }
 
-func (r *Router) getDepartmentSchedule(w http.ResponseWriter, req *http.Request) {
-	dept := req.PathValue("dept")
-	// Demo data
-	schedule := map[string]interface{}{
-		"department": dept,
-		"shifts":     []map[string]interface{}{},
-		"week":       "current",
+// streamChat answers a chat request as Server-Sent Events: a "delta" event
+// per token chunk, then a "done" event carrying the full ChatResponse, or an
+// "error" event if the model fails mid-stream. The model request is
+// cancelled as soon as the client goes away.
+func (r *Router) streamChat(w http.ResponseWriter, req *http.Request, chatReq ChatRequest) {
+	sse := newSSEWriter(w)
+
+	result, err := r.chat.Process(req.Context(), newChatServiceRequest(req, chatReq), func(delta string) error {
+		return sse.Send("delta", map[string]string{"content": delta})
+	})
+	if err != nil {
+		if req.Context().Err() != nil {
+			// Client disconnected, nobody left to tell
+			return
+		}
+		_, body := chatError(err)
+		sse.Send("error", json.RawMessage(body))
+		return
 	}
+
+	sse.Send("done", newChatResponse(result))
+}
+
+func (r *Router) createConversation(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(schedule)
+
+	conv, err := r.chat.Conversations().Create(req.Context(), claims(req).Subject, ai.Department(departmentScope(req)))
+	if err != nil {
+		log.Printf("Failed to create conversation: %v", err)
+		http.Error(w, `{"error": "Failed to create conversation"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conv)
 }
 
-func (r *Router) getAlerts(w http.ResponseWriter, req *http.Request) {
-	// Demo data
-	alerts := []map[string]interface{}{}
+func (r *Router) getConversation(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(alerts)
+
+	conv, err := r.chat.Conversation(req.Context(), req.PathValue("id"), claims(req).Subject, ai.Department(departmentScope(req)))
+	if errors.Is(err, conversation.ErrNotFound) {
+		http.Error(w, `{"error": "Conversation not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load conversation: %v", err)
+		http.Error(w, `{"error": "Failed to load conversation"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(conv)
+}
+
+// Department describes a department agent
+type Department struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Version  int      `json:"version"`
+	Keywords []string `json:"keywords"`
+	Model    string   `json:"model,omitempty"`
+	Tools    []string `json:"tools,omitempty"`
+}
+
+func (r *Router) getDepartments(w http.ResponseWriter, req *http.Request) {
+	definitions := r.chat.Router().Definitions()
+	departments := make([]Department, 0, len(definitions))
+	for _, def := range definitions {
+		departments = append(departments, Department{
+			ID:       string(def.ID),
+			Name:     def.Name,
+			Version:  def.Version,
+			Keywords: def.Keywords,
+			Model:    def.Model,
+			Tools:    def.Tools,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(departments)
 }
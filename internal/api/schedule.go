@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// CoverageGapsResponse lists the short-staffed hours of a week
+type CoverageGapsResponse struct {
+	Start time.Time               `json:"start"`
+	End   time.Time               `json:"end"`
+	Gaps  []schedule.HourCoverage `json:"gaps"`
+}
+
+// parseWeek returns the Monday-to-Monday week containing the "week" query
+// parameter (YYYY-MM-DD), defaulting to the current week
+func parseWeek(req *http.Request) (time.Time, time.Time, bool) {
+	day := time.Now()
+	if v := req.URL.Query().Get("week"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		day = parsed
+	}
+	start := schedule.WeekStart(day)
+	return start, start.AddDate(0, 0, 7), true
+}
+
+// getDepartmentSchedule returns a department's shifts, time off and hourly
+// coverage for the week containing ?week=YYYY-MM-DD
+func (r *Router) getDepartmentSchedule(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dept := req.PathValue("dept")
+	if !requireDepartment(w, req, dept) {
+		return
+	}
+	if _, ok := r.chat.Router().Agent(ai.Department(dept)); !ok {
+		http.Error(w, `{"error": "Unknown department"}`, http.StatusNotFound)
+		return
+	}
+
+	start, end, ok := parseWeek(req)
+	if !ok {
+		http.Error(w, `{"error": "week must be a date as YYYY-MM-DD"}`, http.StatusBadRequest)
+		return
+	}
+
+	period, err := schedule.Load(req.Context(), r.schedules, dept, start, end)
+	if err != nil {
+		log.Printf("Failed to load schedule: %v", err)
+		http.Error(w, `{"error": "Failed to load schedule"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(period)
+}
+
+// getCoverageGaps returns the short-staffed hours of every department the
+// user can see for the week containing ?week=YYYY-MM-DD
+func (r *Router) getCoverageGaps(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	start, end, ok := parseWeek(req)
+	if !ok {
+		http.Error(w, `{"error": "week must be a date as YYYY-MM-DD"}`, http.StatusBadRequest)
+		return
+	}
+
+	period, err := schedule.Load(req.Context(), r.schedules, departmentScope(req), start, end)
+	if err != nil {
+		log.Printf("Failed to load schedule: %v", err)
+		http.Error(w, `{"error": "Failed to load schedule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CoverageGapsResponse{
+		Start: start,
+		End:   end,
+		Gaps:  period.Gaps,
+	})
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseWriter writes Server-Sent Events to an HTTP response
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// newSSEWriter prepares the response for an event stream and flushes the
+// headers so the client sees the connection open immediately
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	rc := http.NewResponseController(w)
+
+	// A streamed answer can take longer than the server's WriteTimeout
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	return &sseWriter{w: w, rc: rc}
+}
+
+// Send writes a single named event with a JSON payload
+func (s *sseWriter) Send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
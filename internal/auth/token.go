@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Role is what a user may do in Opus
+type Role string
+
+const (
+	RoleAssociate        Role = "associate"
+	RoleManager          Role = "manager"
+	RoleAssistantManager Role = "assistant_manager"
+	RoleAdmin            Role = "admin"
+)
+
+// rank orders roles by how much they may do. Associates can ask Opus
+// questions and see their department, but not act on alerts.
+var rank = map[Role]int{
+	RoleAssociate:        1,
+	RoleAssistantManager: 2,
+	RoleManager:          3,
+	RoleAdmin:            4,
+}
+
+// AtLeast reports whether r has all the permissions of min
+func (r Role) AtLeast(min Role) bool {
+	return rank[r] >= rank[min]
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := rank[r]
+	return ok
+}
+
+// ErrNoToken is returned when a request carries no bearer token
+var ErrNoToken = errors.New("no token")
+
+// Claims identify a user. The subject is the user ID. Department is set
+// for department managers and empty for store-level staff.
+type Claims struct {
+	jwt.RegisteredClaims
+	Name       string `json:"name,omitempty"`
+	Role       Role   `json:"role"`
+	Store      string `json:"store,omitempty"`
+	Department string `json:"dept,omitempty"`
+}
+
+// StoreLevel reports whether the user oversees the whole store rather
+// than one department
+func (c *Claims) StoreLevel() bool {
+	return c.Department == "" || c.Role == RoleAdmin
+}
+
+// CanAccessDepartment reports whether the user may see and act on dept's
+// inventory, schedule and alerts
+func (c *Claims) CanAccessDepartment(dept string) bool {
+	return c.StoreLevel() || c.Department == dept
+}
+
+// IssueToken signs an HS256 token for user that expires after ttl
+func IssueToken(secret []byte, user *User, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+		Name:       user.Name,
+		Role:       user.Role,
+		Store:      user.Store,
+		Department: user.Department,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expires, nil
+}
+
+// ParseToken verifies an HS256 token signed with secret and returns its
+// claims. Tokens must expire.
+func ParseToken(secret []byte, token string) (*Claims, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("no signing secret configured")
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token: no subject")
+	}
+	if !claims.Role.Valid() {
+		return nil, fmt.Errorf("invalid token: unknown role %q", claims.Role)
+	}
+	return claims, nil
+}
+
+// TokenFromRequest returns the bearer token from the Authorization header
+func TokenFromRequest(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrNoToken
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", errors.New("malformed Authorization header")
+	}
+	return token, nil
+}
+
+// TokenFromUpgrade returns the bearer token of a WebSocket upgrade request,
+// from the Authorization header or the "token" query parameter since
+// browsers can't set headers on WebSocket requests. Query tokens end up in
+// access logs, so nothing else accepts them.
+func TokenFromUpgrade(r *http.Request) (string, error) {
+	if r.Header.Get("Authorization") == "" {
+		if token := r.URL.Query().Get("token"); token != "" {
+			return token, nil
+		}
+	}
+	return TokenFromRequest(r)
+}
+
+type contextKey struct{}
+
+// WithClaims returns a context carrying the signed-in user's claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims stored by WithClaims
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret")
+
+func TestParseToken(t *testing.T) {
+	user := &User{ID: "jsmith", Name: "Jane Smith", Role: RoleManager, Store: "42", Department: "dairy"}
+	token, _, err := IssueToken(testSecret, user, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	claims, err := ParseToken(testSecret, token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.Subject != "jsmith" || claims.Role != RoleManager || claims.Store != "42" || claims.Department != "dairy" {
+		t.Errorf("got claims %+v", claims)
+	}
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := func(mutate func(*Claims)) *Claims {
+		c := &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "jsmith",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Role: RoleManager,
+		}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong secret", sign(jwt.SigningMethodHS256, []byte("other-secret"), valid(nil))},
+		{"expired", sign(jwt.SigningMethodHS256, testSecret, valid(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		}))},
+		{"no expiry", sign(jwt.SigningMethodHS256, testSecret, valid(func(c *Claims) { c.ExpiresAt = nil }))},
+		{"no subject", sign(jwt.SigningMethodHS256, testSecret, valid(func(c *Claims) { c.Subject = "" }))},
+		{"unknown role", sign(jwt.SigningMethodHS256, testSecret, valid(func(c *Claims) { c.Role = "owner" }))},
+		{"other algorithm", sign(jwt.SigningMethodHS512, testSecret, valid(nil))},
+		{"unsigned", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid(nil))},
+		{"garbage", "not.a.token"},
+	}
+	for _, tt := range tests {
+		if claims, err := ParseToken(testSecret, tt.token); err == nil {
+			t.Errorf("%s: ParseToken accepted the token as %+v", tt.name, claims)
+		}
+	}
+
+	if _, err := ParseToken(nil, sign(jwt.SigningMethodHS256, []byte{}, valid(nil))); err == nil {
+		t.Error("ParseToken accepted a token without a secret configured")
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/alerts?token=from-query", nil)
+	if _, err := TokenFromRequest(req); !errors.Is(err, ErrNoToken) {
+		t.Errorf("TokenFromRequest took the query token, err = %v", err)
+	}
+	if token, err := TokenFromUpgrade(req); err != nil || token != "from-query" {
+		t.Errorf("TokenFromUpgrade = %q, %v, want the query token", token, err)
+	}
+
+	req.Header.Set("Authorization", "Bearer from-header")
+	for name, fn := range map[string]func(*http.Request) (string, error){
+		"TokenFromRequest": TokenFromRequest,
+		"TokenFromUpgrade": TokenFromUpgrade,
+	} {
+		if token, err := fn(req); err != nil || token != "from-header" {
+			t.Errorf("%s = %q, %v, want the header token", name, token, err)
+		}
+	}
+
+	req.Header.Set("Authorization", "Basic amFuZTpwdw==")
+	if _, err := TokenFromUpgrade(req); err == nil || errors.Is(err, ErrNoToken) {
+		t.Errorf("TokenFromUpgrade with a malformed header = %v, want an error", err)
+	}
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	order := []Role{RoleAssociate, RoleAssistantManager, RoleManager, RoleAdmin}
+	for i, r := range order {
+		for j, min := range order {
+			if got := r.AtLeast(min); got != (i >= j) {
+				t.Errorf("%s.AtLeast(%s) = %v", r, min, got)
+			}
+		}
+	}
+	if Role("owner").AtLeast(RoleAssociate) {
+		t.Error("an unknown role counts as an associate")
+	}
+}
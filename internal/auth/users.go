@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown user or wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUnknownUser is returned when a Directory has no user with an ID
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+// User is a person who can sign in to Opus
+type User struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Role       Role   `json:"role"`
+	Store      string `json:"store,omitempty"`
+	Department string `json:"department,omitempty"`
+	Email      string `json:"email,omitempty"`
+	// EmployeeID links the user to their shifts in the schedule
+	EmployeeID string `json:"employeeId,omitempty"`
+}
+
+// Scope is the department the user is limited to, or "" for store-level
+// staff and admins
+func (u *User) Scope() string {
+	if u.Role == RoleAdmin {
+		return ""
+	}
+	return u.Department
+}
+
+// Authenticator checks sign-in credentials. LocalUsers implements it with
+// password hashes from a file; an SSO backend can replace it.
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*User, error)
+}
+
+// Directory looks up users by ID, for channels such as Slack that
+// identify users themselves rather than by password
+type Directory interface {
+	User(ctx context.Context, id string) (*User, error)
+	// Users returns every user, ordered by ID
+	Users(ctx context.Context) ([]*User, error)
+}
+
+// localUser is the YAML form of a user
+type localUser struct {
+	Username     string `yaml:"username"`
+	Name         string `yaml:"name"`
+	Role         Role   `yaml:"role"`
+	Store        string `yaml:"store"`
+	Department   string `yaml:"department"`
+	Email        string `yaml:"email"`
+	EmployeeID   string `yaml:"employee_id"`
+	PasswordHash string `yaml:"password_hash"`
+}
+
+// LocalUsers authenticates against bcrypt password hashes
+type LocalUsers struct {
+	users map[string]localUser
+}
+
+// dummyHash is compared against when the username is unknown so that
+// response times don't reveal which usernames exist
+var dummyHash = []byte("$2a$12$/9w9woxKARgae7y.G/e1z.RFmBjdT47tEnHpZpKmzMnzYCmo1aNaa")
+
+// LoadLocalUsers reads users from a YAML file of the form
+//
+//	users:
+//	  - username: jsmith
+//	    name: Jane Smith
+//	    role: manager
+//	    store: "42"
+//	    department: meat
+//	    email: jsmith@example.com
+//	    employee_id: E1042
+//	    password_hash: $2a$12$...
+//
+// Users without a department are store-level staff. Hashes can be made
+// with cmd/passwd.
+func LoadLocalUsers(path string) (*LocalUsers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	var file struct {
+		Users []localUser `yaml:"users"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+
+	users := &LocalUsers{users: make(map[string]localUser)}
+	var errs []error
+	for i, u := range file.Users {
+		u.Username = strings.ToLower(strings.TrimSpace(u.Username))
+		switch {
+		case u.Username == "":
+			errs = append(errs, fmt.Errorf("user %d: username is required", i+1))
+			continue
+		case !u.Role.Valid():
+			errs = append(errs, fmt.Errorf("user %s: unknown role %q", u.Username, u.Role))
+		case u.Role != RoleAdmin && u.Store == "":
+			errs = append(errs, fmt.Errorf("user %s: store is required", u.Username))
+		}
+		if u.Email != "" {
+			if _, err := mail.ParseAddress(u.Email); err != nil {
+				errs = append(errs, fmt.Errorf("user %s: invalid email %q", u.Username, u.Email))
+			}
+		}
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: password_hash is not a bcrypt hash", u.Username))
+		}
+		if _, ok := users.users[u.Username]; ok {
+			errs = append(errs, fmt.Errorf("user %s is defined twice", u.Username))
+		}
+		users.users[u.Username] = u
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return users, nil
+}
+
+// Len returns the number of users
+func (l *LocalUsers) Len() int {
+	return len(l.users)
+}
+
+// Authenticate checks username and password
+func (l *LocalUsers) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	u, ok := l.users[strings.ToLower(strings.TrimSpace(username))]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return u.user(), nil
+}
+
+// User returns the user with username id, or ErrUnknownUser
+func (l *LocalUsers) User(ctx context.Context, id string) (*User, error) {
+	u, ok := l.users[strings.ToLower(strings.TrimSpace(id))]
+	if !ok {
+		return nil, ErrUnknownUser
+	}
+	return u.user(), nil
+}
+
+// Users returns every user, ordered by username
+func (l *LocalUsers) Users(ctx context.Context) ([]*User, error) {
+	users := make([]*User, 0, len(l.users))
+	for _, u := range l.users {
+		users = append(users, u.user())
+	}
+	slices.SortFunc(users, func(a, b *User) int { return strings.Compare(a.ID, b.ID) })
+	return users, nil
+}
+
+func (u localUser) user() *User {
+	return &User{
+		ID:         u.Username,
+		Name:       u.Name,
+		Role:       u.Role,
+		Store:      u.Store,
+		Department: u.Department,
+		Email:      u.Email,
+		EmployeeID: u.EmployeeID,
+	}
+}
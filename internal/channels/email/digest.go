@@ -0,0 +1,133 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/production"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+const (
+	// maxListed is how many alerts or items a digest lists per section;
+	// the rest are counted
+	maxListed = 5
+	// expiringWindow is how far ahead the digest looks for expirations
+	expiringWindow = 48 * time.Hour
+)
+
+// Summary is one department's part of a digest
+type Summary struct {
+	Department string
+	// Alerts are the most severe open alerts, of AlertCount
+	Alerts     []alerts.Alert
+	AlertCount int
+	Critical   int
+	// LowStock are the items furthest below their reorder point, of
+	// LowStockCount
+	LowStock      []inventory.Item
+	LowStockCount int
+	// Expiring are the items expiring soonest, of ExpiringCount
+	Expiring      []inventory.Item
+	ExpiringCount int
+	// Shifts counts today's shifts, from the start of FirstShift to the
+	// end of LastShift; TimeOff counts approved time off today
+	Shifts     int
+	FirstShift time.Time
+	LastShift  time.Time
+	TimeOff    int
+	// Production is today's production plan, of ProductionCount items
+	Production      []production.Plan
+	ProductionCount int
+}
+
+// digestData is what the digest templates are executed with
+type digestData struct {
+	User        *auth.User
+	Date        time.Time
+	ExpiringBy  time.Time
+	Departments []Summary
+	BaseURL     string
+}
+
+// alertData is what the alert templates are executed with
+type alertData struct {
+	User      *auth.User
+	Alert     *alerts.Alert
+	Change    alerts.Change
+	Escalated bool
+	BaseURL   string
+}
+
+// summarize gathers a department's open alerts, stock, schedule and
+// production for the day starting at day
+func (n *Notifier) summarize(ctx context.Context, dept string, day time.Time) (Summary, error) {
+	s := Summary{Department: dept}
+
+	open, err := n.alerts.List(ctx, alerts.Filter{Department: dept})
+	if err != nil {
+		return s, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	s.AlertCount = len(open)
+	for _, a := range open {
+		if a.Severity == alerts.SeverityCritical {
+			s.Critical++
+		}
+	}
+	s.Alerts = open[:min(len(open), maxListed)]
+
+	if n.items != nil {
+		low, err := n.items.List(ctx, inventory.Query{Department: dept, BelowReorder: true, Sort: inventory.SortOnHand, Limit: maxListed})
+		if err != nil {
+			return s, fmt.Errorf("failed to list low stock: %w", err)
+		}
+		s.LowStock, s.LowStockCount = low.Items, low.Total
+
+		expiring, err := n.items.List(ctx, inventory.Query{Department: dept, ExpiringWithin: expiringWindow, Sort: inventory.SortExpiresOn, Limit: maxListed})
+		if err != nil {
+			return s, fmt.Errorf("failed to list expiring items: %w", err)
+		}
+		s.Expiring, s.ExpiringCount = expiring.Items, expiring.Total
+	}
+
+	if n.schedules != nil {
+		end := day.AddDate(0, 0, 1)
+		shifts, err := n.schedules.Shifts(ctx, dept, day, end)
+		if err != nil {
+			return s, fmt.Errorf("failed to list shifts: %w", err)
+		}
+		s.Shifts = len(shifts)
+		for _, shift := range shifts {
+			if s.FirstShift.IsZero() || shift.Start.Before(s.FirstShift) {
+				s.FirstShift = shift.Start
+			}
+			if shift.End.After(s.LastShift) {
+				s.LastShift = shift.End
+			}
+		}
+
+		timeOff, err := n.schedules.TimeOff(ctx, dept, day, end)
+		if err != nil {
+			return s, fmt.Errorf("failed to list time off: %w", err)
+		}
+		for _, t := range timeOff {
+			if t.Status == schedule.TimeOffApproved {
+				s.TimeOff++
+			}
+		}
+	}
+
+	if n.plans != nil {
+		date := day.Format("2006-01-02")
+		plans, err := n.plans.Plans(ctx, dept, date, date)
+		if err != nil {
+			return s, fmt.Errorf("failed to list production: %w", err)
+		}
+		s.Production, s.ProductionCount = plans[:min(len(plans), maxListed)], len(plans)
+	}
+	return s, nil
+}
@@ -0,0 +1,286 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeMessage is an email received by a FakeServer
+type FakeMessage struct {
+	ID      int               `json:"id"`
+	From    string            `json:"from"`
+	To      []string          `json:"to"`
+	Subject string            `json:"subject"`
+	Headers map[string]string `json:"headers"`
+	Text    string            `json:"text"`
+	HTML    string            `json:"html"`
+	Raw     string            `json:"-"`
+	// Username is who authenticated, if anyone did
+	Username   string    `json:"username,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// FakeServer is an SMTP sink for demos and local testing. It accepts
+// everything sent to it without TLS, so run Opus with EMAIL_SMTP_TLS=none,
+// and shows what it received over HTTP:
+//
+//	GET /messages       ?to=jsmith@store42.example.com
+//	GET /messages/{id}  the raw message
+type FakeServer struct {
+	// Username and Password are required with AUTH PLAIN when set
+	Username string
+	Password string
+
+	mu       sync.Mutex
+	messages []FakeMessage
+}
+
+// NewFakeServer creates an SMTP sink, requiring credentials if username
+// is set
+func NewFakeServer(username, password string) *FakeServer {
+	return &FakeServer{Username: username, Password: password}
+}
+
+// Serve accepts SMTP sessions on l until it's closed
+func (f *FakeServer) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go f.session(conn)
+	}
+}
+
+// Messages returns the messages sent to an address, or all of them if to
+// is empty
+func (f *FakeServer) Messages(to string) []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := []FakeMessage{}
+	for _, m := range f.messages {
+		for _, rcpt := range m.To {
+			if to == "" || strings.EqualFold(rcpt, to) {
+				messages = append(messages, m)
+				break
+			}
+		}
+	}
+	return messages
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path == "/messages" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(f.Messages(r.URL.Query().Get("to")))
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/messages/"))
+	if !strings.HasPrefix(r.URL.Path, "/messages/") || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id < 1 || id > len(f.messages) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "message/rfc822")
+	io.WriteString(w, f.messages[id-1].Raw)
+}
+
+// session speaks just enough SMTP to take messages from net/smtp
+func (f *FakeServer) session(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+	text := textproto.NewConn(conn)
+
+	var from, username string
+	var to []string
+	authenticated := f.Username == ""
+	reply := func(format string, args ...interface{}) bool {
+		return text.PrintfLine(format, args...) == nil
+	}
+
+	if !reply("220 localhost fake SMTP ready") {
+		return
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			text.PrintfLine("250-localhost")
+			text.PrintfLine("250-AUTH PLAIN")
+			text.PrintfLine("250-8BITMIME")
+			reply("250 SIZE 10485760")
+		case "HELO":
+			reply("250 localhost")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				reply("504 Unrecognized authentication type")
+				continue
+			}
+			user, ok := f.checkPlain(initial)
+			if !ok {
+				reply("535 Authentication credentials invalid")
+				continue
+			}
+			authenticated, username = true, user
+			reply("235 Authentication successful")
+		case "MAIL":
+			if !authenticated {
+				reply("530 Authentication required")
+				continue
+			}
+			from, to = address(arg), nil
+			reply("250 OK")
+		case "RCPT":
+			if from == "" {
+				reply("503 Need MAIL first")
+				continue
+			}
+			to = append(to, address(arg))
+			reply("250 OK")
+		case "DATA":
+			if len(to) == 0 {
+				reply("503 Need RCPT first")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			raw, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			id := f.record(from, to, username, raw)
+			from, to = "", nil
+			reply("250 OK queued as %d", id)
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// checkPlain checks AUTH PLAIN credentials against the server's
+func (f *FakeServer) checkPlain(initial string) (string, bool) {
+	b, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(string(b), "\x00")
+	if len(parts) != 3 {
+		return "", false
+	}
+	return parts[1], f.Username == "" || (parts[1] == f.Username && parts[2] == f.Password)
+}
+
+// address extracts the address from "FROM:<a@b> SIZE=123"
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
+
+// record stores a received message, decoding its headers and body parts
+func (f *FakeServer) record(from string, to []string, username string, raw []byte) int {
+	m := FakeMessage{
+		From:       from,
+		To:         to,
+		Headers:    make(map[string]string),
+		Raw:        string(raw),
+		Username:   username,
+		ReceivedAt: time.Now(),
+	}
+	if msg, err := mail.ReadMessage(strings.NewReader(m.Raw)); err != nil {
+		log.Printf("Fake SMTP: failed to parse message from %s: %v", from, err)
+	} else {
+		for name := range msg.Header {
+			m.Headers[name] = msg.Header.Get(name)
+		}
+		dec := new(mime.WordDecoder)
+		if subject, err := dec.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+			m.Subject = subject
+		}
+		m.Text, m.HTML = fakeBody(msg.Header.Get("Content-Type"), msg.Body)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m.ID = len(f.messages) + 1
+	f.messages = append(f.messages, m)
+	return m.ID
+}
+
+// fakeBody returns the text and HTML versions of a message body
+func fakeBody(contentType string, body io.Reader) (text, html string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		b, _ := io.ReadAll(body)
+		return string(b), ""
+	}
+
+	parts := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			return text, html
+		}
+		var r io.Reader = part
+		if part.Header.Get("Content-Transfer-Encoding") == "quoted-printable" {
+			r = quotedprintable.NewReader(part)
+		}
+		b, err := io.ReadAll(bufio.NewReader(r))
+		if err != nil {
+			return text, html
+		}
+		switch t, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); t {
+		case "text/plain":
+			text = string(b)
+		case "text/html":
+			html = string(b)
+		}
+	}
+}
+
+// String summarizes a message for logs
+func (m FakeMessage) String() string {
+	return fmt.Sprintf("#%d %s -> %s: %s", m.ID, m.From, strings.Join(m.To, ", "), m.Subject)
+}
@@ -0,0 +1,88 @@
+package email
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// keepDeliveries is how many deliveries the memory store keeps
+const keepDeliveries = 1000
+
+// MemoryStore keeps preferences and the latest 1000 deliveries in memory
+type MemoryStore struct {
+	preferences map[string]Preferences
+	deliveries  []Delivery
+	nextID      int
+	mu          sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{preferences: make(map[string]Preferences)}
+}
+
+func (m *MemoryStore) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.preferences[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	p.Departments = slices.Clone(p.Departments)
+	return &p, nil
+}
+
+func (m *MemoryStore) SavePreferences(ctx context.Context, p *Preferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *p
+	saved.Departments = slices.Clone(p.Departments)
+	m.preferences[p.UserID] = saved
+	return nil
+}
+
+func (m *MemoryStore) Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := []Delivery{}
+	for i := len(m.deliveries) - 1; i >= 0 && len(deliveries) < f.Limit; i-- {
+		if f.matches(&m.deliveries[i]) {
+			deliveries = append(deliveries, m.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+// SaveDelivery inserts or replaces d, dropping the oldest beyond 1000
+func (m *MemoryStore) SaveDelivery(ctx context.Context, d *Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d.ID != "" {
+		for i := range m.deliveries {
+			if m.deliveries[i].ID == d.ID {
+				m.deliveries[i] = *d
+				return nil
+			}
+		}
+	}
+
+	m.nextID++
+	d.ID = strconv.Itoa(m.nextID)
+	m.deliveries = append(m.deliveries, *d)
+	if len(m.deliveries) > keepDeliveries {
+		m.deliveries = slices.Delete(m.deliveries, 0, len(m.deliveries)-keepDeliveries)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Close() error { return nil }
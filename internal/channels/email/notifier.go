@@ -0,0 +1,528 @@
+// Package email sends store managers a morning digest of their
+// departments and emails them alerts as they're raised or escalated.
+// Emails are rendered from text and HTML templates and sent through an
+// SMTP server; each user's preferences decide what they get, and every
+// delivery is recorded with its attempts.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/notify"
+	"github.com/dokk-dev/opus/internal/production"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// ErrNoAddress is returned when sending to a user without an email
+// address
+var ErrNoAddress = errors.New("user has no email address")
+
+const (
+	// alertQueueSize is how many alert events can wait to be emailed
+	alertQueueSize = 100
+	// maxAttempts is how often a scheduled delivery is tried before it's
+	// recorded as failed
+	maxAttempts = 3
+)
+
+// retryDelay is the wait before each retry of a failed delivery
+var retryDelay = []time.Duration{5 * time.Second, 30 * time.Second}
+
+// Config configures the email notifier
+type Config struct {
+	SMTP SMTPConfig
+	// From is the sender, e.g. "Opus <opus@store42.example.com>"
+	From string
+	// DigestAt is when the morning digest is sent, as the time since
+	// local midnight
+	DigestAt time.Duration
+	// Departments are the store's departments, which store-level users'
+	// digests cover
+	Departments []string
+	// TemplateDir holds templates replacing the built-in ones of the
+	// same name
+	TemplateDir string
+	// BaseURL is the web client's URL, linked from emails when set
+	BaseURL string
+}
+
+// Notifier emails digests and alerts. It is a connector so the registry
+// checks the SMTP server and reports its health.
+type Notifier struct {
+	config    Config
+	from      *mail.Address
+	sender    *sender
+	templates *templates
+	store     Store
+	users     auth.Directory
+	alerts    *alerts.Engine
+	items     inventory.Repository
+	schedules schedule.Repository
+	plans     production.Repository
+	now       func() time.Time
+
+	alertEvents chan alerts.Event
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	mu sync.Mutex
+	// notified is the severity each user was last emailed an alert at
+	notified   map[string]map[string]alerts.Severity
+	nextDigest time.Time
+	lastError  error
+	sent       int
+	failed     int
+}
+
+// New creates a notifier emailing the users in users, with alerts from
+// alertEngine and digests from the inventory, schedule and production
+// repositories, any of which may be nil
+func New(cfg Config, store Store, users auth.Directory, alertEngine *alerts.Engine, items inventory.Repository, schedules schedule.Repository, plans production.Repository) (*Notifier, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", cfg.From, err)
+	}
+	t, err := loadTemplates(cfg.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:      cfg,
+		from:        from,
+		sender:      &sender{config: cfg.SMTP},
+		templates:   t,
+		store:       store,
+		users:       users,
+		alerts:      alertEngine,
+		items:       items,
+		schedules:   schedules,
+		plans:       plans,
+		now:         time.Now,
+		alertEvents: make(chan alerts.Event, alertQueueSize),
+		notified:    make(map[string]map[string]alerts.Severity),
+	}, nil
+}
+
+func (n *Notifier) Name() string { return "email" }
+
+// Connect checks the SMTP server accepts the credentials and starts
+// sending digests and alerts
+func (n *Notifier) Connect(ctx context.Context) error {
+	err := n.sender.check(ctx)
+	n.track(err)
+	if err != nil {
+		return err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+	n.done = make(chan struct{})
+	go n.run(loopCtx)
+	return nil
+}
+
+func (n *Notifier) Disconnect() error {
+	if n.cancel != nil {
+		n.cancel()
+		<-n.done
+		n.cancel = nil
+	}
+	return nil
+}
+
+// Health is unhealthy while the last SMTP session failed
+func (n *Notifier) Health() connectors.HealthStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: n.lastError == nil,
+		Details: map[string]interface{}{
+			"server": n.config.SMTP.Host + ":" + strconv.Itoa(n.config.SMTP.Port),
+			"sent":   n.sent,
+			"failed": n.failed,
+		},
+	}
+	if !n.nextDigest.IsZero() {
+		status.Details["nextDigest"] = n.nextDigest
+	}
+	if n.lastError != nil {
+		status.Message = n.lastError.Error()
+	}
+	return status
+}
+
+// track records the outcome of an SMTP session for Health
+func (n *Notifier) track(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastError = err
+}
+
+// PublishAlert queues an alert event to be emailed. Pass it to
+// alerts.Engine.Subscribe.
+func (n *Notifier) PublishAlert(event alerts.Event) {
+	select {
+	case n.alertEvents <- event:
+	default:
+		log.Printf("Email: alert queue is full, dropping %s for alert %s", event.Type, event.Alert.ID)
+	}
+}
+
+// run sends alert emails as events arrive and the digest every morning
+func (n *Notifier) run(ctx context.Context) {
+	defer close(n.done)
+	for {
+		next := nextDigest(n.now(), n.config.DigestAt)
+		n.mu.Lock()
+		n.nextDigest = next
+		n.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case event := <-n.alertEvents:
+			timer.Stop()
+			n.notifyAlert(ctx, &event.Alert)
+		case <-timer.C:
+			n.sendDigests(ctx)
+		}
+	}
+}
+
+// nextDigest is the first time after now that's at the digest time
+func nextDigest(now time.Time, at time.Duration) time.Time {
+	next := startOfDay(now).Add(at)
+	if !next.After(now) {
+		next = startOfDay(now.AddDate(0, 0, 1)).Add(at)
+	}
+	return next
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Preferences returns the user's preferences, or the defaults if they
+// haven't saved any
+func (n *Notifier) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	p, err := n.store.Preferences(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return DefaultPreferences(userID), nil
+	}
+	return p, err
+}
+
+// SavePreferences validates and saves a user's preferences
+func (n *Notifier) SavePreferences(ctx context.Context, p *Preferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if !p.Alerts && p.MinSeverity == "" {
+		p.MinSeverity = alerts.SeverityCritical
+	}
+	var departments []string
+	for _, d := range p.Departments {
+		if !slices.Contains(n.config.Departments, d) {
+			return fmt.Errorf("%w: unknown department %q", ErrInvalidPreferences, d)
+		}
+		if !slices.Contains(departments, d) {
+			departments = append(departments, d)
+		}
+	}
+	p.Departments = departments
+	p.UpdatedAt = n.now().UTC()
+	return n.store.SavePreferences(ctx, p)
+}
+
+// Deliveries returns the recorded deliveries matching f, newest first
+func (n *Notifier) Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error) {
+	return n.store.Deliveries(ctx, f)
+}
+
+// recipient is a user with an address and their preferences
+type recipient struct {
+	user        *auth.User
+	address     *mail.Address
+	preferences *Preferences
+}
+
+// recipients returns the users with email addresses
+func (n *Notifier) recipients(ctx context.Context) ([]recipient, error) {
+	users, err := n.users.Users(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	var recipients []recipient
+	for _, u := range users {
+		if u.Email == "" {
+			continue
+		}
+		p, err := n.Preferences(ctx, u.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load preferences for %s: %w", u.ID, err)
+		}
+		recipients = append(recipients, recipient{
+			user:        u,
+			address:     &mail.Address{Name: u.Name, Address: u.Email},
+			preferences: p,
+		})
+	}
+	return recipients, nil
+}
+
+// departments are the departments a user's digest and alerts cover:
+// their own, or the store's narrowed by their preferences
+func (n *Notifier) departments(r recipient) []string {
+	if scope := r.user.Scope(); scope != "" {
+		return []string{scope}
+	}
+	var departments []string
+	for _, d := range n.config.Departments {
+		if r.preferences.Covers(d) {
+			departments = append(departments, d)
+		}
+	}
+	return departments
+}
+
+// sendDigests sends the morning digest to everyone who wants it and
+// hasn't had today's yet
+func (n *Notifier) sendDigests(ctx context.Context) {
+	recipients, err := n.recipients(ctx)
+	if err != nil {
+		log.Printf("Email: failed to send digests: %v", err)
+		return
+	}
+
+	today := startOfDay(n.now())
+	for _, r := range recipients {
+		if !r.preferences.Digest {
+			continue
+		}
+		sent, err := n.store.Deliveries(ctx, DeliveryFilter{UserID: r.user.ID, Kind: KindDigest, Status: DeliverySent, Since: today, Limit: 1})
+		if err != nil {
+			log.Printf("Email: failed to check digests sent to %s: %v", r.user.ID, err)
+			continue
+		}
+		if len(sent) > 0 {
+			continue
+		}
+		if _, err := n.digest(ctx, r, maxAttempts); err != nil {
+			log.Printf("Email: failed to send digest to %s: %v", r.user.ID, err)
+		}
+	}
+}
+
+// SendDigest sends a user today's digest now, whatever their preferences,
+// and returns its delivery. It's tried once, since the caller is waiting.
+func (n *Notifier) SendDigest(ctx context.Context, userID string) (*Delivery, error) {
+	user, err := n.users.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == "" {
+		return nil, ErrNoAddress
+	}
+	p, err := n.Preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return n.digest(ctx, recipient{
+		user:        user,
+		address:     &mail.Address{Name: user.Name, Address: user.Email},
+		preferences: p,
+	}, 1)
+}
+
+// digest renders and sends a recipient's digest
+func (n *Notifier) digest(ctx context.Context, r recipient, attempts int) (*Delivery, error) {
+	now := n.now()
+	data := digestData{
+		User:       r.user,
+		Date:       now,
+		ExpiringBy: now.Add(expiringWindow),
+		BaseURL:    n.config.BaseURL,
+	}
+	for _, dept := range n.departments(r) {
+		summary, err := n.summarize(ctx, dept, startOfDay(now))
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize %s: %w", dept, err)
+		}
+		data.Departments = append(data.Departments, summary)
+	}
+
+	subject, text, html, err := n.templates.render("digest", data)
+	if err != nil {
+		return nil, err
+	}
+	return n.deliver(ctx, attempts, &Delivery{Kind: KindDigest, UserID: r.user.ID}, &message{
+		To:      r.address,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+}
+
+// notifyAlert emails an active alert to everyone whose preferences cover
+// it, when it's raised, escalated, or changed to a severity they haven't
+// been emailed it at
+func (n *Notifier) notifyAlert(ctx context.Context, alert *alerts.Alert) {
+	if !alert.Status.Open() {
+		n.mu.Lock()
+		delete(n.notified, alert.ID)
+		n.mu.Unlock()
+		return
+	}
+	if alert.Status != alerts.StatusActive {
+		return
+	}
+
+	last := lastChange(alert)
+	escalated := last.Action == alerts.ActionEscalated
+
+	recipients, err := n.recipients(ctx)
+	if err != nil {
+		log.Printf("Email: failed to send alert %s: %v", alert.ID, err)
+		return
+	}
+	for _, r := range recipients {
+		p := r.preferences
+		if !p.Alerts || !alert.Severity.AtLeast(p.MinSeverity) || !slices.Contains(n.departments(r), alert.Department) {
+			continue
+		}
+
+		n.mu.Lock()
+		previous, ok := n.notified[alert.ID][r.user.ID]
+		n.mu.Unlock()
+		if ok && !escalated && (previous == alert.Severity || previous.AtLeast(alert.Severity)) {
+			continue
+		}
+
+		d, err := n.sendAlert(ctx, maxAttempts, r, alert, last)
+		if err != nil {
+			log.Printf("Email: failed to send alert %s to %s: %v", alert.ID, r.user.ID, err)
+		}
+		if d != nil && d.Status == DeliverySent {
+			n.mu.Lock()
+			if n.notified[alert.ID] == nil {
+				n.notified[alert.ID] = make(map[string]alerts.Severity)
+			}
+			n.notified[alert.ID][r.user.ID] = alert.Severity
+			n.mu.Unlock()
+		}
+	}
+}
+
+// Notify emails user an alert routed to them, making one attempt. It
+// returns notify.ErrUnreachable if they have no email address.
+func (n *Notifier) Notify(ctx context.Context, user *auth.User, note *notify.Notification) error {
+	if user.Email == "" {
+		return notify.ErrUnreachable
+	}
+	r := recipient{user: user, address: &mail.Address{Name: user.Name, Address: user.Email}}
+	_, err := n.sendAlert(ctx, 1, r, note.Alert, lastChange(note.Alert))
+	return err
+}
+
+// sendAlert renders and sends the alert email to r
+func (n *Notifier) sendAlert(ctx context.Context, attempts int, r recipient, alert *alerts.Alert, last alerts.Change) (*Delivery, error) {
+	subject, text, html, err := n.templates.render("alert", alertData{
+		User:      r.user,
+		Alert:     alert,
+		Change:    last,
+		Escalated: last.Action == alerts.ActionEscalated,
+		BaseURL:   n.config.BaseURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render alert: %w", err)
+	}
+	return n.deliver(ctx, attempts, &Delivery{Kind: KindAlert, UserID: r.user.ID, AlertID: alert.ID}, &message{
+		To:      r.address,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{"X-Opus-Alert": alert.ID},
+	})
+}
+
+// lastChange returns the latest entry in the alert's history
+func lastChange(a *alerts.Alert) alerts.Change {
+	if len(a.History) == 0 {
+		return alerts.Change{}
+	}
+	return a.History[len(a.History)-1]
+}
+
+// deliver sends msg, trying up to attempts times while failures are
+// temporary, and records the delivery and its outcome
+func (n *Notifier) deliver(ctx context.Context, attempts int, d *Delivery, msg *message) (*Delivery, error) {
+	msg.From = n.from
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	msg.Headers["Auto-Submitted"] = "auto-generated"
+
+	d.To = msg.To.Address
+	d.Subject = msg.Subject
+	d.CreatedAt = n.now().UTC()
+
+	var err error
+	for d.Attempts < attempts {
+		if d.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(retryDelay[d.Attempts-1]):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		d.Attempts++
+
+		var raw []byte
+		raw, err = msg.bytes(n.now())
+		if err == nil {
+			err = n.sender.send(ctx, n.from.Address, msg.To.Address, raw)
+		}
+		n.track(err)
+		if err == nil || permanent(err) {
+			break
+		}
+		log.Printf("Email: attempt %d to send %q to %s failed: %v", d.Attempts, d.Subject, d.To, err)
+	}
+
+	n.mu.Lock()
+	if err == nil {
+		now := n.now().UTC()
+		d.Status, d.SentAt = DeliverySent, &now
+		n.sent++
+	} else {
+		d.Status, d.Error = DeliveryFailed, err.Error()
+		n.failed++
+	}
+	n.mu.Unlock()
+
+	if saveErr := n.store.SaveDelivery(ctx, d); saveErr != nil {
+		log.Printf("Email: failed to record delivery to %s: %v", d.To, saveErr)
+	}
+	return d, err
+}
@@ -0,0 +1,146 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// TLS modes for SMTPConfig.TLS
+const (
+	// TLSStartTLS upgrades a plain connection, usually on port 587, and
+	// refuses servers that can't
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465
+	TLSImplicit = "tls"
+	// TLSNone sends in the clear, for local sinks only
+	TLSNone = "none"
+)
+
+// smtpTimeout bounds a whole SMTP session
+const smtpTimeout = 30 * time.Second
+
+// SMTPConfig is the server emails are sent through
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth when set, which
+	// needs TLS unless the server is on localhost
+	Username string
+	Password string
+	// TLS is one of the TLS* modes, TLSStartTLS by default
+	TLS string
+	// HelloName is sent with EHLO, "localhost" by default
+	HelloName string
+}
+
+// sender sends messages through an SMTP server
+type sender struct {
+	config SMTPConfig
+}
+
+// dial opens an authenticated SMTP session
+func (s *sender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	var err error
+	if s.config.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	hello := s.config.HelloName
+	if hello == "" {
+		hello = "localhost"
+	}
+	if err := client.Hello(hello); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	if s.config.TLS == "" || s.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("SMTP server doesn't support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// check connects and authenticates without sending anything
+func (s *sender) check(ctx context.Context) error {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Quit()
+}
+
+// send delivers msg from one address to another
+func (s *sender) send(ctx context.Context, from, to string, msg []byte) error {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// permanent reports whether an SMTP error will happen again however often
+// the message is retried, such as a rejected recipient
+func permanent(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
@@ -0,0 +1,110 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP starts an SMTP sink on a local port
+func fakeSMTP(t *testing.T, username, password string) (*FakeServer, SMTPConfig) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	fake := NewFakeServer(username, password)
+	go fake.Serve(l)
+
+	addr := l.Addr().(*net.TCPAddr)
+	return fake, SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     addr.Port,
+		Username: username,
+		Password: password,
+		TLS:      TLSNone,
+	}
+}
+
+func TestSenderDeliversToTheSink(t *testing.T) {
+	fake, cfg := fakeSMTP(t, "opus", "secret")
+	s := &sender{config: cfg}
+
+	msg := &message{
+		From:    &mail.Address{Name: "Opus", Address: "opus@store42.example.com"},
+		To:      &mail.Address{Name: "Jane Smith", Address: "jsmith@store42.example.com"},
+		Subject: "Cooler 1 is warm — 41°F",
+		Text:    "Cooler 1 has been above 40°F for 20 minutes.",
+		HTML:    "<p>Cooler 1 has been above 40°F for 20 minutes.</p>",
+		Headers: map[string]string{"X-Opus-Alert": "42"},
+	}
+	raw, err := msg.bytes(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send(context.Background(), msg.From.Address, msg.To.Address, raw); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	received := fake.Messages("jsmith@store42.example.com")
+	if len(received) != 1 {
+		t.Fatalf("sink has %d messages, want 1", len(received))
+	}
+	got := received[0]
+	if got.From != "opus@store42.example.com" || got.Username != "opus" {
+		t.Errorf("got from %q as %q", got.From, got.Username)
+	}
+	if got.Subject != msg.Subject || got.Headers["X-Opus-Alert"] != "42" {
+		t.Errorf("got subject %q, headers %v", got.Subject, got.Headers)
+	}
+	if strings.TrimSpace(got.Text) != msg.Text || strings.TrimSpace(got.HTML) != msg.HTML {
+		t.Errorf("got text %q and html %q", got.Text, got.HTML)
+	}
+}
+
+func TestSenderRefusedCredentials(t *testing.T) {
+	_, cfg := fakeSMTP(t, "opus", "secret")
+	cfg.Password = "wrong"
+	s := &sender{config: cfg}
+
+	err := s.check(context.Background())
+	if err == nil {
+		t.Fatal("check succeeded with the wrong password")
+	}
+	if !permanent(err) {
+		t.Errorf("refused credentials = %v, want a permanent error", err)
+	}
+}
+
+func TestSenderRequiresStartTLS(t *testing.T) {
+	// The sink doesn't offer STARTTLS, so the default mode must refuse it
+	// rather than send in the clear
+	_, cfg := fakeSMTP(t, "", "")
+	cfg.TLS = ""
+	if err := (&sender{config: cfg}).check(context.Background()); err == nil {
+		t.Fatal("check succeeded without STARTTLS")
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&textproto.Error{Code: 550, Msg: "No such user"}, true},
+		{&textproto.Error{Code: 451, Msg: "Try again later"}, false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := permanent(tt.err); got != tt.want {
+			t.Errorf("permanent(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
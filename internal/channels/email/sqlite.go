@@ -0,0 +1,190 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS email_preferences (
+	user_id      TEXT PRIMARY KEY,
+	digest       INTEGER NOT NULL,
+	alerts       INTEGER NOT NULL,
+	min_severity TEXT NOT NULL,
+	departments  TEXT NOT NULL DEFAULT '',
+	updated_at   INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS email_deliveries (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind       TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	recipient  TEXT NOT NULL,
+	subject    TEXT NOT NULL,
+	alert_id   TEXT NOT NULL DEFAULT '',
+	status     TEXT NOT NULL,
+	attempts   INTEGER NOT NULL,
+	error      TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	sent_at    INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_deliveries_user
+	ON email_deliveries (user_id, created_at);
+`
+
+// SQLiteStore persists preferences and deliveries in a SQLite database
+// file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if needed) the database at path and
+// ensures the schema exists
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	p := &Preferences{UserID: userID}
+	var severity, departments string
+	var updated int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT digest, alerts, min_severity, departments, updated_at FROM email_preferences WHERE user_id = ?`, userID).
+		Scan(&p.Digest, &p.Alerts, &severity, &departments, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load preferences: %w", err)
+	}
+
+	p.MinSeverity = alerts.Severity(severity)
+	if departments != "" {
+		p.Departments = strings.Split(departments, ",")
+	}
+	p.UpdatedAt = time.UnixMilli(updated).UTC()
+	return p, nil
+}
+
+func (s *SQLiteStore) SavePreferences(ctx context.Context, p *Preferences) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO email_preferences (user_id, digest, alerts, min_severity, departments, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id) DO UPDATE SET digest = excluded.digest, alerts = excluded.alerts,
+		 min_severity = excluded.min_severity, departments = excluded.departments, updated_at = excluded.updated_at`,
+		p.UserID, p.Digest, p.Alerts, string(p.MinSeverity), strings.Join(p.Departments, ","), p.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	query := `SELECT id, kind, user_id, recipient, subject, alert_id, status, attempts, error, created_at, sent_at
+		FROM email_deliveries WHERE 1 = 1`
+	var args []interface{}
+	if f.UserID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, f.UserID)
+	}
+	if f.Kind != "" {
+		query += ` AND kind = ?`
+		args = append(args, string(f.Kind))
+	}
+	if f.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(f.Status))
+	}
+	if !f.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, f.Since.UnixMilli())
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var id, created int64
+		var sent sql.NullInt64
+		if err := rows.Scan(&id, &d.Kind, &d.UserID, &d.To, &d.Subject, &d.AlertID, &d.Status, &d.Attempts, &d.Error, &created, &sent); err != nil {
+			return nil, fmt.Errorf("failed to read delivery: %w", err)
+		}
+		d.ID = strconv.FormatInt(id, 10)
+		d.CreatedAt = time.UnixMilli(created).UTC()
+		if sent.Valid {
+			at := time.UnixMilli(sent.Int64).UTC()
+			d.SentAt = &at
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *SQLiteStore) SaveDelivery(ctx context.Context, d *Delivery) error {
+	var sent interface{}
+	if d.SentAt != nil {
+		sent = d.SentAt.UnixMilli()
+	}
+
+	if d.ID != "" {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE email_deliveries SET status = ?, attempts = ?, error = ?, sent_at = ? WHERE id = ?`,
+			string(d.Status), d.Attempts, d.Error, sent, d.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update delivery: %w", err)
+		}
+		return nil
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO email_deliveries (kind, user_id, recipient, subject, alert_id, status, attempts, error, created_at, sent_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		string(d.Kind), d.UserID, d.To, d.Subject, d.AlertID, string(d.Status), d.Attempts, d.Error, d.CreatedAt.UnixMilli(), sent)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	d.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
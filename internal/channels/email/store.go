@@ -0,0 +1,130 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+)
+
+// ErrNotFound is returned when a user has no saved preferences
+var ErrNotFound = errors.New("preferences not found")
+
+// ErrInvalidPreferences is returned when saving preferences with unknown
+// values
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// Preferences are what a user wants emailed
+type Preferences struct {
+	UserID string `json:"userId"`
+	// Digest sends the morning summary of the user's departments
+	Digest bool `json:"digest"`
+	// Alerts sends alerts of at least MinSeverity as they're raised or
+	// escalated
+	Alerts      bool            `json:"alerts"`
+	MinSeverity alerts.Severity `json:"minSeverity"`
+	// Departments narrows a store-level user's digest and alerts; empty
+	// means every department. Department managers only ever get their
+	// own.
+	Departments []string  `json:"departments,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt,omitzero"`
+}
+
+// DefaultPreferences are used until a user saves their own: the digest
+// and critical alerts
+func DefaultPreferences(userID string) *Preferences {
+	return &Preferences{
+		UserID:      userID,
+		Digest:      true,
+		Alerts:      true,
+		MinSeverity: alerts.SeverityCritical,
+	}
+}
+
+// Validate checks the preferences' values
+func (p *Preferences) Validate() error {
+	if p.Alerts && !p.MinSeverity.Valid() {
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidPreferences, p.MinSeverity)
+	}
+	return nil
+}
+
+// Covers reports whether the preferences include dept
+func (p *Preferences) Covers(dept string) bool {
+	return len(p.Departments) == 0 || slices.Contains(p.Departments, dept)
+}
+
+// Kind is what an email was about
+type Kind string
+
+const (
+	KindDigest Kind = "digest"
+	KindAlert  Kind = "alert"
+)
+
+// DeliveryStatus is how sending an email went
+type DeliveryStatus string
+
+const (
+	DeliverySent DeliveryStatus = "sent"
+	// DeliveryFailed means every attempt failed, or the server refused
+	// the message outright
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Delivery records sending one email to one user
+type Delivery struct {
+	ID      string `json:"id"`
+	Kind    Kind   `json:"kind"`
+	UserID  string `json:"userId"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	// AlertID is set for alert emails
+	AlertID  string         `json:"alertId,omitempty"`
+	Status   DeliveryStatus `json:"status"`
+	Attempts int            `json:"attempts"`
+	// Error is the last attempt's error
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	SentAt    *time.Time `json:"sentAt,omitempty"`
+}
+
+// DeliveryFilter selects deliveries. Zero values mean no filter.
+type DeliveryFilter struct {
+	UserID string
+	Kind   Kind
+	Status DeliveryStatus
+	// Since keeps deliveries created at or after it
+	Since time.Time
+	// Limit defaults to 50
+	Limit int
+}
+
+func (f DeliveryFilter) matches(d *Delivery) bool {
+	switch {
+	case f.UserID != "" && d.UserID != f.UserID:
+	case f.Kind != "" && d.Kind != f.Kind:
+	case f.Status != "" && d.Status != f.Status:
+	case !f.Since.IsZero() && d.CreatedAt.Before(f.Since):
+	default:
+		return true
+	}
+	return false
+}
+
+// Store keeps preferences and delivery records
+type Store interface {
+	// Preferences returns the user's saved preferences, or ErrNotFound
+	Preferences(ctx context.Context, userID string) (*Preferences, error)
+	// SavePreferences inserts or replaces the user's preferences
+	SavePreferences(ctx context.Context, p *Preferences) error
+	// Deliveries returns the deliveries matching f, newest first
+	Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error)
+	// SaveDelivery inserts a delivery, setting its ID, or replaces it by
+	// ID
+	SaveDelivery(ctx context.Context, d *Delivery) error
+	Close() error
+}
@@ -0,0 +1,134 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+)
+
+// defaultTemplates are the built-in templates. Each email has a .txt
+// template, which also defines its "subject", and a .html one using the
+// blocks in layout.html.
+//
+//go:embed templates
+var defaultTemplates embed.FS
+
+// templateNames are the emails that can be rendered
+var templateNames = []string{"digest", "alert"}
+
+// templates renders emails
+type templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// loadTemplates parses the built-in templates, replacing any that dir
+// has a file of the same name for
+func loadTemplates(dir string) (*templates, error) {
+	fsys, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to read email templates: %w", err)
+		}
+		fsys = overlay{top: os.DirFS(dir), bottom: fsys}
+	}
+
+	t := &templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	for _, name := range templateNames {
+		text, err := texttemplate.New(name+".txt").Funcs(texttemplate.FuncMap(funcs)).ParseFS(fsys, name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s.txt: %w", name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("%s.txt doesn't define a subject", name)
+		}
+		html, err := htmltemplate.New(name+".html").Funcs(htmltemplate.FuncMap(funcs)).ParseFS(fsys, "layout.html", name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s.html: %w", name, err)
+		}
+		t.text[name], t.html[name] = text, html
+	}
+	return t, nil
+}
+
+// render executes an email's templates with data
+func (t *templates) render(name string, data interface{}) (subject, text, html string, err error) {
+	var b bytes.Buffer
+	if err := t.text[name].ExecuteTemplate(&b, "subject", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if err := t.text[name].Execute(&b, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render %s.txt: %w", name, err)
+	}
+	text = strings.TrimSpace(b.String()) + "\n"
+
+	b.Reset()
+	if err := t.html[name].Execute(&b, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render %s.html: %w", name, err)
+	}
+	return subject, text, b.String(), nil
+}
+
+// overlay reads files from top, falling back to bottom for files top
+// doesn't have
+type overlay struct {
+	top, bottom fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.bottom.Open(name)
+	}
+	return f, err
+}
+
+// severityColors are the badge colors of alert severities
+var severityColors = map[alerts.Severity]string{
+	alerts.SeverityInfo:     "#0969da",
+	alerts.SeverityWarning:  "#9a6700",
+	alerts.SeverityCritical: "#cf222e",
+}
+
+// funcs are available to every template
+var funcs = map[string]interface{}{
+	"date":     func(t time.Time) string { return t.Local().Format("Monday, Jan 2") },
+	"datetime": func(t time.Time) string { return t.Local().Format("Jan 2, 3:04 PM") },
+	"clock":    func(t time.Time) string { return t.Local().Format("3:04 PM") },
+	"upper":    func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+	// quantity drops the decimals of whole numbers
+	"quantity": func(q float64) string { return strconv.FormatFloat(q, 'f', -1, 64) },
+	// more notes the entries left out of a shortened list
+	"more": func(total, shown int) string {
+		if total <= shown {
+			return ""
+		}
+		return fmt.Sprintf("and %d more", total-shown)
+	},
+	"severityColor": func(s alerts.Severity) string { return severityColors[s] },
+}
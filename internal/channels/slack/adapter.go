@@ -0,0 +1,541 @@
+// Package slack lets store staff ask Opus questions from Slack and posts
+// alerts to department channels. Questions arrive as Events API mentions
+// and direct messages or as a slash command, verified with the app's
+// signing secret, and are answered in threads through the chat pipeline
+// shared with the web client.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/connectors"
+)
+
+// DefaultAPIURL is the Slack Web API
+const DefaultAPIURL = "https://slack.com/api"
+
+const (
+	// maxBody is the largest request accepted from Slack
+	maxBody = 1 << 20
+	// answerTimeout bounds how long a question may take to answer
+	answerTimeout = 2 * time.Minute
+	// threadTTL is how long a quiet thread keeps its conversation
+	threadTTL = 24 * time.Hour
+	// eventTTL is how long event IDs are remembered to ignore Slack's
+	// retries
+	eventTTL = 10 * time.Minute
+	// alertQueueSize is how many alert events can wait to be posted
+	alertQueueSize = 100
+	// minAlertSeverity is the least severe alert posted to channels
+	minAlertSeverity = alerts.SeverityWarning
+)
+
+// Config configures the Slack adapter
+type Config struct {
+	// Token is the bot token (xoxb-...) and SigningSecret verifies
+	// requests from Slack
+	Token         string
+	SigningSecret string
+	// APIURL defaults to DefaultAPIURL
+	APIURL string
+	// Users maps Slack user IDs to Opus user IDs; unmapped users can't
+	// ask questions
+	Users map[string]string
+	// AlertChannels maps departments to the channel IDs their alerts are
+	// posted to. Alerts from every department go to the "*" channel.
+	AlertChannels map[string]string
+	HTTPClient    *http.Client
+}
+
+// Adapter answers questions from Slack and posts alerts to it. It is a
+// connector so the registry checks its token and reports its health.
+type Adapter struct {
+	config Config
+	client *client
+	chat   *chat.Service
+	users  auth.Directory
+	now    func() time.Time
+
+	alertEvents chan alerts.Event
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	mu        sync.Mutex
+	botUserID string
+	team      string
+	threads   map[string]*thread
+	seen      map[string]time.Time
+	posts     map[string][]post
+	lastError error
+	answered  int
+	posted    int
+}
+
+// thread is a Slack thread or direct message conversation with Opus
+type thread struct {
+	conversationID string
+	// pending is a question that was too ambiguous to route, asked again
+	// once the user names one of candidates
+	pending    string
+	candidates []ai.Department
+	lastUsed   time.Time
+}
+
+// post is a message about an alert, which later changes are threaded
+// under
+type post struct {
+	channel string
+	ts      string
+}
+
+// New creates a Slack adapter answering through chatService as the Opus
+// users looked up in users
+func New(cfg Config, chatService *chat.Service, users auth.Directory) *Adapter {
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Adapter{
+		config: cfg,
+		client: &client{
+			baseURL: strings.TrimRight(cfg.APIURL, "/"),
+			token:   cfg.Token,
+			http:    cfg.HTTPClient,
+		},
+		chat:        chatService,
+		users:       users,
+		now:         time.Now,
+		alertEvents: make(chan alerts.Event, alertQueueSize),
+		threads:     make(map[string]*thread),
+		seen:        make(map[string]time.Time),
+		posts:       make(map[string][]post),
+	}
+}
+
+func (a *Adapter) Name() string { return "slack" }
+
+// Connect checks the bot token and starts posting alerts
+func (a *Adapter) Connect(ctx context.Context) error {
+	id, err := a.client.authTest(ctx)
+	a.track(err)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.botUserID = id.UserID
+	a.team = id.Team
+	a.mu.Unlock()
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+	go a.postAlerts(loopCtx)
+	return nil
+}
+
+func (a *Adapter) Disconnect() error {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+		a.cancel = nil
+	}
+	return nil
+}
+
+// Health is unhealthy while the last Web API call failed
+func (a *Adapter) Health() connectors.HealthStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: a.lastError == nil,
+		Details: map[string]interface{}{
+			"team":     a.team,
+			"answered": a.answered,
+			"alerts":   a.posted,
+			"threads":  len(a.threads),
+		},
+	}
+	if a.lastError != nil {
+		status.Message = a.lastError.Error()
+	}
+	return status
+}
+
+// track records the outcome of a Web API call for Health. Errors about a
+// particular channel don't make the adapter unhealthy.
+func (a *Adapter) track(err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.Code == "channel_not_found" || apiErr.Code == "not_in_channel" || apiErr.Code == "is_archived") {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastError = err
+}
+
+// event is the part of an Events API event the adapter reads
+type event struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// HandleEvents receives Events API requests. Mentions of the bot, direct
+// messages and replies in threads Opus is part of are answered in the
+// background, since Slack expects a response within three seconds.
+func (a *Adapter) HandleEvents(w http.ResponseWriter, req *http.Request) {
+	body, ok := a.readVerified(w, req)
+	if !ok {
+		return
+	}
+
+	var envelope struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		EventID   string `json:"event_id"`
+		Event     event  `json:"event"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		http.Error(w, `{"error": "Invalid event"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch envelope.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"challenge": envelope.Challenge})
+	case "event_callback":
+		w.WriteHeader(http.StatusOK)
+		if !a.firstDelivery(envelope.EventID) {
+			return
+		}
+		go a.handleEvent(envelope.Event)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// firstDelivery reports whether an event hasn't been seen before, so
+// Slack's retries of slow deliveries are ignored
+func (a *Adapter) firstDelivery(eventID string) bool {
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, at := range a.seen {
+		if now.Sub(at) > eventTTL {
+			delete(a.seen, id)
+		}
+	}
+	if _, ok := a.seen[eventID]; ok {
+		return false
+	}
+	a.seen[eventID] = now
+	return true
+}
+
+func (a *Adapter) handleEvent(ev event) {
+	a.mu.Lock()
+	botUserID := a.botUserID
+	a.mu.Unlock()
+
+	// Ignore the bot's own messages, and edits, joins and the like
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" || ev.User == botUserID {
+		return
+	}
+
+	q := query{user: ev.User, channel: ev.Channel, text: ev.Text}
+	switch {
+	case ev.Type == "app_mention":
+		q.threadTS = ev.ThreadTS
+		if q.threadTS == "" {
+			q.threadTS = ev.TS
+		}
+	case ev.Type == "message" && ev.ChannelType == "im":
+		// Direct messages are one conversation unless threaded
+		q.threadTS = ev.ThreadTS
+	case ev.Type == "message" && ev.ThreadTS != "":
+		// A mention in a thread also arrives as app_mention
+		if strings.Contains(ev.Text, "<@"+botUserID+">") || !a.inThread(ev.Channel+"/"+ev.ThreadTS) {
+			return
+		}
+		q.threadTS = ev.ThreadTS
+	default:
+		return
+	}
+	q.key = q.channel + "/" + q.threadTS
+
+	ctx, cancel := context.WithTimeout(context.Background(), answerTimeout)
+	defer cancel()
+	a.ask(ctx, q)
+}
+
+// HandleCommand receives the slash command, e.g. "/opus how much milk is
+// left?". The question is posted to the channel and answered in its
+// thread; where the bot can't post, the answer goes only to the asker.
+func (a *Adapter) HandleCommand(w http.ResponseWriter, req *http.Request) {
+	body, ok := a.readVerified(w, req)
+	if !ok {
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, `{"error": "Invalid command"}`, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	text := strings.TrimSpace(form.Get("text"))
+	if text == "" || strings.EqualFold(text, "help") {
+		json.NewEncoder(w).Encode(ephemeral("Ask Opus about your store, e.g. `" + form.Get("command") + " which items are below reorder point?`. Mention @Opus or message it directly to keep the conversation going in a thread."))
+		return
+	}
+	if _, err := a.user(req.Context(), form.Get("user_id")); err != nil {
+		json.NewEncoder(w).Encode(ephemeral(notLinked))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+
+	q := query{
+		user:        form.Get("user_id"),
+		channel:     form.Get("channel_id"),
+		text:        text,
+		responseURL: form.Get("response_url"),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), answerTimeout)
+		defer cancel()
+
+		ts, err := a.client.postMessage(ctx, message{
+			Channel: q.channel,
+			Text:    "<@" + q.user + "> asked: " + mrkdwn(text),
+		})
+		a.track(err)
+		if err == nil {
+			q.threadTS = ts
+			q.key = q.channel + "/" + ts
+		} else {
+			log.Printf("Slack: can't post to %s, answering %s privately: %v", q.channel, q.user, err)
+		}
+		a.ask(ctx, q)
+	}()
+}
+
+// readVerified reads a request's body, refusing it unless it's signed
+// with the signing secret
+func (a *Adapter) readVerified(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBody))
+	if err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	if err := verify(a.config.SigningSecret, req.Header, body, a.now()); err != nil {
+		log.Printf("Rejected Slack request from %s: %v", req.RemoteAddr, err)
+		http.Error(w, `{"error": "Invalid signature"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
+
+type commandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func ephemeral(text string) commandResponse {
+	return commandResponse{ResponseType: "ephemeral", Text: text}
+}
+
+const notLinked = "Your Slack account isn't linked to an Opus user yet. Ask your store admin to add you."
+
+// query is a question from Slack and where to answer it
+type query struct {
+	user    string
+	channel string
+	text    string
+	// threadTS is the thread to answer in; empty answers a direct message
+	// in the conversation itself
+	threadTS string
+	// key identifies the thread's conversation; empty answers without
+	// one
+	key string
+	// responseURL answers privately when nothing can be posted in the
+	// channel
+	responseURL string
+}
+
+// user returns the Opus user a Slack user is mapped to
+func (a *Adapter) user(ctx context.Context, slackUser string) (*auth.User, error) {
+	id, ok := a.config.Users[slackUser]
+	if !ok || a.users == nil {
+		return nil, auth.ErrUnknownUser
+	}
+	return a.users.User(ctx, id)
+}
+
+// ask answers a question as the Slack user's Opus user, within their
+// department scope
+func (a *Adapter) ask(ctx context.Context, q query) {
+	user, err := a.user(ctx, q.user)
+	if err != nil {
+		a.reply(ctx, q, notLinked)
+		return
+	}
+	text := question(q.text)
+	if text == "" {
+		a.reply(ctx, q, "What would you like to know? Ask about inventory, schedules, alerts or store procedures.")
+		return
+	}
+
+	req := chat.Request{Message: text, UserID: user.ID, Scope: ai.Department(user.Scope())}
+	var t *thread
+	if q.key != "" {
+		if t, err = a.thread(ctx, q.key, user); err != nil {
+			log.Printf("Slack: failed to start conversation for %s: %v", q.key, err)
+			a.reply(ctx, q, "Sorry, I couldn't start a conversation. Please try again.")
+			return
+		}
+		req.ConversationID = t.conversationID
+		a.resume(t, &req)
+	}
+
+	result, err := a.chat.Process(ctx, req, nil)
+	if err != nil {
+		log.Printf("Slack: failed to answer %s for %s: %v", q.user, user.ID, err)
+		a.reply(ctx, q, failure(err))
+		return
+	}
+
+	if t != nil {
+		a.mu.Lock()
+		t.pending, t.candidates = "", nil
+		if result.Ambiguous {
+			t.pending, t.candidates = req.Message, result.Candidates
+		}
+		a.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	a.answered++
+	a.mu.Unlock()
+	a.reply(ctx, q, answer(result))
+}
+
+// resume turns a reply naming one of the departments Opus asked about
+// into the pending question for that department
+func (a *Adapter) resume(t *thread, req *chat.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if t.pending == "" {
+		return
+	}
+	reply := strings.ToLower(strings.Trim(req.Message, " .!?"))
+	for _, d := range t.candidates {
+		if reply == string(d) || reply == "the "+string(d) {
+			req.Message, req.Department = t.pending, d
+			return
+		}
+	}
+}
+
+// failure is the reply for a question that couldn't be answered
+func failure(err error) string {
+	switch {
+	case errors.Is(err, chat.ErrOutOfScope):
+		return "You can only ask about your own department."
+	case errors.Is(err, chat.ErrUnknownDepartment):
+		return "I don't know that department."
+	default:
+		return "Sorry, I couldn't answer that right now. Please try again in a minute."
+	}
+}
+
+// reply answers q in its thread, or privately through the slash command's
+// response URL
+func (a *Adapter) reply(ctx context.Context, q query, text string) {
+	if q.key == "" && q.responseURL != "" {
+		if err := a.client.respond(ctx, q.responseURL, text); err != nil {
+			log.Printf("Slack: failed to reply to %s: %v", q.user, err)
+		}
+		return
+	}
+
+	_, err := a.client.postMessage(ctx, message{Channel: q.channel, Text: text, ThreadTS: q.threadTS})
+	a.track(err)
+	if err != nil {
+		log.Printf("Slack: failed to reply in %s: %v", q.channel, err)
+	}
+}
+
+// inThread reports whether Opus is part of the thread with key, with
+// anyone
+func (a *Adapter) inThread(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k := range a.threads {
+		if strings.HasPrefix(k, key+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// thread returns the user's conversation in the thread with key, starting
+// one if it's new. Everyone asking in a thread gets their own
+// conversation, since only its owner can continue it. Threads quiet for a
+// day are forgotten.
+func (a *Adapter) thread(ctx context.Context, key string, user *auth.User) (*thread, error) {
+	key += "/" + user.ID
+	now := a.now()
+	a.mu.Lock()
+	if t, ok := a.threads[key]; ok {
+		t.lastUsed = now
+		a.mu.Unlock()
+		return t, nil
+	}
+	a.mu.Unlock()
+
+	conv, err := a.chat.Conversations().Create(ctx, user.ID, ai.Department(user.Scope()))
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, t := range a.threads {
+		if now.Sub(t.lastUsed) > threadTTL {
+			delete(a.threads, k)
+		}
+	}
+	// Another message in the thread may have started it meanwhile
+	if t, ok := a.threads[key]; ok {
+		return t, nil
+	}
+	t := &thread{conversationID: conv.ID, lastUsed: now}
+	a.threads[key] = t
+	return t, nil
+}
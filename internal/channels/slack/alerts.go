@@ -0,0 +1,125 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/notify"
+)
+
+// PublishAlert queues an alert event to be posted. Pass it to
+// alerts.Engine.Subscribe.
+func (a *Adapter) PublishAlert(event alerts.Event) {
+	select {
+	case a.alertEvents <- event:
+	default:
+		log.Printf("Slack: alert queue is full, dropping %s for alert %s", event.Type, event.Alert.ID)
+	}
+}
+
+func (a *Adapter) postAlerts(ctx context.Context) {
+	defer close(a.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-a.alertEvents:
+			a.postAlert(ctx, &event.Alert)
+		}
+	}
+}
+
+// postAlert posts an active alert of at least minAlertSeverity to its
+// department's channels, and threads later changes under that message
+func (a *Adapter) postAlert(ctx context.Context, alert *alerts.Alert) {
+	a.mu.Lock()
+	posts := a.posts[alert.ID]
+	a.mu.Unlock()
+
+	if len(posts) == 0 {
+		if alert.Status != alerts.StatusActive || !alert.Severity.AtLeast(minAlertSeverity) {
+			return
+		}
+		for _, channel := range a.alertChannels(alert.Department) {
+			ts, err := a.client.postMessage(ctx, message{Channel: channel, Text: alertText(alert)})
+			a.track(err)
+			if err != nil {
+				log.Printf("Slack: failed to post alert %s to %s: %v", alert.ID, channel, err)
+				continue
+			}
+			posts = append(posts, post{channel: channel, ts: ts})
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if len(posts) > 0 {
+			a.posts[alert.ID] = posts
+			a.posted++
+		}
+		return
+	}
+
+	if text := changeText(alert); text != "" {
+		for _, p := range posts {
+			_, err := a.client.postMessage(ctx, message{
+				Channel:  p.channel,
+				Text:     text,
+				ThreadTS: p.ts,
+				// Escalations need to be seen, not just followed
+				Broadcast: alert.History[len(alert.History)-1].Action == alerts.ActionEscalated,
+			})
+			a.track(err)
+			if err != nil {
+				log.Printf("Slack: failed to update alert %s in %s: %v", alert.ID, p.channel, err)
+			}
+		}
+	}
+
+	if !alert.Status.Open() {
+		a.mu.Lock()
+		delete(a.posts, alert.ID)
+		a.mu.Unlock()
+	}
+}
+
+// Notify messages user an alert routed to them, returning
+// notify.ErrUnreachable if they have no linked Slack account
+func (a *Adapter) Notify(ctx context.Context, user *auth.User, n *notify.Notification) error {
+	var slackUser string
+	for id, opusID := range a.config.Users {
+		if opusID == user.ID {
+			slackUser = id
+			break
+		}
+	}
+	if slackUser == "" {
+		return notify.ErrUnreachable
+	}
+
+	text := alertText(n.Alert)
+	if n.Escalated {
+		text = "*Escalated to you* — nobody has acknowledged this yet\n" + text
+	}
+	// Posting to a user ID sends it from the bot as a direct message
+	_, err := a.client.postMessage(ctx, message{Channel: slackUser, Text: text})
+	a.track(err)
+	if err != nil {
+		return fmt.Errorf("failed to message %s: %w", slackUser, err)
+	}
+	return nil
+}
+
+// alertChannels returns the channels a department's alerts go to
+func (a *Adapter) alertChannels(dept string) []string {
+	var channels []string
+	for _, key := range []string{dept, "*"} {
+		if channel := a.config.AlertChannels[key]; channel != "" && !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxRetries is how many times a rate-limited call is retried
+	maxRetries = 3
+	// maxRetryAfter caps how long a rate-limited call waits
+	maxRetryAfter = 30 * time.Second
+)
+
+// APIError is an error returned by the Slack Web API, e.g.
+// "channel_not_found" or "not_in_channel"
+type APIError struct {
+	Method string
+	Code   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Method, e.Code)
+}
+
+// client calls the Slack Web API with a bot token
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// message is a chat.postMessage request
+type message struct {
+	Channel  string `json:"channel"`
+	Text     string `json:"text"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+	// Broadcast also shows a thread reply in the channel
+	Broadcast   bool `json:"reply_broadcast,omitempty"`
+	UnfurlLinks bool `json:"unfurl_links"`
+}
+
+// identity is the bot's own user, from auth.test
+type identity struct {
+	UserID string `json:"user_id"`
+	BotID  string `json:"bot_id"`
+	Team   string `json:"team"`
+}
+
+// authTest checks the token and returns who it belongs to
+func (c *client) authTest(ctx context.Context) (*identity, error) {
+	var id identity
+	if err := c.call(ctx, "auth.test", struct{}{}, &id); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// postMessage posts msg and returns its timestamp, which identifies it
+// for thread replies
+func (c *client) postMessage(ctx context.Context, msg message) (string, error) {
+	var resp struct {
+		TS string `json:"ts"`
+	}
+	if err := c.call(ctx, "chat.postMessage", msg, &resp); err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+// postEphemeral shows text to user alone in channel
+func (c *client) postEphemeral(ctx context.Context, channel, user, text string) error {
+	body := map[string]string{"channel": channel, "user": user, "text": text}
+	return c.call(ctx, "chat.postEphemeral", body, nil)
+}
+
+// respond posts text to a slash command's response_url, which works even
+// in channels the bot hasn't joined
+func (c *client) respond(ctx context.Context, responseURL, text string) error {
+	body, err := json.Marshal(map[string]string{"response_type": "ephemeral", "text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to response URL: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response URL returned %s", resp.Status)
+	}
+	return nil
+}
+
+// call POSTs body as JSON to a Web API method and decodes the response
+// into out. Rate-limited calls are retried after the delay Slack asks
+// for.
+func (c *client) call(ctx context.Context, method string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+method, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", method, err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			resp.Body.Close()
+			wait := time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = min(time.Duration(seconds)*time.Second, maxRetryAfter)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return fmt.Errorf("failed to read %s response: %w", method, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s failed: %s %s", method, resp.Status, strings.TrimSpace(string(data)))
+		}
+
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, err)
+		}
+		if !result.OK {
+			return &APIError{Method: method, Code: result.Error}
+		}
+		if out != nil {
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("failed to decode %s response: %w", method, err)
+			}
+		}
+		return nil
+	}
+}
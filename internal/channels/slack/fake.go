@@ -0,0 +1,300 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeBotUserID is the bot's user ID on a FakeServer
+const FakeBotUserID = "UOPUSBOT"
+
+// FakeMessage is a message posted to a FakeServer, by the bot or by a
+// simulated user
+type FakeMessage struct {
+	Channel   string `json:"channel"`
+	TS        string `json:"ts"`
+	ThreadTS  string `json:"threadTs,omitempty"`
+	User      string `json:"user"`
+	Text      string `json:"text"`
+	Broadcast bool   `json:"broadcast,omitempty"`
+	// Ephemeral messages were shown to User alone
+	Ephemeral bool `json:"ephemeral,omitempty"`
+}
+
+// FakeServer stands in for Slack for demos and local testing. It serves
+// the Web API methods the adapter calls under /api/, records every
+// message, and plays users: POST /send signs an event or slash command
+// and delivers it to Opus.
+//
+//	POST /send      {"user": "U01", "channel": "C0DAIRY", "text": "<@UOPUSBOT> milk?", "threadTs": "..."}
+//	POST /send      {"user": "U01", "channel": "C0DAIRY", "command": "/opus", "text": "milk?"}
+//	GET  /messages  ?channel=C0DAIRY
+type FakeServer struct {
+	Token         string
+	SigningSecret string
+	// OpusURL is where events are delivered, e.g. http://localhost:8080
+	OpusURL string
+	// Channels are the channels the bot has joined, by ID. Direct
+	// message channels (D...) are always open, and posting to a user
+	// (U...) messages them directly.
+	Channels map[string]string
+
+	mu       sync.Mutex
+	messages []FakeMessage
+	seq      int
+	http     *http.Client
+}
+
+// NewFakeServer creates a fake Slack workspace with a few department
+// channels
+func NewFakeServer(token, signingSecret, opusURL string) *FakeServer {
+	return &FakeServer{
+		Token:         token,
+		SigningSecret: signingSecret,
+		OpusURL:       strings.TrimRight(opusURL, "/"),
+		Channels: map[string]string{
+			"C0GENERAL": "general",
+			"C0STORE":   "store-alerts",
+			"C0DAIRY":   "dairy",
+			"C0MEAT":    "meat",
+			"C0BAKERY":  "bakery",
+			"C0FRONT":   "front-end",
+		},
+		http: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Messages returns the messages posted to channel, or to every channel
+// if it's empty
+func (f *FakeServer) Messages(channel string) []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := []FakeMessage{}
+	for _, m := range f.messages {
+		if channel == "" || m.Channel == channel {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/messages":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(f.Messages(r.URL.Query().Get("channel")))
+	case r.Method == http.MethodPost && r.URL.Path == "/send":
+		f.send(w, r)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/respond/"):
+		f.respond(w, r)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/"):
+		f.api(w, r, strings.TrimPrefix(r.URL.Path, "/api/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// api serves a Web API method
+func (f *FakeServer) api(w http.ResponseWriter, r *http.Request, method string) {
+	if r.Header.Get("Authorization") != "Bearer "+f.Token {
+		writeFakeResult(w, map[string]interface{}{"ok": false, "error": "invalid_auth"})
+		return
+	}
+
+	var params struct {
+		Channel   string `json:"channel"`
+		User      string `json:"user"`
+		Text      string `json:"text"`
+		ThreadTS  string `json:"thread_ts"`
+		Broadcast bool   `json:"reply_broadcast"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeFakeResult(w, map[string]interface{}{"ok": false, "error": "invalid_json"})
+		return
+	}
+
+	switch method {
+	case "auth.test":
+		writeFakeResult(w, map[string]interface{}{"ok": true, "user_id": FakeBotUserID, "bot_id": "BOPUS", "team": "Fake Grocery"})
+	case "chat.postMessage", "chat.postEphemeral":
+		if !f.joined(params.Channel) {
+			writeFakeResult(w, map[string]interface{}{"ok": false, "error": "not_in_channel"})
+			return
+		}
+		if params.Text == "" {
+			writeFakeResult(w, map[string]interface{}{"ok": false, "error": "no_text"})
+			return
+		}
+		// Ephemeral messages are recorded against the user who sees them
+		user := FakeBotUserID
+		if method == "chat.postEphemeral" {
+			user = params.User
+		}
+		msg := f.record(FakeMessage{
+			Channel:   params.Channel,
+			ThreadTS:  params.ThreadTS,
+			User:      user,
+			Text:      params.Text,
+			Broadcast: params.Broadcast,
+			Ephemeral: method == "chat.postEphemeral",
+		})
+		writeFakeResult(w, map[string]interface{}{"ok": true, "channel": msg.Channel, "ts": msg.TS})
+	default:
+		writeFakeResult(w, map[string]interface{}{"ok": false, "error": "unknown_method"})
+	}
+}
+
+// respond records a reply to a slash command's response_url, shown to
+// the user who ran it
+func (f *FakeServer) respond(w http.ResponseWriter, r *http.Request) {
+	channel, user, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/respond/"), "/")
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+		return
+	}
+	f.record(FakeMessage{Channel: channel, User: user, Text: body.Text, Ephemeral: true})
+	w.Write([]byte("ok"))
+}
+
+// send plays a user posting a message or running a slash command, and
+// returns Opus's response
+func (f *FakeServer) send(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		User     string `json:"user"`
+		Channel  string `json:"channel"`
+		Text     string `json:"text"`
+		ThreadTS string `json:"threadTs"`
+		Command  string `json:"command"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.User == "" || in.Channel == "" {
+		http.Error(w, `{"error": "user and channel are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var status int
+	var err error
+	if in.Command != "" {
+		form := url.Values{
+			"command":      {in.Command},
+			"text":         {in.Text},
+			"user_id":      {in.User},
+			"channel_id":   {in.Channel},
+			"response_url": {"http://" + r.Host + "/respond/" + in.Channel + "/" + in.User},
+		}
+		status, err = f.deliver("/slack/commands", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	} else {
+		msg := f.record(FakeMessage{Channel: in.Channel, ThreadTS: in.ThreadTS, User: in.User, Text: in.Text})
+		status, err = f.deliverMessage(msg)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "messages": len(f.Messages(""))})
+}
+
+// deliverMessage sends the events Slack would for a message: a message
+// event, and an app_mention if it mentions the bot
+func (f *FakeServer) deliverMessage(msg FakeMessage) (int, error) {
+	ev := map[string]interface{}{
+		"type":    "message",
+		"user":    msg.User,
+		"text":    msg.Text,
+		"channel": msg.Channel,
+		"ts":      msg.TS,
+	}
+	if msg.ThreadTS != "" {
+		ev["thread_ts"] = msg.ThreadTS
+	}
+	if strings.HasPrefix(msg.Channel, "D") {
+		ev["channel_type"] = "im"
+	} else {
+		ev["channel_type"] = "channel"
+	}
+
+	events := []map[string]interface{}{ev}
+	if strings.Contains(msg.Text, "<@"+FakeBotUserID+">") {
+		mention := make(map[string]interface{}, len(ev))
+		for k, v := range ev {
+			mention[k] = v
+		}
+		mention["type"] = "app_mention"
+		delete(mention, "channel_type")
+		events = append(events, mention)
+	}
+
+	var status int
+	for _, e := range events {
+		f.mu.Lock()
+		f.seq++
+		id := "Ev" + strconv.Itoa(f.seq)
+		f.mu.Unlock()
+
+		body, err := json.Marshal(map[string]interface{}{
+			"type":     "event_callback",
+			"event_id": id,
+			"event":    e,
+		})
+		if err != nil {
+			return 0, err
+		}
+		if status, err = f.deliver("/slack/events", "application/json", body); err != nil {
+			return 0, err
+		}
+	}
+	return status, nil
+}
+
+// deliver signs body and POSTs it to Opus
+func (f *FakeServer) deliver(path, contentType string, body []byte) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, f.OpusURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", sign(f.SigningSecret, timestamp, body))
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+func (f *FakeServer) joined(channel string) bool {
+	_, ok := f.Channels[channel]
+	return ok || strings.HasPrefix(channel, "D") || strings.HasPrefix(channel, "U")
+}
+
+// record stores msg with a new timestamp
+func (f *FakeServer) record(msg FakeMessage) FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	msg.TS = fmt.Sprintf("%d.%06d", time.Now().Unix(), f.seq)
+	f.messages = append(f.messages, msg)
+	return msg
+}
+
+func writeFakeResult(w http.ResponseWriter, result map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
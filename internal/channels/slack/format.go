@@ -0,0 +1,135 @@
+package slack
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/chat"
+)
+
+var (
+	boldPattern    = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	headingPattern = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+	linkPattern    = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+)
+
+// mrkdwn converts an agent's Markdown answer to Slack's mrkdwn: special
+// characters escaped, **bold** and headings as *bold*, and links as
+// <url|text>
+func mrkdwn(text string) string {
+	text = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+	text = boldPattern.ReplaceAllString(text, "*$1*")
+	text = headingPattern.ReplaceAllString(text, "*$1*")
+	return linkPattern.ReplaceAllString(text, "<$2|$1>")
+}
+
+// question returns a message's text without mentions of the bot
+func question(text string) string {
+	text = mentionPattern.ReplaceAllString(text, "")
+	text = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// answer formats a chat result with the agent that answered, the
+// documents it cites and its suggested actions
+func answer(result *chat.Result) string {
+	var b strings.Builder
+	b.WriteString(mrkdwn(result.Response))
+
+	if len(result.Actions) > 0 {
+		b.WriteString("\n\n*Suggested next steps*")
+		for _, action := range result.Actions {
+			fmt.Fprintf(&b, "\n• %s", mrkdwn(action.Label))
+		}
+	}
+
+	if len(result.Citations) > 0 {
+		b.WriteString("\n")
+		for _, c := range result.Citations {
+			fmt.Fprintf(&b, "\n_[%d] %s", c.Number, mrkdwn(c.Source))
+			if c.Section != "" {
+				fmt.Fprintf(&b, ", %s", mrkdwn(c.Section))
+			}
+			if c.Page > 0 {
+				fmt.Fprintf(&b, ", p. %d", c.Page)
+			}
+			b.WriteString("_")
+		}
+	}
+
+	switch {
+	case len(result.Departments) > 0:
+		names := make([]string, len(result.Departments))
+		for i, d := range result.Departments {
+			names[i] = string(d)
+		}
+		fmt.Fprintf(&b, "\n\n_Answered by the %s agents_", strings.Join(names, ", "))
+	case result.Department != "":
+		fmt.Fprintf(&b, "\n\n_Answered by the %s agent_", result.Department)
+	}
+	return b.String()
+}
+
+// severityIcons mark alerts by severity
+var severityIcons = map[alerts.Severity]string{
+	alerts.SeverityInfo:     ":information_source:",
+	alerts.SeverityWarning:  ":warning:",
+	alerts.SeverityCritical: ":rotating_light:",
+}
+
+// alertText formats a newly posted alert
+func alertText(a *alerts.Alert) string {
+	text := fmt.Sprintf("%s *%s* · %s\n%s", severityIcons[a.Severity], strings.ToUpper(string(a.Severity)), a.Department, mrkdwn(a.Title))
+	if a.Detail != "" {
+		text += "\n" + mrkdwn(a.Detail)
+	}
+	return text + fmt.Sprintf("\n_Alert %s_", a.ID)
+}
+
+// changeText describes an alert's latest change for a thread reply, or
+// returns "" for changes not worth a reply
+func changeText(a *alerts.Alert) string {
+	if len(a.History) == 0 {
+		return ""
+	}
+	change := a.History[len(a.History)-1]
+
+	var text string
+	switch change.Action {
+	case alerts.ActionAcknowledged:
+		text = "Acknowledged"
+	case alerts.ActionSnoozed:
+		text = "Snoozed"
+		if a.SnoozedUntil != nil {
+			text += " until " + a.SnoozedUntil.Local().Format("3:04 PM")
+		}
+	case alerts.ActionWoke:
+		return "Snooze ended, this needs attention again"
+	case alerts.ActionEscalated:
+		text = fmt.Sprintf("%s Escalated to %s", severityIcons[a.Severity], a.Severity)
+	case alerts.ActionChanged:
+		// Rules refresh titles and details on every evaluation; only a
+		// new severity is news
+		if change.Note == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s Now %s: %s", severityIcons[a.Severity], a.Severity, mrkdwn(a.Title))
+	case alerts.ActionResolved:
+		text = ":white_check_mark: Resolved"
+	case alerts.ActionCleared:
+		return ":white_check_mark: Cleared, the condition is gone"
+	default:
+		return ""
+	}
+
+	if change.By != "" {
+		text += " by " + mrkdwn(change.By)
+	}
+	if change.Note != "" {
+		text += ": " + mrkdwn(change.Note)
+	}
+	return text
+}
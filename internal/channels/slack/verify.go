@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxSkew is how far a request's timestamp may be from now before it's
+// refused as a replay
+const maxSkew = 5 * time.Minute
+
+// Errors returned for requests that aren't from Slack
+var (
+	ErrMissingSignature = errors.New("missing Slack signature headers")
+	ErrStaleRequest     = errors.New("Slack request timestamp is too old")
+	ErrBadSignature     = errors.New("invalid Slack signature")
+)
+
+// verify checks a request's X-Slack-Signature, an HMAC-SHA256 of
+// "v0:{timestamp}:{body}" keyed with the app's signing secret
+func verify(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > maxSkew || skew < -maxSkew {
+		return ErrStaleRequest
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(sign(secret, timestamp, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// sign returns the X-Slack-Signature for body sent at timestamp
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// The example request from Slack's "Verifying requests from Slack" guide
+const (
+	exampleSecret    = "8f742231b10e8888abcd99yyyzzz85a5"
+	exampleTimestamp = "1531420618"
+	exampleBody      = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	exampleSignature = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+)
+
+func TestVerify(t *testing.T) {
+	sent := time.Unix(1531420618, 0)
+	headers := func(timestamp, signature string) http.Header {
+		h := http.Header{}
+		if timestamp != "" {
+			h.Set("X-Slack-Request-Timestamp", timestamp)
+		}
+		if signature != "" {
+			h.Set("X-Slack-Signature", signature)
+		}
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header http.Header
+		body   string
+		now    time.Time
+		want   error
+	}{
+		{"good", exampleSecret, headers(exampleTimestamp, exampleSignature), exampleBody, sent.Add(time.Minute), nil},
+		{"stale", exampleSecret, headers(exampleTimestamp, exampleSignature), exampleBody, sent.Add(maxSkew + time.Second), ErrStaleRequest},
+		{"from the future", exampleSecret, headers(exampleTimestamp, exampleSignature), exampleBody, sent.Add(-maxSkew - time.Second), ErrStaleRequest},
+		{"wrong secret", "another-secret", headers(exampleTimestamp, exampleSignature), exampleBody, sent, ErrBadSignature},
+		{"tampered body", exampleSecret, headers(exampleTimestamp, exampleSignature), exampleBody + "&admin=true", sent, ErrBadSignature},
+		{"replayed with a new timestamp", exampleSecret, headers("1531420700", exampleSignature), exampleBody, sent, ErrBadSignature},
+		{"no signature", exampleSecret, headers(exampleTimestamp, ""), exampleBody, sent, ErrMissingSignature},
+		{"no timestamp", exampleSecret, headers("", exampleSignature), exampleBody, sent, ErrMissingSignature},
+		{"bad timestamp", exampleSecret, headers("yesterday", exampleSignature), exampleBody, sent, ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		if err := verify(tt.secret, tt.header, []byte(tt.body), tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	if got := sign(exampleSecret, exampleTimestamp, []byte(exampleBody)); got != exampleSignature {
+		t.Errorf("sign = %s, want Slack's example %s", got, exampleSignature)
+	}
+}
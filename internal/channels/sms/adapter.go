@@ -0,0 +1,425 @@
+// Package sms lets managers on the floor ask Opus questions by text
+// message and pages them for critical alerts. Messages arrive on a
+// provider's webhook, verified with its signature, and are answered
+// through the chat pipeline shared with the web client, condensed to a
+// few SMS segments. Paged managers acknowledge by replying "ACK 42".
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/connectors"
+)
+
+// DefaultMaxLength fits two concatenated GSM-7 segments
+const DefaultMaxLength = 306
+
+const (
+	// maxBody is the largest webhook request accepted
+	maxBody = 64 << 10
+	// answerTimeout bounds how long a question may take to answer
+	answerTimeout = 2 * time.Minute
+	// sessionTTL is how long a quiet number keeps its conversation,
+	// about a shift
+	sessionTTL = 8 * time.Hour
+	// alertQueueSize is how many alert events can wait to be paged
+	alertQueueSize = 100
+)
+
+// Config configures the SMS adapter
+type Config struct {
+	Provider Provider
+	// Users maps phone numbers to Opus user IDs; other numbers can't ask
+	// questions
+	Users map[string]string
+	// OnCall maps departments to the Opus users paged for their critical
+	// alerts. The "*" user is paged for every department's.
+	OnCall map[string]string
+	// MaxLength is the longest message sent; the rest of a longer answer
+	// is sent on MORE. Defaults to DefaultMaxLength.
+	MaxLength int
+}
+
+// Adapter answers questions by text message and pages on-call managers.
+// It is a connector so the registry checks the provider's credentials
+// and reports its health.
+type Adapter struct {
+	config   Config
+	provider Provider
+	chat     *chat.Service
+	users    auth.Directory
+	alerts   *alerts.Engine
+	now      func() time.Time
+
+	// numbers maps Opus user IDs back to their phone numbers
+	numbers map[string]string
+
+	alertEvents chan alerts.Event
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	// pages are the numbers paged for each open alert, and lastPage the
+	// alert last paged to each number, which a bare "ACK" acknowledges
+	pages     map[string][]string
+	lastPage  map[string]string
+	lastError error
+	answered  int
+	paged     int
+}
+
+// session is a phone number's conversation with Opus
+type session struct {
+	conversationID string
+	// pending is a question that was too ambiguous to route, asked again
+	// once the user names one of candidates
+	pending    string
+	candidates []ai.Department
+	// more is the rest of the last answer, sent on MORE
+	more     string
+	lastUsed time.Time
+}
+
+// New creates an SMS adapter answering through chatService as the Opus
+// users looked up in users, and acknowledging alerts in alertEngine
+func New(cfg Config, chatService *chat.Service, users auth.Directory, alertEngine *alerts.Engine) *Adapter {
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultMaxLength
+	}
+
+	phones := make(map[string]string, len(cfg.Users))
+	numbers := make(map[string]string, len(cfg.Users))
+	for number, id := range cfg.Users {
+		number = normalize(number)
+		phones[number] = id
+		numbers[id] = number
+	}
+	cfg.Users = phones
+
+	return &Adapter{
+		config:      cfg,
+		provider:    cfg.Provider,
+		chat:        chatService,
+		users:       users,
+		alerts:      alertEngine,
+		now:         time.Now,
+		numbers:     numbers,
+		alertEvents: make(chan alerts.Event, alertQueueSize),
+		sessions:    make(map[string]*session),
+		pages:       make(map[string][]string),
+		lastPage:    make(map[string]string),
+	}
+}
+
+func (a *Adapter) Name() string { return "sms" }
+
+// Connect checks the provider's credentials and starts paging alerts
+func (a *Adapter) Connect(ctx context.Context) error {
+	err := a.provider.Check(ctx)
+	a.track(err)
+	if err != nil {
+		return err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+	go a.pageAlerts(loopCtx)
+	return nil
+}
+
+func (a *Adapter) Disconnect() error {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+		a.cancel = nil
+	}
+	return nil
+}
+
+// Health is unhealthy while the last provider call failed
+func (a *Adapter) Health() connectors.HealthStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: a.lastError == nil,
+		Details: map[string]interface{}{
+			"provider": a.provider.Name(),
+			"answered": a.answered,
+			"paged":    a.paged,
+			"sessions": len(a.sessions),
+		},
+	}
+	if a.lastError != nil {
+		status.Message = a.lastError.Error()
+	}
+	return status
+}
+
+// track records the outcome of a provider call for Health
+func (a *Adapter) track(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastError = err
+}
+
+// HandleMessage receives the provider's incoming message webhook. The
+// reply is sent in the background, since answering can take longer than
+// the provider waits for a response.
+func (a *Adapter) HandleMessage(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxBody)
+	msg, err := a.provider.Receive(req)
+	if errors.Is(err, ErrInvalidSignature) {
+		log.Printf("Rejected SMS webhook from %s: %v", req.RemoteAddr, err)
+		http.Error(w, `{"error": "Invalid signature"}`, http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Rejected SMS webhook from %s: %v", req.RemoteAddr, err)
+		http.Error(w, `{"error": "Invalid message"}`, http.StatusBadRequest)
+		return
+	}
+	a.provider.Respond(w)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), answerTimeout)
+		defer cancel()
+		a.handle(ctx, msg)
+	}()
+}
+
+const (
+	help      = "Text Opus a question about your store, e.g. \"how much 2% milk is left?\". Reply MORE for the rest of a long answer, or ACK 42 to acknowledge alert 42."
+	notLinked = "This number isn't registered with Opus. Ask your store admin to add it."
+)
+
+func (a *Adapter) handle(ctx context.Context, msg *Message) {
+	from := normalize(msg.From)
+	c, isCommand := parseCommand(msg.Body)
+	switch {
+	case isCommand && (c.name == "STOP" || c.name == "START" || c.name == "UNSTOP"):
+		// Carriers handle opt-outs and don't allow replies to them
+		return
+	case isCommand && c.name == "HELP":
+		a.send(ctx, from, help)
+		return
+	}
+
+	user, err := a.user(ctx, from)
+	if err != nil {
+		a.send(ctx, from, notLinked)
+		return
+	}
+
+	switch {
+	case isCommand && c.name == "MORE":
+		a.more(ctx, from)
+	case isCommand && c.name == "ACK":
+		a.acknowledge(ctx, from, user, c.arg)
+	default:
+		a.ask(ctx, from, user, strings.TrimSpace(msg.Body))
+	}
+}
+
+// user returns the Opus user a phone number is registered to
+func (a *Adapter) user(ctx context.Context, number string) (*auth.User, error) {
+	id, ok := a.config.Users[number]
+	if !ok || a.users == nil {
+		return nil, auth.ErrUnknownUser
+	}
+	return a.users.User(ctx, id)
+}
+
+// ask answers a question as the number's Opus user, within their
+// department scope, continuing the number's conversation
+func (a *Adapter) ask(ctx context.Context, from string, user *auth.User, text string) {
+	if text == "" {
+		a.send(ctx, from, help)
+		return
+	}
+
+	s, err := a.session(ctx, from, user)
+	if err != nil {
+		log.Printf("SMS: failed to start conversation for %s: %v", user.ID, err)
+		a.send(ctx, from, "Sorry, I couldn't start a conversation. Please try again.")
+		return
+	}
+	req := chat.Request{Message: text, UserID: user.ID, Scope: ai.Department(user.Scope()), ConversationID: s.conversationID}
+	a.resume(s, &req)
+
+	result, err := a.chat.Process(ctx, req, nil)
+	if err != nil {
+		log.Printf("SMS: failed to answer %s: %v", user.ID, err)
+		a.send(ctx, from, failure(err))
+		return
+	}
+
+	text = plain(result.Response)
+	if result.Ambiguous && len(result.Candidates) > 0 {
+		names := make([]string, len(result.Candidates))
+		for i, d := range result.Candidates {
+			names[i] = string(d)
+		}
+		text += "\nReply " + strings.Join(names, ", ") + "."
+	}
+	part, rest := split(text, a.config.MaxLength)
+
+	a.mu.Lock()
+	s.pending, s.candidates = "", nil
+	if result.Ambiguous {
+		s.pending, s.candidates = req.Message, result.Candidates
+	}
+	s.more = rest
+	a.answered++
+	a.mu.Unlock()
+
+	a.send(ctx, from, part)
+}
+
+// resume turns a reply naming one of the departments Opus asked about
+// into the pending question for that department
+func (a *Adapter) resume(s *session, req *chat.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s.pending == "" {
+		return
+	}
+	reply := strings.ToLower(strings.Trim(req.Message, " .!?"))
+	for _, d := range s.candidates {
+		if reply == string(d) || reply == "the "+string(d) {
+			req.Message, req.Department = s.pending, d
+			return
+		}
+	}
+}
+
+// more sends the next part of the number's last answer
+func (a *Adapter) more(ctx context.Context, from string) {
+	a.mu.Lock()
+	var part string
+	if s, ok := a.sessions[from]; ok && s.more != "" {
+		part, s.more = split(s.more, a.config.MaxLength)
+		s.lastUsed = a.now()
+	}
+	a.mu.Unlock()
+
+	if part == "" {
+		part = "That's the whole answer. Text another question any time."
+	}
+	a.send(ctx, from, part)
+}
+
+// acknowledge acknowledges an alert as user, or the alert last paged to
+// the number when id is empty
+func (a *Adapter) acknowledge(ctx context.Context, from string, user *auth.User, id string) {
+	if id == "" {
+		a.mu.Lock()
+		id = a.lastPage[from]
+		a.mu.Unlock()
+		if id == "" {
+			a.send(ctx, from, "Reply ACK and the alert number, e.g. ACK 42.")
+			return
+		}
+	}
+
+	alert, err := a.alerts.Get(ctx, id)
+	if errors.Is(err, alerts.ErrNotFound) {
+		a.send(ctx, from, "Alert "+id+" doesn't exist.")
+		return
+	}
+	if err != nil {
+		log.Printf("SMS: failed to load alert %s: %v", id, err)
+		a.send(ctx, from, "Sorry, I couldn't acknowledge that alert. Please try again.")
+		return
+	}
+	if !user.Role.AtLeast(auth.RoleAssistantManager) {
+		a.send(ctx, from, "Only managers can acknowledge alerts.")
+		return
+	}
+	if scope := user.Scope(); scope != "" && scope != alert.Department {
+		a.send(ctx, from, "You can only acknowledge your own department's alerts.")
+		return
+	}
+
+	alert, err = a.alerts.Acknowledge(ctx, id, user.ID)
+	switch {
+	case errors.Is(err, alerts.ErrInvalidTransition):
+		a.send(ctx, from, "Alert "+id+" is already resolved.")
+	case err != nil:
+		log.Printf("SMS: failed to acknowledge alert %s: %v", id, err)
+		a.send(ctx, from, "Sorry, I couldn't acknowledge that alert. Please try again.")
+	case alert.AcknowledgedBy != user.ID:
+		a.send(ctx, from, fmt.Sprintf("Alert %s was already acknowledged by %s.", id, alert.AcknowledgedBy))
+	default:
+		a.send(ctx, from, gsm.Replace(fmt.Sprintf("Acknowledged alert %s: %s.", id, alert.Title)))
+	}
+}
+
+// failure is the reply for a question that couldn't be answered
+func failure(err error) string {
+	switch {
+	case errors.Is(err, chat.ErrOutOfScope):
+		return "You can only ask about your own department."
+	case errors.Is(err, chat.ErrUnknownDepartment):
+		return "I don't know that department."
+	default:
+		return "Sorry, I couldn't answer that right now. Please try again in a minute."
+	}
+}
+
+// send texts a number, logging failures since there's no one else to
+// tell
+func (a *Adapter) send(ctx context.Context, to, body string) bool {
+	_, err := a.provider.Send(ctx, to, body)
+	a.track(err)
+	if err != nil {
+		log.Printf("SMS: failed to send to %s: %v", to, err)
+		return false
+	}
+	return true
+}
+
+// session returns the number's session, starting a conversation for user
+// if it has none. Sessions quiet for sessionTTL are forgotten.
+func (a *Adapter) session(ctx context.Context, number string, user *auth.User) (*session, error) {
+	now := a.now()
+	a.mu.Lock()
+	if s, ok := a.sessions[number]; ok {
+		s.lastUsed = now
+		a.mu.Unlock()
+		return s, nil
+	}
+	a.mu.Unlock()
+
+	conv, err := a.chat.Conversations().Create(ctx, user.ID, ai.Department(user.Scope()))
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, s := range a.sessions {
+		if now.Sub(s.lastUsed) > sessionTTL {
+			delete(a.sessions, key)
+		}
+	}
+	if s, ok := a.sessions[number]; ok {
+		return s, nil
+	}
+	s := &session{conversationID: conv.ID, lastUsed: now}
+	a.sessions[number] = s
+	return s, nil
+}
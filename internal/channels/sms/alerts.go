@@ -0,0 +1,139 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/notify"
+)
+
+// PublishAlert queues an alert event to be paged. Pass it to
+// alerts.Engine.Subscribe.
+func (a *Adapter) PublishAlert(event alerts.Event) {
+	select {
+	case a.alertEvents <- event:
+	default:
+		log.Printf("SMS: alert queue is full, dropping %s for alert %s", event.Type, event.Alert.ID)
+	}
+}
+
+func (a *Adapter) pageAlerts(ctx context.Context) {
+	defer close(a.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-a.alertEvents:
+			a.pageAlert(ctx, &event.Alert)
+		}
+	}
+}
+
+// pageAlert pages the department's on-call managers when an alert becomes
+// active and critical, and again when it escalates or wakes from a
+// snooze. Once someone acknowledges it the others paged are told, so they
+// can stand down.
+func (a *Adapter) pageAlert(ctx context.Context, alert *alerts.Alert) {
+	if !alert.Status.Open() {
+		a.mu.Lock()
+		delete(a.pages, alert.ID)
+		a.mu.Unlock()
+		return
+	}
+
+	a.mu.Lock()
+	paged := a.pages[alert.ID]
+	a.mu.Unlock()
+
+	var last alerts.Change
+	if len(alert.History) > 0 {
+		last = alert.History[len(alert.History)-1]
+	}
+
+	switch {
+	case alert.Status == alerts.StatusActive && alert.Severity == alerts.SeverityCritical &&
+		(len(paged) == 0 || last.Action == alerts.ActionEscalated || last.Action == alerts.ActionWoke):
+		text := pageText(alert)
+		var sent []string
+		for _, number := range a.onCall(alert.Department) {
+			if a.send(ctx, number, text) {
+				sent = append(sent, number)
+			}
+		}
+		if len(sent) == 0 {
+			return
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		for _, number := range sent {
+			if !slices.Contains(paged, number) {
+				paged = append(paged, number)
+			}
+			a.lastPage[number] = alert.ID
+		}
+		a.pages[alert.ID] = paged
+		a.paged++
+
+	case last.Action == alerts.ActionAcknowledged && len(paged) > 0:
+		text := gsm.Replace("Alert " + alert.ID + " acknowledged by " + last.By + ": " + alert.Title + ".")
+		for _, number := range paged {
+			// The acknowledger got a reply to their ACK
+			if number != a.numbers[last.By] {
+				a.send(ctx, number, text)
+			}
+		}
+	}
+}
+
+// Notify texts user an alert routed to them, returning
+// notify.ErrUnreachable if they have no phone number. Like a page, they
+// can reply ACK to acknowledge it and hear when someone else does.
+func (a *Adapter) Notify(ctx context.Context, user *auth.User, n *notify.Notification) error {
+	number, ok := a.numbers[user.ID]
+	if !ok {
+		return notify.ErrUnreachable
+	}
+	text := pageText(n.Alert)
+	if n.Escalated {
+		text = "ESCALATED " + text
+	}
+	_, err := a.provider.Send(ctx, number, text)
+	a.track(err)
+	if err != nil {
+		return fmt.Errorf("failed to send to %s: %w", number, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !slices.Contains(a.pages[n.Alert.ID], number) {
+		a.pages[n.Alert.ID] = append(a.pages[n.Alert.ID], number)
+	}
+	a.lastPage[number] = n.Alert.ID
+	a.paged++
+	return nil
+}
+
+// onCall returns the numbers paged for a department's critical alerts
+func (a *Adapter) onCall(dept string) []string {
+	var numbers []string
+	for _, key := range []string{dept, "*"} {
+		id := a.config.OnCall[key]
+		if id == "" {
+			continue
+		}
+		number, ok := a.numbers[id]
+		if !ok {
+			log.Printf("SMS: on-call user %s for %s has no phone number", id, key)
+			continue
+		}
+		if !slices.Contains(numbers, number) {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
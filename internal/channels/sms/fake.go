@@ -0,0 +1,210 @@
+package sms
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeMessage is a text message sent through a FakeServer, by Opus or by
+// a simulated phone
+type FakeMessage struct {
+	SID      string    `json:"sid"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Body     string    `json:"body"`
+	Segments int       `json:"segments"`
+	SentAt   time.Time `json:"sentAt"`
+}
+
+// FakeServer stands in for Twilio for demos and local testing. It serves
+// the account and Messages API resources Opus uses, records what's sent,
+// and plays phones: POST /send signs a messaging webhook and delivers it
+// to Opus.
+//
+//	POST /send      {"from": "+15551230001", "body": "how much milk is left?"}
+//	GET  /messages  ?number=+15551230001
+type FakeServer struct {
+	AccountSID string
+	AuthToken  string
+	// Number is the number Opus sends from and phones text
+	Number string
+	// WebhookURL is where incoming messages are delivered, e.g.
+	// http://localhost:8080/sms/messages
+	WebhookURL string
+
+	mu       sync.Mutex
+	messages []FakeMessage
+	http     *http.Client
+}
+
+// NewFakeServer creates a fake Twilio account
+func NewFakeServer(accountSID, authToken, number, webhookURL string) *FakeServer {
+	return &FakeServer{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		Number:     number,
+		WebhookURL: webhookURL,
+		http:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Messages returns the messages to or from number, or all of them if
+// number is empty
+func (f *FakeServer) Messages(number string) []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	number = normalize(number)
+	messages := []FakeMessage{}
+	for _, m := range f.messages {
+		if number == "" || m.From == number || m.To == number {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	account := "/2010-04-01/Accounts/" + f.AccountSID
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/messages":
+		// "+" in a query string is a space
+		writeFakeJSON(w, http.StatusOK, f.Messages(strings.ReplaceAll(r.URL.Query().Get("number"), " ", "+")))
+	case r.Method == http.MethodPost && r.URL.Path == "/send":
+		f.send(w, r)
+	case strings.HasPrefix(r.URL.Path, "/2010-04-01/Accounts/"):
+		if sid, token, ok := r.BasicAuth(); !ok || sid != f.AccountSID || token != f.AuthToken {
+			writeFakeJSON(w, http.StatusUnauthorized, TwilioError{Status: 401, Code: 20003, Message: "Authenticate"})
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == account+".json":
+			writeFakeJSON(w, http.StatusOK, map[string]string{"sid": f.AccountSID, "status": "active"})
+		case r.Method == http.MethodPost && r.URL.Path == account+"/Messages.json":
+			f.create(w, r)
+		default:
+			writeFakeJSON(w, http.StatusNotFound, TwilioError{Status: 404, Code: 20404, Message: "The requested resource was not found"})
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// create sends a message from the account's number
+func (f *FakeServer) create(w http.ResponseWriter, r *http.Request) {
+	to, from, body := normalize(r.FormValue("To")), r.FormValue("From"), r.FormValue("Body")
+	switch {
+	case to == "":
+		writeFakeJSON(w, http.StatusBadRequest, TwilioError{Status: 400, Code: 21604, Message: "A 'To' phone number is required."})
+		return
+	case from != f.Number:
+		writeFakeJSON(w, http.StatusBadRequest, TwilioError{Status: 400, Code: 21606, Message: "The 'From' phone number provided is not a valid message-capable phone number for this account."})
+		return
+	case body == "":
+		writeFakeJSON(w, http.StatusBadRequest, TwilioError{Status: 400, Code: 21602, Message: "Message body is required."})
+		return
+	case len([]rune(body)) > 1600:
+		writeFakeJSON(w, http.StatusBadRequest, TwilioError{Status: 400, Code: 21617, Message: "The concatenated message body exceeds the 1600 character limit."})
+		return
+	}
+
+	m := f.record(from, to, body)
+	writeFakeJSON(w, http.StatusCreated, map[string]interface{}{
+		"sid":          m.SID,
+		"from":         m.From,
+		"to":           m.To,
+		"body":         m.Body,
+		"status":       "queued",
+		"num_segments": fmt.Sprint(m.Segments),
+	})
+}
+
+// send plays a phone texting the account's number, and returns Opus's
+// response to the webhook
+func (f *FakeServer) send(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		From string `json:"from"`
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.From == "" {
+		http.Error(w, `{"error": "from is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	m := f.record(normalize(in.From), f.Number, in.Body)
+	params := url.Values{
+		"MessageSid":  {m.SID},
+		"AccountSid":  {f.AccountSID},
+		"From":        {m.From},
+		"To":          {m.To},
+		"Body":        {m.Body},
+		"NumMedia":    {"0"},
+		"NumSegments": {fmt.Sprint(m.Segments)},
+	}
+	req, err := http.NewRequest(http.MethodPost, f.WebhookURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", base64.StdEncoding.EncodeToString(twilioSignature(f.AuthToken, f.WebhookURL, params)))
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"sid": m.SID, "status": resp.StatusCode, "response": string(body)})
+}
+
+// record stores a message with a new SID
+func (f *FakeServer) record(from, to, body string) FakeMessage {
+	b := make([]byte, 16)
+	rand.Read(b)
+	m := FakeMessage{
+		SID:      "SM" + hex.EncodeToString(b),
+		From:     from,
+		To:       to,
+		Body:     body,
+		Segments: segments(body),
+		SentAt:   time.Now(),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, m)
+	return m
+}
+
+// segments estimates how many SMS segments a message is billed as
+func segments(body string) int {
+	n, per, single := len([]rune(body)), 153, 160
+	for _, r := range body {
+		if r > 0x7f {
+			// Close enough: anything outside ASCII is treated as UCS-2
+			per, single = 67, 70
+			break
+		}
+	}
+	if n <= single {
+		return 1
+	}
+	return (n + per - 1) / per
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
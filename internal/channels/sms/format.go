@@ -0,0 +1,137 @@
+package sms
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+)
+
+// moreHint ends a message whose text continues in the next part
+const moreHint = " (MORE)"
+
+var (
+	fencePattern    = regexp.MustCompile("(?s)```[a-z]*\n?(.*?)```")
+	boldPattern     = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicPattern   = regexp.MustCompile(`\*([^*\s][^*\n]*?)\*|\b_([^_\n]+?)_\b`)
+	codePattern     = regexp.MustCompile("`([^`\n]+)`")
+	headingPattern  = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	bulletPattern   = regexp.MustCompile(`(?m)^\s*[*+•-]\s+`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	citationPattern = regexp.MustCompile(`\s*\[\d+(?:,\s*\d+)*\]`)
+	blankPattern    = regexp.MustCompile(`\n\s*\n+`)
+	spacePattern    = regexp.MustCompile(`[ \t]+`)
+)
+
+// gsm replaces typographic characters outside the GSM alphabet, any of
+// which would make the carrier send the whole message as UCS-2 in
+// 70-character segments
+var gsm = strings.NewReplacer(
+	"‘", "'", "’", "'", "“", `"`, "”", `"`,
+	"–", "-", "—", "-", "…", "...", "•", "-",
+	"°", "", "×", "x",
+)
+
+// plain turns an agent's Markdown answer into plain text: no emphasis,
+// headings or citation markers, links as their text, and blank lines
+// collapsed
+func plain(text string) string {
+	text = fencePattern.ReplaceAllString(text, "$1")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = bulletPattern.ReplaceAllString(text, "- ")
+	text = boldPattern.ReplaceAllString(text, "$1$2")
+	text = italicPattern.ReplaceAllString(text, "$1$2")
+	text = codePattern.ReplaceAllString(text, "$1")
+	text = headingPattern.ReplaceAllString(text, "")
+	text = citationPattern.ReplaceAllString(text, "")
+	text = gsm.Replace(text)
+	text = spacePattern.ReplaceAllString(text, " ")
+	text = blankPattern.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
+
+// split cuts text into a part of at most max characters and the rest.
+// It breaks after a sentence or line if one ends in the second half of
+// the part, otherwise between words, and marks the part with moreHint.
+func split(text string, max int) (string, string) {
+	if utf8.RuneCountInString(text) <= max {
+		return text, ""
+	}
+
+	runes := []rune(text)
+	limit := max - utf8.RuneCountInString(moreHint)
+	cut := -1
+	for i := limit - 1; i > limit/2; i-- {
+		if runes[i] == '\n' || (strings.ContainsRune(".!?", runes[i]) && i+1 < len(runes) && runes[i+1] == ' ') {
+			cut = i + 1
+			break
+		}
+	}
+	if cut < 0 {
+		for i := limit; i > limit/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+	}
+	if cut < 0 {
+		cut = limit
+	}
+	return strings.TrimSpace(string(runes[:cut])) + moreHint, strings.TrimSpace(string(runes[cut:]))
+}
+
+// pageText is the page sent for an alert: to on-call managers for
+// critical ones, or to whoever an alert is routed to
+func pageText(a *alerts.Alert) string {
+	text := strings.ToUpper(string(a.Severity)) + " " + a.Department + ": " + a.Title
+	if a.Detail != "" {
+		text += ". " + a.Detail
+	}
+	return gsm.Replace(text) + ". Reply ACK " + a.ID + " to acknowledge."
+}
+
+// command is a message that isn't a question
+type command struct {
+	name string
+	arg  string
+}
+
+// parseCommand recognizes HELP, MORE, ACK with an optional alert ID
+// ("ACK 42", "ack #42"), and the opt-out keywords carriers handle
+// themselves
+func parseCommand(body string) (command, bool) {
+	fields := strings.Fields(strings.ToUpper(strings.TrimSpace(body)))
+	if len(fields) == 0 || len(fields) > 2 {
+		return command{}, false
+	}
+	switch fields[0] {
+	case "HELP", "MORE", "STOP", "START", "UNSTOP":
+		if len(fields) == 1 {
+			return command{name: fields[0]}, true
+		}
+	case "ACK":
+		c := command{name: "ACK"}
+		if len(fields) == 2 {
+			c.arg = strings.TrimPrefix(fields[1], "#")
+		}
+		return c, true
+	}
+	return command{}, false
+}
+
+// normalize reduces a phone number to E.164-like digits with a leading
+// +, so numbers can be written with spaces, dashes or parentheses
+func normalize(number string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(number) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
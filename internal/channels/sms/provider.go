@@ -0,0 +1,37 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidSignature is returned for webhook requests that weren't
+// signed by the provider
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Message is a text message received from a phone
+type Message struct {
+	// ID is the provider's ID for the message
+	ID   string
+	From string
+	To   string
+	Body string
+}
+
+// Provider sends and receives text messages through an SMS gateway, so
+// the adapter works the same whichever carries the messages
+type Provider interface {
+	// Name identifies the provider in logs and health details
+	Name() string
+	// Check verifies the provider's credentials
+	Check(ctx context.Context) error
+	// Send delivers a text message and returns the provider's ID for it
+	Send(ctx context.Context, to, body string) (string, error)
+	// Receive authenticates an incoming message webhook and parses it,
+	// returning ErrInvalidSignature if it wasn't sent by the provider
+	Receive(req *http.Request) (*Message, error)
+	// Respond answers a received webhook without replying to the sender;
+	// replies are sent with Send
+	Respond(w http.ResponseWriter)
+}
@@ -0,0 +1,180 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTwilioURL is Twilio's REST API
+const DefaultTwilioURL = "https://api.twilio.com"
+
+// Twilio sends messages through Twilio's Messages API and receives them
+// on its messaging webhook. Any gateway with a Twilio-compatible API can
+// be used by changing APIURL.
+type Twilio struct {
+	AccountSID string
+	AuthToken  string
+	// From is the number messages are sent from, in E.164 form
+	From string
+	// APIURL defaults to DefaultTwilioURL
+	APIURL string
+	// WebhookURL is the public URL Twilio posts messages to, which
+	// requests are signed with. If empty it's worked out from the
+	// request, which is wrong behind a proxy that rewrites it.
+	WebhookURL string
+	HTTPClient *http.Client
+}
+
+// TwilioError is an error response from the Twilio API
+type TwilioError struct {
+	Status  int    `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *TwilioError) Error() string {
+	return fmt.Sprintf("twilio error %d: %s", e.Code, e.Message)
+}
+
+func (t *Twilio) Name() string { return "twilio" }
+
+// Check fetches the account, which fails if the credentials are wrong or
+// the account is suspended
+func (t *Twilio) Check(ctx context.Context) error {
+	var account struct {
+		Status string `json:"status"`
+	}
+	if err := t.call(ctx, http.MethodGet, ".json", nil, &account); err != nil {
+		return err
+	}
+	if account.Status != "" && account.Status != "active" {
+		return fmt.Errorf("twilio account is %s", account.Status)
+	}
+	return nil
+}
+
+func (t *Twilio) Send(ctx context.Context, to, body string) (string, error) {
+	form := url.Values{"To": {to}, "From": {t.From}, "Body": {body}}
+	var message struct {
+		SID string `json:"sid"`
+	}
+	if err := t.call(ctx, http.MethodPost, "/Messages.json", form, &message); err != nil {
+		return "", err
+	}
+	return message.SID, nil
+}
+
+func (t *Twilio) Receive(req *http.Request) (*Message, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	if !t.signed(req) {
+		return nil, ErrInvalidSignature
+	}
+	if req.PostForm.Get("AccountSid") != t.AccountSID {
+		return nil, fmt.Errorf("message is for account %q", req.PostForm.Get("AccountSid"))
+	}
+	return &Message{
+		ID:   req.PostForm.Get("MessageSid"),
+		From: req.PostForm.Get("From"),
+		To:   req.PostForm.Get("To"),
+		Body: req.PostForm.Get("Body"),
+	}, nil
+}
+
+// Respond returns empty TwiML, so Twilio sends nothing back itself
+func (t *Twilio) Respond(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/xml")
+	io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`)
+}
+
+// signed checks the X-Twilio-Signature header, an HMAC-SHA1 of the
+// webhook URL followed by the POST parameters sorted by name
+func (t *Twilio) signed(req *http.Request) bool {
+	signature, err := base64.StdEncoding.DecodeString(req.Header.Get("X-Twilio-Signature"))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+	webhookURL := t.WebhookURL
+	if webhookURL == "" {
+		scheme := "http"
+		if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		webhookURL = scheme + "://" + req.Host + req.URL.RequestURI()
+	}
+	return hmac.Equal(signature, twilioSignature(t.AuthToken, webhookURL, req.PostForm))
+}
+
+// twilioSignature signs a webhook URL and its POST parameters
+func twilioSignature(authToken, webhookURL string, params url.Values) []byte {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	io.WriteString(mac, webhookURL)
+	for _, name := range names {
+		for _, value := range params[name] {
+			io.WriteString(mac, name+value)
+		}
+	}
+	return mac.Sum(nil)
+}
+
+// call makes a request to the account's API resources and decodes the
+// response into out
+func (t *Twilio) call(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	apiURL := t.APIURL
+	if apiURL == "" {
+		apiURL = DefaultTwilioURL
+	}
+	endpoint := strings.TrimRight(apiURL, "/") + "/2010-04-01/Accounts/" + url.PathEscape(t.AccountSID) + path
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	client := t.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &TwilioError{Status: resp.StatusCode}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
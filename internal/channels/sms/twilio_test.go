@@ -0,0 +1,74 @@
+package sms
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The example request from Twilio's webhook security guide
+const (
+	exampleAuthToken  = "12345"
+	exampleURL        = "https://mycompany.com/myapp.php?foo=1&bar=2"
+	exampleSignature  = "0/KCTR6DLpKmkAf8muzZqo1nDgQ="
+	exampleParameters = "CallSid=CA1234567890ABCDE&Caller=%2B12349013030&Digits=1234&From=%2B12349013030&To=%2B18005551212"
+)
+
+func exampleRequest(t *testing.T, target, body, signature string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if signature != "" {
+		req.Header.Set("X-Twilio-Signature", signature)
+	}
+	if err := req.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestTwilioSigned(t *testing.T) {
+	configured := &Twilio{AuthToken: exampleAuthToken, WebhookURL: exampleURL}
+	tests := []struct {
+		name   string
+		twilio *Twilio
+		req    *http.Request
+		want   bool
+	}{
+		{"good", configured, exampleRequest(t, "/sms/webhook", exampleParameters, exampleSignature), true},
+		{"wrong auth token", &Twilio{AuthToken: "54321", WebhookURL: exampleURL}, exampleRequest(t, "/sms/webhook", exampleParameters, exampleSignature), false},
+		{"tampered parameter", configured, exampleRequest(t, "/sms/webhook", strings.Replace(exampleParameters, "Digits=1234", "Digits=9999", 1), exampleSignature), false},
+		{"added parameter", configured, exampleRequest(t, "/sms/webhook", exampleParameters+"&Body=hi", exampleSignature), false},
+		{"signed for another URL", &Twilio{AuthToken: exampleAuthToken, WebhookURL: "https://mycompany.com/other.php"}, exampleRequest(t, "/sms/webhook", exampleParameters, exampleSignature), false},
+		{"no signature", configured, exampleRequest(t, "/sms/webhook", exampleParameters, ""), false},
+		{"signature isn't base64", configured, exampleRequest(t, "/sms/webhook", exampleParameters, "not base64!"), false},
+		// Without WebhookURL the URL the request arrived at is signed,
+		// here over plain HTTP rather than the https URL Twilio signed
+		{"URL from the request", &Twilio{AuthToken: exampleAuthToken}, exampleRequest(t, "http://mycompany.com/myapp.php?foo=1&bar=2", exampleParameters, exampleSignature), false},
+	}
+	for _, tt := range tests {
+		if got := tt.twilio.signed(tt.req); got != tt.want {
+			t.Errorf("%s: signed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Behind a TLS-terminating proxy the scheme comes from X-Forwarded-Proto
+	req := exampleRequest(t, "http://mycompany.com/myapp.php?foo=1&bar=2", exampleParameters, exampleSignature)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if !(&Twilio{AuthToken: exampleAuthToken}).signed(req) {
+		t.Error("request forwarded over HTTPS isn't signed for its https URL")
+	}
+}
+
+func TestTwilioSignature(t *testing.T) {
+	params, err := url.ParseQuery(exampleParameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.StdEncoding.EncodeToString(twilioSignature(exampleAuthToken, exampleURL, params)); got != exampleSignature {
+		t.Errorf("twilioSignature = %s, want Twilio's example %s", got, exampleSignature)
+	}
+}
@@ -0,0 +1,112 @@
+package teams
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Activity types the adapter handles
+const (
+	ActivityMessage            = "message"
+	ActivityConversationUpdate = "conversationUpdate"
+	ActivityTyping             = "typing"
+)
+
+// Activity is a Bot Framework activity: a message, a typing indicator, a
+// change to a conversation's members and so on
+type Activity struct {
+	Type         string           `json:"type"`
+	ID           string           `json:"id,omitempty"`
+	Timestamp    string           `json:"timestamp,omitempty"`
+	ServiceURL   string           `json:"serviceUrl,omitempty"`
+	ChannelID    string           `json:"channelId,omitempty"`
+	From         *ChannelAccount  `json:"from,omitempty"`
+	Recipient    *ChannelAccount  `json:"recipient,omitempty"`
+	Conversation *ConversationRef `json:"conversation,omitempty"`
+	ReplyToID    string           `json:"replyToId,omitempty"`
+	Text         string           `json:"text,omitempty"`
+	Summary      string           `json:"summary,omitempty"`
+	TextFormat   string           `json:"textFormat,omitempty"`
+	Attachments  []Attachment     `json:"attachments,omitempty"`
+	MembersAdded []ChannelAccount `json:"membersAdded,omitempty"`
+	ChannelData  *ChannelData     `json:"channelData,omitempty"`
+	Value        json.RawMessage  `json:"value,omitempty"`
+}
+
+// ChannelAccount is a user or bot. AADObjectID is the user's Azure AD
+// object ID, which doesn't change when they're renamed.
+type ChannelAccount struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	AADObjectID string `json:"aadObjectId,omitempty"`
+}
+
+// ConversationRef identifies a personal chat, group chat or channel
+// thread
+type ConversationRef struct {
+	ID               string `json:"id"`
+	ConversationType string `json:"conversationType,omitempty"`
+	TenantID         string `json:"tenantId,omitempty"`
+	IsGroup          bool   `json:"isGroup,omitempty"`
+}
+
+// ChannelData is the Teams-specific part of an activity
+type ChannelData struct {
+	Tenant  *TeamsRef `json:"tenant,omitempty"`
+	Team    *TeamsRef `json:"team,omitempty"`
+	Channel *TeamsRef `json:"channel,omitempty"`
+}
+
+// TeamsRef identifies a tenant, team or channel
+type TeamsRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Attachment is a card or file attached to a message
+type Attachment struct {
+	ContentType string      `json:"contentType"`
+	Content     interface{} `json:"content"`
+}
+
+// conversationParams creates a conversation, e.g. a new thread in a
+// channel or a personal chat with Members
+type conversationParams struct {
+	IsGroup     bool             `json:"isGroup"`
+	Members     []ChannelAccount `json:"members,omitempty"`
+	ChannelData *ChannelData     `json:"channelData,omitempty"`
+	Activity    *Activity        `json:"activity,omitempty"`
+	TenantID    string           `json:"tenantId,omitempty"`
+}
+
+// conversationResource is the conversation created by
+// conversationParams
+type conversationResource struct {
+	ID         string `json:"id"`
+	ActivityID string `json:"activityId,omitempty"`
+	ServiceURL string `json:"serviceUrl,omitempty"`
+}
+
+// submit is the value of an Action.Submit from one of Opus's cards
+type submit struct {
+	// Question and Department answer an ambiguous question for the
+	// department picked
+	Question   string `json:"question,omitempty"`
+	Department string `json:"department,omitempty"`
+	// Acknowledge is the ID of an alert to acknowledge
+	Acknowledge string `json:"acknowledge,omitempty"`
+}
+
+var (
+	mentionPattern = regexp.MustCompile(`<at>[^<]*</at>`)
+	tagPattern     = regexp.MustCompile(`<[^>]+>`)
+)
+
+// question returns a message's text without mentions of the bot or HTML
+func question(text string) string {
+	text = mentionPattern.ReplaceAllString(text, "")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = strings.NewReplacer("&nbsp;", " ", "&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(text)
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,446 @@
+// Package teams is a Microsoft Teams bot for Opus. It implements the Bot
+// Framework activity protocol: activities from the Bot Connector are
+// checked against its signing keys, questions are answered through the
+// chat pipeline shared with the web client, structured answers are
+// rendered as adaptive cards, and alerts are posted proactively to
+// department channels.
+package teams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/connectors"
+)
+
+// DefaultServiceURL is the Bot Connector service for Teams, used for
+// proactive alerts until an activity names the tenant's own
+const DefaultServiceURL = "https://smba.trafficmanager.net/teams/"
+
+const (
+	// maxBody is the largest activity accepted
+	maxBody = 1 << 20
+	// answerTimeout bounds how long a question may take to answer
+	answerTimeout = 2 * time.Minute
+	// threadTTL is how long a quiet Teams conversation keeps its Opus
+	// conversation
+	threadTTL = 24 * time.Hour
+	// alertQueueSize is how many alert events can wait to be posted
+	alertQueueSize = 100
+	// minAlertSeverity is the least severe alert posted to channels
+	minAlertSeverity = alerts.SeverityWarning
+)
+
+// Config configures the Teams adapter
+type Config struct {
+	// AppID and AppPassword are the bot's Microsoft app registration
+	AppID       string
+	AppPassword string
+	// OpenIDURL and TokenURL default to the Bot Framework's
+	OpenIDURL string
+	TokenURL  string
+	// ServiceURL and TenantID are where proactive alerts are sent until
+	// an activity arrives from the tenant
+	ServiceURL string
+	TenantID   string
+	// Users maps Azure AD object IDs (or Teams user IDs) to Opus user
+	// IDs; unmapped users can't ask questions
+	Users map[string]string
+	// AlertChannels maps departments to the Teams channel IDs their alerts
+	// are posted to. Alerts from every department go to the "*" channel.
+	AlertChannels map[string]string
+	HTTPClient    *http.Client
+}
+
+// Adapter answers questions from Teams and posts alerts to it. It is a
+// connector so the registry checks its credentials and reports its
+// health.
+type Adapter struct {
+	config   Config
+	verifier *verifier
+	client   *client
+	chat     *chat.Service
+	users    auth.Directory
+	alerts   *alerts.Engine
+
+	alertEvents chan alerts.Event
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	mu         sync.Mutex
+	serviceURL string
+	tenantID   string
+	threads    map[string]*thread
+	posts      map[string][]post
+	lastError  error
+	answered   int
+	posted     int
+}
+
+// thread is a Teams conversation with Opus: a personal chat, group chat
+// or channel thread
+type thread struct {
+	conversationID string
+	lastUsed       time.Time
+}
+
+// post is a channel thread started for an alert, which later changes are
+// replied in
+type post struct {
+	serviceURL     string
+	conversationID string
+}
+
+// New creates a Teams adapter answering through chatService as the Opus
+// users looked up in users, and acknowledging alerts in alertEngine
+func New(cfg Config, chatService *chat.Service, users auth.Directory, alertEngine *alerts.Engine) *Adapter {
+	if cfg.OpenIDURL == "" {
+		cfg.OpenIDURL = DefaultOpenIDURL
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = DefaultTokenURL
+	}
+	if cfg.ServiceURL == "" {
+		cfg.ServiceURL = DefaultServiceURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Adapter{
+		config: cfg,
+		verifier: &verifier{
+			openIDURL: cfg.OpenIDURL,
+			appID:     cfg.AppID,
+			http:      cfg.HTTPClient,
+		},
+		client: &client{
+			tokens: &tokenSource{
+				url:      cfg.TokenURL,
+				appID:    cfg.AppID,
+				password: cfg.AppPassword,
+				http:     cfg.HTTPClient,
+			},
+			http: cfg.HTTPClient,
+		},
+		chat:        chatService,
+		users:       users,
+		alerts:      alertEngine,
+		alertEvents: make(chan alerts.Event, alertQueueSize),
+		serviceURL:  cfg.ServiceURL,
+		tenantID:    cfg.TenantID,
+		threads:     make(map[string]*thread),
+		posts:       make(map[string][]post),
+	}
+}
+
+func (a *Adapter) Name() string { return "teams" }
+
+// Connect loads the Bot Connector's signing keys, checks the app
+// credentials and starts posting alerts
+func (a *Adapter) Connect(ctx context.Context) error {
+	if err := a.verifier.refresh(ctx, false); err != nil {
+		a.track(err)
+		return err
+	}
+	_, err := a.client.tokens.accessToken(ctx)
+	a.track(err)
+	if err != nil {
+		return err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+	go a.postAlerts(loopCtx)
+	return nil
+}
+
+func (a *Adapter) Disconnect() error {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+		a.cancel = nil
+	}
+	return nil
+}
+
+// Health is unhealthy while the last call to the Bot Connector failed
+func (a *Adapter) Health() connectors.HealthStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: a.lastError == nil,
+		Details: map[string]interface{}{
+			"serviceUrl": a.serviceURL,
+			"answered":   a.answered,
+			"alerts":     a.posted,
+			"threads":    len(a.threads),
+		},
+	}
+	if a.lastError != nil {
+		status.Message = a.lastError.Error()
+	}
+	return status
+}
+
+// track records the outcome of a Bot Connector call for Health
+func (a *Adapter) track(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastError = err
+}
+
+// HandleActivities receives activities from the Bot Connector. Messages
+// are answered in the background since the connector expects a quick
+// response.
+func (a *Adapter) HandleActivities(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBody))
+	if err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	var activity Activity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Conversation == nil || activity.From == nil {
+		http.Error(w, `{"error": "Invalid activity"}`, http.StatusBadRequest)
+		return
+	}
+	if err := a.verifier.verify(req.Context(), req.Header.Get("Authorization"), &activity); err != nil {
+		log.Printf("Rejected Teams activity from %s: %v", req.RemoteAddr, err)
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// Proactive messages go to the service URL and tenant the latest
+	// verified activity came from
+	a.mu.Lock()
+	a.serviceURL = activity.ServiceURL
+	if activity.ChannelData != nil && activity.ChannelData.Tenant != nil {
+		a.tenantID = activity.ChannelData.Tenant.ID
+	}
+	a.mu.Unlock()
+
+	switch activity.Type {
+	case ActivityMessage:
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), answerTimeout)
+			defer cancel()
+			a.handleMessage(ctx, &activity)
+		}()
+	case ActivityConversationUpdate:
+		w.WriteHeader(http.StatusAccepted)
+		if activity.Recipient != nil && addedBot(&activity) {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), answerTimeout)
+				defer cancel()
+				a.reply(ctx, &activity, text(welcome))
+			}()
+		}
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// addedBot reports whether a conversation update added the bot, e.g.
+// when it's installed in a team or a personal chat
+func addedBot(activity *Activity) bool {
+	for _, member := range activity.MembersAdded {
+		if member.ID == activity.Recipient.ID {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	welcome   = "Hi, I'm Opus. Ask me about inventory, schedules, alerts or store procedures. In a channel, mention me to ask."
+	notLinked = "Your Teams account isn't linked to an Opus user yet. Ask your store admin to add you."
+)
+
+func text(s string) *Activity {
+	return &Activity{Type: ActivityMessage, Text: s, TextFormat: "markdown"}
+}
+
+// user returns the Opus user a Teams user is mapped to, by Azure AD
+// object ID or Teams user ID
+func (a *Adapter) user(ctx context.Context, from *ChannelAccount) (*auth.User, error) {
+	id, ok := a.config.Users[from.AADObjectID]
+	if !ok || from.AADObjectID == "" {
+		id, ok = a.config.Users[from.ID]
+	}
+	if !ok || a.users == nil {
+		return nil, auth.ErrUnknownUser
+	}
+	return a.users.User(ctx, id)
+}
+
+// handleMessage answers a message, or a button pressed on one of Opus's
+// cards, as the sender's Opus user within their department scope
+func (a *Adapter) handleMessage(ctx context.Context, activity *Activity) {
+	user, err := a.user(ctx, activity.From)
+	if err != nil {
+		a.reply(ctx, activity, text(notLinked))
+		return
+	}
+
+	var pressed submit
+	if len(activity.Value) > 0 {
+		if err := json.Unmarshal(activity.Value, &pressed); err != nil {
+			return
+		}
+	}
+	if pressed.Acknowledge != "" {
+		a.acknowledge(ctx, activity, user, pressed.Acknowledge)
+		return
+	}
+
+	req := chat.Request{Message: question(activity.Text), UserID: user.ID, Scope: ai.Department(user.Scope())}
+	if pressed.Question != "" {
+		req.Message = pressed.Question
+		req.Department = ai.Department(pressed.Department)
+	}
+	if req.Message == "" {
+		a.reply(ctx, activity, text("What would you like to know? Ask about inventory, schedules, alerts or store procedures."))
+		return
+	}
+
+	a.typing(ctx, activity)
+
+	t, err := a.thread(ctx, activity.Conversation.ID, user)
+	if err != nil {
+		log.Printf("Teams: failed to start conversation for %s: %v", activity.Conversation.ID, err)
+		a.reply(ctx, activity, text("Sorry, I couldn't start a conversation. Please try again."))
+		return
+	}
+	req.ConversationID = t.conversationID
+
+	result, err := a.chat.Process(ctx, req, nil)
+	if err != nil {
+		log.Printf("Teams: failed to answer %s: %v", user.ID, err)
+		a.reply(ctx, activity, text(failure(err)))
+		return
+	}
+
+	a.mu.Lock()
+	a.answered++
+	a.mu.Unlock()
+	a.reply(ctx, activity, answer(result, req.Message))
+}
+
+// acknowledge handles the Acknowledge button on an alert card. The
+// engine's update is then replied in the alert's thread.
+func (a *Adapter) acknowledge(ctx context.Context, activity *Activity, user *auth.User, id string) {
+	alert, err := a.alerts.Get(ctx, id)
+	if errors.Is(err, alerts.ErrNotFound) {
+		a.reply(ctx, activity, text("That alert no longer exists."))
+		return
+	}
+	if err != nil {
+		log.Printf("Teams: failed to load alert %s: %v", id, err)
+		a.reply(ctx, activity, text("Sorry, I couldn't acknowledge that alert. Please try again."))
+		return
+	}
+	if !user.Role.AtLeast(auth.RoleAssistantManager) {
+		a.reply(ctx, activity, text("Only managers can acknowledge alerts."))
+		return
+	}
+	if scope := user.Scope(); scope != "" && scope != alert.Department {
+		a.reply(ctx, activity, text("You can only acknowledge your own department's alerts."))
+		return
+	}
+
+	if _, err := a.alerts.Acknowledge(ctx, id, user.ID); err != nil {
+		if errors.Is(err, alerts.ErrInvalidTransition) {
+			a.reply(ctx, activity, text("That alert is already resolved."))
+			return
+		}
+		log.Printf("Teams: failed to acknowledge alert %s: %v", id, err)
+		a.reply(ctx, activity, text("Sorry, I couldn't acknowledge that alert. Please try again."))
+	}
+}
+
+// failure is the reply for a question that couldn't be answered
+func failure(err error) string {
+	switch {
+	case errors.Is(err, chat.ErrOutOfScope):
+		return "You can only ask about your own department."
+	case errors.Is(err, chat.ErrUnknownDepartment):
+		return "I don't know that department."
+	default:
+		return "Sorry, I couldn't answer that right now. Please try again in a minute."
+	}
+}
+
+// typing shows the typing indicator while an answer is generated
+func (a *Adapter) typing(ctx context.Context, to *Activity) {
+	_, err := a.client.reply(ctx, to.ServiceURL, to.Conversation.ID, "", &Activity{
+		Type:         ActivityTyping,
+		From:         to.Recipient,
+		Recipient:    to.From,
+		Conversation: to.Conversation,
+	})
+	if err != nil {
+		log.Printf("Teams: failed to send typing indicator: %v", err)
+	}
+}
+
+// reply sends activity in reply to to, in the same conversation
+func (a *Adapter) reply(ctx context.Context, to *Activity, activity *Activity) {
+	activity.From = to.Recipient
+	activity.Recipient = to.From
+	activity.Conversation = to.Conversation
+	activity.ReplyToID = to.ID
+
+	_, err := a.client.reply(ctx, to.ServiceURL, to.Conversation.ID, to.ID, activity)
+	a.track(err)
+	if err != nil {
+		log.Printf("Teams: failed to reply in %s: %v", to.Conversation.ID, err)
+	}
+}
+
+// thread returns the user's Opus conversation in the Teams conversation
+// with id, starting one if it's new. Everyone asking in a channel thread or
+// group chat gets their own, since only its owner can continue it. Threads
+// quiet for a day are forgotten.
+func (a *Adapter) thread(ctx context.Context, id string, user *auth.User) (*thread, error) {
+	id += "/" + user.ID
+	now := time.Now()
+	a.mu.Lock()
+	if t, ok := a.threads[id]; ok {
+		t.lastUsed = now
+		a.mu.Unlock()
+		return t, nil
+	}
+	a.mu.Unlock()
+
+	conv, err := a.chat.Conversations().Create(ctx, user.ID, ai.Department(user.Scope()))
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, t := range a.threads {
+		if now.Sub(t.lastUsed) > threadTTL {
+			delete(a.threads, k)
+		}
+	}
+	if t, ok := a.threads[id]; ok {
+		return t, nil
+	}
+	t := &thread{conversationID: conv.ID, lastUsed: now}
+	a.threads[id] = t
+	return t, nil
+}
@@ -0,0 +1,150 @@
+package teams
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/notify"
+)
+
+// PublishAlert queues an alert event to be posted. Pass it to
+// alerts.Engine.Subscribe.
+func (a *Adapter) PublishAlert(event alerts.Event) {
+	select {
+	case a.alertEvents <- event:
+	default:
+		log.Printf("Teams: alert queue is full, dropping %s for alert %s", event.Type, event.Alert.ID)
+	}
+}
+
+func (a *Adapter) postAlerts(ctx context.Context) {
+	defer close(a.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-a.alertEvents:
+			a.postAlert(ctx, &event.Alert)
+		}
+	}
+}
+
+// postAlert starts a thread in its department's channels for an active
+// alert of at least minAlertSeverity, and replies there with later
+// changes
+func (a *Adapter) postAlert(ctx context.Context, alert *alerts.Alert) {
+	a.mu.Lock()
+	posts := a.posts[alert.ID]
+	serviceURL, tenantID := a.serviceURL, a.tenantID
+	a.mu.Unlock()
+
+	if len(posts) == 0 {
+		if alert.Status != alerts.StatusActive || !alert.Severity.AtLeast(minAlertSeverity) {
+			return
+		}
+		for _, channel := range a.alertChannels(alert.Department) {
+			conv, err := a.client.createConversation(ctx, serviceURL, &conversationParams{
+				IsGroup: true,
+				ChannelData: &ChannelData{
+					Channel: &TeamsRef{ID: channel},
+					Tenant:  &TeamsRef{ID: tenantID},
+				},
+				Activity: alertCard(alert),
+				TenantID: tenantID,
+			})
+			a.track(err)
+			if err != nil {
+				log.Printf("Teams: failed to post alert %s to %s: %v", alert.ID, channel, err)
+				continue
+			}
+			posts = append(posts, post{serviceURL: serviceURL, conversationID: conv.ID})
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if len(posts) > 0 {
+			a.posts[alert.ID] = posts
+			a.posted++
+		}
+		return
+	}
+
+	if text := changeText(alert); text != "" {
+		for _, p := range posts {
+			_, err := a.client.reply(ctx, p.serviceURL, p.conversationID, "", &Activity{
+				Type:         ActivityMessage,
+				Text:         text,
+				TextFormat:   "markdown",
+				Conversation: &ConversationRef{ID: p.conversationID},
+			})
+			a.track(err)
+			if err != nil {
+				log.Printf("Teams: failed to update alert %s in %s: %v", alert.ID, p.conversationID, err)
+			}
+		}
+	}
+
+	if !alert.Status.Open() {
+		a.mu.Lock()
+		delete(a.posts, alert.ID)
+		a.mu.Unlock()
+	}
+}
+
+// Notify sends user an alert routed to them in a personal chat with the
+// bot, returning notify.ErrUnreachable if they have no linked Teams
+// account
+func (a *Adapter) Notify(ctx context.Context, user *auth.User, n *notify.Notification) error {
+	var member string
+	for id, opusID := range a.config.Users {
+		if opusID == user.ID {
+			member = id
+			break
+		}
+	}
+	if member == "" {
+		return notify.ErrUnreachable
+	}
+
+	a.mu.Lock()
+	serviceURL, tenantID := a.serviceURL, a.tenantID
+	a.mu.Unlock()
+
+	conv, err := a.client.createConversation(ctx, serviceURL, &conversationParams{
+		Members:     []ChannelAccount{{ID: member}},
+		ChannelData: &ChannelData{Tenant: &TeamsRef{ID: tenantID}},
+		TenantID:    tenantID,
+	})
+	a.track(err)
+	if err != nil {
+		return fmt.Errorf("failed to start a chat with %s: %w", member, err)
+	}
+
+	card := alertCard(n.Alert)
+	if n.Escalated {
+		card.Text = "**Escalated to you**: nobody has acknowledged this yet"
+		card.TextFormat = "markdown"
+	}
+	card.Conversation = &ConversationRef{ID: conv.ID}
+	_, err = a.client.reply(ctx, serviceURL, conv.ID, "", card)
+	a.track(err)
+	if err != nil {
+		return fmt.Errorf("failed to message %s: %w", member, err)
+	}
+	return nil
+}
+
+// alertChannels returns the channels a department's alerts go to
+func (a *Adapter) alertChannels(dept string) []string {
+	var channels []string
+	for _, key := range []string{dept, "*"} {
+		if channel := a.config.AlertChannels[key]; channel != "" && !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
@@ -0,0 +1,286 @@
+package teams
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultOpenIDURL is the Bot Connector's OpenID metadata, which names
+	// the token issuer and its signing keys
+	DefaultOpenIDURL = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+	// DefaultTokenURL issues the tokens Opus calls the Bot Connector with
+	DefaultTokenURL = "https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token"
+	// tokenScope is the scope requested for Bot Connector tokens
+	tokenScope = "https://api.botframework.com/.default"
+
+	// keysTTL is how long signing keys are cached before being fetched
+	// again; keys with unknown IDs are fetched at most every keysRetry
+	keysTTL   = 24 * time.Hour
+	keysRetry = 5 * time.Minute
+	// leeway allows for clock skew between Opus and the Bot Connector
+	leeway = 5 * time.Minute
+	// tokenRefresh is how long before expiry outgoing tokens are renewed
+	tokenRefresh = 5 * time.Minute
+)
+
+// connectorClaims are the claims of a Bot Connector token
+type connectorClaims struct {
+	jwt.RegisteredClaims
+	// ServiceURL must match the activity's, so a token for one service
+	// can't direct replies elsewhere
+	ServiceURL string `json:"serviceurl"`
+}
+
+// jwk is an RSA signing key. Endorsements are the channels, such as
+// "msteams", that the key may sign activities from.
+type jwk struct {
+	KeyID        string   `json:"kid"`
+	KeyType      string   `json:"kty"`
+	N            string   `json:"n"`
+	E            string   `json:"e"`
+	Endorsements []string `json:"endorsements"`
+}
+
+type signingKey struct {
+	key          *rsa.PublicKey
+	endorsements []string
+}
+
+// verifier checks the bearer tokens the Bot Connector sends with
+// activities against the keys in its OpenID metadata
+type verifier struct {
+	openIDURL string
+	appID     string
+	http      *http.Client
+
+	mu        sync.Mutex
+	issuer    string
+	keys      map[string]signingKey
+	fetchedAt time.Time
+}
+
+// verify checks the request's token was issued by the Bot Connector for
+// this bot and the activity's channel and service URL
+func (v *verifier) verify(ctx context.Context, header string, activity *Activity) error {
+	raw, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || raw == "" {
+		return errors.New("missing bearer token")
+	}
+
+	if err := v.refresh(ctx, false); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	issuer := v.issuer
+	v.mu.Unlock()
+
+	var endorsements []string
+	claims := &connectorClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := v.key(ctx, kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		endorsements = key.endorsements
+		return key.key, nil
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(v.appID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+
+	if len(endorsements) > 0 && !slices.Contains(endorsements, activity.ChannelID) {
+		return fmt.Errorf("signing key isn't endorsed for channel %q", activity.ChannelID)
+	}
+	if claims.ServiceURL != activity.ServiceURL {
+		return fmt.Errorf("token is for service URL %q, not %q", claims.ServiceURL, activity.ServiceURL)
+	}
+	return nil
+}
+
+// key returns the signing key with kid, fetching the keys again if it's
+// new, since the Bot Connector rotates them
+func (v *verifier) key(ctx context.Context, kid string) (signingKey, bool) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	v.mu.Unlock()
+	if ok {
+		return key, true
+	}
+
+	if err := v.refresh(ctx, true); err != nil {
+		return signingKey{}, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key, ok = v.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the OpenID metadata and signing keys when they're
+// older than keysTTL, or keysRetry if force is set
+func (v *verifier) refresh(ctx context.Context, force bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	age := time.Since(v.fetchedAt)
+	if v.keys != nil && (age < keysRetry || (!force && age < keysTTL)) {
+		return nil
+	}
+
+	var metadata struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := getJSON(ctx, v.http, v.openIDURL, &metadata); err != nil {
+		return fmt.Errorf("failed to fetch OpenID metadata: %w", err)
+	}
+	if metadata.Issuer == "" || metadata.JWKSURI == "" {
+		return errors.New("OpenID metadata has no issuer or jwks_uri")
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, v.http, metadata.JWKSURI, &set); err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]signingKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" {
+			continue
+		}
+		key, err := rsaKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.KeyID] = signingKey{key: key, endorsements: k.Endorsements}
+	}
+	if len(keys) == 0 {
+		return errors.New("OpenID metadata has no RSA signing keys")
+	}
+
+	v.issuer, v.keys, v.fetchedAt = metadata.Issuer, keys, time.Now()
+	return nil
+}
+
+// rsaKey decodes a JWK's base64url modulus and exponent
+func rsaKey(n, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(eb)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(exponent.Int64())}, nil
+}
+
+// tokenSource gets client-credentials tokens for calling the Bot
+// Connector, cached until shortly before they expire
+type tokenSource struct {
+	url      string
+	appID    string
+	password string
+	http     *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (s *tokenSource) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Until(s.expires) > tokenRefresh {
+		return s.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {s.appID},
+		"client_secret": {s.password},
+		"scope":         {tokenScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("access token request failed: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("access token response has no token")
+	}
+
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// invalidate drops the cached token after the Bot Connector refused it
+func (s *tokenSource) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,86 @@
+package teams
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifierVerify(t *testing.T) {
+	fake, err := NewFakeServer("opus-app", "secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	v := &verifier{openIDURL: srv.URL + fakeOpenIDPath, appID: "opus-app", http: srv.Client()}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const serviceURL = "https://smba.trafficmanager.net/amer/"
+	type token struct {
+		key *rsa.PrivateKey
+		kid string
+		connectorClaims
+	}
+	valid := func(mutate func(*token)) string {
+		t.Helper()
+		now := time.Now()
+		tok := token{
+			key: fake.key,
+			kid: fakeKeyID,
+			connectorClaims: connectorClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:    fakeIssuer,
+					Audience:  jwt.ClaimStrings{"opus-app"},
+					NotBefore: jwt.NewNumericDate(now),
+					ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				},
+				ServiceURL: serviceURL,
+			},
+		}
+		if mutate != nil {
+			mutate(&tok)
+		}
+		jt := jwt.NewWithClaims(jwt.SigningMethodRS256, tok.connectorClaims)
+		jt.Header["kid"] = tok.kid
+		signed, err := jt.SignedString(tok.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	teams := &Activity{ChannelID: "msteams", ServiceURL: serviceURL}
+
+	tests := []struct {
+		name     string
+		header   string
+		activity *Activity
+		ok       bool
+	}{
+		{"good", valid(nil), teams, true},
+		{"another bot's audience", valid(func(tok *token) { tok.Audience = jwt.ClaimStrings{"other-app"} }), teams, false},
+		{"another issuer", valid(func(tok *token) { tok.Issuer = "https://sts.windows.net/evil/" }), teams, false},
+		{"channel the key isn't endorsed for", valid(nil), &Activity{ChannelID: "webchat", ServiceURL: serviceURL}, false},
+		{"service URL mismatch", valid(nil), &Activity{ChannelID: "msteams", ServiceURL: "https://evil.example.com/"}, false},
+		{"expired", valid(func(tok *token) { tok.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-leeway - time.Minute)) }), teams, false},
+		{"no expiry", valid(func(tok *token) { tok.ExpiresAt = nil }), teams, false},
+		{"unknown key", valid(func(tok *token) { tok.kid = "other-key" }), teams, false},
+		{"signed with another key", valid(func(tok *token) { tok.key = otherKey }), teams, false},
+		{"no bearer token", "", teams, false},
+	}
+	for _, tt := range tests {
+		err := v.verify(context.Background(), tt.header, tt.activity)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: verify = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
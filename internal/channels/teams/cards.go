@@ -0,0 +1,197 @@
+package teams
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/chat"
+)
+
+// contentTypeAdaptiveCard marks an attachment as an adaptive card
+const contentTypeAdaptiveCard = "application/vnd.microsoft.card.adaptive"
+
+// card is an adaptive card. Elements and actions are kept as maps since
+// each type has its own fields.
+type card struct {
+	Type    string                   `json:"type"`
+	Schema  string                   `json:"$schema"`
+	Version string                   `json:"version"`
+	Body    []map[string]interface{} `json:"body"`
+	Actions []map[string]interface{} `json:"actions,omitempty"`
+}
+
+func newCard() *card {
+	return &card{
+		Type:    "AdaptiveCard",
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Version: "1.4",
+	}
+}
+
+// text adds a text block; options such as "weight" or "isSubtle" are
+// given in pairs
+func (c *card) text(text string, options ...interface{}) {
+	block := map[string]interface{}{"type": "TextBlock", "text": text, "wrap": true}
+	for i := 0; i+1 < len(options); i += 2 {
+		block[options[i].(string)] = options[i+1]
+	}
+	c.Body = append(c.Body, block)
+}
+
+// facts adds a fact set of title and value pairs
+func (c *card) facts(pairs ...string) {
+	var facts []map[string]string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			facts = append(facts, map[string]string{"title": pairs[i], "value": pairs[i+1]})
+		}
+	}
+	c.Body = append(c.Body, map[string]interface{}{"type": "FactSet", "facts": facts})
+}
+
+// submit adds a button that sends data back to the bot
+func (c *card) submit(title string, data submit) {
+	c.Actions = append(c.Actions, map[string]interface{}{"type": "Action.Submit", "title": title, "data": data})
+}
+
+func (c *card) attachment() Attachment {
+	return Attachment{ContentType: contentTypeAdaptiveCard, Content: c}
+}
+
+// answer renders a chat result as a message. Plain answers are Markdown
+// text; answers with suggested actions, citations or a choice of
+// departments are adaptive cards.
+func answer(result *chat.Result, question string) *Activity {
+	footer := answeredBy(result)
+	if len(result.Actions) == 0 && len(result.Citations) == 0 && !result.Ambiguous {
+		text := result.Response
+		if footer != "" {
+			text += "\n\n_" + footer + "_"
+		}
+		return &Activity{Type: ActivityMessage, Text: text, TextFormat: "markdown"}
+	}
+
+	c := newCard()
+	c.text(result.Response)
+
+	if len(result.Actions) > 0 {
+		c.text("Suggested next steps", "weight", "bolder", "spacing", "medium")
+		for _, action := range result.Actions {
+			c.text("• " + action.Label)
+		}
+	}
+
+	if len(result.Citations) > 0 {
+		var sources []string
+		for _, cite := range result.Citations {
+			source := fmt.Sprintf("[%d] %s", cite.Number, cite.Source)
+			if cite.Section != "" {
+				source += ", " + cite.Section
+			}
+			if cite.Page > 0 {
+				source += fmt.Sprintf(", p. %d", cite.Page)
+			}
+			sources = append(sources, source)
+		}
+		c.text(strings.Join(sources, "\n\n"), "size", "small", "isSubtle", true, "spacing", "medium")
+	}
+
+	if footer != "" {
+		c.text(footer, "size", "small", "isSubtle", true)
+	}
+
+	// Picking a department asks the question again for it
+	for _, d := range result.Candidates {
+		c.submit(string(d), submit{Question: question, Department: string(d)})
+	}
+
+	return &Activity{Type: ActivityMessage, Attachments: []Attachment{c.attachment()}}
+}
+
+// answeredBy names the agents behind an answer
+func answeredBy(result *chat.Result) string {
+	switch {
+	case len(result.Departments) > 0:
+		names := make([]string, len(result.Departments))
+		for i, d := range result.Departments {
+			names[i] = string(d)
+		}
+		return "Answered by the " + strings.Join(names, ", ") + " agents"
+	case result.Department != "":
+		return "Answered by the " + string(result.Department) + " agent"
+	default:
+		return ""
+	}
+}
+
+// severityColors are the text colors alerts are titled in
+var severityColors = map[alerts.Severity]string{
+	alerts.SeverityInfo:     "accent",
+	alerts.SeverityWarning:  "warning",
+	alerts.SeverityCritical: "attention",
+}
+
+// alertCard renders a newly posted alert with a button to acknowledge it
+func alertCard(a *alerts.Alert) *Activity {
+	c := newCard()
+	c.text(strings.ToUpper(string(a.Severity))+" · "+a.Department, "size", "small", "weight", "bolder", "color", severityColors[a.Severity])
+	c.text(a.Title, "size", "medium", "weight", "bolder")
+	if a.Detail != "" {
+		c.text(a.Detail)
+	}
+	c.facts("Alert", a.ID, "Raised", a.CreatedAt.Local().Format("Jan 2 3:04 PM"))
+	if a.Status == alerts.StatusActive {
+		c.submit("Acknowledge", submit{Acknowledge: a.ID})
+	}
+	return &Activity{
+		Type:        ActivityMessage,
+		Summary:     a.Title,
+		Attachments: []Attachment{c.attachment()},
+	}
+}
+
+// changeText describes an alert's latest change for a reply in its
+// thread, or returns "" for changes not worth a reply
+func changeText(a *alerts.Alert) string {
+	if len(a.History) == 0 {
+		return ""
+	}
+	change := a.History[len(a.History)-1]
+
+	var text string
+	switch change.Action {
+	case alerts.ActionAcknowledged:
+		text = "Acknowledged"
+	case alerts.ActionSnoozed:
+		text = "Snoozed"
+		if a.SnoozedUntil != nil {
+			text += " until " + a.SnoozedUntil.Local().Format("3:04 PM")
+		}
+	case alerts.ActionWoke:
+		return "Snooze ended, this needs attention again"
+	case alerts.ActionEscalated:
+		text = "**Escalated to " + string(a.Severity) + "**"
+	case alerts.ActionChanged:
+		// Rules refresh titles and details on every evaluation; only a
+		// new severity is news
+		if change.Note == "" {
+			return ""
+		}
+		return fmt.Sprintf("**Now %s:** %s", a.Severity, a.Title)
+	case alerts.ActionResolved:
+		text = "✅ Resolved"
+	case alerts.ActionCleared:
+		return "✅ Cleared, the condition is gone"
+	default:
+		return ""
+	}
+
+	if change.By != "" {
+		text += " by " + change.By
+	}
+	if change.Note != "" {
+		text += ": " + change.Note
+	}
+	return text
+}
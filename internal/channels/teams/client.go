@@ -0,0 +1,91 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// client calls the Bot Connector REST API at a conversation's service URL
+type client struct {
+	tokens *tokenSource
+	http   *http.Client
+}
+
+// reply sends activity in reply to the activity with replyToID
+func (c *client) reply(ctx context.Context, serviceURL, conversationID, replyToID string, activity *Activity) (string, error) {
+	path := "/v3/conversations/" + url.PathEscape(conversationID) + "/activities"
+	if replyToID != "" {
+		path += "/" + url.PathEscape(replyToID)
+	}
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := c.post(ctx, serviceURL, path, activity, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// createConversation starts a conversation, e.g. a new thread in a
+// channel, and returns its ID
+func (c *client) createConversation(ctx context.Context, serviceURL string, params *conversationParams) (*conversationResource, error) {
+	var resp conversationResource
+	if err := c.post(ctx, serviceURL, "/v3/conversations", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// post sends body as JSON and decodes the response into out. A 401 drops
+// the cached token and retries once.
+func (c *client) post(ctx context.Context, serviceURL, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(serviceURL, "/") + path
+
+	for attempt := 0; ; attempt++ {
+		token, err := c.tokens.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("POST %s failed: %w", path, err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			c.tokens.invalidate()
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("POST %s failed: %s %s", path, resp.Status, strings.TrimSpace(string(data)))
+		}
+		if out != nil {
+			// Typing indicators and some replies come back empty
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+				return fmt.Errorf("failed to decode %s response: %w", path, err)
+			}
+		}
+		return nil
+	}
+}
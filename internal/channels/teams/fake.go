@@ -0,0 +1,341 @@
+package teams
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// FakeBotID is the bot's account ID on a FakeServer
+	FakeBotID = "28:opus-bot"
+	// FakeTenantID is the tenant a FakeServer's activities come from
+	FakeTenantID = "fake-tenant"
+	// fakeIssuer is the Bot Connector's real issuer, so tokens from a
+	// FakeServer are checked exactly as real ones are
+	fakeIssuer = "https://api.botframework.com"
+	fakeKeyID  = "fake-key"
+	// Paths a FakeServer serves the Bot Framework endpoints at
+	fakeOpenIDPath = "/v1/.well-known/openidconfiguration"
+	fakeKeysPath   = "/v1/.well-known/keys"
+	fakeTokenPath  = "/botframework.com/oauth2/v2.0/token"
+)
+
+// FakeActivity is an activity sent to a FakeServer, by the bot or by a
+// simulated user
+type FakeActivity struct {
+	Conversation string          `json:"conversation"`
+	ID           string          `json:"id"`
+	ReplyToID    string          `json:"replyToId,omitempty"`
+	Type         string          `json:"type"`
+	From         string          `json:"from"`
+	Text         string          `json:"text,omitempty"`
+	Attachments  json.RawMessage `json:"attachments,omitempty"`
+}
+
+// FakeServer stands in for the Bot Connector for demos and local testing.
+// It serves the OpenID metadata and signing keys Opus checks tokens with,
+// issues client-credentials tokens, records the activities Opus sends,
+// and plays users: POST /send signs an activity and delivers it to Opus.
+//
+//	POST /send      {"user": "29:jsmith", "aadObjectId": "aad-jsmith", "conversation": "a:personal-jsmith", "text": "how much milk?"}
+//	POST /send      {"user": "29:jsmith", "aadObjectId": "aad-jsmith", "conversation": "a:personal-jsmith", "value": {"acknowledge": "3"}}
+//	GET  /messages  ?conversation=19:dairy@thread.tacv2
+type FakeServer struct {
+	AppID       string
+	AppPassword string
+	// OpusURL is where activities are delivered, e.g.
+	// http://localhost:8080
+	OpusURL string
+
+	key        *rsa.PrivateKey
+	mu         sync.Mutex
+	tokens     map[string]time.Time
+	activities []FakeActivity
+	seq        int
+	http       *http.Client
+}
+
+// NewFakeServer creates a fake Bot Connector with a new signing key
+func NewFakeServer(appID, appPassword, opusURL string) (*FakeServer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return &FakeServer{
+		AppID:       appID,
+		AppPassword: appPassword,
+		OpusURL:     strings.TrimRight(opusURL, "/"),
+		key:         key,
+		tokens:      make(map[string]time.Time),
+		http:        &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Activities returns the activities in conversations starting with
+// prefix, e.g. a channel's ID for every thread in it
+func (f *FakeServer) Activities(prefix string) []FakeActivity {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	activities := []FakeActivity{}
+	for _, a := range f.activities {
+		if strings.HasPrefix(a.Conversation, prefix) {
+			activities = append(activities, a)
+		}
+	}
+	return activities
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	base := "http://" + r.Host
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == fakeOpenIDPath:
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                fakeIssuer,
+			"jwks_uri":                              base + fakeKeysPath,
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == fakeKeysPath:
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"keys": []jwk{{
+			KeyID:        fakeKeyID,
+			KeyType:      "RSA",
+			N:            base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			E:            base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+			Endorsements: []string{"msteams"},
+		}}})
+	case r.Method == http.MethodPost && r.URL.Path == fakeTokenPath:
+		f.issueToken(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/messages":
+		writeFakeJSON(w, http.StatusOK, f.Activities(r.URL.Query().Get("conversation")))
+	case r.Method == http.MethodPost && r.URL.Path == "/send":
+		f.send(w, r, base)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v3/conversations"):
+		f.conversations(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// issueToken implements the client-credentials grant
+func (f *FakeServer) issueToken(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != f.AppID || r.FormValue("client_secret") != f.AppPassword {
+		writeFakeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.FormValue("scope") != tokenScope {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_scope"})
+		return
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	f.mu.Lock()
+	f.tokens[token] = time.Now().Add(time.Hour)
+	f.mu.Unlock()
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"token_type": "Bearer", "access_token": token, "expires_in": 3600})
+}
+
+// conversations serves creating a conversation and sending or replying
+// to activities in one
+func (f *FakeServer) conversations(w http.ResponseWriter, r *http.Request) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	f.mu.Lock()
+	expires, ok := f.tokens[token]
+	f.mu.Unlock()
+	if !ok || time.Now().After(expires) {
+		writeFakeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Authorization has been denied for this request."})
+		return
+	}
+
+	// /v3/conversations creates one; /v3/conversations/{id}/activities[/{replyTo}]
+	// sends to one
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/v3/conversations"), "/")
+	if len(parts) == 1 {
+		var params conversationParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeFakeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid conversation"})
+			return
+		}
+		// A personal chat with one member; activities are sent to it
+		// separately
+		if len(params.Members) == 1 && !params.IsGroup {
+			writeFakeJSON(w, http.StatusCreated, conversationResource{ID: "a:" + params.Members[0].ID})
+			return
+		}
+		if params.ChannelData == nil || params.ChannelData.Channel == nil || params.Activity == nil {
+			writeFakeJSON(w, http.StatusBadRequest, map[string]string{"message": "channelData.channel and activity are required"})
+			return
+		}
+		f.mu.Lock()
+		f.seq++
+		id := params.ChannelData.Channel.ID + ";messageid=" + strconv.Itoa(f.seq)
+		f.mu.Unlock()
+		activity := f.record(id, "", params.Activity)
+		writeFakeJSON(w, http.StatusCreated, conversationResource{ID: id, ActivityID: activity.ID})
+		return
+	}
+	if len(parts) < 3 || parts[2] != "activities" {
+		http.NotFound(w, r)
+		return
+	}
+
+	conversation, err := url.PathUnescape(parts[1])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var replyTo string
+	if len(parts) > 3 {
+		replyTo, _ = url.PathUnescape(parts[3])
+	}
+	var activity Activity
+	if err := json.NewDecoder(r.Body).Decode(&activity); err != nil || activity.Type == "" {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid activity"})
+		return
+	}
+	recorded := f.record(conversation, replyTo, &activity)
+	writeFakeJSON(w, http.StatusOK, map[string]string{"id": recorded.ID})
+}
+
+// send plays a user sending a message or pressing a card button, or
+// adding the bot to a conversation, and returns Opus's response
+func (f *FakeServer) send(w http.ResponseWriter, r *http.Request, base string) {
+	var in struct {
+		Type             string          `json:"type"`
+		User             string          `json:"user"`
+		Name             string          `json:"name"`
+		AADObjectID      string          `json:"aadObjectId"`
+		Conversation     string          `json:"conversation"`
+		ConversationType string          `json:"conversationType"`
+		Text             string          `json:"text"`
+		Value            json.RawMessage `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.User == "" || in.Conversation == "" {
+		http.Error(w, `{"error": "user and conversation are required"}`, http.StatusBadRequest)
+		return
+	}
+	if in.Type == "" {
+		in.Type = ActivityMessage
+	}
+	if in.ConversationType == "" {
+		in.ConversationType = "personal"
+		if strings.HasPrefix(in.Conversation, "19:") {
+			in.ConversationType = "channel"
+		}
+	}
+
+	activity := &Activity{
+		Type:       in.Type,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		ServiceURL: base + "/",
+		ChannelID:  "msteams",
+		From:       &ChannelAccount{ID: in.User, Name: in.Name, AADObjectID: in.AADObjectID},
+		Recipient:  &ChannelAccount{ID: FakeBotID, Name: "Opus"},
+		Conversation: &ConversationRef{
+			ID:               in.Conversation,
+			ConversationType: in.ConversationType,
+			TenantID:         FakeTenantID,
+			IsGroup:          in.ConversationType != "personal",
+		},
+		Text:        in.Text,
+		Value:       in.Value,
+		ChannelData: &ChannelData{Tenant: &TeamsRef{ID: FakeTenantID}},
+	}
+	if in.Type == ActivityConversationUpdate {
+		activity.MembersAdded = []ChannelAccount{{ID: FakeBotID}}
+	}
+	recorded := f.record(in.Conversation, "", activity)
+	activity.ID = recorded.ID
+
+	status, err := f.deliver(activity)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadGateway)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"status": status, "id": activity.ID})
+}
+
+// deliver signs a Bot Connector token for activity and POSTs it to Opus
+func (f *FakeServer) deliver(activity *Activity) (int, error) {
+	now := time.Now()
+	claims := connectorClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    fakeIssuer,
+			Audience:  jwt.ClaimStrings{f.AppID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		ServiceURL: activity.ServiceURL,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = fakeKeyID
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, f.OpusURL+"/teams/messages", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+signed)
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// record stores an activity in conversation with a new ID
+func (f *FakeServer) record(conversation, replyTo string, activity *Activity) FakeActivity {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+
+	recorded := FakeActivity{
+		Conversation: conversation,
+		ID:           strconv.Itoa(f.seq),
+		ReplyToID:    replyTo,
+		Type:         activity.Type,
+		Text:         activity.Text,
+	}
+	if activity.From != nil {
+		recorded.From = activity.From.ID
+	}
+	if len(activity.Attachments) > 0 {
+		recorded.Attachments, _ = json.Marshal(activity.Attachments)
+	}
+	f.activities = append(f.activities, recorded)
+	return recorded
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/conversation"
+)
+
+// Errors returned for requests that can't be processed as given
+var (
+	ErrEmptyMessage      = errors.New("message is required")
+	ErrUnknownDepartment = errors.New("unknown department")
+	ErrHistoryConflict   = errors.New("history can't be sent with a conversation ID")
+	// ErrOutOfScope is returned when Department is outside Scope
+	ErrOutOfScope = errors.New("department is outside the user's scope")
+)
+
+// Request is a chat message from any channel
+type Request struct {
+	Message string
+	// History is client-supplied context for stateless chats. It is
+	// rejected when ConversationID is set since the server owns that
+	// conversation's history.
+	History []ai.Message
+	// ConversationID continues a conversation UserID started
+	ConversationID string
+	// UserID is the signed-in user asking
+	UserID string
+	// Department skips routing, e.g. after the user picks one of the
+	// candidates from an ambiguous answer
+	Department ai.Department
+	// Scope limits a department manager to their own department's agent,
+	// and through it their department's data; empty allows every agent
+	Scope ai.Department
+}
+
+// Result is the answer to a chat Request
+type Result struct {
+	*ai.QueryResult
+	ConversationID string
+}
+
+// Service is the chat pipeline shared by every channel: it loads
+// conversation history, routes the message to an agent and records the
+// exchange
+type Service struct {
+	router        *ai.Router
+	conversations conversation.Store
+}
+
+// NewService creates a chat service
+func NewService(router *ai.Router, conversations conversation.Store) *Service {
+	return &Service{
+		router:        router,
+		conversations: conversations,
+	}
+}
+
+// Router returns the AI router behind the service
+func (s *Service) Router() *ai.Router {
+	return s.router
+}
+
+// Conversations returns the conversation store
+func (s *Service) Conversations() conversation.Store {
+	return s.conversations
+}
+
+// Conversation returns the conversation with id if the user with userID,
+// limited to scope, may see it. Anyone else gets conversation.ErrNotFound,
+// so conversation IDs can't be probed.
+func (s *Service) Conversation(ctx context.Context, id, userID string, scope ai.Department) (*conversation.Conversation, error) {
+	conv, err := s.conversations.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !conv.Allows(userID, scope) {
+		return nil, conversation.ErrNotFound
+	}
+	return conv, nil
+}
+
+// Process answers req. When onDelta is non-nil the answer is streamed
+// through it as it is generated.
+func (s *Service) Process(ctx context.Context, req Request, onDelta ai.DeltaFunc) (*Result, error) {
+	if req.Message == "" {
+		return nil, ErrEmptyMessage
+	}
+	if req.Scope != "" {
+		if req.Department != "" && req.Department != req.Scope {
+			return nil, ErrOutOfScope
+		}
+		req.Department = req.Scope
+	}
+
+	var agent *ai.Agent
+	if req.Department != "" {
+		var ok bool
+		if agent, ok = s.router.Agent(req.Department); !ok {
+			return nil, ErrUnknownDepartment
+		}
+	}
+
+	history := req.History
+	var conv *conversation.Conversation
+	if req.ConversationID != "" {
+		if len(req.History) > 0 {
+			return nil, ErrHistoryConflict
+		}
+		var err error
+		if conv, err = s.Conversation(ctx, req.ConversationID, req.UserID, req.Scope); err != nil {
+			return nil, err
+		}
+		history = conv.Messages()
+	}
+
+	history = s.fitHistory(ctx, conv, history)
+
+	var result *ai.QueryResult
+	var err error
+	switch {
+	case agent != nil && onDelta != nil:
+		result, err = agent.ProcessQueryStream(ctx, req.Message, history, onDelta)
+	case agent != nil:
+		result, err = agent.ProcessQuery(ctx, req.Message, history)
+	case onDelta != nil:
+		result, err = s.router.ProcessQueryStream(ctx, req.Message, history, onDelta)
+	default:
+		result, err = s.router.ProcessQuery(ctx, req.Message, history)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to process message: %w", err)
+	}
+
+	// A clarifying question isn't recorded: the client resends the same
+	// message with a department, and that exchange is the one to keep
+	if req.ConversationID != "" && !result.Ambiguous {
+		err := s.conversations.Append(ctx, req.ConversationID,
+			conversation.Turn{Role: "user", Content: req.Message, Department: result.Department},
+			conversation.Turn{Role: "assistant", Content: result.Response, Department: result.Department},
+		)
+		if err != nil {
+			// The user already has the answer; losing the record is
+			// worth logging but not worth failing the request
+			log.Printf("Failed to record turn in conversation %s: %v", req.ConversationID, err)
+		}
+	}
+
+	return &Result{QueryResult: result, ConversationID: req.ConversationID}, nil
+}
+
+// fitHistory keeps history within the model's context window. A
+// conversation's summary is saved so older turns are only summarized once.
+func (s *Service) fitHistory(ctx context.Context, conv *conversation.Conversation, history []ai.Message) []ai.Message {
+	var summary string
+	if conv != nil {
+		summary = conv.Summary
+	}
+
+	compaction := s.router.ContextManager().Compact(ctx, summary, history)
+	if conv != nil && compaction.Summarized > 0 {
+		turns := conv.SummarizedTurns + compaction.Summarized
+		if err := s.conversations.SetSummary(ctx, conv.ID, compaction.Summary, turns); err != nil {
+			log.Printf("Failed to save summary for conversation %s: %v", conv.ID, err)
+		} else {
+			log.Printf("Summarized %d turns of conversation %s", turns, conv.ID)
+		}
+	}
+
+	return compaction.History
+}
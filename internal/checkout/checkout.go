@@ -0,0 +1,169 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Department is the department checkout lanes belong to
+const Department = "frontend"
+
+// LaneStatus is what a lane is doing
+type LaneStatus string
+
+const (
+	LaneOpen   LaneStatus = "open"
+	LaneClosed LaneStatus = "closed"
+	// LaneOffline means the register isn't reachable
+	LaneOffline LaneStatus = "offline"
+	// LaneNeedsAssistance means the cashier or a self-checkout customer
+	// has called for help
+	LaneNeedsAssistance LaneStatus = "needs_assistance"
+)
+
+// ParseLaneStatus reads a status, accepting "needs-assistance" and any case
+func ParseLaneStatus(value string) (LaneStatus, error) {
+	status := LaneStatus(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), "-", "_"))
+	switch status {
+	case LaneOpen, LaneClosed, LaneOffline, LaneNeedsAssistance:
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown lane status %q", value)
+}
+
+// Lane is a staffed or self-checkout register with today's totals
+type Lane struct {
+	ID string `json:"id"`
+	// Name is how the lane is signed in the store, e.g. "Lane 3" or
+	// "Self-checkout 2"
+	Name    string     `json:"name"`
+	Status  LaneStatus `json:"status"`
+	Cashier string     `json:"cashier,omitempty"`
+	// StatusSince is when the lane entered its current status
+	StatusSince time.Time `json:"statusSince"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	// Transactions and Sales are since midnight
+	Transactions      int        `json:"transactions"`
+	Sales             float64    `json:"sales"`
+	LastTransactionAt *time.Time `json:"lastTransactionAt,omitempty"`
+}
+
+// StatusChange reports a lane's status. Name and Cashier are kept from
+// earlier changes when empty.
+type StatusChange struct {
+	Lane    string
+	Name    string
+	Status  LaneStatus
+	Cashier string
+	At      time.Time
+}
+
+// LineItem is one item sold in a transaction
+type LineItem struct {
+	SKU         string `json:"sku"`
+	Description string `json:"description,omitempty"`
+	Department  string `json:"department,omitempty"`
+	// Quantity is fractional for items sold by weight
+	Quantity float64 `json:"quantity"`
+	// Amount is the extended price after discounts
+	Amount float64 `json:"amount"`
+}
+
+// Transaction is a completed sale at a lane
+type Transaction struct {
+	ID    string     `json:"id"`
+	Lane  string     `json:"lane"`
+	At    time.Time  `json:"at"`
+	Total float64    `json:"total"`
+	Items []LineItem `json:"items,omitempty"`
+}
+
+// HourSales totals the transactions of one hour
+type HourSales struct {
+	Start        time.Time `json:"start"`
+	Transactions int       `json:"transactions"`
+	Items        float64   `json:"items"`
+	Sales        float64   `json:"sales"`
+}
+
+// ItemSales totals one item's sales
+type ItemSales struct {
+	SKU         string  `json:"sku"`
+	Description string  `json:"description,omitempty"`
+	Department  string  `json:"department,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	Sales       float64 `json:"sales"`
+}
+
+// Repository stores lane status and transactions
+type Repository interface {
+	// Lanes returns every lane ordered by ID, with totals since midnight
+	Lanes(ctx context.Context) ([]Lane, error)
+	// UpdateLane applies a status change, ignoring it if the lane has
+	// since been updated. It reports whether it was applied.
+	UpdateLane(ctx context.Context, change StatusChange) (bool, error)
+	// RecordTransaction saves a transaction, reporting false if one with
+	// its ID was already recorded. A transaction at an unknown lane adds
+	// it as open.
+	RecordTransaction(ctx context.Context, t Transaction) (bool, error)
+	// SalesByHour totals transactions in [from, to) by hour, leaving out
+	// hours before the first sale and after the last
+	SalesByHour(ctx context.Context, from, to time.Time) ([]HourSales, error)
+	// TopItems returns the best-selling items in [from, to) by amount
+	TopItems(ctx context.Context, from, to time.Time, limit int) ([]ItemSales, error)
+}
+
+// validate checks a transaction has what's needed to count it
+func (t *Transaction) validate() error {
+	switch {
+	case t.ID == "":
+		return errors.New("transaction id is required")
+	case t.Lane == "":
+		return fmt.Errorf("transaction %s: lane is required", t.ID)
+	case t.At.IsZero():
+		return fmt.Errorf("transaction %s: time is required", t.ID)
+	}
+	return nil
+}
+
+// Day is a day's sales by hour with its best sellers
+type Day struct {
+	Date         string      `json:"date"`
+	Transactions int         `json:"transactions"`
+	Sales        float64     `json:"sales"`
+	Hours        []HourSales `json:"hours"`
+	TopItems     []ItemSales `json:"topItems"`
+}
+
+// LoadDay totals the sales of the day containing date, in date's location
+func LoadDay(ctx context.Context, repo Repository, date time.Time, topItems int) (*Day, error) {
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+
+	hours, err := repo.SalesByHour(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sales: %w", err)
+	}
+	items, err := repo.TopItems(ctx, start, end, topItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load top items: %w", err)
+	}
+
+	day := &Day{Date: start.Format(time.DateOnly), Hours: hours, TopItems: items}
+	if day.Hours == nil {
+		day.Hours = []HourSales{}
+	}
+	if day.TopItems == nil {
+		day.TopItems = []ItemSales{}
+	}
+	for _, h := range hours {
+		day.Transactions += h.Transactions
+		day.Sales += h.Sales
+	}
+	day.Sales = cents(day.Sales)
+	return day, nil
+}
@@ -0,0 +1,238 @@
+package checkout
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// retention is how long the memory repository keeps transactions
+const retention = 8 * 24 * time.Hour
+
+// MemoryRepository keeps lanes and the last eight days of transactions in
+// memory
+type MemoryRepository struct {
+	lanes        map[string]Lane
+	transactions []Transaction
+	recorded     map[string]bool
+	mu           sync.RWMutex
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		lanes:    make(map[string]Lane),
+		recorded: make(map[string]bool),
+	}
+}
+
+// Lanes returns every lane ordered by ID, with totals since midnight
+func (m *MemoryRepository) Lanes(ctx context.Context) ([]Lane, error) {
+	now := time.Now()
+	y, mo, d := now.Date()
+	midnight := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	totals := make(map[string]*Lane)
+	for _, t := range m.transactions {
+		if t.At.Before(midnight) {
+			continue
+		}
+		lane, ok := totals[t.Lane]
+		if !ok {
+			lane = &Lane{}
+			totals[t.Lane] = lane
+		}
+		lane.Transactions++
+		lane.Sales += t.Total
+		if lane.LastTransactionAt == nil || t.At.After(*lane.LastTransactionAt) {
+			at := t.At
+			lane.LastTransactionAt = &at
+		}
+	}
+
+	lanes := make([]Lane, 0, len(m.lanes))
+	for _, lane := range m.lanes {
+		if total, ok := totals[lane.ID]; ok {
+			lane.Transactions = total.Transactions
+			lane.Sales = cents(total.Sales)
+			lane.LastTransactionAt = total.LastTransactionAt
+		}
+		lanes = append(lanes, lane)
+	}
+	slices.SortFunc(lanes, func(a, b Lane) int {
+		return cmp.Or(cmp.Compare(len(a.ID), len(b.ID)), cmp.Compare(a.ID, b.ID))
+	})
+	return lanes, nil
+}
+
+// UpdateLane applies a status change unless the lane has a later one
+func (m *MemoryRepository) UpdateLane(ctx context.Context, change StatusChange) (bool, error) {
+	if change.At.IsZero() {
+		change.At = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lane, ok := m.lanes[change.Lane]
+	if ok && change.At.Before(lane.UpdatedAt) {
+		return false, nil
+	}
+	if !ok {
+		lane = Lane{ID: change.Lane, Name: "Lane " + change.Lane}
+	}
+	if change.Name != "" {
+		lane.Name = change.Name
+	}
+	if change.Cashier != "" || change.Status == LaneClosed {
+		lane.Cashier = change.Cashier
+	}
+	if lane.Status != change.Status {
+		lane.Status = change.Status
+		lane.StatusSince = change.At
+	}
+	lane.UpdatedAt = change.At
+	m.lanes[change.Lane] = lane
+	return true, nil
+}
+
+// RecordTransaction saves a transaction once, dropping any older than
+// eight days
+func (m *MemoryRepository) RecordTransaction(ctx context.Context, t Transaction) (bool, error) {
+	if err := t.validate(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.recorded[t.ID] {
+		return false, nil
+	}
+	if _, ok := m.lanes[t.Lane]; !ok {
+		m.lanes[t.Lane] = Lane{ID: t.Lane, Name: "Lane " + t.Lane, Status: LaneOpen, StatusSince: t.At, UpdatedAt: t.At}
+	}
+
+	t.Items = slices.Clone(t.Items)
+	m.transactions = append(m.transactions, t)
+	m.recorded[t.ID] = true
+	m.prune(time.Now().Add(-retention))
+	return true, nil
+}
+
+// prune drops transactions before cutoff
+func (m *MemoryRepository) prune(cutoff time.Time) {
+	kept := m.transactions[:0]
+	for _, t := range m.transactions {
+		if t.At.Before(cutoff) {
+			delete(m.recorded, t.ID)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	clear(m.transactions[len(kept):])
+	m.transactions = kept
+}
+
+// SalesByHour totals transactions in [from, to) by hour
+func (m *MemoryRepository) SalesByHour(ctx context.Context, from, to time.Time) ([]HourSales, error) {
+	m.mu.RLock()
+	byHour := make(map[int64]*HourSales)
+	var first, last time.Time
+	for _, t := range m.transactions {
+		if t.At.Before(from) || !t.At.Before(to) {
+			continue
+		}
+		hour := hourOf(t.At.In(from.Location()))
+		h, ok := byHour[hour.Unix()]
+		if !ok {
+			h = &HourSales{Start: hour}
+			byHour[hour.Unix()] = h
+		}
+		h.Transactions++
+		h.Sales += t.Total
+		for _, item := range t.Items {
+			h.Items += item.Quantity
+		}
+		if first.IsZero() || hour.Before(first) {
+			first = hour
+		}
+		if hour.After(last) {
+			last = hour
+		}
+	}
+	m.mu.RUnlock()
+
+	if len(byHour) == 0 {
+		return nil, nil
+	}
+	var hours []HourSales
+	for hour := first; !hour.After(last); hour = hour.Add(time.Hour) {
+		h := HourSales{Start: hour}
+		if sales, ok := byHour[hour.Unix()]; ok {
+			h = *sales
+			h.Sales = cents(h.Sales)
+			h.Items = units(h.Items)
+		}
+		hours = append(hours, h)
+	}
+	return hours, nil
+}
+
+// TopItems returns the best-selling items in [from, to) by amount
+func (m *MemoryRepository) TopItems(ctx context.Context, from, to time.Time, limit int) ([]ItemSales, error) {
+	m.mu.RLock()
+	bySKU := make(map[string]*ItemSales)
+	for _, t := range m.transactions {
+		if t.At.Before(from) || !t.At.Before(to) {
+			continue
+		}
+		for _, item := range t.Items {
+			s, ok := bySKU[item.SKU]
+			if !ok {
+				s = &ItemSales{SKU: item.SKU, Description: item.Description, Department: item.Department}
+				bySKU[item.SKU] = s
+			}
+			s.Quantity += item.Quantity
+			s.Sales += item.Amount
+		}
+	}
+	m.mu.RUnlock()
+
+	items := make([]ItemSales, 0, len(bySKU))
+	for _, s := range bySKU {
+		s.Sales = cents(s.Sales)
+		s.Quantity = units(s.Quantity)
+		items = append(items, *s)
+	}
+	slices.SortFunc(items, func(a, b ItemSales) int {
+		return cmp.Or(cmp.Compare(b.Sales, a.Sales), cmp.Compare(a.SKU, b.SKU))
+	})
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// hourOf returns the start of t's hour in t's location, which differs
+// from Truncate in zones with a non-hour UTC offset
+func hourOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+}
+
+// cents rounds an amount of money to the cent
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// units rounds a quantity to what a scale reads, thousandths of a pound
+func units(quantity float64) float64 {
+	return math.Round(quantity*1000) / 1000
+}
@@ -1,42 +1,486 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	// Server settings
 	ServerAddr string
+	// StoreID is the store this server serves. Department channels carry
+	// its alerts, so only its staff may join them.
+	StoreID string
 
 	// AI settings
-	OllamaURL       string
-	OllamaModel     string
-	ClaudeAPIKey    string
-	ClaudeFallback  bool
+	OllamaURL      string
+	OllamaModel    string
+	ClaudeURL      string
+	ClaudeAPIKey   string
+	ClaudeModel    string
+	ClaudeFallback bool
+	// RoutingMode is "keyword" or "classifier"
+	RoutingMode string
+	// AnswerFormat is "structured" for JSON answers with suggested
+	// actions, or "text"
+	AnswerFormat string
+	// DepartmentsDir holds department definition YAML files; empty uses
+	// the built-in departments
+	DepartmentsDir string
+	// ContextWindows overrides the built-in context window, in tokens, of
+	// a model or model prefix
+	ContextWindows map[string]int
+
+	// Knowledge base: documents under KnowledgeDir are embedded with
+	// EmbeddingModel and searched by agents. KnowledgeIndex, if set, keeps
+	// the embeddings between restarts.
+	KnowledgeDir   string
+	KnowledgeIndex string
+	EmbeddingModel string
 
 	// Database
 	DatabaseURL string
+	// ConversationDB is the SQLite file for chat conversations; empty
+	// keeps them in memory
+	ConversationDB string
 
-	// Security
-	JWTSecret   string
+	// Security. Without JWTSecret the API refuses every request unless
+	// AuthDisabled is set, which signs everyone in as an admin for local
+	// development.
+	JWTSecret    string
+	AuthDisabled bool
+	// UsersFile lists the users who can sign in, with bcrypt password
+	// hashes; TokenTTL is how long their tokens last
+	UsersFile string
+	TokenTTL  time.Duration
+	// CORSOrigins are the browser origins allowed to call the API and open
+	// WebSocket connections
 	CORSOrigins []string
+
+	// Alerts: rules are evaluated every AlertInterval
+	AlertInterval time.Duration
+
+	// Connectors are probed every ConnectorProbeInterval. MockConnector
+	// adds an in-process connector for demos.
+	ConnectorProbeInterval time.Duration
+	MockConnector          bool
+
+	// UKG workforce connector, enabled when UKGURL is set. Schedules,
+	// time off and punches are pulled every UKGSyncInterval; UKGDepartments
+	// maps UKG department names to Opus departments where they differ.
+	UKGURL          string
+	UKGClientID     string
+	UKGClientSecret string
+	UKGSyncInterval time.Duration
+	UKGDepartments  map[string]string
+
+	// POSFeed is where lane status and transactions are read from: a file
+	// path to tail or tcp://host:port for a bridge. Empty uses demo data.
+	POSFeed string
+
+	// Periscope connector: exports dropped in PeriscopeDir or pulled from
+	// PeriscopeURL are imported every PeriscopeInterval. PeriscopeDepartments
+	// maps Periscope department names to Opus departments where they differ.
+	PeriscopeDir         string
+	PeriscopeURL         string
+	PeriscopeAPIKey      string
+	PeriscopeInterval    time.Duration
+	PeriscopeDepartments map[string]string
+
+	// Slack adapter, enabled when SlackBotToken is set. SlackUsers maps
+	// Slack user IDs to users in UsersFile; SlackAlertChannels maps
+	// departments to channel IDs, with "*" receiving every department's.
+	SlackBotToken      string
+	SlackSigningSecret string
+	SlackAPIURL        string
+	SlackUsers         map[string]string
+	SlackAlertChannels map[string]string
+
+	// Teams adapter, enabled when TeamsAppID is set. The OpenID, token and
+	// service URLs default to the Bot Framework's. TeamsUsers maps Azure
+	// AD object IDs to users in UsersFile; TeamsAlertChannels maps
+	// departments to channel IDs, with "*" receiving every department's.
+	TeamsAppID         string
+	TeamsAppPassword   string
+	TeamsOpenIDURL     string
+	TeamsTokenURL      string
+	TeamsServiceURL    string
+	TeamsTenantID      string
+	TeamsUsers         map[string]string
+	TeamsAlertChannels map[string]string
+
+	// SMS adapter, enabled when SMSAccountSID is set. SMSProvider names
+	// the gateway; "twilio" covers any with a Twilio-compatible API at
+	// SMSAPIURL. SMSWebhookURL is the public URL webhooks are signed
+	// with. SMSUsers maps phone numbers to users in UsersFile, and
+	// SMSOnCall maps departments to the users paged for critical alerts.
+	SMSProvider   string
+	SMSAccountSID string
+	SMSAuthToken  string
+	SMSFrom       string
+	SMSAPIURL     string
+	SMSWebhookURL string
+	SMSMaxLength  int
+	SMSUsers      map[string]string
+	SMSOnCall     map[string]string
+
+	// Email notifier, enabled when EmailSMTPHost is set. EmailSMTPTLS is
+	// starttls, tls or none. EmailDigestAt is when the morning digest is
+	// sent, as the time since local midnight. Deliveries and preferences
+	// are kept in EmailDB if set, in memory otherwise.
+	EmailSMTPHost     string
+	EmailSMTPPort     int
+	EmailSMTPUsername string
+	EmailSMTPPassword string
+	EmailSMTPTLS      string
+	EmailFrom         string
+	EmailDigestAt     time.Duration
+	EmailTemplates    string
+	EmailBaseURL      string
+	EmailDB           string
+
+	// Notification router, enabled when NotifyTiers is set. Alerts of at
+	// least NotifyMinSeverity are routed to the first tier and escalated
+	// through the rest. NotifyChannels is the default channel order,
+	// NotifyStore limits recipients to one store's users, and NotifyOnCall
+	// maps departments to the users preferred over the rest of a tier.
+	// Preferences and records are kept in NotifyDB if set, in memory
+	// otherwise.
+	NotifyTiers       []EscalationTier
+	NotifyChannels    []string
+	NotifyMinSeverity string
+	NotifyStore       string
+	NotifyOnCall      map[string]string
+	NotifyDB          string
+}
+
+// EscalationTier is a tier of the escalation policy and how long it has
+// to acknowledge an alert before the next tier is notified
+type EscalationTier struct {
+	Name string
+	Wait time.Duration
 }
 
 func Load() (*Config, error) {
 	cfg := &Config{
-		ServerAddr:     getEnv("SERVER_ADDR", ":8080"),
-		OllamaURL:      getEnv("OLLAMA_URL", "http://localhost:11434"),
-		OllamaModel:    getEnv("OLLAMA_MODEL", "llama3"),
-		ClaudeAPIKey:   getEnv("CLAUDE_API_KEY", ""),
-		ClaudeFallback: getEnv("CLAUDE_FALLBACK", "true") == "true",
-		DatabaseURL:    getEnv("DATABASE_URL", ""),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		CORSOrigins:    []string{getEnv("CORS_ORIGINS", "http://localhost:5173")},
+		ServerAddr:         getEnv("SERVER_ADDR", ":8080"),
+		StoreID:            getEnv("STORE_ID", ""),
+		OllamaURL:          getEnv("OLLAMA_URL", "http://localhost:11434"),
+		OllamaModel:        getEnv("OLLAMA_MODEL", "llama3"),
+		ClaudeURL:          getEnv("CLAUDE_URL", "https://api.anthropic.com"),
+		ClaudeAPIKey:       getEnv("CLAUDE_API_KEY", ""),
+		ClaudeModel:        getEnv("CLAUDE_MODEL", "claude-sonnet-4-5"),
+		ClaudeFallback:     getEnv("CLAUDE_FALLBACK", "true") == "true",
+		RoutingMode:        getEnv("ROUTING_MODE", "keyword"),
+		AnswerFormat:       getEnv("ANSWER_FORMAT", "structured"),
+		DepartmentsDir:     getEnv("DEPARTMENTS_DIR", ""),
+		KnowledgeDir:       getEnv("KNOWLEDGE_DIR", ""),
+		KnowledgeIndex:     getEnv("KNOWLEDGE_INDEX", ""),
+		EmbeddingModel:     getEnv("EMBEDDING_MODEL", "nomic-embed-text"),
+		DatabaseURL:        getEnv("DATABASE_URL", ""),
+		ConversationDB:     getEnv("CONVERSATION_DB", ""),
+		JWTSecret:          getEnv("JWT_SECRET", ""),
+		AuthDisabled:       getEnv("AUTH_DISABLED", "false") == "true",
+		UsersFile:          getEnv("USERS_FILE", ""),
+		CORSOrigins:        splitList(getEnv("CORS_ORIGINS", "http://localhost:5173")),
+		MockConnector:      getEnv("MOCK_CONNECTOR", "false") == "true",
+		UKGURL:             getEnv("UKG_URL", ""),
+		UKGClientID:        getEnv("UKG_CLIENT_ID", ""),
+		UKGClientSecret:    getEnv("UKG_CLIENT_SECRET", ""),
+		POSFeed:            getEnv("POS_FEED", ""),
+		PeriscopeDir:       getEnv("PERISCOPE_DIR", ""),
+		PeriscopeURL:       getEnv("PERISCOPE_URL", ""),
+		PeriscopeAPIKey:    getEnv("PERISCOPE_API_KEY", ""),
+		SlackBotToken:      getEnv("SLACK_BOT_TOKEN", ""),
+		SlackSigningSecret: getEnv("SLACK_SIGNING_SECRET", ""),
+		SlackAPIURL:        getEnv("SLACK_API_URL", "https://slack.com/api"),
+		TeamsAppID:         getEnv("TEAMS_APP_ID", ""),
+		TeamsAppPassword:   getEnv("TEAMS_APP_PASSWORD", ""),
+		TeamsOpenIDURL:     getEnv("TEAMS_OPENID_URL", ""),
+		TeamsTokenURL:      getEnv("TEAMS_TOKEN_URL", ""),
+		TeamsServiceURL:    getEnv("TEAMS_SERVICE_URL", ""),
+		TeamsTenantID:      getEnv("TEAMS_TENANT_ID", ""),
+		SMSProvider:        getEnv("SMS_PROVIDER", "twilio"),
+		SMSAccountSID:      getEnv("SMS_ACCOUNT_SID", ""),
+		SMSAuthToken:       getEnv("SMS_AUTH_TOKEN", ""),
+		SMSFrom:            getEnv("SMS_FROM", ""),
+		SMSAPIURL:          getEnv("SMS_API_URL", "https://api.twilio.com"),
+		SMSWebhookURL:      getEnv("SMS_WEBHOOK_URL", ""),
+		EmailSMTPHost:      getEnv("EMAIL_SMTP_HOST", ""),
+		EmailSMTPUsername:  getEnv("EMAIL_SMTP_USERNAME", ""),
+		EmailSMTPPassword:  getEnv("EMAIL_SMTP_PASSWORD", ""),
+		EmailSMTPTLS:       getEnv("EMAIL_SMTP_TLS", "starttls"),
+		EmailFrom:          getEnv("EMAIL_FROM", ""),
+		EmailTemplates:     getEnv("EMAIL_TEMPLATES", ""),
+		EmailBaseURL:       strings.TrimSuffix(getEnv("EMAIL_BASE_URL", ""), "/"),
+		EmailDB:            getEnv("EMAIL_DB", ""),
+		NotifyChannels:     splitList(getEnv("NOTIFY_CHANNELS", "")),
+		NotifyMinSeverity:  getEnv("NOTIFY_MIN_SEVERITY", "warning"),
+		NotifyStore:        getEnv("NOTIFY_STORE", ""),
+		NotifyDB:           getEnv("NOTIFY_DB", ""),
+	}
+
+	switch cfg.RoutingMode {
+	case "keyword", "classifier":
+	default:
+		return nil, fmt.Errorf("invalid ROUTING_MODE %q, expected keyword or classifier", cfg.RoutingMode)
+	}
+
+	switch cfg.AnswerFormat {
+	case "structured", "text":
+	default:
+		return nil, fmt.Errorf("invalid ANSWER_FORMAT %q, expected structured or text", cfg.AnswerFormat)
+	}
+
+	windows, err := parseContextWindows(getEnv("CONTEXT_WINDOWS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.ContextWindows = windows
+
+	ttl, err := time.ParseDuration(getEnv("TOKEN_TTL", "12h"))
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("invalid TOKEN_TTL %q, expected a duration such as 12h", getEnv("TOKEN_TTL", ""))
+	}
+	cfg.TokenTTL = ttl
+
+	interval, err := time.ParseDuration(getEnv("ALERT_INTERVAL", "1m"))
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid ALERT_INTERVAL %q, expected a duration such as 1m", getEnv("ALERT_INTERVAL", ""))
+	}
+	cfg.AlertInterval = interval
+
+	probe, err := time.ParseDuration(getEnv("CONNECTOR_PROBE_INTERVAL", "30s"))
+	if err != nil || probe <= 0 {
+		return nil, fmt.Errorf("invalid CONNECTOR_PROBE_INTERVAL %q, expected a duration such as 30s", getEnv("CONNECTOR_PROBE_INTERVAL", ""))
+	}
+	cfg.ConnectorProbeInterval = probe
+
+	if cfg.UKGURL != "" && (cfg.UKGClientID == "" || cfg.UKGClientSecret == "") {
+		return nil, fmt.Errorf("UKG_URL is set but UKG_CLIENT_ID or UKG_CLIENT_SECRET is empty")
+	}
+	ukgInterval, err := time.ParseDuration(getEnv("UKG_SYNC_INTERVAL", "5m"))
+	if err != nil || ukgInterval <= 0 {
+		return nil, fmt.Errorf("invalid UKG_SYNC_INTERVAL %q, expected a duration such as 5m", getEnv("UKG_SYNC_INTERVAL", ""))
+	}
+	cfg.UKGSyncInterval = ukgInterval
+
+	ukgDepartments, err := parsePairs("UKG_DEPARTMENTS", getEnv("UKG_DEPARTMENTS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.UKGDepartments = ukgDepartments
+
+	if cfg.PeriscopeURL != "" && cfg.PeriscopeAPIKey == "" {
+		return nil, fmt.Errorf("PERISCOPE_URL is set but PERISCOPE_API_KEY is empty")
+	}
+	periscopeInterval, err := time.ParseDuration(getEnv("PERISCOPE_INTERVAL", "5m"))
+	if err != nil || periscopeInterval <= 0 {
+		return nil, fmt.Errorf("invalid PERISCOPE_INTERVAL %q, expected a duration such as 5m", getEnv("PERISCOPE_INTERVAL", ""))
+	}
+	cfg.PeriscopeInterval = periscopeInterval
+
+	periscopeDepartments, err := parsePairs("PERISCOPE_DEPARTMENTS", getEnv("PERISCOPE_DEPARTMENTS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.PeriscopeDepartments = periscopeDepartments
+
+	if cfg.SlackBotToken != "" && cfg.SlackSigningSecret == "" {
+		return nil, fmt.Errorf("SLACK_BOT_TOKEN is set but SLACK_SIGNING_SECRET is empty")
+	}
+	slackUsers, err := parsePairs("SLACK_USERS", getEnv("SLACK_USERS", ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(slackUsers) > 0 && cfg.UsersFile == "" {
+		return nil, fmt.Errorf("SLACK_USERS is set but USERS_FILE is empty")
+	}
+	cfg.SlackUsers = slackUsers
+
+	slackChannels, err := parsePairs("SLACK_ALERT_CHANNELS", getEnv("SLACK_ALERT_CHANNELS", ""))
+	if err != nil {
+		return nil, err
 	}
+	cfg.SlackAlertChannels = slackChannels
+
+	if cfg.TeamsAppID != "" && cfg.TeamsAppPassword == "" {
+		return nil, fmt.Errorf("TEAMS_APP_ID is set but TEAMS_APP_PASSWORD is empty")
+	}
+	teamsUsers, err := parsePairs("TEAMS_USERS", getEnv("TEAMS_USERS", ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(teamsUsers) > 0 && cfg.UsersFile == "" {
+		return nil, fmt.Errorf("TEAMS_USERS is set but USERS_FILE is empty")
+	}
+	cfg.TeamsUsers = teamsUsers
+
+	teamsChannels, err := parsePairs("TEAMS_ALERT_CHANNELS", getEnv("TEAMS_ALERT_CHANNELS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TeamsAlertChannels = teamsChannels
+
+	if cfg.SMSAccountSID != "" {
+		if cfg.SMSProvider != "twilio" {
+			return nil, fmt.Errorf("unknown SMS_PROVIDER %q", cfg.SMSProvider)
+		}
+		if cfg.SMSAuthToken == "" || cfg.SMSFrom == "" {
+			return nil, fmt.Errorf("SMS_ACCOUNT_SID is set but SMS_AUTH_TOKEN or SMS_FROM is empty")
+		}
+	}
+	smsMaxLength, err := strconv.Atoi(getEnv("SMS_MAX_LENGTH", "306"))
+	if err != nil || smsMaxLength < 70 || smsMaxLength > 1600 {
+		return nil, fmt.Errorf("invalid SMS_MAX_LENGTH, expected 70 to 1600 characters")
+	}
+	cfg.SMSMaxLength = smsMaxLength
+
+	smsUsers, err := parsePairs("SMS_USERS", getEnv("SMS_USERS", ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(smsUsers) > 0 && cfg.UsersFile == "" {
+		return nil, fmt.Errorf("SMS_USERS is set but USERS_FILE is empty")
+	}
+	cfg.SMSUsers = smsUsers
+
+	smsOnCall, err := parsePairs("SMS_ONCALL", getEnv("SMS_ONCALL", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.SMSOnCall = smsOnCall
+
+	if cfg.EmailSMTPHost != "" {
+		if cfg.EmailFrom == "" {
+			return nil, fmt.Errorf("EMAIL_SMTP_HOST is set but EMAIL_FROM is empty")
+		}
+		if cfg.UsersFile == "" {
+			return nil, fmt.Errorf("EMAIL_SMTP_HOST is set but USERS_FILE is empty")
+		}
+	}
+	switch cfg.EmailSMTPTLS {
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("invalid EMAIL_SMTP_TLS %q, expected starttls, tls or none", cfg.EmailSMTPTLS)
+	}
+	defaultPort := "587"
+	if cfg.EmailSMTPTLS == "tls" {
+		defaultPort = "465"
+	}
+	emailPort, err := strconv.Atoi(getEnv("EMAIL_SMTP_PORT", defaultPort))
+	if err != nil || emailPort < 1 || emailPort > 65535 {
+		return nil, fmt.Errorf("invalid EMAIL_SMTP_PORT %q", getEnv("EMAIL_SMTP_PORT", ""))
+	}
+	cfg.EmailSMTPPort = emailPort
+
+	digestAt, err := time.Parse("15:04", getEnv("EMAIL_DIGEST_AT", "06:30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_DIGEST_AT %q, expected a time such as 06:30", getEnv("EMAIL_DIGEST_AT", ""))
+	}
+	cfg.EmailDigestAt = time.Duration(digestAt.Hour())*time.Hour + time.Duration(digestAt.Minute())*time.Minute
+
+	tiers, err := parseTiers(getEnv("NOTIFY_TIERS", ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(tiers) > 0 && cfg.UsersFile == "" {
+		return nil, fmt.Errorf("NOTIFY_TIERS is set but USERS_FILE is empty")
+	}
+	cfg.NotifyTiers = tiers
+
+	notifyOnCall, err := parsePairs("NOTIFY_ONCALL", getEnv("NOTIFY_ONCALL", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.NotifyOnCall = notifyOnCall
 
 	return cfg, nil
 }
 
+// parseTiers reads an escalation policy like
+// "assistant_manager=10m,department_manager=15m,store_manager". The last
+// tier has nobody to escalate to, so its wait can be left out.
+func parseTiers(value string) ([]EscalationTier, error) {
+	var tiers []EscalationTier
+	for _, entry := range splitList(value) {
+		name, wait, ok := strings.Cut(entry, "=")
+		tier := EscalationTier{Name: strings.TrimSpace(name)}
+		if ok {
+			d, err := time.ParseDuration(strings.TrimSpace(wait))
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid NOTIFY_TIERS wait for %s: %q", tier.Name, wait)
+			}
+			tier.Wait = d
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// parseContextWindows reads a list like "llama3=8192,mistral=32768"
+func parseContextWindows(value string) (map[string]int, error) {
+	windows := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, tokens, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid CONTEXT_WINDOWS entry %q, expected model=tokens", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(tokens))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid context window for %s: %q", model, tokens)
+		}
+		windows[strings.TrimSpace(model)] = n
+	}
+	return windows, nil
+}
+
+// parsePairs reads a list like "Meat & Seafood=meat,Front End=frontend"
+func parsePairs(name, value string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(entry, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || key == "" || val == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected name=value", name, entry)
+		}
+		pairs[key] = val
+	}
+	return pairs, nil
+}
+
+// AllowsOrigin reports whether origin is one of CORSOrigins
+func (c *Config) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.CORSOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList reads a comma-separated list, ignoring empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
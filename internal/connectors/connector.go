@@ -0,0 +1,54 @@
+package connectors
+
+import (
+	"context"
+	"time"
+)
+
+// Connector links Opus to an external system such as a POS, inventory or
+// workforce system.
+//
+// Connect returns once the connector is ready, starting any background
+// syncing it does; Disconnect stops it. The registry calls Health
+// periodically while connected and reconnects after repeated failures.
+type Connector interface {
+	Name() string
+	Connect(ctx context.Context) error
+	Disconnect() error
+	Health() HealthStatus
+}
+
+// HealthStatus is a connector's own report on its link to the external
+// system
+type HealthStatus struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+	// Details are connector-specific, e.g. when it last synced
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// State is where a connector is in the registry's lifecycle
+type State string
+
+const (
+	StateConnecting   State = "connecting"
+	StateConnected    State = "connected"
+	StateDegraded     State = "degraded"
+	StateReconnecting State = "reconnecting"
+	StateStopped      State = "stopped"
+)
+
+// Status is a snapshot of a connector for the status endpoint
+type Status struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	// Health is the latest probe, taken while connected
+	Health      *HealthStatus `json:"health,omitempty"`
+	CheckedAt   *time.Time    `json:"checkedAt,omitempty"`
+	ConnectedAt *time.Time    `json:"connectedAt,omitempty"`
+	// Failures counts failed connects or probes since it was last healthy
+	Failures    int        `json:"failures"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty"`
+}
@@ -0,0 +1,22 @@
+package connectors
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DepartmentID maps an external system's department name to an Opus
+// department ID. Names without an entry in overrides are lowercased with
+// spaces and punctuation removed, so "Front End" becomes "frontend".
+func DepartmentID(name string, overrides map[string]string) string {
+	name = strings.TrimSpace(name)
+	if dept, ok := overrides[name]; ok {
+		return dept
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
@@ -0,0 +1,91 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Mock is an in-process connector for demos and tests. Connect fails as
+// many times as FailConnects says, and Health reports whatever SetHealthy
+// last set.
+type Mock struct {
+	name string
+
+	mu           sync.Mutex
+	connected    bool
+	healthy      bool
+	message      string
+	failConnects int
+	connects     int
+	connectedAt  time.Time
+}
+
+// NewMock creates a healthy mock connector
+func NewMock(name string) *Mock {
+	return &Mock{name: name, healthy: true}
+}
+
+func (m *Mock) Name() string { return m.name }
+
+// Connect succeeds unless failures are still scripted
+func (m *Mock) Connect(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connects++
+	if m.failConnects > 0 {
+		m.failConnects--
+		return errors.New("mock connection refused")
+	}
+	m.connected = true
+	m.connectedAt = time.Now()
+	return nil
+}
+
+func (m *Mock) Disconnect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected = false
+	return nil
+}
+
+func (m *Mock) Health() HealthStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.connected {
+		return HealthStatus{Message: "not connected"}
+	}
+	return HealthStatus{
+		Healthy: m.healthy,
+		Message: m.message,
+		Details: map[string]interface{}{
+			"connects":    m.connects,
+			"connectedAt": m.connectedAt,
+		},
+	}
+}
+
+// FailConnects makes the next n Connect calls fail
+func (m *Mock) FailConnects(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failConnects = n
+}
+
+// SetHealthy sets what Health reports while connected
+func (m *Mock) SetHealthy(healthy bool, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.healthy = healthy
+	m.message = message
+}
+
+// Connected reports whether the mock is connected
+func (m *Mock) Connected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.connected
+}
@@ -0,0 +1,334 @@
+// Package periscope imports item master, on-hand counts, production plans
+// and shrink from Periscope exports into the inventory and production
+// stores. Exports are CSV files dropped in a watched directory, or pulled
+// from the Periscope REST API, and every import is recorded as a run with
+// its rejected rows.
+package periscope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/imports"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/production"
+)
+
+const (
+	// settle is how long a dropped file must go unmodified before it is
+	// read, so files still being copied in are left alone
+	settle = 5 * time.Second
+	// Subdirectories of Dir that imported files are moved to
+	processedDir = "processed"
+	failedDir    = "failed"
+)
+
+// Config configures the Periscope connector. At least one of Dir and URL
+// must be set.
+type Config struct {
+	// Dir is watched for CSV exports named after their kind, e.g.
+	// items_20261017.csv or onhand_20261017.csv
+	Dir string
+	// URL is the Periscope API base URL, from which each kind is pulled
+	// as {URL}/exports/{kind}.csv with APIKey as a bearer token
+	URL    string
+	APIKey string
+	// Interval is how often Dir is scanned and URL pulled
+	Interval time.Duration
+	// Departments maps Periscope department names to Opus department IDs
+	// when they don't match after lowercasing
+	Departments map[string]string
+	HTTPClient  *http.Client
+}
+
+// Connector imports Periscope exports
+type Connector struct {
+	config     Config
+	reconciler *reconciler
+	runs       imports.Repository
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	etags     map[string]string
+	lastPoll  time.Time
+	lastError error
+	imported  int
+}
+
+// New creates a Periscope connector writing into items and plans, and
+// recording runs
+func New(cfg Config, items inventory.Repository, plans production.Repository, runs imports.Repository) *Connector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 2 * time.Minute}
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+
+	return &Connector{
+		config: cfg,
+		reconciler: &reconciler{
+			items:       items,
+			production:  plans,
+			departments: cfg.Departments,
+		},
+		runs:  runs,
+		etags: make(map[string]string),
+	}
+}
+
+func (c *Connector) Name() string { return "periscope" }
+
+// Connect checks the drop directory, then imports every Interval until
+// Disconnect
+func (c *Connector) Connect(ctx context.Context) error {
+	if c.config.Dir != "" {
+		if info, err := os.Stat(c.config.Dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("drop directory %s is not a directory", c.config.Dir)
+		}
+		for _, dir := range []string{processedDir, failedDir} {
+			if err := os.MkdirAll(filepath.Join(c.config.Dir, dir), 0o755); err != nil {
+				return fmt.Errorf("failed to create %s directory: %w", dir, err)
+			}
+		}
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.run(loopCtx)
+	return nil
+}
+
+func (c *Connector) Disconnect() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+		c.cancel = nil
+	}
+	return nil
+}
+
+func (c *Connector) run(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+	for {
+		c.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Health is unhealthy while the last scan or pull failed
+func (c *Connector) Health() connectors.HealthStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: c.lastError == nil,
+		Details: map[string]interface{}{
+			"imports": c.imported,
+		},
+	}
+	if !c.lastPoll.IsZero() {
+		status.Details["lastPoll"] = c.lastPoll
+	}
+	if c.lastError != nil {
+		status.Message = c.lastError.Error()
+	}
+	return status
+}
+
+// Poll imports any settled files in Dir and any changed exports at URL
+func (c *Connector) Poll(ctx context.Context) {
+	var errs []error
+	if c.config.Dir != "" {
+		if err := c.scan(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.config.URL != "" {
+		for _, kind := range kinds {
+			if err := c.pull(ctx, kind); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	err := errors.Join(errs...)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Periscope import failed: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastPoll = time.Now()
+	c.lastError = err
+}
+
+// scan imports the settled exports in Dir, item master first, moving each
+// to processed/ or, if nothing in it could be applied, failed/
+func (c *Connector) scan(ctx context.Context) error {
+	entries, err := os.ReadDir(c.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read drop directory: %w", err)
+	}
+
+	type drop struct {
+		name, kind string
+	}
+	var drops []drop
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < settle {
+			continue
+		}
+		drops = append(drops, drop{name: entry.Name(), kind: kindOf(entry.Name())})
+	}
+	slices.SortFunc(drops, func(a, b drop) int {
+		if ka, kb := slices.Index(kinds, a.kind), slices.Index(kinds, b.kind); ka != kb {
+			return ka - kb
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	for _, d := range drops {
+		if ctx.Err() != nil {
+			return nil
+		}
+		path := filepath.Join(c.config.Dir, d.name)
+		run := c.importFile(ctx, path, d.kind)
+
+		dest := processedDir
+		if run.Status == imports.StatusFailed {
+			dest = failedDir
+		}
+		moved := filepath.Join(c.config.Dir, dest, time.Now().Format("20060102T150405")+"-"+d.name)
+		if err := os.Rename(path, moved); err != nil {
+			return fmt.Errorf("failed to move %s: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Connector) importFile(ctx context.Context, path, kind string) *imports.Run {
+	run := c.startRun(ctx, kind, filepath.Base(path))
+	if kind == "" {
+		c.finishRun(ctx, run, errors.New("unknown export; file names must start with items, item_master, onhand, on_hand, production or shrink"))
+		return run
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		c.finishRun(ctx, run, err)
+		return run
+	}
+	defer f.Close()
+	c.finishRun(ctx, run, c.importTable(ctx, f, kind, run))
+	return run
+}
+
+// pull downloads one kind of export unless it hasn't changed since the
+// last pull
+func (c *Connector) pull(ctx context.Context, kind string) error {
+	url := c.config.URL + "/exports/" + kind + ".csv"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	req.Header.Set("Accept", "text/csv")
+	c.mu.Lock()
+	if etag := c.etags[kind]; etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	c.mu.Unlock()
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to pull %s export: %w", kind, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pull of %s export failed: %s %s", kind, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	run := c.startRun(ctx, kind, url)
+	err = c.importTable(ctx, resp.Body, kind, run)
+	c.finishRun(ctx, run, err)
+
+	// A failed run is tried again next time rather than skipped as
+	// unchanged
+	if run.Status != imports.StatusFailed {
+		c.mu.Lock()
+		c.etags[kind] = resp.Header.Get("ETag")
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+func (c *Connector) importTable(ctx context.Context, r io.Reader, kind string, run *imports.Run) error {
+	t, err := readTable(r, kind)
+	if err != nil {
+		return err
+	}
+	return c.reconciler.apply(ctx, kind, t, run)
+}
+
+func (c *Connector) startRun(ctx context.Context, kind, origin string) *imports.Run {
+	run := &imports.Run{
+		Source:    c.Name(),
+		Kind:      kind,
+		Origin:    origin,
+		Status:    imports.StatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := c.runs.Save(ctx, run); err != nil {
+		log.Printf("Failed to record import run: %v", err)
+	}
+	return run
+}
+
+func (c *Connector) finishRun(ctx context.Context, run *imports.Run, err error) {
+	run.Finish(err)
+	if err := c.runs.Save(ctx, run); err != nil {
+		log.Printf("Failed to record import run: %v", err)
+	}
+	if run.Error != "" {
+		log.Printf("Periscope import from %s failed: %s", run.Origin, run.Error)
+	} else {
+		log.Printf("Periscope import from %s %s: %d rows, %d created, %d updated, %d unchanged, %d rejected",
+			run.Origin, run.Status, run.Rows, run.Created, run.Updated, run.Unchanged, run.Rejected)
+	}
+
+	c.mu.Lock()
+	c.imported++
+	c.mu.Unlock()
+}
@@ -0,0 +1,169 @@
+package periscope
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export kinds, in the order they're imported so items exist before their
+// counts, plans and shrink
+const (
+	KindItems      = "items"
+	KindOnHand     = "onhand"
+	KindProduction = "production"
+	KindShrink     = "shrink"
+)
+
+var kinds = []string{KindItems, KindOnHand, KindProduction, KindShrink}
+
+// Columns, as they appear in export headers
+const (
+	colItemCode    = "ITEM_CODE"
+	colUPC         = "UPC"
+	colDescription = "DESCRIPTION"
+	colDepartment  = "DEPARTMENT"
+	colUnit        = "UOM"
+	colReorder     = "REORDER_POINT"
+	colPar         = "PAR_LEVEL"
+	colOnHand      = "ON_HAND_QTY"
+	colLot         = "LOT_NUMBER"
+	colExpiration  = "EXPIRATION_DATE"
+	colPlanDate    = "PLAN_DATE"
+	colPlanned     = "PLANNED_QTY"
+	colProduced    = "PRODUCED_QTY"
+	colEntryID     = "ENTRY_ID"
+	colEntryDate   = "ENTRY_DATE"
+	colQuantity    = "QTY"
+	colReason      = "REASON"
+	colCost        = "COST"
+)
+
+// requiredColumns are the columns each export must have; others are
+// optional
+var requiredColumns = map[string][]string{
+	KindItems:      {colItemCode, colDescription, colDepartment, colUnit},
+	KindOnHand:     {colItemCode, colOnHand},
+	KindProduction: {colPlanDate, colItemCode, colPlanned},
+	KindShrink:     {colEntryID, colEntryDate, colItemCode, colQuantity},
+}
+
+// filePrefixes name the kind of a dropped file, e.g. onhand_20261017.csv
+var filePrefixes = []struct{ prefix, kind string }{
+	{"item_master", KindItems},
+	{"items", KindItems},
+	{"on_hand", KindOnHand},
+	{"onhand", KindOnHand},
+	{"production", KindProduction},
+	{"shrink", KindShrink},
+}
+
+// kindOf returns the kind of export a file holds, or "" if it isn't one
+func kindOf(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	if filepath.Ext(name) != ".csv" {
+		return ""
+	}
+	for _, p := range filePrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.kind
+		}
+	}
+	return ""
+}
+
+// table is a parsed export
+type table struct {
+	columns map[string]int
+	rows    []row
+}
+
+// row is one record of a table with its line in the file
+type row struct {
+	line   int
+	fields []string
+	table  *table
+}
+
+// get returns the row's value in column, or "" if the export doesn't have
+// it
+func (r row) get(column string) string {
+	i, ok := r.table.columns[column]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[i])
+}
+
+// readTable parses a CSV export, checking it has kind's required columns.
+// Headers are matched case-insensitively, with spaces read as
+// underscores.
+func readTable(r io.Reader, kind string) (*table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	t := &table{columns: make(map[string]int, len(header))}
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		name = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+		t.columns[name] = i
+	}
+	var missing []string
+	for _, column := range requiredColumns[kind] {
+		if _, ok := t.columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+		t.rows = append(t.rows, row{line: line, fields: fields, table: t})
+	}
+}
+
+// parseNumber reads a quantity or amount, allowing thousands separators
+// and a leading currency sign
+func parseNumber(value string) (float64, error) {
+	cleaned := strings.TrimPrefix(strings.ReplaceAll(value, ",", ""), "$")
+	return strconv.ParseFloat(cleaned, 64)
+}
+
+// dateLayouts are the date formats exports use
+var dateLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006", "20060102"}
+
+// parseDate reads a date into YYYY-MM-DD
+func parseDate(value string) (string, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.DateOnly), nil
+		}
+	}
+	return "", errors.New("invalid date")
+}
@@ -0,0 +1,105 @@
+package periscope
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/drop/items_20261017.csv", KindItems},
+		{"Item_Master.CSV", KindItems},
+		{"on_hand_store12.csv", KindOnHand},
+		{"onhand.csv", KindOnHand},
+		{"production-week42.csv", KindProduction},
+		{"shrink_20261017.csv", KindShrink},
+		{"items_20261017.xlsx", ""},
+		{"sales_20261017.csv", ""},
+	}
+	for _, tt := range tests {
+		if got := kindOf(tt.path); got != tt.want {
+			t.Errorf("kindOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	csv := "\ufeffItem Code, on_hand_qty ,Lot Number\nD-100,12,L1\n\nD-200,3\n"
+	tbl, err := readTable(strings.NewReader(csv), KindOnHand)
+	if err != nil {
+		t.Fatalf("readTable: %v", err)
+	}
+	if len(tbl.rows) != 2 {
+		t.Fatalf("got %d rows, want the blank line skipped", len(tbl.rows))
+	}
+	first, second := tbl.rows[0], tbl.rows[1]
+	if first.line != 2 || first.get(colItemCode) != "D-100" || first.get(colOnHand) != "12" || first.get(colLot) != "L1" {
+		t.Errorf("first row = line %d %v", first.line, first.fields)
+	}
+	// A short row reads its missing columns as empty
+	if second.line != 4 || second.get(colLot) != "" || second.get(colExpiration) != "" {
+		t.Errorf("second row = line %d %v", second.line, second.fields)
+	}
+}
+
+func TestReadTableErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{"empty", "", "file is empty"},
+		{"missing columns", "ITEM_CODE,DESCRIPTION\nD-100,Milk\n", "missing required columns: DEPARTMENT, UOM"},
+		{"bad quoting", "ITEM_CODE,DESCRIPTION,DEPARTMENT,UOM\nD-100,\"Milk,Dairy,EA\n", "invalid CSV"},
+	}
+	for _, tt := range tests {
+		_, err := readTable(strings.NewReader(tt.csv), KindItems)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"12", 12, true},
+		{"2.5", 2.5, true},
+		{"1,200", 1200, true},
+		{"$3.99", 3.99, true},
+		{"twelve", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseNumber(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseNumber(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"2026-03-07", "2026-03-07"},
+		{"03/07/2026", "2026-03-07"},
+		{"3/7/2026", "2026-03-07"},
+		{"20260307", "2026-03-07"},
+		{"March 7", ""},
+		{"2026-02-30", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.value)
+		if got != tt.want || (err == nil) != (tt.want != "") {
+			t.Errorf("parseDate(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
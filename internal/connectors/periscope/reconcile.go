@@ -0,0 +1,460 @@
+package periscope
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/imports"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/production"
+)
+
+// saveBatch is how many records are saved at a time
+const saveBatch = 500
+
+// reconciler applies exports to the stores, saving only records that
+// changed
+type reconciler struct {
+	items       inventory.Repository
+	production  production.Repository
+	departments map[string]string
+}
+
+func (rc *reconciler) apply(ctx context.Context, kind string, t *table, run *imports.Run) error {
+	run.Rows = len(t.rows)
+	switch kind {
+	case KindItems:
+		return rc.applyItems(ctx, t, run)
+	case KindOnHand:
+		return rc.applyOnHand(ctx, t, run)
+	case KindProduction:
+		return rc.applyProduction(ctx, t, run)
+	case KindShrink:
+		return rc.applyShrink(ctx, t, run)
+	}
+	return fmt.Errorf("unknown export kind %q", kind)
+}
+
+// loadItems returns every item by SKU
+func (rc *reconciler) loadItems(ctx context.Context) (map[string]inventory.Item, error) {
+	items := make(map[string]inventory.Item)
+	q := inventory.Query{Limit: inventory.MaxLimit}
+	for {
+		page, err := rc.items.List(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load inventory: %w", err)
+		}
+		for _, item := range page.Items {
+			items[item.SKU] = item
+		}
+		q.Offset += len(page.Items)
+		if len(page.Items) == 0 || q.Offset >= page.Total {
+			return items, nil
+		}
+	}
+}
+
+// number reads a numeric column, rejecting the row if it isn't one. An
+// empty optional column reads as fallback.
+func number(r row, column string, required bool, fallback float64, run *imports.Run) (float64, bool) {
+	value := r.get(column)
+	if value == "" {
+		if required {
+			run.Reject(r.line, column, "", "%s is required", column)
+			return 0, false
+		}
+		return fallback, true
+	}
+	n, err := parseNumber(value)
+	if err != nil || n < 0 {
+		run.Reject(r.line, column, value, "%s must be a number of at least zero", column)
+		return 0, false
+	}
+	return n, true
+}
+
+// text reads a required text column, rejecting the row if it is empty
+func text(r row, column string, run *imports.Run) (string, bool) {
+	value := r.get(column)
+	if value == "" {
+		run.Reject(r.line, column, "", "%s is required", column)
+		return "", false
+	}
+	return value, true
+}
+
+// date reads a required date column into YYYY-MM-DD
+func date(r row, column string, run *imports.Run) (string, bool) {
+	value, ok := text(r, column, run)
+	if !ok {
+		return "", false
+	}
+	parsed, err := parseDate(value)
+	if err != nil {
+		run.Reject(r.line, column, value, "%s must be a date such as 2026-03-07 or 03/07/2026", column)
+		return "", false
+	}
+	return parsed, true
+}
+
+// applyItems updates the item master fields of items, creating new ones
+// with nothing on hand
+func (rc *reconciler) applyItems(ctx context.Context, t *table, run *imports.Run) error {
+	existing, err := rc.loadItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	seen := make(map[string]int)
+	var changed []inventory.Item
+	for _, r := range t.rows {
+		sku, ok := text(r, colItemCode, run)
+		if !ok {
+			continue
+		}
+		if line, dup := seen[sku]; dup {
+			run.Reject(r.line, colItemCode, sku, "duplicate item, first listed on line %d", line)
+			continue
+		}
+		description, ok := text(r, colDescription, run)
+		if !ok {
+			continue
+		}
+		department, ok := text(r, colDepartment, run)
+		if !ok {
+			continue
+		}
+		unit, ok := text(r, colUnit, run)
+		if !ok {
+			continue
+		}
+		reorder, ok := number(r, colReorder, false, 0, run)
+		if !ok {
+			continue
+		}
+		par, ok := number(r, colPar, false, 0, run)
+		if !ok {
+			continue
+		}
+		seen[sku] = r.line
+
+		item, found := existing[sku]
+		updated := item
+		updated.SKU = sku
+		updated.Description = description
+		updated.Department = connectors.DepartmentID(department, rc.departments)
+		updated.Unit = unit
+		if upc := r.get(colUPC); upc != "" {
+			updated.UPC = upc
+		}
+		if r.get(colReorder) != "" {
+			updated.ReorderPoint = reorder
+		}
+		if r.get(colPar) != "" {
+			updated.ParLevel = par
+		}
+
+		switch {
+		case !found:
+			run.Created++
+		case sameMaster(&item, &updated):
+			run.Unchanged++
+			continue
+		default:
+			run.Updated++
+		}
+		updated.UpdatedAt = now
+		changed = append(changed, updated)
+	}
+	return rc.saveItems(ctx, changed)
+}
+
+func sameMaster(a, b *inventory.Item) bool {
+	return a.UPC == b.UPC && a.Description == b.Description && a.Department == b.Department &&
+		a.Unit == b.Unit && a.ReorderPoint == b.ReorderPoint && a.ParLevel == b.ParLevel
+}
+
+// applyOnHand replaces the counted items' on-hand quantity and lots. An
+// item is only updated if all of its rows are valid, since a partial
+// count would understate it.
+func (rc *reconciler) applyOnHand(ctx context.Context, t *table, run *imports.Run) error {
+	existing, err := rc.loadItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	type count struct {
+		onHand   float64
+		lots     []inventory.Lot
+		lines    []int
+		rejected int
+	}
+	var order []string
+	counts := make(map[string]*count)
+	for _, r := range t.rows {
+		sku, ok := text(r, colItemCode, run)
+		if !ok {
+			continue
+		}
+		c, ok := counts[sku]
+		if !ok {
+			c = &count{}
+			counts[sku] = c
+			order = append(order, sku)
+		}
+
+		if _, known := existing[sku]; !known {
+			run.Reject(r.line, colItemCode, sku, "unknown item; import it in the item master first")
+			c.rejected = r.line
+			continue
+		}
+		qty, ok := number(r, colOnHand, true, 0, run)
+		if !ok {
+			c.rejected = r.line
+			continue
+		}
+		lot := inventory.Lot{Number: r.get(colLot), Quantity: qty}
+		if value := r.get(colExpiration); value != "" {
+			expires, err := parseDate(value)
+			if err != nil {
+				run.Reject(r.line, colExpiration, value, "%s must be a date such as 2026-03-07 or 03/07/2026", colExpiration)
+				c.rejected = r.line
+				continue
+			}
+			lot.ExpiresOn = expires
+			if lot.Number == "" {
+				lot.Number = sku + "-" + expires
+			}
+		}
+
+		c.onHand += qty
+		c.lines = append(c.lines, r.line)
+		if lot.Number != "" {
+			c.lots = append(c.lots, lot)
+		}
+	}
+
+	now := time.Now().UTC()
+	var changed []inventory.Item
+	for _, sku := range order {
+		c := counts[sku]
+		if c.rejected > 0 {
+			for _, line := range c.lines {
+				run.Reject(line, colItemCode, sku, "item not updated because line %d was rejected", c.rejected)
+			}
+			continue
+		}
+
+		item := existing[sku]
+		slices.SortFunc(c.lots, func(a, b inventory.Lot) int { return cmp.Compare(a.Number, b.Number) })
+		current := slices.Clone(item.Lots)
+		slices.SortFunc(current, func(a, b inventory.Lot) int { return cmp.Compare(a.Number, b.Number) })
+		if item.OnHand == c.onHand && slices.Equal(current, c.lots) {
+			run.Unchanged++
+			continue
+		}
+		item.OnHand = c.onHand
+		item.Lots = c.lots
+		item.UpdatedAt = now
+		run.Updated++
+		changed = append(changed, item)
+	}
+	return rc.saveItems(ctx, changed)
+}
+
+func (rc *reconciler) saveItems(ctx context.Context, items []inventory.Item) error {
+	for batch := range slices.Chunk(items, saveBatch) {
+		if err := rc.items.Save(ctx, batch...); err != nil {
+			return fmt.Errorf("failed to save items: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyProduction saves production plans by date and item. The
+// department and description come from the item master when the export
+// leaves them out.
+func (rc *reconciler) applyProduction(ctx context.Context, t *table, run *imports.Run) error {
+	items, err := rc.loadItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var plans []production.Plan
+	seen := make(map[string]int)
+	for _, r := range t.rows {
+		day, ok := date(r, colPlanDate, run)
+		if !ok {
+			continue
+		}
+		sku, ok := text(r, colItemCode, run)
+		if !ok {
+			continue
+		}
+		planned, ok := number(r, colPlanned, true, 0, run)
+		if !ok {
+			continue
+		}
+		produced, ok := number(r, colProduced, false, 0, run)
+		if !ok {
+			continue
+		}
+
+		item, known := items[sku]
+		p := production.Plan{
+			Date:        day,
+			SKU:         sku,
+			Description: cmp.Or(r.get(colDescription), item.Description),
+			Department:  item.Department,
+			Planned:     planned,
+			Produced:    produced,
+			Unit:        cmp.Or(r.get(colUnit), item.Unit),
+			UpdatedAt:   now,
+		}
+		if dept := r.get(colDepartment); dept != "" {
+			p.Department = connectors.DepartmentID(dept, rc.departments)
+		}
+		if !known && p.Department == "" {
+			run.Reject(r.line, colItemCode, sku, "unknown item and no %s given", colDepartment)
+			continue
+		}
+		if line, dup := seen[p.Key()]; dup {
+			run.Reject(r.line, colItemCode, sku, "duplicate plan for %s, first listed on line %d", day, line)
+			continue
+		}
+		seen[p.Key()] = r.line
+		plans = append(plans, p)
+	}
+	if len(plans) == 0 {
+		return nil
+	}
+
+	from, to := plans[0].Date, plans[0].Date
+	for _, p := range plans {
+		from, to = min(from, p.Date), max(to, p.Date)
+	}
+	current, err := rc.production.Plans(ctx, "", from, to)
+	if err != nil {
+		return fmt.Errorf("failed to load production plans: %w", err)
+	}
+	byKey := make(map[string]production.Plan, len(current))
+	for _, p := range current {
+		byKey[p.Key()] = p
+	}
+
+	var changed []production.Plan
+	for _, p := range plans {
+		old, found := byKey[p.Key()]
+		old.UpdatedAt = p.UpdatedAt
+		switch {
+		case !found:
+			run.Created++
+		case old == p:
+			run.Unchanged++
+			continue
+		default:
+			run.Updated++
+		}
+		changed = append(changed, p)
+	}
+	if err := rc.production.SavePlans(ctx, changed...); err != nil {
+		return fmt.Errorf("failed to save production plans: %w", err)
+	}
+	return nil
+}
+
+// applyShrink saves shrink entries by ID, in the department of their item
+func (rc *reconciler) applyShrink(ctx context.Context, t *table, run *imports.Run) error {
+	items, err := rc.loadItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var entries []production.Shrink
+	seen := make(map[string]int)
+	for _, r := range t.rows {
+		id, ok := text(r, colEntryID, run)
+		if !ok {
+			continue
+		}
+		if line, dup := seen[id]; dup {
+			run.Reject(r.line, colEntryID, id, "duplicate entry, first listed on line %d", line)
+			continue
+		}
+		day, ok := date(r, colEntryDate, run)
+		if !ok {
+			continue
+		}
+		sku, ok := text(r, colItemCode, run)
+		if !ok {
+			continue
+		}
+		item, known := items[sku]
+		if !known {
+			run.Reject(r.line, colItemCode, sku, "unknown item; import it in the item master first")
+			continue
+		}
+		qty, ok := number(r, colQuantity, true, 0, run)
+		if !ok {
+			continue
+		}
+		cost, ok := number(r, colCost, false, 0, run)
+		if !ok {
+			continue
+		}
+		seen[id] = r.line
+		entries = append(entries, production.Shrink{
+			ID:         id,
+			Date:       day,
+			SKU:        sku,
+			Department: item.Department,
+			Quantity:   qty,
+			Reason:     r.get(colReason),
+			Cost:       cost,
+			UpdatedAt:  now,
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	from, to := entries[0].Date, entries[0].Date
+	for _, e := range entries {
+		from, to = min(from, e.Date), max(to, e.Date)
+	}
+	current, err := rc.production.Shrink(ctx, "", from, to)
+	if err != nil {
+		return fmt.Errorf("failed to load shrink: %w", err)
+	}
+	byID := make(map[string]production.Shrink, len(current))
+	for _, e := range current {
+		byID[e.ID] = e
+	}
+
+	var changed []production.Shrink
+	for _, e := range entries {
+		old, found := byID[e.ID]
+		old.UpdatedAt = e.UpdatedAt
+		switch {
+		case !found:
+			run.Created++
+		case old == e:
+			run.Unchanged++
+			continue
+		default:
+			run.Updated++
+		}
+		changed = append(changed, e)
+	}
+	if err := rc.production.SaveShrink(ctx, changed...); err != nil {
+		return fmt.Errorf("failed to save shrink: %w", err)
+	}
+	return nil
+}
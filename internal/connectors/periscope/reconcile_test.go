@@ -0,0 +1,224 @@
+package periscope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dokk-dev/opus/internal/imports"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/production"
+)
+
+// itemsCSV has three good items and three rejected rows: no item code, a
+// duplicate and a negative reorder point
+const itemsCSV = `ITEM_CODE,UPC,DESCRIPTION,DEPARTMENT,UOM,REORDER_POINT,PAR_LEVEL
+D-100,041900076641,Whole Milk,Dairy,EA,20,40
+D-200,,Butter,Dairy,EA,,
+B-100,,Sourdough,Bakery & Cafe,EA,3,8
+,,No Code,Dairy,EA,,
+D-100,,Whole Milk,Dairy,EA,,
+D-400,,Cream,Dairy,EA,-1,
+`
+
+// testReconciler returns a reconciler whose inventory holds itemsCSV
+func testReconciler(t *testing.T) *reconciler {
+	t.Helper()
+	rc := &reconciler{
+		items:       inventory.NewMemoryRepository(),
+		production:  production.NewMemoryRepository(),
+		departments: map[string]string{"Bakery & Cafe": "bakery"},
+	}
+	run := reconcile(t, rc, KindItems, itemsCSV)
+	if run.Created != 3 || run.Status != imports.StatusPartial {
+		t.Fatalf("item master created %d items, status %s, want 3, partial", run.Created, run.Status)
+	}
+	return rc
+}
+
+// reconcile applies an export the way an import run does
+func reconcile(t *testing.T, rc *reconciler, kind, csv string) *imports.Run {
+	t.Helper()
+	tbl, err := readTable(strings.NewReader(csv), kind)
+	if err != nil {
+		t.Fatalf("readTable: %v", err)
+	}
+	run := &imports.Run{Kind: kind}
+	run.Finish(rc.apply(context.Background(), kind, tbl, run))
+	return run
+}
+
+// rejected lists a run's row errors as line:field
+func rejected(run *imports.Run) string {
+	var list []string
+	for _, e := range run.Errors {
+		list = append(list, fmt.Sprintf("%d:%s", e.Line, e.Field))
+	}
+	return strings.Join(list, ",")
+}
+
+// counts summarizes a run for comparison
+func counts(run *imports.Run) string {
+	return fmt.Sprintf("rows %d, created %d, updated %d, unchanged %d, rejected %s",
+		run.Rows, run.Created, run.Updated, run.Unchanged, rejected(run))
+}
+
+func TestApplyItems(t *testing.T) {
+	rc := testReconciler(t)
+	ctx := context.Background()
+
+	milk, err := rc.items.Get(ctx, "D-100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if milk.UPC != "041900076641" || milk.Department != "dairy" || milk.ReorderPoint != 20 || milk.ParLevel != 40 || milk.OnHand != 0 {
+		t.Errorf("D-100 = %+v", milk)
+	}
+	if bread, _ := rc.items.Get(ctx, "B-100"); bread == nil || bread.Department != "bakery" {
+		t.Errorf("B-100 = %+v, want the mapped bakery department", bread)
+	}
+
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{
+			"unchanged",
+			itemsCSV,
+			"rows 6, created 0, updated 0, unchanged 3, rejected 5:ITEM_CODE,6:ITEM_CODE,7:REORDER_POINT",
+		},
+		// Blank optional columns keep what was imported before
+		{
+			"blanks keep values",
+			"ITEM_CODE,UPC,DESCRIPTION,DEPARTMENT,UOM,REORDER_POINT\nD-100,,Whole Milk,Dairy,EA,\n",
+			"rows 1, created 0, updated 0, unchanged 1, rejected ",
+		},
+		{
+			"changed",
+			"ITEM_CODE,DESCRIPTION,DEPARTMENT,UOM,PAR_LEVEL\nD-100,Whole Milk Gallon,Dairy,EA,40\nD-200,Butter,Dairy,EA,12\n",
+			"rows 2, created 0, updated 2, unchanged 0, rejected ",
+		},
+		{
+			"missing required",
+			"ITEM_CODE,DESCRIPTION,DEPARTMENT,UOM\nD-500,,Dairy,EA\nD-500,Yogurt,,EA\nD-500,Yogurt,Dairy,\n",
+			"rows 3, created 0, updated 0, unchanged 0, rejected 2:DESCRIPTION,3:DEPARTMENT,4:UOM",
+		},
+	}
+	for _, tt := range tests {
+		if got := counts(reconcile(t, rc, KindItems, tt.csv)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if milk, _ := rc.items.Get(ctx, "D-100"); milk.Description != "Whole Milk Gallon" || milk.ReorderPoint != 20 {
+		t.Errorf("D-100 = %+v, want the new description and the old reorder point", milk)
+	}
+}
+
+func TestApplyOnHand(t *testing.T) {
+	rc := testReconciler(t)
+	ctx := context.Background()
+	csv := `ITEM_CODE,ON_HAND_QTY,LOT_NUMBER,EXPIRATION_DATE
+D-100,6,L1,2026-11-02
+D-100,4,,11/05/2026
+D-200,"1,200"
+X-999,1
+B-100,2
+B-100,two
+B-100,1,,someday
+`
+	// An unknown item is rejected, and B-100's good row goes with its bad
+	// ones so it isn't undercounted
+	run := reconcile(t, rc, KindOnHand, csv)
+	want := "rows 7, created 0, updated 2, unchanged 0, rejected 5:ITEM_CODE,6:ITEM_CODE,7:ON_HAND_QTY,8:EXPIRATION_DATE"
+	if got := counts(run); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if run.Errors[1].Message != "item not updated because line 8 was rejected" {
+		t.Errorf("B-100 line 6 rejected with %q", run.Errors[1].Message)
+	}
+
+	milk, _ := rc.items.Get(ctx, "D-100")
+	if milk.OnHand != 10 || len(milk.Lots) != 2 || milk.Lots[0].Number != "D-100-2026-11-05" || milk.ExpiresOn != "2026-11-02" {
+		t.Errorf("D-100 = %+v, want 10 on hand in two lots", milk)
+	}
+	if butter, _ := rc.items.Get(ctx, "D-200"); butter.OnHand != 1200 || len(butter.Lots) != 0 {
+		t.Errorf("D-200 = %+v, want 1200 without lots", butter)
+	}
+	if bread, _ := rc.items.Get(ctx, "B-100"); bread.OnHand != 0 {
+		t.Errorf("B-100 on hand = %v, want it left alone", bread.OnHand)
+	}
+
+	// The same count in another order changes nothing
+	again := "ITEM_CODE,ON_HAND_QTY,LOT_NUMBER,EXPIRATION_DATE\nD-200,1200\nD-100,4,,2026-11-05\nD-100,6,L1,2026-11-02\n"
+	if got := counts(reconcile(t, rc, KindOnHand, again)); got != "rows 3, created 0, updated 0, unchanged 2, rejected " {
+		t.Errorf("recount: got %s", got)
+	}
+	recount := "ITEM_CODE,ON_HAND_QTY,LOT_NUMBER,EXPIRATION_DATE\nD-100,6,L1,2026-11-02\n"
+	if got := counts(reconcile(t, rc, KindOnHand, recount)); got != "rows 1, created 0, updated 1, unchanged 0, rejected " {
+		t.Errorf("sold lot: got %s", got)
+	}
+}
+
+func TestApplyProduction(t *testing.T) {
+	rc := testReconciler(t)
+	csv := `PLAN_DATE,ITEM_CODE,PLANNED_QTY,PRODUCED_QTY,DEPARTMENT
+2026-10-18,B-100,12,,
+10/18/2026,B-100,5,,
+2026-10-19,B-900,8,,Bakery & Cafe
+2026-10-19,B-901,8,,
+yesterday,B-100,1,,
+2026-10-19,B-100,,,
+2026-10-19,B-100,6,-2,
+`
+	run := reconcile(t, rc, KindProduction, csv)
+	want := "rows 7, created 2, updated 0, unchanged 0, rejected 3:ITEM_CODE,5:ITEM_CODE,6:PLAN_DATE,7:PLANNED_QTY,8:PRODUCED_QTY"
+	if got := counts(run); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	plans, _ := rc.production.Plans(context.Background(), "", "2026-10-18", "2026-10-19")
+	if len(plans) != 2 || plans[0].Description != "Sourdough" || plans[0].Department != "bakery" || plans[1].Department != "bakery" {
+		t.Errorf("plans = %+v, want B-100 filled in from the item master and B-900 as given", plans)
+	}
+
+	update := "PLAN_DATE,ITEM_CODE,PLANNED_QTY,PRODUCED_QTY\n2026-10-18,B-100,12,12\n2026-10-19,B-900,8,\n"
+	if got := counts(reconcile(t, rc, KindProduction, update)); got != "rows 2, created 0, updated 1, unchanged 0, rejected 3:ITEM_CODE" {
+		t.Errorf("update: got %s", got)
+	}
+}
+
+func TestApplyShrink(t *testing.T) {
+	rc := testReconciler(t)
+	csv := `ENTRY_ID,ENTRY_DATE,ITEM_CODE,QTY,REASON,COST
+S1,2026-10-18,D-100,2,Expired,$7.98
+S1,2026-10-18,D-100,3,Expired,
+S2,2026-10-18,X-999,1,Damaged,
+S3,2026-10-18,D-200,,Damaged,
+`
+	run := reconcile(t, rc, KindShrink, csv)
+	want := "rows 4, created 1, updated 0, unchanged 0, rejected 3:ENTRY_ID,4:ITEM_CODE,5:QTY"
+	if got := counts(run); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	shrink, _ := rc.production.Shrink(context.Background(), "", "2026-10-18", "2026-10-18")
+	if len(shrink) != 1 || shrink[0].Department != "dairy" || shrink[0].Cost != 7.98 || shrink[0].Reason != "Expired" {
+		t.Errorf("shrink = %+v", shrink)
+	}
+
+	again := "ENTRY_ID,ENTRY_DATE,ITEM_CODE,QTY,REASON,COST\nS1,10/18/2026,D-100,2,Expired,7.98\n"
+	if got := counts(reconcile(t, rc, KindShrink, again)); got != "rows 1, created 0, updated 0, unchanged 1, rejected " {
+		t.Errorf("again: got %s", got)
+	}
+}
+
+func TestEveryRowRejectedFailsTheRun(t *testing.T) {
+	rc := testReconciler(t)
+	run := reconcile(t, rc, KindOnHand, "ITEM_CODE,ON_HAND_QTY\nX-1,1\nX-2,2\n")
+	if run.Status != imports.StatusFailed || run.Error != "every row was rejected" {
+		t.Errorf("status %s %q, want failed", run.Status, run.Error)
+	}
+}
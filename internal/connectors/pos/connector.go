@@ -0,0 +1,182 @@
+// Package pos reads lane status and completed transactions from the
+// point-of-sale system into the checkout store. Events are JSON lines
+// from a Source: a file the POS appends to, or a TCP bridge.
+package pos
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+	"github.com/dokk-dev/opus/internal/connectors"
+)
+
+// maxLine is the longest event accepted, enough for a large order
+const maxLine = 1 << 20
+
+// Connector applies a POS feed to a checkout repository
+type Connector struct {
+	source Source
+	repo   checkout.Repository
+
+	mu          sync.Mutex
+	stream      io.ReadCloser
+	done        chan struct{}
+	stopping    bool
+	feedErr     error
+	lastEventAt time.Time
+	stats       Stats
+}
+
+// Stats counts the lines read since the connector was created
+type Stats struct {
+	Lanes        int `json:"lanes"`
+	Transactions int `json:"transactions"`
+	// Duplicates are transactions already recorded, e.g. when a file is
+	// read again after a reconnect
+	Duplicates int `json:"duplicates"`
+	// Rejected are lines that couldn't be parsed or applied
+	Rejected int `json:"rejected"`
+}
+
+// New creates a POS connector reading source into repo
+func New(source Source, repo checkout.Repository) *Connector {
+	return &Connector{source: source, repo: repo}
+}
+
+func (c *Connector) Name() string { return "pos" }
+
+// Connect opens the feed and applies its events in the background until
+// Disconnect or the feed is lost
+func (c *Connector) Connect(ctx context.Context) error {
+	stream, err := c.source.Open(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.stream = stream
+	c.done = make(chan struct{})
+	c.stopping = false
+	c.feedErr = nil
+	c.mu.Unlock()
+
+	go c.read(stream, c.done)
+	return nil
+}
+
+func (c *Connector) Disconnect() error {
+	c.mu.Lock()
+	stream, done := c.stream, c.done
+	c.stream = nil
+	c.stopping = true
+	c.mu.Unlock()
+
+	if stream == nil {
+		return nil
+	}
+	err := stream.Close()
+	<-done
+	return err
+}
+
+// Health is unhealthy once the feed is lost, so the registry reconnects
+func (c *Connector) Health() connectors.HealthStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: c.stream != nil && c.feedErr == nil,
+		Details: map[string]interface{}{
+			"source": c.source.String(),
+			"stats":  c.stats,
+		},
+	}
+	if !c.lastEventAt.IsZero() {
+		status.Details["lastEventAt"] = c.lastEventAt
+	}
+	switch {
+	case c.feedErr != nil:
+		status.Message = "feed lost: " + c.feedErr.Error()
+	case c.stream == nil:
+		status.Message = "not connected"
+	}
+	return status
+}
+
+func (c *Connector) read(stream io.Reader, done chan struct{}) {
+	defer close(done)
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		c.apply(scanner.Bytes())
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = errors.New("feed closed")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.stopping {
+		c.feedErr = err
+		log.Printf("POS feed from %s lost: %v", c.source, err)
+	}
+}
+
+// apply records one event, counting it in the stats
+func (c *Connector) apply(line []byte) {
+	applied, duplicate, err := c.applyEvent(line)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case err != nil:
+		c.stats.Rejected++
+		log.Printf("POS: rejected event: %v", err)
+		return
+	case duplicate:
+		c.stats.Duplicates++
+	case applied == eventLane:
+		c.stats.Lanes++
+	case applied == eventTransaction:
+		c.stats.Transactions++
+	}
+	c.lastEventAt = time.Now()
+}
+
+func (c *Connector) applyEvent(line []byte) (string, bool, error) {
+	e, err := parseEvent(line)
+	if err != nil {
+		return "", false, err
+	}
+
+	ctx := context.Background()
+	if e.Type == eventLane {
+		change, err := e.statusChange()
+		if err != nil {
+			return "", false, err
+		}
+		if _, err := c.repo.UpdateLane(ctx, change); err != nil {
+			return "", false, fmt.Errorf("failed to update lane %s: %w", e.Lane, err)
+		}
+		return eventLane, false, nil
+	}
+
+	recorded, err := c.repo.RecordTransaction(ctx, e.transaction())
+	if err != nil {
+		return "", false, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return eventTransaction, !recorded, nil
+}
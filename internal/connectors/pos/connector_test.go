@@ -0,0 +1,90 @@
+package pos
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+)
+
+// feedSource serves a fixed feed that ends after its last line
+type feedSource struct {
+	lines []string
+}
+
+func (s *feedSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(strings.Join(s.lines, "\n"))), nil
+}
+
+func (s *feedSource) String() string { return "test feed" }
+
+func TestConnectorAppliesFeed(t *testing.T) {
+	at := time.Now().Format(time.RFC3339)
+	source := &feedSource{lines: []string{
+		`{"type":"lane","lane":"3","name":"Lane 3","status":"open","cashier":"Megan R","at":"` + at + `"}`,
+		``,
+		`{"type":"transaction","id":"T1","lane":"3","at":"` + at + `","total":3.99,"items":[{"sku":"DAI-1001","quantity":1,"amount":3.99}]}`,
+		`{"type":"transaction","id":"T1","lane":"3","at":"` + at + `","total":3.99}`,
+		`{"type":"transaction","lane":"3","at":"` + at + `","total":1.00}`,
+		`{"type":"lane","lane":"4","status":"on break"}`,
+		`not an event`,
+	}}
+	repo := checkout.NewMemoryRepository()
+	c := New(source, repo)
+
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	<-c.done
+
+	// The transaction without an ID, the unknown status and the garbage
+	// line are rejected
+	want := Stats{Lanes: 1, Transactions: 1, Duplicates: 1, Rejected: 3}
+	health := c.Health()
+	if stats := health.Details["stats"]; stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if health.Healthy || !strings.Contains(health.Message, "feed closed") {
+		t.Errorf("health = %+v, want the lost feed reported", health)
+	}
+
+	lanes, _ := repo.Lanes(context.Background())
+	if len(lanes) != 1 || lanes[0].Cashier != "Megan R" || lanes[0].Transactions != 1 || lanes[0].Sales != 3.99 {
+		t.Errorf("lanes = %+v, want lane 3 with one sale", lanes)
+	}
+
+	if err := c.Disconnect(); err != nil {
+		t.Errorf("Disconnect: %v", err)
+	}
+	if c.Health().Healthy {
+		t.Error("healthy after Disconnect")
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		feed string
+		want string
+	}{
+		{"/var/pos/events.jsonl", "file /var/pos/events.jsonl"},
+		{"file:///var/pos/events.jsonl", "file /var/pos/events.jsonl"},
+		{"tcp://10.0.4.2:9100", "tcp 10.0.4.2:9100"},
+		{"tcp://", ""},
+		{"http://pos/events", ""},
+	}
+	for _, tt := range tests {
+		source, err := ParseSource(tt.feed)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseSource(%q) = %s, want an error", tt.feed, source)
+			}
+			continue
+		}
+		if err != nil || source.String() != tt.want {
+			t.Errorf("ParseSource(%q) = %v, %v, want %s", tt.feed, source, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package pos
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+)
+
+// Event types in the feed
+const (
+	eventLane        = "lane"
+	eventTransaction = "transaction"
+)
+
+// event is one line of the feed, either a lane status
+//
+//	{"type":"lane","lane":"3","status":"needs_assistance","cashier":"Megan R","at":"2026-03-07T16:02:11-05:00"}
+//
+// or a completed transaction
+//
+//	{"type":"transaction","id":"0142-3-88412","lane":"3","at":"2026-03-07T16:04:40-05:00","total":12.47,
+//	 "items":[{"sku":"DAI-1001","description":"2% Milk, gallon","department":"dairy","quantity":1,"amount":3.99}]}
+type event struct {
+	Type    string    `json:"type"`
+	Lane    string    `json:"lane"`
+	At      time.Time `json:"at"`
+	Name    string    `json:"name,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Cashier string    `json:"cashier,omitempty"`
+
+	ID    string              `json:"id,omitempty"`
+	Total float64             `json:"total,omitempty"`
+	Items []checkout.LineItem `json:"items,omitempty"`
+}
+
+// parseEvent decodes and checks one line
+func parseEvent(line []byte) (*event, error) {
+	var e event
+	if err := json.Unmarshal(line, &e); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if e.Lane == "" {
+		return nil, fmt.Errorf("%s event has no lane", e.Type)
+	}
+	switch e.Type {
+	case eventLane, eventTransaction:
+	default:
+		return nil, fmt.Errorf("unknown event type %q", e.Type)
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	return &e, nil
+}
+
+func (e *event) statusChange() (checkout.StatusChange, error) {
+	status, err := checkout.ParseLaneStatus(e.Status)
+	if err != nil {
+		return checkout.StatusChange{}, fmt.Errorf("lane %s: %w", e.Lane, err)
+	}
+	return checkout.StatusChange{
+		Lane:    e.Lane,
+		Name:    e.Name,
+		Status:  status,
+		Cashier: e.Cashier,
+		At:      e.At,
+	}, nil
+}
+
+func (e *event) transaction() checkout.Transaction {
+	return checkout.Transaction{
+		ID:    e.ID,
+		Lane:  e.Lane,
+		At:    e.At,
+		Total: e.Total,
+		Items: e.Items,
+	}
+}
@@ -0,0 +1,64 @@
+package pos
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+)
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"lane", `{"type":"lane","lane":"3","status":"open","at":"2026-03-07T16:02:11-05:00"}`, ""},
+		{"transaction", `{"type":"transaction","id":"0142-3-88412","lane":"3","total":3.99,"items":[{"sku":"DAI-1001","quantity":1,"amount":3.99}]}`, ""},
+		{"not JSON", `lane 3 open`, "invalid JSON"},
+		{"bad time", `{"type":"lane","lane":"3","at":"yesterday"}`, "invalid JSON"},
+		{"no lane", `{"type":"lane","status":"open"}`, "lane event has no lane"},
+		{"unknown type", `{"type":"refund","lane":"3"}`, `unknown event type "refund"`},
+	}
+	for _, tt := range tests {
+		_, err := parseEvent([]byte(tt.line))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseEventDefaultsTimeToNow(t *testing.T) {
+	before := time.Now()
+	e, err := parseEvent([]byte(`{"type":"lane","lane":"3","status":"open"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.At.Before(before) || e.At.After(time.Now()) {
+		t.Errorf("At = %s, want the time it was read", e.At)
+	}
+}
+
+func TestStatusChange(t *testing.T) {
+	e, err := parseEvent([]byte(`{"type":"lane","lane":"3","name":"Lane 3","status":"Needs-Assistance","cashier":"Megan R","at":"2026-03-07T16:02:11-05:00"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := e.statusChange()
+	if err != nil {
+		t.Fatalf("statusChange: %v", err)
+	}
+	at := time.Date(2026, 3, 7, 21, 2, 11, 0, time.UTC)
+	if change.Lane != "3" || change.Name != "Lane 3" || change.Status != checkout.LaneNeedsAssistance || change.Cashier != "Megan R" || !change.At.Equal(at) {
+		t.Errorf("got %+v", change)
+	}
+
+	e.Status = "on break"
+	if _, err := e.statusChange(); err == nil || !strings.Contains(err.Error(), "lane 3") {
+		t.Errorf("err = %v, want an unknown status naming the lane", err)
+	}
+}
@@ -0,0 +1,182 @@
+package pos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source is where POS events come from: a stream of JSON lines that ends
+// when the feed is lost
+type Source interface {
+	Open(ctx context.Context) (io.ReadCloser, error)
+	String() string
+}
+
+// ParseSource reads a feed setting: "tcp://host:port" for a bridge, or a
+// file path, optionally as "file:///path", to tail
+func ParseSource(feed string) (Source, error) {
+	if !strings.Contains(feed, "://") {
+		return &FileSource{Path: feed}, nil
+	}
+	u, err := url.Parse(feed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POS feed %q: %w", feed, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid POS feed %q, expected tcp://host:port", feed)
+		}
+		return &TCPSource{Addr: u.Host}, nil
+	case "file":
+		return &FileSource{Path: u.Path}, nil
+	}
+	return nil, fmt.Errorf("invalid POS feed %q, expected a file path or tcp://host:port", feed)
+}
+
+// FileSource tails a file the POS appends events to. It reads from the
+// start, since recorded events are applied once, then waits for more. A
+// truncated or replaced file is read again from the start.
+type FileSource struct {
+	Path string
+	// Poll is how often to check for new lines at the end of the file;
+	// defaults to 500ms
+	Poll time.Duration
+}
+
+func (s *FileSource) String() string { return "file " + s.Path }
+
+func (s *FileSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open POS feed: %w", err)
+	}
+	poll := s.Poll
+	if poll <= 0 {
+		poll = 500 * time.Millisecond
+	}
+	return &tail{path: s.Path, poll: poll, file: f, closed: make(chan struct{})}, nil
+}
+
+// tail reads a file as it grows, blocking at the end until more is
+// written or it is closed
+type tail struct {
+	path string
+	poll time.Duration
+
+	// mu guards the file against Close while it is read or reopened
+	mu     sync.Mutex
+	file   *os.File
+	offset int64
+	closed chan struct{}
+}
+
+func (t *tail) Read(p []byte) (int, error) {
+	for {
+		n, err := t.read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+
+		select {
+		case <-t.closed:
+			return 0, io.EOF
+		case <-time.After(t.poll):
+		}
+		if err := t.reopenIfReplaced(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (t *tail) read(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.isClosed() {
+		return 0, io.EOF
+	}
+	n, err := t.file.Read(p)
+	t.offset += int64(n)
+	return n, err
+}
+
+// reopenIfReplaced starts over when the file was rotated or truncated
+func (t *tail) reopenIfReplaced() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.isClosed() {
+		return nil
+	}
+
+	current, err := os.Stat(t.path)
+	if os.IsNotExist(err) {
+		// Between rotation and the new file being created
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	open, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+	if os.SameFile(open, current) && current.Size() >= t.offset {
+		return nil
+	}
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	t.file.Close()
+	t.file, t.offset = f, 0
+	return nil
+}
+
+func (t *tail) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.isClosed() {
+		return nil
+	}
+	close(t.closed)
+	return t.file.Close()
+}
+
+func (t *tail) isClosed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// TCPSource connects to an on-prem bridge that streams events over TCP
+type TCPSource struct {
+	Addr string
+	// DialTimeout defaults to 10s
+	DialTimeout time.Duration
+}
+
+func (s *TCPSource) String() string { return "tcp " + s.Addr }
+
+func (s *TCPSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	timeout := s.DialTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to POS bridge: %w", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,270 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Options tune how the registry supervises connectors. Zero values use
+// the defaults.
+type Options struct {
+	// ProbeInterval is how often Health is called while connected
+	ProbeInterval time.Duration
+	// MaxFailedProbes is how many unhealthy probes in a row trigger a
+	// reconnect
+	MaxFailedProbes int
+	// MinBackoff and MaxBackoff bound the exponential delay between
+	// reconnect attempts
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.ProbeInterval <= 0 {
+		o.ProbeInterval = 30 * time.Second
+	}
+	if o.MaxFailedProbes <= 0 {
+		o.MaxFailedProbes = 3
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = time.Second
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = max(5*time.Minute, o.MinBackoff)
+	}
+}
+
+// Registry runs connectors for the life of the server, connecting them,
+// probing their health and reconnecting with backoff when they fail
+type Registry struct {
+	opts    Options
+	entries []*entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+}
+
+type entry struct {
+	connector Connector
+	status    Status
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry(opts Options) *Registry {
+	opts.setDefaults()
+	return &Registry{opts: opts}
+}
+
+// Register adds a connector. Connectors must be registered before Start.
+func (r *Registry) Register(c Connector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.entries {
+		if e.connector.Name() == c.Name() {
+			return fmt.Errorf("connector %s is already registered", c.Name())
+		}
+	}
+	r.entries = append(r.entries, &entry{
+		connector: c,
+		status:    Status{Name: c.Name(), State: StateStopped},
+	})
+	return nil
+}
+
+// Len returns how many connectors are registered
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.entries)
+}
+
+// Start connects every connector in the background and keeps them
+// connected until Stop
+func (r *Registry) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		e.status.State = StateConnecting
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.supervise(ctx, e)
+		}()
+	}
+}
+
+// Stop disconnects every connector and waits for them to finish
+func (r *Registry) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Status returns a snapshot of every connector in registration order
+func (r *Registry) Status() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(r.entries))
+	for _, e := range r.entries {
+		statuses = append(statuses, e.status)
+	}
+	return statuses
+}
+
+// update changes a connector's status under the registry lock
+func (r *Registry) update(e *entry, fn func(s *Status)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&e.status)
+}
+
+// supervise connects a connector, probes it while connected and
+// reconnects with backoff until ctx is cancelled
+func (r *Registry) supervise(ctx context.Context, e *entry) {
+	c := e.connector
+	name := c.Name()
+
+	for {
+		err := c.Connect(ctx)
+		if ctx.Err() != nil {
+			if err == nil {
+				disconnect(c)
+			}
+			r.update(e, func(s *Status) { s.State = StateStopped })
+			return
+		}
+
+		if err != nil {
+			if !r.retry(ctx, e, fmt.Errorf("connect failed: %w", err)) {
+				return
+			}
+			continue
+		}
+
+		now := time.Now()
+		r.update(e, func(s *Status) {
+			s.State = StateConnected
+			s.ConnectedAt = &now
+			s.Failures = 0
+			s.NextRetryAt = nil
+		})
+		log.Printf("Connector %s connected", name)
+
+		probeErr := r.probe(ctx, e)
+		disconnect(c)
+		if probeErr == nil {
+			// Stopped
+			r.update(e, func(s *Status) { s.State = StateStopped })
+			return
+		}
+		// The probes' failures aren't connect failures, so the first
+		// reconnect waits MinBackoff like any other
+		r.update(e, func(s *Status) { s.Failures = 0 })
+		if !r.retry(ctx, e, probeErr) {
+			return
+		}
+	}
+}
+
+// probe checks the connector's health every ProbeInterval. It returns nil
+// when ctx is cancelled, or an error after MaxFailedProbes unhealthy
+// probes in a row.
+func (r *Registry) probe(ctx context.Context, e *entry) error {
+	ticker := time.NewTicker(r.opts.ProbeInterval)
+	defer ticker.Stop()
+
+	failed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		health := e.connector.Health()
+		now := time.Now()
+		if health.Healthy {
+			failed = 0
+		} else {
+			failed++
+		}
+
+		r.update(e, func(s *Status) {
+			s.Health = &health
+			s.CheckedAt = &now
+			if health.Healthy {
+				s.State = StateConnected
+				s.Failures = 0
+				return
+			}
+			s.State = StateDegraded
+			s.Failures = failed
+			s.LastError = health.Message
+			s.LastErrorAt = &now
+		})
+
+		if failed >= r.opts.MaxFailedProbes {
+			return fmt.Errorf("unhealthy for %d probes: %s", failed, health.Message)
+		}
+	}
+}
+
+// retry records a failure and waits out the backoff before the next
+// connect attempt. It returns false if ctx is cancelled while waiting.
+func (r *Registry) retry(ctx context.Context, e *entry, err error) bool {
+	now := time.Now()
+	var delay time.Duration
+	r.update(e, func(s *Status) {
+		s.Failures++
+		delay = r.backoff(s.Failures)
+		next := now.Add(delay)
+		s.State = StateReconnecting
+		s.Health = nil
+		s.ConnectedAt = nil
+		s.LastError = err.Error()
+		s.LastErrorAt = &now
+		s.NextRetryAt = &next
+	})
+	log.Printf("Connector %s: %v, retrying in %s", e.connector.Name(), err, delay.Round(time.Millisecond))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		r.update(e, func(s *Status) {
+			s.State = StateStopped
+			s.NextRetryAt = nil
+		})
+		return false
+	case <-timer.C:
+		r.update(e, func(s *Status) { s.State = StateConnecting })
+		return true
+	}
+}
+
+// backoff doubles the delay with each failure up to MaxBackoff, with
+// jitter so connectors failing together don't retry in lockstep
+func (r *Registry) backoff(failures int) time.Duration {
+	delay := r.opts.MinBackoff
+	for i := 1; i < failures && delay < r.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, r.opts.MaxBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func disconnect(c Connector) {
+	if err := c.Disconnect(); err != nil {
+		log.Printf("Connector %s: disconnect failed: %v", c.Name(), err)
+	}
+}
@@ -0,0 +1,149 @@
+package connectors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor polls the registry until ok accepts the connector's status
+func waitFor(t *testing.T, r *Registry, ok func(s Status) bool) Status {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if s := r.Status()[0]; ok(s) {
+			return s
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("status never got there: %+v", r.Status()[0])
+	return Status{}
+}
+
+func TestUnhealthyConnectorReconnectsAfterMinBackoff(t *testing.T) {
+	mock := NewMock("pos")
+	mock.SetHealthy(false, "lanes offline")
+	r := NewRegistry(Options{ProbeInterval: time.Millisecond, MaxFailedProbes: 3, MinBackoff: time.Second, MaxBackoff: time.Minute})
+	if err := r.Register(mock); err != nil {
+		t.Fatal(err)
+	}
+	r.Start(context.Background())
+	defer r.Stop()
+
+	s := waitFor(t, r, func(s Status) bool { return s.State == StateReconnecting })
+	if s.Failures != 1 {
+		t.Errorf("Failures = %d after the probes gave up, want 1", s.Failures)
+	}
+	if wait := s.NextRetryAt.Sub(*s.LastErrorAt); wait > time.Second {
+		t.Errorf("first reconnect waits %s, want at most MinBackoff", wait)
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want Options
+	}{
+		{"zero", Options{}, Options{ProbeInterval: 30 * time.Second, MaxFailedProbes: 3, MinBackoff: time.Second, MaxBackoff: 5 * time.Minute}},
+		{"max below min", Options{MinBackoff: 10 * time.Minute, MaxBackoff: time.Minute}, Options{ProbeInterval: 30 * time.Second, MaxFailedProbes: 3, MinBackoff: 10 * time.Minute, MaxBackoff: 10 * time.Minute}},
+		{"set", Options{ProbeInterval: time.Second, MaxFailedProbes: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Second}, Options{ProbeInterval: time.Second, MaxFailedProbes: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Second}},
+	}
+	for _, tt := range tests {
+		tt.opts.setDefaults()
+		if tt.opts != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, tt.opts, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := NewRegistry(Options{MinBackoff: time.Second, MaxBackoff: 30 * time.Second})
+
+	tests := []struct {
+		failures int
+		full     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 16 * time.Second},
+		{6, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		// Jitter keeps each delay between half and all of the full one
+		for i := 0; i < 20; i++ {
+			if got := r.backoff(tt.failures); got < tt.full/2 || got > tt.full {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.failures, got, tt.full/2, tt.full)
+				break
+			}
+		}
+	}
+}
+
+func TestRegisterRejectsDuplicateNames(t *testing.T) {
+	r := NewRegistry(Options{})
+	if err := r.Register(NewMock("pos")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(NewMock("pos")); err == nil {
+		t.Error("registered a second connector named pos")
+	}
+	if r.Len() != 1 || r.Status()[0].State != StateStopped {
+		t.Errorf("got %d connectors %+v, want one stopped", r.Len(), r.Status())
+	}
+}
+
+func TestFailedConnectsAreRetried(t *testing.T) {
+	mock := NewMock("pos")
+	mock.FailConnects(3)
+	r := NewRegistry(Options{ProbeInterval: time.Hour, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+	if err := r.Register(mock); err != nil {
+		t.Fatal(err)
+	}
+	r.Start(context.Background())
+	defer r.Stop()
+
+	s := waitFor(t, r, func(s Status) bool { return s.State == StateConnected })
+	if s.Failures != 0 || s.NextRetryAt != nil || s.ConnectedAt == nil {
+		t.Errorf("connected with %+v, want failures cleared", s)
+	}
+	if !strings.Contains(s.LastError, "mock connection refused") {
+		t.Errorf("LastError = %q, want the connect failure", s.LastError)
+	}
+	mock.mu.Lock()
+	connects := mock.connects
+	mock.mu.Unlock()
+	if connects != 4 {
+		t.Errorf("connected after %d attempts, want 4", connects)
+	}
+
+	r.Stop()
+	if s := r.Status()[0]; s.State != StateStopped || mock.Connected() {
+		t.Errorf("after Stop got %s, connected %v, want stopped and disconnected", s.State, mock.Connected())
+	}
+}
+
+func TestProbesMarkConnectorDegradedUntilHealthy(t *testing.T) {
+	mock := NewMock("pos")
+	mock.SetHealthy(false, "lane 4 offline")
+	r := NewRegistry(Options{ProbeInterval: time.Millisecond, MaxFailedProbes: 1000})
+	if err := r.Register(mock); err != nil {
+		t.Fatal(err)
+	}
+	r.Start(context.Background())
+	defer r.Stop()
+
+	s := waitFor(t, r, func(s Status) bool { return s.State == StateDegraded && s.Failures >= 2 })
+	if s.LastError != "lane 4 offline" || s.Health == nil || s.Health.Healthy {
+		t.Errorf("degraded with %+v, want the probe's message", s)
+	}
+
+	mock.SetHealthy(true, "")
+	s = waitFor(t, r, func(s Status) bool { return s.State == StateConnected })
+	if s.Failures != 0 || s.CheckedAt == nil || !s.Health.Healthy {
+		t.Errorf("recovered with %+v, want a healthy probe and failures cleared", s)
+	}
+}
@@ -0,0 +1,173 @@
+package ukg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API paths, relative to the tenant's base URL
+const (
+	pathToken    = "/api/authentication/access_token"
+	pathPersons  = "/api/v1/commons/persons"
+	pathShifts   = "/api/v1/scheduling/shifts"
+	pathTimeOff  = "/api/v1/timekeeping/timeoff_requests"
+	pathPunches  = "/api/v1/timekeeping/punches"
+	pageSize     = 200
+	maxPages     = 1000
+	tokenRefresh = time.Minute
+)
+
+// client calls the UKG API with an OAuth client-credentials token, which
+// it caches until shortly before it expires
+type client struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	http         *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// page is the envelope of a paged list response
+type page[T any] struct {
+	Records []T `json:"records"`
+	Paging  struct {
+		Page         int `json:"page"`
+		PageSize     int `json:"pageSize"`
+		TotalPages   int `json:"totalPages"`
+		TotalRecords int `json:"totalRecords"`
+	} `json:"paging"`
+}
+
+// accessToken returns a valid token, requesting a new one if needed
+func (c *client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Until(c.expires) > tokenRefresh {
+		return c.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+pathToken, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("access token request failed: %s", responseError(resp))
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("access token response has no token")
+	}
+
+	c.token = token.AccessToken
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// get decodes a GET response into out. A 401 drops the cached token and
+// retries once with a new one, in case it was revoked early.
+func (c *client) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("GET %s failed: %w", path, err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			c.mu.Lock()
+			c.token = ""
+			c.mu.Unlock()
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s failed: %s", path, responseError(resp))
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// getAll fetches every page of a list endpoint
+func getAll[T any](ctx context.Context, c *client, path string, params url.Values) ([]T, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("page_size", strconv.Itoa(pageSize))
+
+	var records []T
+	for n := 1; n <= maxPages; n++ {
+		query.Set("page", strconv.Itoa(n))
+
+		var p page[T]
+		if err := c.get(ctx, path, query, &p); err != nil {
+			return nil, err
+		}
+		records = append(records, p.Records...)
+		if n >= p.Paging.TotalPages || len(p.Records) == 0 {
+			return records, nil
+		}
+	}
+	return nil, fmt.Errorf("GET %s returned more than %d pages", path, maxPages)
+}
+
+// responseError describes a failed response with the start of its body
+func responseError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Sprintf("%s: %s", resp.Status, msg)
+	}
+	return resp.Status
+}
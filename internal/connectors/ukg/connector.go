@@ -0,0 +1,411 @@
+// Package ukg syncs employees, schedules, time off and punches from a UKG
+// workforce management tenant into the schedule store.
+package ukg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+const (
+	// syncOverlap is subtracted from the last sync time when asking for
+	// changes, so clock skew between us and UKG can't lose any
+	syncOverlap = time.Minute
+	// fullSyncEvery re-reads the whole window, picking up shifts that were
+	// published before they came into range and dropping ones UKG no
+	// longer has
+	fullSyncEvery = 24 * time.Hour
+	// Shifts and time off are synced from a week back to three weeks ahead
+	pastWindow   = 7 * 24 * time.Hour
+	futureWindow = 21 * 24 * time.Hour
+)
+
+// Config configures the UKG connector
+type Config struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	// Interval is how often changes are pulled
+	Interval time.Duration
+	// Location is the store's time zone, which UKG date-times are in;
+	// nil means the server's
+	Location *time.Location
+	// Departments maps UKG department names to Opus department IDs when
+	// they don't match after lowercasing, e.g. "Meat & Seafood" to meat
+	Departments map[string]string
+	HTTPClient  *http.Client
+}
+
+// Connector pulls from UKG into a schedule repository
+type Connector struct {
+	config Config
+	client *client
+	mapper *mapper
+	repo   schedule.Repository
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu           sync.Mutex
+	lastSync     time.Time
+	lastFullSync time.Time
+	lastError    error
+	lastStats    Stats
+}
+
+// Stats counts the records applied by one sync
+type Stats struct {
+	Full      bool `json:"full"`
+	Employees int  `json:"employees"`
+	// Terminated are employees removed because UKG says they left
+	Terminated int `json:"terminatedEmployees"`
+	Shifts     int `json:"shifts"`
+	Deleted    int `json:"deletedShifts"`
+	TimeOff    int `json:"timeOff"`
+	Punches    int `json:"punches"`
+	// Skipped are records that couldn't be mapped, e.g. for an unknown
+	// employee
+	Skipped int `json:"skipped"`
+}
+
+// New creates a UKG connector writing into repo
+func New(cfg Config, repo schedule.Repository) *Connector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Connector{
+		config: cfg,
+		client: &client{
+			baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+			clientID:     cfg.ClientID,
+			clientSecret: cfg.ClientSecret,
+			http:         cfg.HTTPClient,
+		},
+		mapper: &mapper{location: cfg.Location, departments: cfg.Departments},
+		repo:   repo,
+	}
+}
+
+func (c *Connector) Name() string { return "ukg" }
+
+// Connect runs a full sync, then keeps pulling changes every Interval
+// until Disconnect
+func (c *Connector) Connect(ctx context.Context) error {
+	if err := c.Sync(ctx); err != nil {
+		return err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.run(loopCtx)
+	return nil
+}
+
+func (c *Connector) Disconnect() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+		c.cancel = nil
+	}
+	return nil
+}
+
+func (c *Connector) run(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Sync(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("UKG sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// Health is healthy while the last sync succeeded and isn't overdue
+func (c *Connector) Health() connectors.HealthStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := connectors.HealthStatus{
+		Healthy: true,
+		Details: map[string]interface{}{
+			"lastSync": c.lastSync,
+			"stats":    c.lastStats,
+		},
+	}
+	switch {
+	case c.lastError != nil:
+		status.Healthy = false
+		status.Message = c.lastError.Error()
+	case time.Since(c.lastSync) > 3*c.config.Interval:
+		status.Healthy = false
+		status.Message = fmt.Sprintf("no successful sync since %s", c.lastSync.Format(time.RFC3339))
+	}
+	return status
+}
+
+// Sync pulls everything changed since the last sync, or the whole window
+// if there hasn't been one today
+func (c *Connector) Sync(ctx context.Context) error {
+	c.mu.Lock()
+	since := c.lastSync.Add(-syncOverlap)
+	full := c.lastSync.IsZero() || time.Since(c.lastFullSync) > fullSyncEvery
+	c.mu.Unlock()
+	if full {
+		since = time.Time{}
+	}
+
+	started := time.Now()
+	stats, err := c.sync(ctx, started, since)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastError = err
+	if err != nil {
+		return err
+	}
+	c.lastSync = started
+	if full {
+		c.lastFullSync = started
+	}
+	c.lastStats = stats
+	return nil
+}
+
+// window is the span of days synced, from midnight on its first day to
+// midnight after its last
+type window struct {
+	start, end time.Time
+}
+
+func (c *Connector) window(now time.Time) window {
+	first := now.Add(-pastWindow).In(c.config.Location)
+	last := now.Add(futureWindow).In(c.config.Location)
+	return window{
+		start: time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, c.config.Location),
+		end:   time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, c.config.Location),
+	}
+}
+
+// sync applies changes since since, or everything in the window if since
+// is zero
+func (c *Connector) sync(ctx context.Context, now, since time.Time) (Stats, error) {
+	stats := Stats{Full: since.IsZero()}
+	span := c.window(now)
+
+	changed := url.Values{}
+	if !since.IsZero() {
+		changed.Set("modified_since", since.UTC().Format(time.RFC3339))
+	}
+	windowed := url.Values{
+		"start_date": {span.start.Format(time.DateOnly)},
+		"end_date":   {span.end.AddDate(0, 0, -1).Format(time.DateOnly)},
+	}
+	for k, v := range changed {
+		windowed[k] = v
+	}
+
+	persons, err := getAll[person](ctx, c.client, pathPersons, changed)
+	if err != nil {
+		return stats, err
+	}
+	var employees []schedule.Employee
+	var terminated []string
+	for _, p := range persons {
+		if p.Status == "Terminated" {
+			terminated = append(terminated, p.PersonNumber)
+			continue
+		}
+		employees = append(employees, c.mapper.employee(p))
+	}
+	if err := c.repo.SaveEmployees(ctx, employees...); err != nil {
+		return stats, fmt.Errorf("failed to save employees: %w", err)
+	}
+	stats.Employees = len(employees)
+	if err := c.terminate(ctx, now, span, terminated, &stats); err != nil {
+		return stats, err
+	}
+
+	// Shifts, time off and punches name employees who may not have
+	// changed in this sync
+	known, err := c.repo.Employees(ctx, "")
+	if err != nil {
+		return stats, fmt.Errorf("failed to load employees: %w", err)
+	}
+	byID := make(map[string]schedule.Employee, len(known))
+	for _, e := range known {
+		byID[e.ID] = e
+	}
+
+	if err := c.syncShifts(ctx, windowed, span, byID, &stats); err != nil {
+		return stats, err
+	}
+	if err := c.syncTimeOff(ctx, windowed, byID, &stats); err != nil {
+		return stats, err
+	}
+	if err := c.syncPunches(ctx, windowed, byID, &stats); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// terminate removes employees who left, with their shifts from now on so
+// they no longer count towards coverage
+func (c *Connector) terminate(ctx context.Context, now time.Time, span window, ids []string, stats *Stats) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := c.repo.DeleteEmployees(ctx, ids...); err != nil {
+		return fmt.Errorf("failed to delete employees: %w", err)
+	}
+	stats.Terminated = len(ids)
+
+	left := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		left[id] = true
+	}
+	upcoming, err := c.repo.Shifts(ctx, "", now, span.end)
+	if err != nil {
+		return fmt.Errorf("failed to load shifts: %w", err)
+	}
+	var deleted []string
+	for _, s := range upcoming {
+		if left[s.EmployeeID] {
+			deleted = append(deleted, s.ID)
+		}
+	}
+	if err := c.repo.DeleteShifts(ctx, deleted...); err != nil {
+		return fmt.Errorf("failed to delete shifts: %w", err)
+	}
+	stats.Deleted += len(deleted)
+	return nil
+}
+
+// syncShifts applies shift changes. A full sync also deletes the shifts in
+// the window that UKG didn't return, since deletions older than the last
+// full sync aren't reported as changes.
+func (c *Connector) syncShifts(ctx context.Context, params url.Values, span window, employees map[string]schedule.Employee, stats *Stats) error {
+	records, err := getAll[shift](ctx, c.client, pathShifts, params)
+	if err != nil {
+		return err
+	}
+
+	var shifts []schedule.Shift
+	var deleted []string
+	returned := make(map[string]bool, len(records))
+	for _, r := range records {
+		if r.Deleted {
+			deleted = append(deleted, r.ID)
+			continue
+		}
+		returned[r.ID] = true
+		s, err := c.mapper.shift(r, employees)
+		if err != nil {
+			c.skip(stats, err)
+			continue
+		}
+		shifts = append(shifts, s)
+	}
+
+	if stats.Full {
+		existing, err := c.repo.Shifts(ctx, "", span.start, span.end)
+		if err != nil {
+			return fmt.Errorf("failed to load shifts: %w", err)
+		}
+		for _, s := range existing {
+			if !returned[s.ID] {
+				deleted = append(deleted, s.ID)
+			}
+		}
+	}
+
+	if err := c.repo.SaveShifts(ctx, shifts...); err != nil {
+		return fmt.Errorf("failed to save shifts: %w", err)
+	}
+	if err := c.repo.DeleteShifts(ctx, deleted...); err != nil {
+		return fmt.Errorf("failed to delete shifts: %w", err)
+	}
+	stats.Shifts = len(shifts)
+	stats.Deleted += len(deleted)
+	return nil
+}
+
+func (c *Connector) syncTimeOff(ctx context.Context, params url.Values, employees map[string]schedule.Employee, stats *Stats) error {
+	records, err := getAll[timeOffRequest](ctx, c.client, pathTimeOff, params)
+	if err != nil {
+		return err
+	}
+
+	var requests []schedule.TimeOff
+	for _, r := range records {
+		t, err := c.mapper.timeOff(r, employees)
+		if err == nil && t.Department == "" {
+			err = fmt.Errorf("time-off request %s: unknown employee %s", r.ID, r.PersonNumber)
+		}
+		if err != nil {
+			c.skip(stats, err)
+			continue
+		}
+		requests = append(requests, t)
+	}
+
+	if err := c.repo.SaveTimeOff(ctx, requests...); err != nil {
+		return fmt.Errorf("failed to save time off: %w", err)
+	}
+	stats.TimeOff = len(requests)
+	return nil
+}
+
+func (c *Connector) syncPunches(ctx context.Context, params url.Values, employees map[string]schedule.Employee, stats *Stats) error {
+	records, err := getAll[punch](ctx, c.client, pathPunches, params)
+	if err != nil {
+		return err
+	}
+
+	var punches []schedule.Punch
+	for _, r := range records {
+		p, err := c.mapper.punch(r, employees)
+		if err == nil && p.Department == "" {
+			err = fmt.Errorf("punch %s: unknown employee %s", r.ID, r.PersonNumber)
+		}
+		if err != nil {
+			c.skip(stats, err)
+			continue
+		}
+		punches = append(punches, p)
+	}
+
+	if err := c.repo.SavePunches(ctx, punches...); err != nil {
+		return fmt.Errorf("failed to save punches: %w", err)
+	}
+	stats.Punches = len(punches)
+	return nil
+}
+
+func (c *Connector) skip(stats *Stats, err error) {
+	stats.Skipped++
+	log.Printf("UKG: skipping %v", err)
+}
@@ -0,0 +1,176 @@
+package ukg
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// testConnector syncs from a fake UKG tenant that serves two records a
+// page, so every list is paged through
+func testConnector(t *testing.T) (*Connector, *FakeServer, *schedule.MemoryRepository) {
+	t.Helper()
+	fake, err := NewFakeServer("opus", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.PageSize = 2
+	fake.Location = time.UTC
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	repo := schedule.NewMemoryRepository()
+	c := New(Config{
+		BaseURL:      srv.URL,
+		ClientID:     "opus",
+		ClientSecret: "secret",
+		Location:     time.UTC,
+	}, repo)
+	return c, fake, repo
+}
+
+// fixtureTime is the fixture date-time the fake moves to t
+func fixtureTime(t *testing.T, fake *FakeServer, at time.Time) time.Time {
+	t.Helper()
+	q, err := fake.parseQuery(httptest.NewRequest("GET", pathShifts, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at.AddDate(0, 0, -q.days)
+}
+
+func TestFullSyncPagesThroughEverything(t *testing.T) {
+	c, fake, repo := testConnector(t)
+	ctx := context.Background()
+
+	if err := c.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	employees, _ := repo.Employees(ctx, "")
+	if len(employees) != len(fake.persons)-1 {
+		t.Errorf("got %d employees, want every active person of %d", len(employees), len(fake.persons))
+	}
+	shifts, _ := repo.Shifts(ctx, "", time.Now().AddDate(0, 0, -14), time.Now().AddDate(0, 0, 14))
+	if len(shifts) != len(fake.shifts)-1 {
+		t.Errorf("got %d shifts, want the %d that aren't deleted", len(shifts), len(fake.shifts)-1)
+	}
+	if fake.Requests() < len(fake.shifts)/fake.PageSize {
+		t.Errorf("only %d requests, want a page at a time", fake.Requests())
+	}
+	// The fixtures have one time-off request for an unknown employee
+	if stats := c.lastStats; !stats.Full || stats.Skipped != 1 {
+		t.Errorf("stats = %+v, want a full sync skipping one record", stats)
+	}
+}
+
+func TestSyncAppliesChangesSince(t *testing.T) {
+	c, fake, repo := testConnector(t)
+	ctx := context.Background()
+	if err := c.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	since := time.Now()
+	fake.mu.Lock()
+	fake.shifts[0].JobName = "Receiving"
+	fake.shifts[0].ModifiedAt = fixtureTime(t, fake, since.Add(time.Minute))
+	fake.mu.Unlock()
+
+	stats, err := c.sync(ctx, since, since)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if stats.Full || stats.Shifts != 1 || stats.Employees != 0 {
+		t.Errorf("stats = %+v, want only the changed shift", stats)
+	}
+
+	shifts, _ := repo.Shifts(ctx, "", time.Now().AddDate(0, 0, -14), time.Now().AddDate(0, 0, 14))
+	for _, s := range shifts {
+		if s.ID == fake.shifts[0].ID && s.Role != "Receiving" {
+			t.Errorf("shift %s has role %q, want the change applied", s.ID, s.Role)
+		}
+	}
+}
+
+func TestSyncRemovesTerminatedEmployees(t *testing.T) {
+	c, fake, repo := testConnector(t)
+	ctx := context.Background()
+
+	// Maria has a shift tomorrow when she leaves
+	tomorrow := fixtureTime(t, fake, time.Now().UTC().Add(24*time.Hour))
+	fake.shifts = append(fake.shifts, shift{
+		ID:            "SH-1001-LATE",
+		PersonNumber:  "1001",
+		OrgPath:       "Store/0142/Dairy",
+		JobName:       "Dairy Manager",
+		StartDateTime: tomorrow.Format(localLayout),
+		EndDateTime:   tomorrow.Add(4 * time.Hour).Format(localLayout),
+		ModifiedAt:    fake.shifts[0].ModifiedAt,
+	})
+	if err := c.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	since := time.Now()
+	fake.mu.Lock()
+	fake.persons[0].Status = "Terminated"
+	fake.persons[0].ModifiedAt = since.Add(time.Minute)
+	fake.mu.Unlock()
+
+	stats, err := c.sync(ctx, since, since)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if stats.Terminated != 1 {
+		t.Errorf("stats = %+v, want one terminated employee", stats)
+	}
+	employees, _ := repo.Employees(ctx, "")
+	for _, e := range employees {
+		if e.ID == "1001" {
+			t.Error("terminated employee 1001 is still in the schedule")
+		}
+	}
+	upcoming, _ := repo.Shifts(ctx, "", time.Now(), time.Now().AddDate(0, 0, 14))
+	for _, s := range upcoming {
+		if s.EmployeeID == "1001" {
+			t.Errorf("terminated employee 1001 still has shift %s", s.ID)
+		}
+	}
+}
+
+func TestFullSyncDropsShiftsUKGNoLongerHas(t *testing.T) {
+	c, fake, repo := testConnector(t)
+	ctx := context.Background()
+	if err := c.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	reported := c.lastStats.Deleted
+
+	// Removed outright rather than reported as deleted
+	fake.mu.Lock()
+	gone := fake.shifts[0].ID
+	fake.shifts = fake.shifts[1:]
+	fake.mu.Unlock()
+
+	now := time.Now()
+	if stats, err := c.sync(ctx, now, now.Add(-time.Hour)); err != nil || stats.Deleted != 0 {
+		t.Fatalf("incremental sync = %+v, %v; want nothing deleted", stats, err)
+	}
+	stats, err := c.sync(ctx, now, time.Time{})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if stats.Deleted != reported+1 {
+		t.Errorf("stats = %+v, want one more shift deleted than the %d UKG reports", stats, reported)
+	}
+	shifts, _ := repo.Shifts(ctx, "", time.Now().AddDate(0, 0, -14), time.Now().AddDate(0, 0, 14))
+	for _, s := range shifts {
+		if s.ID == gone {
+			t.Errorf("shift %s is still in the schedule", gone)
+		}
+	}
+}
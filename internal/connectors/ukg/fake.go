@@ -0,0 +1,294 @@
+package ukg
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// fixtureWeek is the Monday of the week the fixtures were recorded in
+var fixtureWeek = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+// FakeServer serves recorded UKG fixtures for demos and local testing:
+// the token endpoint, then paged persons, shifts, time-off requests and
+// punches filtered by modified_since and start_date/end_date. Dates are
+// moved by whole weeks so the recorded week is always the current one.
+type FakeServer struct {
+	ClientID     string
+	ClientSecret string
+	// PageSize caps page_size so paging is exercised; defaults to 25
+	PageSize int
+	// TokenTTL is how long issued tokens last; defaults to 30 minutes
+	TokenTTL time.Duration
+	// Location is the store time zone of the recorded date-times
+	Location *time.Location
+
+	persons  []person
+	shifts   []shift
+	timeOff  []timeOffRequest
+	punches  []punch
+	mu       sync.Mutex
+	tokens   map[string]time.Time
+	requests int
+}
+
+// NewFakeServer loads the fixtures and accepts the given credentials
+func NewFakeServer(clientID, clientSecret string) (*FakeServer, error) {
+	f := &FakeServer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		PageSize:     25,
+		TokenTTL:     30 * time.Minute,
+		Location:     time.Local,
+		tokens:       make(map[string]time.Time),
+	}
+	for name, dst := range map[string]interface{}{
+		"persons":          &f.persons,
+		"shifts":           &f.shifts,
+		"timeoff_requests": &f.timeOff,
+		"punches":          &f.punches,
+	} {
+		data, err := fixtures.ReadFile("fixtures/" + name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, dst); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+// Requests counts the API calls served, not including token requests
+func (f *FakeServer) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == pathToken {
+		f.issueToken(w, r)
+		return
+	}
+	if !f.authorized(r) {
+		writeFakeError(w, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	q, err := f.parseQuery(r)
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch r.URL.Path {
+	case pathPersons:
+		writePage(w, q, filter(f.persons, func(p person) bool {
+			return p.ModifiedAt.After(q.modifiedSince)
+		}))
+	case pathShifts:
+		writePage(w, q, filter(f.moveShifts(q.days), func(s shift) bool {
+			return s.ModifiedAt.After(q.modifiedSince) && q.overlaps(s.StartDateTime, s.EndDateTime)
+		}))
+	case pathTimeOff:
+		writePage(w, q, filter(f.moveTimeOff(q.days), func(t timeOffRequest) bool {
+			return t.ModifiedAt.After(q.modifiedSince) && q.overlaps(t.StartDateTime, t.EndDateTime)
+		}))
+	case pathPunches:
+		writePage(w, q, filter(f.movePunches(q.days), func(p punch) bool {
+			return p.ModifiedAt.After(q.modifiedSince) && q.overlaps(p.PunchDateTime, p.PunchDateTime)
+		}))
+	default:
+		writeFakeError(w, http.StatusNotFound, "not_found")
+	}
+}
+
+// issueToken implements the client-credentials grant, taking credentials
+// from the form or HTTP basic auth
+func (f *FakeServer) issueToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeFakeError(w, http.StatusMethodNotAllowed, "method_not_allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeFakeError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != f.ClientID || secret != f.ClientSecret {
+		writeFakeError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	f.mu.Lock()
+	f.tokens[token] = time.Now().Add(f.TokenTTL)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(f.TokenTTL.Seconds()),
+	})
+}
+
+func (f *FakeServer) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	expires, ok := f.tokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+// fakeQuery is a list request's parsed parameters
+type fakeQuery struct {
+	page, pageSize int
+	modifiedSince  time.Time
+	// start and end bound dates as YYYY-MM-DD, inclusive; empty is open
+	start, end string
+	// days is how far fixture dates move to land in the current week
+	days int
+}
+
+func (f *FakeServer) parseQuery(r *http.Request) (*fakeQuery, error) {
+	values := r.URL.Query()
+	q := &fakeQuery{page: 1, pageSize: f.PageSize, start: values.Get("start_date"), end: values.Get("end_date")}
+
+	if v := values.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid page %q", v)
+		}
+		q.page = n
+	}
+	if v := values.Get("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid page_size %q", v)
+		}
+		q.pageSize = min(n, f.PageSize)
+	}
+	if v := values.Get("modified_since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modified_since %q", v)
+		}
+		q.modifiedSince = t
+	}
+
+	week := schedule.WeekStart(time.Now().In(f.Location))
+	recorded := time.Date(fixtureWeek.Year(), fixtureWeek.Month(), fixtureWeek.Day(), 0, 0, 0, 0, f.Location)
+	q.days = int(math.Round(week.Sub(recorded).Hours() / 24))
+	return q, nil
+}
+
+// overlaps reports whether the local date-times [start, end] fall within
+// the query's date range
+func (q *fakeQuery) overlaps(start, end string) bool {
+	return (q.end == "" || start[:10] <= q.end) && (q.start == "" || end[:10] >= q.start)
+}
+
+func (f *FakeServer) move(value string, days int) string {
+	t, err := time.ParseInLocation(localLayout, value, f.Location)
+	if err != nil {
+		return value
+	}
+	return t.AddDate(0, 0, days).Format(localLayout)
+}
+
+func (f *FakeServer) moveShifts(days int) []shift {
+	moved := make([]shift, len(f.shifts))
+	for i, s := range f.shifts {
+		s.StartDateTime, s.EndDateTime = f.move(s.StartDateTime, days), f.move(s.EndDateTime, days)
+		s.Segments = append([]segment(nil), s.Segments...)
+		for j := range s.Segments {
+			s.Segments[j].StartDateTime = f.move(s.Segments[j].StartDateTime, days)
+			s.Segments[j].EndDateTime = f.move(s.Segments[j].EndDateTime, days)
+		}
+		s.ModifiedAt = s.ModifiedAt.AddDate(0, 0, days)
+		moved[i] = s
+	}
+	return moved
+}
+
+func (f *FakeServer) moveTimeOff(days int) []timeOffRequest {
+	moved := make([]timeOffRequest, len(f.timeOff))
+	for i, t := range f.timeOff {
+		t.StartDateTime, t.EndDateTime = f.move(t.StartDateTime, days), f.move(t.EndDateTime, days)
+		t.ModifiedAt = t.ModifiedAt.AddDate(0, 0, days)
+		moved[i] = t
+	}
+	return moved
+}
+
+func (f *FakeServer) movePunches(days int) []punch {
+	moved := make([]punch, len(f.punches))
+	for i, p := range f.punches {
+		p.PunchDateTime = f.move(p.PunchDateTime, days)
+		p.ModifiedAt = p.ModifiedAt.AddDate(0, 0, days)
+		moved[i] = p
+	}
+	return moved
+}
+
+func filter[T any](records []T, keep func(T) bool) []T {
+	var kept []T
+	for _, r := range records {
+		if keep(r) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func writePage[T any](w http.ResponseWriter, q *fakeQuery, records []T) {
+	var p page[T]
+	p.Paging.Page = q.page
+	p.Paging.PageSize = q.pageSize
+	p.Paging.TotalRecords = len(records)
+	p.Paging.TotalPages = (len(records) + q.pageSize - 1) / q.pageSize
+
+	start := min((q.page-1)*q.pageSize, len(records))
+	end := min(start+q.pageSize, len(records))
+	p.Records = records[start:end]
+	if p.Records == nil {
+		p.Records = []T{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
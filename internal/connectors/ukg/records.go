@@ -0,0 +1,213 @@
+package ukg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/connectors"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// localLayout is how UKG writes store-local date-times
+const localLayout = "2006-01-02T15:04:05"
+
+// UKG record shapes, trimmed to the fields Opus uses
+
+type job struct {
+	// OrgPath locates the job in the org tree, e.g. "Store/0142/Front End"
+	OrgPath string `json:"orgPath"`
+	JobName string `json:"jobName"`
+}
+
+type person struct {
+	PersonNumber string   `json:"personNumber"`
+	FirstName    string   `json:"firstName"`
+	LastName     string   `json:"lastName"`
+	PrimaryJob   job      `json:"primaryJob"`
+	Skills       []string `json:"skills,omitempty"`
+	// Status is Active, Inactive or Terminated
+	Status     string    `json:"status"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type segment struct {
+	// Type is REGULAR, MEAL or BREAK
+	Type          string `json:"type"`
+	StartDateTime string `json:"startDateTime"`
+	EndDateTime   string `json:"endDateTime"`
+	Paid          bool   `json:"paid"`
+}
+
+type shift struct {
+	ID            string    `json:"id"`
+	PersonNumber  string    `json:"personNumber"`
+	OrgPath       string    `json:"orgPath"`
+	JobName       string    `json:"jobName"`
+	StartDateTime string    `json:"startDateTime"`
+	EndDateTime   string    `json:"endDateTime"`
+	Segments      []segment `json:"segments,omitempty"`
+	Deleted       bool      `json:"deleted,omitempty"`
+	ModifiedAt    time.Time `json:"modifiedAt"`
+}
+
+type timeOffRequest struct {
+	ID            string `json:"id"`
+	PersonNumber  string `json:"personNumber"`
+	StartDateTime string `json:"startDateTime"`
+	EndDateTime   string `json:"endDateTime"`
+	// Status is PENDING, APPROVED, REFUSED or CANCELLED
+	Status     string    `json:"status"`
+	PayCode    string    `json:"payCode,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type punch struct {
+	ID            string `json:"id"`
+	PersonNumber  string `json:"personNumber"`
+	PunchDateTime string `json:"punchDateTime"`
+	// Type is IN, OUT, MEAL_START or MEAL_END
+	Type       string    `json:"type"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+var timeOffStatuses = map[string]schedule.TimeOffStatus{
+	"PENDING":   schedule.TimeOffPending,
+	"APPROVED":  schedule.TimeOffApproved,
+	"REFUSED":   schedule.TimeOffDenied,
+	"CANCELLED": schedule.TimeOffCancelled,
+}
+
+var punchTypes = map[string]schedule.PunchType{
+	"IN":         schedule.PunchIn,
+	"OUT":        schedule.PunchOut,
+	"MEAL_START": schedule.PunchBreakStart,
+	"MEAL_END":   schedule.PunchBreakEnd,
+}
+
+// mapper turns UKG records into schedule records
+type mapper struct {
+	location *time.Location
+	// departments maps UKG department names to Opus department IDs
+	departments map[string]string
+}
+
+// department maps the last element of an org path to a department ID
+func (m *mapper) department(orgPath string) string {
+	return connectors.DepartmentID(orgPath[strings.LastIndex(orgPath, "/")+1:], m.departments)
+}
+
+func (m *mapper) localTime(value string) (time.Time, error) {
+	t, err := time.ParseInLocation(localLayout, value, m.location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date-time %q", value)
+	}
+	return t, nil
+}
+
+func (m *mapper) employee(p person) schedule.Employee {
+	roles := []string{p.PrimaryJob.JobName}
+	for _, skill := range p.Skills {
+		if skill != p.PrimaryJob.JobName {
+			roles = append(roles, skill)
+		}
+	}
+	return schedule.Employee{
+		ID:         p.PersonNumber,
+		Name:       strings.TrimSpace(p.FirstName + " " + p.LastName),
+		Department: m.department(p.PrimaryJob.OrgPath),
+		Roles:      roles,
+	}
+}
+
+func (m *mapper) shift(s shift, employees map[string]schedule.Employee) (schedule.Shift, error) {
+	start, err := m.localTime(s.StartDateTime)
+	if err != nil {
+		return schedule.Shift{}, fmt.Errorf("shift %s: %w", s.ID, err)
+	}
+	end, err := m.localTime(s.EndDateTime)
+	if err != nil {
+		return schedule.Shift{}, fmt.Errorf("shift %s: %w", s.ID, err)
+	}
+
+	result := schedule.Shift{
+		ID:           s.ID,
+		EmployeeID:   s.PersonNumber,
+		EmployeeName: employees[s.PersonNumber].Name,
+		Department:   m.department(s.OrgPath),
+		Role:         s.JobName,
+		Start:        start,
+		End:          end,
+	}
+	for _, seg := range s.Segments {
+		if seg.Type != "MEAL" && seg.Type != "BREAK" {
+			continue
+		}
+		bStart, err := m.localTime(seg.StartDateTime)
+		if err != nil {
+			return schedule.Shift{}, fmt.Errorf("shift %s: %w", s.ID, err)
+		}
+		bEnd, err := m.localTime(seg.EndDateTime)
+		if err != nil {
+			return schedule.Shift{}, fmt.Errorf("shift %s: %w", s.ID, err)
+		}
+		result.Breaks = append(result.Breaks, schedule.Break{Start: bStart, End: bEnd, Paid: seg.Paid})
+	}
+	return result, nil
+}
+
+func (m *mapper) timeOff(r timeOffRequest, employees map[string]schedule.Employee) (schedule.TimeOff, error) {
+	start, err := m.localTime(r.StartDateTime)
+	if err != nil {
+		return schedule.TimeOff{}, fmt.Errorf("time-off request %s: %w", r.ID, err)
+	}
+	end, err := m.localTime(r.EndDateTime)
+	if err != nil {
+		return schedule.TimeOff{}, fmt.Errorf("time-off request %s: %w", r.ID, err)
+	}
+	status, ok := timeOffStatuses[r.Status]
+	if !ok {
+		return schedule.TimeOff{}, fmt.Errorf("time-off request %s: unknown status %q", r.ID, r.Status)
+	}
+
+	employee := employees[r.PersonNumber]
+	reason := r.PayCode
+	switch {
+	case reason == "":
+		reason = r.Comment
+	case r.Comment != "":
+		reason += ": " + r.Comment
+	}
+	return schedule.TimeOff{
+		ID:           r.ID,
+		EmployeeID:   r.PersonNumber,
+		EmployeeName: employee.Name,
+		Department:   employee.Department,
+		Start:        start,
+		End:          end,
+		Status:       status,
+		Reason:       reason,
+	}, nil
+}
+
+func (m *mapper) punch(p punch, employees map[string]schedule.Employee) (schedule.Punch, error) {
+	at, err := m.localTime(p.PunchDateTime)
+	if err != nil {
+		return schedule.Punch{}, fmt.Errorf("punch %s: %w", p.ID, err)
+	}
+	punchType, ok := punchTypes[p.Type]
+	if !ok {
+		return schedule.Punch{}, fmt.Errorf("punch %s: unknown type %q", p.ID, p.Type)
+	}
+
+	employee := employees[p.PersonNumber]
+	return schedule.Punch{
+		ID:           p.ID,
+		EmployeeID:   p.PersonNumber,
+		EmployeeName: employee.Name,
+		Department:   employee.Department,
+		Type:         punchType,
+		At:           at,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package conversation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+)
+
+// ErrNotFound is returned when a conversation ID doesn't exist
+var ErrNotFound = errors.New("conversation not found")
+
+// Turn is one message in a conversation along with the department whose
+// agent handled it
+type Turn struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	Department ai.Department `json:"department,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+// Conversation is a server-side chat session
+type Conversation struct {
+	ID string `json:"id"`
+	// Owner is the ID of the user who started the conversation, and Scope
+	// the department they were limited to; only they can read or continue
+	// it
+	Owner     string        `json:"owner,omitempty"`
+	Scope     ai.Department `json:"scope,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Turns     []Turn        `json:"turns"`
+	// Summary condenses the first SummarizedTurns turns so long
+	// conversations fit in the model's context window
+	Summary         string `json:"summary,omitempty"`
+	SummarizedTurns int    `json:"summarizedTurns,omitempty"`
+}
+
+// Allows reports whether the user with userID, limited to scope, may read
+// and continue the conversation. A user limited to a department can't
+// pick up a conversation they started with a wider scope.
+func (c *Conversation) Allows(userID string, scope ai.Department) bool {
+	return c.Owner == userID && (scope == "" || scope == c.Scope)
+}
+
+// Messages returns the turns not yet covered by Summary as history for an
+// agent
+func (c *Conversation) Messages() []ai.Message {
+	start := min(c.SummarizedTurns, len(c.Turns))
+	messages := make([]ai.Message, 0, len(c.Turns)-start)
+	for _, turn := range c.Turns[start:] {
+		messages = append(messages, ai.Message{Role: turn.Role, Content: turn.Content})
+	}
+	return messages
+}
+
+// Store persists conversations
+type Store interface {
+	// Create starts a new, empty conversation for owner, limited to scope
+	Create(ctx context.Context, owner string, scope ai.Department) (*Conversation, error)
+	// Get returns the conversation with all its turns, or ErrNotFound
+	Get(ctx context.Context, id string) (*Conversation, error)
+	// Append adds turns to the end of a conversation
+	Append(ctx context.Context, id string, turns ...Turn) error
+	// SetSummary records that the first turns turns are condensed into
+	// summary
+	SetSummary(ctx context.Context, id string, summary string, turns int) error
+	Close() error
+}
+
+// newID generates a random conversation ID
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "conv_" + hex.EncodeToString(b)
+}
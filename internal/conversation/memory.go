@@ -0,0 +1,100 @@
+package conversation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+)
+
+// MemoryStore keeps conversations in memory. They are lost on restart.
+type MemoryStore struct {
+	conversations map[string]*Conversation
+	mu            sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		conversations: make(map[string]*Conversation),
+	}
+}
+
+// Create starts a new, empty conversation
+func (s *MemoryStore) Create(ctx context.Context, owner string, scope ai.Department) (*Conversation, error) {
+	now := time.Now().UTC()
+	conv := &Conversation{
+		ID:        newID(),
+		Owner:     owner,
+		Scope:     scope,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Turns:     []Turn{},
+	}
+
+	s.mu.Lock()
+	s.conversations[conv.ID] = conv
+	s.mu.Unlock()
+
+	return copyConversation(conv), nil
+}
+
+// Get returns a copy of the conversation
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conv, ok := s.conversations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyConversation(conv), nil
+}
+
+// Append adds turns to the end of a conversation
+func (s *MemoryStore) Append(ctx context.Context, id string, turns ...Turn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	for _, turn := range turns {
+		if turn.CreatedAt.IsZero() {
+			turn.CreatedAt = now
+		}
+		conv.Turns = append(conv.Turns, turn)
+	}
+	conv.UpdatedAt = now
+	return nil
+}
+
+// SetSummary records the conversation's running summary
+func (s *MemoryStore) SetSummary(ctx context.Context, id string, summary string, turns int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	conv.Summary = summary
+	conv.SummarizedTurns = turns
+	return nil
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func copyConversation(conv *Conversation) *Conversation {
+	c := *conv
+	c.Turns = append([]Turn{}, conv.Turns...)
+	return &c
+}
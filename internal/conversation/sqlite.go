@@ -0,0 +1,183 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS conversations (
+	id               TEXT PRIMARY KEY,
+	owner            TEXT NOT NULL DEFAULT '',
+	scope            TEXT NOT NULL DEFAULT '',
+	created_at       INTEGER NOT NULL,
+	updated_at       INTEGER NOT NULL,
+	summary          TEXT NOT NULL DEFAULT '',
+	summarized_turns INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS conversation_turns (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	conversation_id TEXT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	role            TEXT NOT NULL,
+	content         TEXT NOT NULL,
+	department      TEXT NOT NULL DEFAULT '',
+	created_at      INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_turns_conversation
+	ON conversation_turns (conversation_id, id);
+`
+
+// SQLiteStore persists conversations in a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if needed) the database at path and
+// ensures the schema exists
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Create starts a new, empty conversation
+func (s *SQLiteStore) Create(ctx context.Context, owner string, scope ai.Department) (*Conversation, error) {
+	now := time.Now().UTC()
+	conv := &Conversation{
+		ID:        newID(),
+		Owner:     owner,
+		Scope:     scope,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Turns:     []Turn{},
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO conversations (id, owner, scope, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		conv.ID, owner, string(scope), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+
+	return conv, nil
+}
+
+// Get returns the conversation with all its turns
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*Conversation, error) {
+	var created, updated int64
+	var owner, scope, summary string
+	var summarized int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT owner, scope, created_at, updated_at, summary, summarized_turns FROM conversations WHERE id = ?`, id).
+		Scan(&owner, &scope, &created, &updated, &summary, &summarized)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation: %w", err)
+	}
+
+	conv := &Conversation{
+		ID:              id,
+		Owner:           owner,
+		Scope:           ai.Department(scope),
+		CreatedAt:       time.UnixMilli(created).UTC(),
+		UpdatedAt:       time.UnixMilli(updated).UTC(),
+		Turns:           []Turn{},
+		Summary:         summary,
+		SummarizedTurns: summarized,
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT role, content, department, created_at FROM conversation_turns
+		 WHERE conversation_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load turns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var turn Turn
+		var dept string
+		var at int64
+		if err := rows.Scan(&turn.Role, &turn.Content, &dept, &at); err != nil {
+			return nil, fmt.Errorf("failed to read turn: %w", err)
+		}
+		turn.Department = ai.Department(dept)
+		turn.CreatedAt = time.UnixMilli(at).UTC()
+		conv.Turns = append(conv.Turns, turn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read turns: %w", err)
+	}
+
+	return conv, nil
+}
+
+// Append adds turns to the end of a conversation in one transaction
+func (s *SQLiteStore) Append(ctx context.Context, id string, turns ...Turn) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	res, err := tx.ExecContext(ctx, `UPDATE conversations SET updated_at = ? WHERE id = ?`, now.UnixMilli(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	for _, turn := range turns {
+		at := turn.CreatedAt
+		if at.IsZero() {
+			at = now
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO conversation_turns (conversation_id, role, content, department, created_at)
+			 VALUES (?, ?, ?, ?, ?)`,
+			id, turn.Role, turn.Content, string(turn.Department), at.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to append turn: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetSummary records the conversation's running summary
+func (s *SQLiteStore) SetSummary(ctx context.Context, id string, summary string, turns int) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE conversations SET summary = ?, summarized_turns = ? WHERE id = ?`, summary, turns, id)
+	if err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,60 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/dokk-dev/opus/internal/ai"
+)
+
+func TestSQLiteStoreKeepsOwner(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "conversations.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer store.Close()
+
+	conv, err := store.Create(ctx, "jsmith", ai.DeptDairy)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := store.Get(ctx, conv.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Owner != "jsmith" || got.Scope != ai.DeptDairy {
+		t.Errorf("got owner %q scope %q, want jsmith and dairy", got.Owner, got.Scope)
+	}
+	if _, err := store.Get(ctx, "conv_missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConversationAllows(t *testing.T) {
+	conv := &Conversation{Owner: "jsmith", Scope: ai.DeptDairy}
+	store := &Conversation{Owner: "boss"}
+
+	tests := []struct {
+		name   string
+		conv   *Conversation
+		userID string
+		scope  ai.Department
+		want   bool
+	}{
+		{"owner", conv, "jsmith", ai.DeptDairy, true},
+		{"owner promoted to store level", conv, "jsmith", "", true},
+		{"another user", conv, "amy", ai.DeptDairy, false},
+		{"owner in another department", conv, "jsmith", ai.DeptMeat, false},
+		{"store-level conversation, owner narrowed to a department", store, "boss", ai.DeptDairy, false},
+		{"store-level conversation, owner", store, "boss", "", true},
+	}
+	for _, tt := range tests {
+		if got := tt.conv.Allows(tt.userID, tt.scope); got != tt.want {
+			t.Errorf("%s: Allows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/checkout"
+	"github.com/dokk-dev/opus/internal/inventory"
+)
+
+// demoLane is a lane's state in the demo: open from 7am unless closed,
+// switching to status minutes ago
+type demoLane struct {
+	id, name, cashier string
+	status            checkout.LaneStatus
+	minutesAgo        int
+}
+
+var demoLanes = []demoLane{
+	{"1", "Lane 1", "Eli Turner", checkout.LaneOpen, 0},
+	{"2", "Lane 2", "Megan Ross", checkout.LaneOpen, 0},
+	{"3", "Lane 3", "Ben Scott", checkout.LaneNeedsAssistance, 2},
+	{"4", "Lane 4", "", checkout.LaneClosed, 0},
+	{"5", "Lane 5", "", checkout.LaneClosed, 0},
+	{"6", "Lane 6", "", checkout.LaneClosed, 0},
+	{"SC1", "Self-checkout 1", "", checkout.LaneOpen, 0},
+	{"SC2", "Self-checkout 2", "", checkout.LaneOpen, 0},
+	{"SC3", "Self-checkout 3", "", checkout.LaneOpen, 0},
+	{"SC4", "Self-checkout 4", "", checkout.LaneOffline, 40},
+}
+
+// transactionsPerHour is the demo's traffic from 7am, peaking at lunch and
+// after work
+var transactionsPerHour = []int{12, 20, 28, 34, 46, 52, 40, 32, 30, 38, 54, 60, 48, 30, 18}
+
+// SeedCheckout fills repo with demo lanes and today's transactions up to
+// now. The same day always produces the same sales. Lane 3 has just
+// called for help and self-checkout 4 has been offline for 40 minutes.
+func SeedCheckout(ctx context.Context, repo checkout.Repository, now time.Time) error {
+	y, m, d := now.Date()
+	opening := time.Date(y, m, d, 7, 0, 0, 0, now.Location())
+	if now.Before(opening) {
+		opening = now
+	}
+
+	for _, lane := range demoLanes {
+		change := checkout.StatusChange{Lane: lane.id, Name: lane.name, Status: checkout.LaneClosed, At: opening}
+		if lane.status != checkout.LaneClosed {
+			change.Status, change.Cashier = checkout.LaneOpen, lane.cashier
+		}
+		if _, err := repo.UpdateLane(ctx, change); err != nil {
+			return err
+		}
+	}
+
+	var open []string
+	for _, lane := range demoLanes {
+		if lane.status == checkout.LaneOpen || lane.status == checkout.LaneNeedsAssistance {
+			open = append(open, lane.id)
+		}
+	}
+
+	items := Inventory()
+	rng := rand.New(rand.NewPCG(uint64(y), uint64(m)*31+uint64(d)))
+	n := 0
+	for hour, count := range transactionsPerHour {
+		start := opening.Add(time.Duration(hour) * time.Hour)
+		for i := 0; i < count; i++ {
+			at := start.Add(time.Duration(rng.IntN(3600)) * time.Second)
+			if !at.Before(now) {
+				continue
+			}
+			n++
+			t := checkout.Transaction{
+				ID:   fmt.Sprintf("DEMO-%s-%05d", opening.Format("0102"), n),
+				Lane: open[rng.IntN(len(open))],
+				At:   at,
+			}
+			for j := rng.IntN(8); j >= 0; j-- {
+				t.Items = append(t.Items, demoLineItem(items, rng))
+			}
+			for _, item := range t.Items {
+				t.Total += item.Amount
+			}
+			if _, err := repo.RecordTransaction(ctx, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Lanes whose status changed recently, after their sales
+	for _, lane := range demoLanes {
+		if lane.minutesAgo == 0 {
+			continue
+		}
+		at := now.Add(-time.Duration(lane.minutesAgo) * time.Minute)
+		if at.Before(opening) {
+			at = opening
+		}
+		change := checkout.StatusChange{Lane: lane.id, Status: lane.status, At: at}
+		if _, err := repo.UpdateLane(ctx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// demoLineItem picks an item, priced from its position in the demo
+// inventory, by the pound for items sold by weight
+func demoLineItem(items []inventory.Item, rng *rand.Rand) checkout.LineItem {
+	i := rng.IntN(len(items))
+	item := items[i]
+	price := 1.49 + float64(i%9)
+	quantity := 1.0
+	if item.Unit == "lb" {
+		quantity = float64(5+rng.IntN(25)) / 10
+	}
+	amount := float64(int(price*quantity*100+0.5)) / 100
+	return checkout.LineItem{
+		SKU:         item.SKU,
+		Description: item.Description,
+		Department:  item.Department,
+		Quantity:    quantity,
+		Amount:      amount,
+	}
+}
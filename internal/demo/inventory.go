@@ -0,0 +1,49 @@
+package demo
+
+import (
+	"time"
+
+	"github.com/dokk-dev/opus/internal/inventory"
+)
+
+// Inventory returns demo items for every built-in department, used to
+// seed the in-memory inventory repository. Expiration dates are relative
+// to today so the demo stays current.
+func Inventory() []inventory.Item {
+	in := func(days int) string {
+		return time.Now().AddDate(0, 0, days).Format("2006-01-02")
+	}
+
+	return []inventory.Item{
+		// Dairy
+		{SKU: "DAI-1001", UPC: "041250000107", Description: "2% Milk, gallon", Department: "dairy", OnHand: 24, Unit: "each", ReorderPoint: 36, ParLevel: 72, Lots: []inventory.Lot{{Number: "DAI-1001-A", Quantity: 16, ExpiresOn: in(1)}, {Number: "DAI-1001-B", Quantity: 8, ExpiresOn: in(4)}}},
+		{SKU: "DAI-1002", UPC: "041250000114", Description: "Whole Milk, gallon", Department: "dairy", OnHand: 40, Unit: "each", ReorderPoint: 30, ParLevel: 60, Lots: []inventory.Lot{{Number: "DAI-1002-A", Quantity: 26, ExpiresOn: in(6)}, {Number: "DAI-1002-B", Quantity: 14, ExpiresOn: in(9)}}},
+		{SKU: "DAI-2001", UPC: "041250000121", Description: "Large Eggs, dozen", Department: "dairy", OnHand: 60, Unit: "each", ReorderPoint: 48, ParLevel: 96, Lots: []inventory.Lot{{Number: "DAI-2001-A", Quantity: 60, ExpiresOn: in(21)}}},
+		{SKU: "DAI-3001", UPC: "041250000128", Description: "Greek Yogurt, 32oz", Department: "dairy", OnHand: 12, Unit: "each", ReorderPoint: 18, ParLevel: 36, Lots: []inventory.Lot{{Number: "DAI-3001-A", Quantity: 12, ExpiresOn: in(9)}}},
+		{SKU: "DAI-4001", UPC: "041250000135", Description: "Salted Butter, 1lb", Department: "dairy", OnHand: 30, Unit: "each", ReorderPoint: 20, ParLevel: 40, Lots: []inventory.Lot{{Number: "DAI-4001-A", Quantity: 30, ExpiresOn: in(45)}}},
+		// Produce
+		{SKU: "PRO-1001", UPC: "041250000142", Description: "Organic Bananas", Department: "produce", OnHand: 18, Unit: "lb", ReorderPoint: 60, ParLevel: 120, Lots: []inventory.Lot{{Number: "PRO-1001-A", Quantity: 18, ExpiresOn: in(3)}}},
+		{SKU: "PRO-1002", UPC: "041250000149", Description: "Hass Avocados", Department: "produce", OnHand: 85, Unit: "each", ReorderPoint: 50, ParLevel: 100, Lots: []inventory.Lot{{Number: "PRO-1002-A", Quantity: 56, ExpiresOn: in(4)}, {Number: "PRO-1002-B", Quantity: 29, ExpiresOn: in(7)}}},
+		{SKU: "PRO-2001", UPC: "041250000156", Description: "Romaine Hearts, 3ct", Department: "produce", OnHand: 40, Unit: "each", ReorderPoint: 30, ParLevel: 60, Lots: []inventory.Lot{{Number: "PRO-2001-A", Quantity: 26, ExpiresOn: in(5)}, {Number: "PRO-2001-B", Quantity: 14, ExpiresOn: in(8)}}},
+		{SKU: "PRO-3001", UPC: "041250000163", Description: "Honeycrisp Apples", Department: "produce", OnHand: 120, Unit: "lb", ReorderPoint: 80, ParLevel: 160, Lots: []inventory.Lot{{Number: "PRO-3001-A", Quantity: 120, ExpiresOn: in(14)}}},
+		// Meat
+		{SKU: "MEA-1001", UPC: "041250000170", Description: "80/20 Ground Beef", Department: "meat", OnHand: 45, Unit: "lb", ReorderPoint: 40, ParLevel: 80, Lots: []inventory.Lot{{Number: "MEA-1001-A", Quantity: 30, ExpiresOn: in(2)}, {Number: "MEA-1001-B", Quantity: 15, ExpiresOn: in(5)}}},
+		{SKU: "MEA-2001", UPC: "041250000177", Description: "Boneless Chicken Breast", Department: "meat", OnHand: 30, Unit: "lb", ReorderPoint: 50, ParLevel: 100, Lots: []inventory.Lot{{Number: "MEA-2001-A", Quantity: 20, ExpiresOn: in(3)}, {Number: "MEA-2001-B", Quantity: 10, ExpiresOn: in(6)}}},
+		{SKU: "MEA-3001", UPC: "041250000184", Description: "Ribeye Steak", Department: "meat", OnHand: 16, Unit: "lb", ReorderPoint: 12, ParLevel: 24, Lots: []inventory.Lot{{Number: "MEA-3001-A", Quantity: 16, ExpiresOn: in(4)}}},
+		// Bakery
+		{SKU: "BAK-1001", UPC: "041250000191", Description: "French Baguette", Department: "bakery", OnHand: 22, Unit: "each", ReorderPoint: 15, ParLevel: 30, Lots: []inventory.Lot{{Number: "BAK-1001-A", Quantity: 14, ExpiresOn: in(1)}, {Number: "BAK-1001-B", Quantity: 8, ExpiresOn: in(4)}}},
+		{SKU: "BAK-2001", UPC: "041250000198", Description: "All-Purpose Flour, 50lb", Department: "bakery", OnHand: 4, Unit: "bag", ReorderPoint: 6, ParLevel: 12},
+		{SKU: "BAK-3001", UPC: "041250000205", Description: "Glazed Donuts, dozen", Department: "bakery", OnHand: 10, Unit: "each", ReorderPoint: 8, ParLevel: 16, Lots: []inventory.Lot{{Number: "BAK-3001-A", Quantity: 10, ExpiresOn: in(0)}}},
+		// Deli
+		{SKU: "DEL-1001", UPC: "041250000212", Description: "Oven Roasted Turkey", Department: "deli", OnHand: 18, Unit: "lb", ReorderPoint: 15, ParLevel: 30, Lots: []inventory.Lot{{Number: "DEL-1001-A", Quantity: 18, ExpiresOn: in(5)}}},
+		{SKU: "DEL-2001", UPC: "041250000219", Description: "Sharp Cheddar, sliced", Department: "deli", OnHand: 9, Unit: "lb", ReorderPoint: 12, ParLevel: 24, Lots: []inventory.Lot{{Number: "DEL-2001-A", Quantity: 9, ExpiresOn: in(10)}}},
+		{SKU: "DEL-3001", UPC: "041250000226", Description: "Egg Salad, 1lb tub", Department: "deli", OnHand: 14, Unit: "each", ReorderPoint: 10, ParLevel: 20, Lots: []inventory.Lot{{Number: "DEL-3001-A", Quantity: 14, ExpiresOn: in(2)}}},
+		// Grocery
+		{SKU: "GRO-1001", UPC: "041250000233", Description: "Canned Black Beans, 15oz", Department: "grocery", OnHand: 140, Unit: "each", ReorderPoint: 96, ParLevel: 192},
+		{SKU: "GRO-2001", UPC: "041250000240", Description: "Honey Nut Cereal, 12oz", Department: "grocery", OnHand: 36, Unit: "each", ReorderPoint: 48, ParLevel: 96},
+		{SKU: "GRO-3001", UPC: "041250000247", Description: "Tortilla Chips, 13oz", Department: "grocery", OnHand: 72, Unit: "each", ReorderPoint: 60, ParLevel: 120},
+		// FrontEnd
+		{SKU: "FE-1001", UPC: "041250000254", Description: "Paper Bags, bundle of 500", Department: "frontend", OnHand: 3, Unit: "bundle", ReorderPoint: 5, ParLevel: 10},
+		{SKU: "FE-2001", UPC: "041250000261", Description: "Receipt Paper Rolls", Department: "frontend", OnHand: 40, Unit: "roll", ReorderPoint: 24, ParLevel: 48},
+	}
+}
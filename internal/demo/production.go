@@ -0,0 +1,39 @@
+package demo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/production"
+)
+
+// SeedProduction fills repo with today's bakery and deli production plans
+// and the last two days of shrink, for when Periscope isn't connected
+func SeedProduction(ctx context.Context, repo production.Repository, now time.Time) error {
+	today := now.Format(time.DateOnly)
+	yesterday := now.AddDate(0, 0, -1).Format(time.DateOnly)
+
+	plans := []production.Plan{
+		{Date: today, SKU: "BAK-1001", Description: "French Baguette", Department: "bakery", Planned: 60, Produced: 24, Unit: "each"},
+		{Date: today, SKU: "BAK-3001", Description: "Glazed Donuts, dozen", Department: "bakery", Planned: 24, Produced: 24, Unit: "each"},
+		{Date: today, SKU: "DEL-3001", Description: "Egg Salad, 1lb tub", Department: "deli", Planned: 12, Produced: 6, Unit: "each"},
+		{Date: today, SKU: "DEL-1001", Description: "Oven Roasted Turkey", Department: "deli", Planned: 20, Produced: 0, Unit: "lb"},
+	}
+	shrink := []production.Shrink{
+		{ID: "DEMO-S1", Date: yesterday, SKU: "BAK-1001", Department: "bakery", Quantity: 6, Reason: "Stale", Cost: 5.94},
+		{ID: "DEMO-S2", Date: yesterday, SKU: "DAI-1001", Department: "dairy", Quantity: 4, Reason: "Expired", Cost: 11.96},
+		{ID: "DEMO-S3", Date: today, SKU: "PRO-1001", Department: "produce", Quantity: 3.5, Reason: "Damaged", Cost: 2.07},
+		{ID: "DEMO-S4", Date: today, SKU: "MEA-1001", Department: "meat", Quantity: 2, Reason: "Expired", Cost: 9.58},
+	}
+	for i := range plans {
+		plans[i].UpdatedAt = now
+	}
+	for i := range shrink {
+		shrink[i].UpdatedAt = now
+	}
+
+	if err := repo.SavePlans(ctx, plans...); err != nil {
+		return err
+	}
+	return repo.SaveShrink(ctx, shrink...)
+}
@@ -0,0 +1,152 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// demoShift is a shift worked every day; End before Start ends the next day
+type demoShift struct {
+	employee   string
+	role       string
+	start, end string // HH:MM
+}
+
+var demoShifts = map[string][]demoShift{
+	"dairy":    {{"Maria Lopez", "Dairy Manager", "06:00", "14:30"}, {"Kevin Park", "Dairy Clerk", "14:00", "22:30"}},
+	"produce":  {{"Sam Patel", "Produce Manager", "05:00", "13:30"}, {"Alicia Chen", "Produce Clerk", "07:00", "15:30"}, {"Jordan Reyes", "Produce Clerk", "13:00", "21:30"}},
+	"meat":     {{"Dan Murphy", "Meat Manager", "06:00", "14:30"}, {"Luis Ortega", "Meat Cutter", "10:00", "18:30"}},
+	"bakery":   {{"Grace Kim", "Bakery Manager", "04:00", "12:30"}, {"Tom Willis", "Baker", "04:00", "12:30"}, {"Nina Shah", "Cake Decorator", "09:00", "17:30"}},
+	"deli":     {{"Rachel Adams", "Deli Manager", "07:00", "15:30"}, {"Chris Young", "Deli Clerk", "11:00", "19:30"}, {"Ava Brooks", "Deli Clerk", "14:00", "22:00"}},
+	"grocery":  {{"Mike Johnson", "Grocery Manager", "06:00", "14:30"}, {"Overnight Crew", "Stocker", "22:00", "06:30"}},
+	"frontend": {{"Tasha Green", "Front End Manager", "08:00", "16:30"}, {"Eli Turner", "Cashier", "09:00", "17:00"}, {"Megan Ross", "Cashier", "12:00", "20:00"}, {"Ben Scott", "Cashier", "16:00", "22:00"}},
+}
+
+// demoTargets are staffing needs for every day of the week. The shift
+// template meets them except during the Saturday afternoon rush.
+var demoTargets = []schedule.CoverageTarget{
+	{Department: "dairy", StartHour: 6, EndHour: 22, Required: 1},
+	{Department: "produce", StartHour: 5, EndHour: 21, Required: 1},
+	{Department: "produce", StartHour: 13, EndHour: 15, Required: 2},
+	{Department: "meat", StartHour: 6, EndHour: 18, Required: 1},
+	{Department: "bakery", StartHour: 4, EndHour: 17, Required: 1},
+	{Department: "deli", StartHour: 7, EndHour: 22, Required: 1},
+	{Department: "deli", StartHour: 14, EndHour: 19, Required: 2},
+	{Department: "grocery", StartHour: 6, EndHour: 14, Required: 1},
+	{Department: "frontend", Role: "Front End Manager", StartHour: 8, EndHour: 17, Required: 1},
+	{Department: "frontend", Role: "Cashier", StartHour: 9, EndHour: 12, Required: 1},
+	{Department: "frontend", Role: "Cashier", StartHour: 12, EndHour: 20, Required: 2},
+	{Department: "frontend", Role: "Cashier", StartHour: 20, EndHour: 22, Required: 1},
+}
+
+// saturdayRush is the extra cashier needed on Saturday afternoons
+var saturdayRush = schedule.CoverageTarget{Department: "frontend", Role: "Cashier", Weekday: time.Saturday, StartHour: 16, EndHour: 17, Required: 3}
+
+// mealBreakAfter is when a shift of six hours or more gets its unpaid
+// 30-minute meal break
+const mealBreakAfter = 4 * time.Hour
+
+// SeedSchedule fills repo with demo employees, coverage targets and the
+// same daily shifts from the week before now to two weeks after it.
+// Megan Ross has approved time off on the coming Saturday, leaving the
+// front end a cashier short that afternoon.
+func SeedSchedule(ctx context.Context, repo schedule.Repository, now time.Time) error {
+	var employees []schedule.Employee
+	var shifts []schedule.Shift
+	byName := make(map[string]schedule.Employee)
+
+	weekStart := schedule.WeekStart(now)
+	first, last := weekStart.AddDate(0, 0, -7), weekStart.AddDate(0, 0, 21)
+
+	for dept, template := range demoShifts {
+		for i, sh := range template {
+			employee := schedule.Employee{
+				ID:         fmt.Sprintf("%s-%d", dept, i+1),
+				Name:       sh.employee,
+				Department: dept,
+				Roles:      []string{sh.role},
+			}
+			employees = append(employees, employee)
+			byName[employee.Name] = employee
+
+			for day := first; day.Before(last); day = day.AddDate(0, 0, 1) {
+				start, end := clock(day, sh.start), clock(day, sh.end)
+				if !end.After(start) {
+					end = end.AddDate(0, 0, 1)
+				}
+				shift := schedule.Shift{
+					ID:           fmt.Sprintf("%s-%s", employee.ID, day.Format("20060102")),
+					EmployeeID:   employee.ID,
+					EmployeeName: employee.Name,
+					Department:   dept,
+					Role:         sh.role,
+					Start:        start,
+					End:          end,
+				}
+				if end.Sub(start) >= 6*time.Hour {
+					breakStart := start.Add(mealBreakAfter)
+					shift.Breaks = []schedule.Break{{Start: breakStart, End: breakStart.Add(30 * time.Minute)}}
+				}
+				shifts = append(shifts, shift)
+			}
+		}
+	}
+
+	today := clock(now, "00:00")
+	saturday := today.AddDate(0, 0, (int(time.Saturday)-int(today.Weekday())+7)%7)
+	megan, tom := byName["Megan Ross"], byName["Tom Willis"]
+	timeOff := []schedule.TimeOff{
+		{
+			ID:           "to-1",
+			EmployeeID:   megan.ID,
+			EmployeeName: megan.Name,
+			Department:   megan.Department,
+			Start:        saturday,
+			End:          saturday.AddDate(0, 0, 1),
+			Status:       schedule.TimeOffApproved,
+			Reason:       "Family event",
+		},
+		{
+			ID:           "to-2",
+			EmployeeID:   tom.ID,
+			EmployeeName: tom.Name,
+			Department:   tom.Department,
+			Start:        saturday.AddDate(0, 0, 3),
+			End:          saturday.AddDate(0, 0, 5),
+			Status:       schedule.TimeOffPending,
+			Reason:       "Vacation",
+		},
+	}
+
+	if err := repo.SaveEmployees(ctx, employees...); err != nil {
+		return err
+	}
+	if err := repo.SaveShifts(ctx, shifts...); err != nil {
+		return err
+	}
+	if err := repo.SaveTimeOff(ctx, timeOff...); err != nil {
+		return err
+	}
+	return repo.SaveTargets(ctx, CoverageTargets()...)
+}
+
+// CoverageTargets returns demo staffing needs for every day of the week
+func CoverageTargets() []schedule.CoverageTarget {
+	var targets []schedule.CoverageTarget
+	for _, t := range demoTargets {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			t.Weekday = day
+			targets = append(targets, t)
+		}
+	}
+	return append(targets, saturdayRush)
+}
+
+// clock returns the time HH:MM on day
+func clock(day time.Time, hhmm string) time.Time {
+	t, _ := time.Parse("15:04", hhmm)
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+}
@@ -0,0 +1,27 @@
+package demo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+)
+
+// Sensors reports fixed cooler, freezer and case temperatures until a
+// monitoring system is connected. The deli's service case runs warm.
+type Sensors struct{}
+
+// Temperatures returns a fresh reading from every demo sensor
+func (Sensors) Temperatures(ctx context.Context) ([]alerts.TemperatureReading, error) {
+	readAt := time.Now().Add(-time.Minute)
+	return []alerts.TemperatureReading{
+		{SensorID: "dairy-cooler-1", Name: "Dairy cooler 1", Department: "dairy", Fahrenheit: 36.5, MinF: 33, MaxF: 40, ReadAt: readAt},
+		{SensorID: "dairy-cooler-2", Name: "Dairy cooler 2", Department: "dairy", Fahrenheit: 37.8, MinF: 33, MaxF: 40, ReadAt: readAt},
+		{SensorID: "meat-case-1", Name: "Meat case", Department: "meat", Fahrenheit: 32.4, MinF: 28, MaxF: 36, ReadAt: readAt},
+		{SensorID: "meat-freezer-1", Name: "Meat freezer", Department: "meat", Fahrenheit: -4.2, MinF: -20, MaxF: 0, ReadAt: readAt},
+		{SensorID: "produce-cooler-1", Name: "Produce cooler", Department: "produce", Fahrenheit: 38.1, MinF: 32, MaxF: 45, ReadAt: readAt},
+		{SensorID: "deli-case-1", Name: "Deli grab-and-go case", Department: "deli", Fahrenheit: 38.9, MinF: 33, MaxF: 41, ReadAt: readAt},
+		{SensorID: "deli-case-2", Name: "Deli service case", Department: "deli", Fahrenheit: 43.6, MinF: 33, MaxF: 41, ReadAt: readAt},
+		{SensorID: "frozen-aisle-1", Name: "Frozen aisle doors 1-12", Department: "grocery", Fahrenheit: -2.0, MinF: -10, MaxF: 5, ReadAt: readAt},
+	}, nil
+}
@@ -0,0 +1,167 @@
+package departments
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"gopkg.in/yaml.v3"
+)
+
+// defaults are the built-in definitions, used when no directory is
+// configured
+//
+//go:embed defaults/*.yaml
+var defaults embed.FS
+
+// idPattern restricts IDs to what works in URLs, tool arguments and
+// directory names
+var idPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// reservedIDs are department values with a special meaning elsewhere
+var reservedIDs = map[string]bool{
+	string(ai.DeptStore): true,
+	"ambiguous":          true,
+}
+
+// definition is the YAML form of a department
+type definition struct {
+	ID        string   `yaml:"id"`
+	Name      string   `yaml:"name"`
+	Version   int      `yaml:"version"`
+	Prompt    string   `yaml:"prompt"`
+	Knowledge string   `yaml:"knowledge"`
+	Keywords  []string `yaml:"keywords"`
+	Model     string   `yaml:"model"`
+	Tools     []string `yaml:"tools"`
+}
+
+// Load reads every .yaml or .yml file in dir as a department definition.
+// Files are read in name order, which is also routing priority order, so
+// prefix them with numbers to control it. An empty dir loads the built-in
+// definitions.
+func Load(dir string) ([]ai.DepartmentDefinition, error) {
+	if dir == "" {
+		sub, err := fs.Sub(defaults, "defaults")
+		if err != nil {
+			return nil, err
+		}
+		return load(sub)
+	}
+	return load(os.DirFS(dir))
+}
+
+func load(fsys fs.FS) ([]ai.DepartmentDefinition, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read department definitions: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var defs []ai.DepartmentDefinition
+	var errs []error
+	seen := make(map[ai.Department]string)
+
+	for _, name := range names {
+		def, err := loadFile(fsys, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if other, ok := seen[def.ID]; ok {
+			errs = append(errs, fmt.Errorf("%s: department %q is already defined in %s", name, def.ID, other))
+			continue
+		}
+		seen[def.ID] = name
+		defs = append(defs, def)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("no department definitions found")
+	}
+	return defs, nil
+}
+
+func loadFile(fsys fs.FS, name string) (ai.DepartmentDefinition, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return ai.DepartmentDefinition{}, err
+	}
+
+	var d definition
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+		return ai.DepartmentDefinition{}, err
+	}
+
+	def := ai.DepartmentDefinition{
+		ID:             ai.Department(d.ID),
+		Name:           strings.TrimSpace(d.Name),
+		Version:        d.Version,
+		PromptTemplate: d.Prompt,
+		Knowledge:      strings.TrimSpace(d.Knowledge),
+		Keywords:       d.Keywords,
+		Model:          strings.TrimSpace(d.Model),
+		Tools:          d.Tools,
+	}
+	return def, validate(def)
+}
+
+// validate checks everything about a definition that doesn't depend on
+// the rest of the server; tool names are checked when agents are built
+func validate(def ai.DepartmentDefinition) error {
+	var errs []error
+
+	switch id := string(def.ID); {
+	case id == "":
+		errs = append(errs, errors.New("id is required"))
+	case !idPattern.MatchString(id):
+		errs = append(errs, fmt.Errorf("id %q must be lowercase letters, digits and underscores", id))
+	case reservedIDs[id]:
+		errs = append(errs, fmt.Errorf("id %q is reserved", id))
+	}
+
+	if def.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if def.Version < 1 {
+		errs = append(errs, errors.New("version must be 1 or higher"))
+	}
+
+	if len(def.Keywords) == 0 {
+		errs = append(errs, errors.New("at least one routing keyword is required"))
+	}
+	for _, keyword := range def.Keywords {
+		if strings.TrimSpace(keyword) == "" {
+			errs = append(errs, errors.New("keywords must not be empty"))
+			break
+		}
+	}
+
+	if _, err := def.SystemPrompt(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
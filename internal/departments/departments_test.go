@@ -0,0 +1,99 @@
+package departments
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dokk-dev/opus/internal/ai"
+)
+
+const dairyYAML = `id: dairy
+name: Dairy
+version: 2
+keywords: [milk, cheese]
+model: llama3.1
+tools: [get_inventory]
+knowledge: |
+  Coolers stay at 35-38F.
+`
+
+func TestLoadDefaults(t *testing.T) {
+	defs, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(defs) < 7 || defs[0].ID != ai.DeptDairy {
+		t.Errorf("got %d definitions starting with %q, want the built-ins starting with dairy", len(defs), defs[0].ID)
+	}
+}
+
+func TestLoadReadsDefinitionsInNameOrder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20-meat.yml":   {Data: []byte("id: meat\nname: Meat\nversion: 1\nkeywords: [beef]\n")},
+		"10-dairy.yaml": {Data: []byte(dairyYAML)},
+		"README.md":     {Data: []byte("not a definition")},
+	}
+
+	defs, err := load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(defs) != 2 || defs[0].ID != ai.DeptDairy || defs[1].ID != ai.DeptMeat {
+		t.Fatalf("got %+v, want dairy then meat", defs)
+	}
+	dairy := defs[0]
+	if dairy.Name != "Dairy" || dairy.Version != 2 || dairy.Model != "llama3.1" || dairy.Knowledge != "Coolers stay at 35-38F." {
+		t.Errorf("dairy = %+v", dairy)
+	}
+	if strings.Join(dairy.Keywords, ",") != "milk,cheese" || strings.Join(dairy.Tools, ",") != "get_inventory" {
+		t.Errorf("dairy keywords %v, tools %v", dairy.Keywords, dairy.Tools)
+	}
+}
+
+func TestLoadRejectsInvalidDefinitions(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown field", dairyYAML + "colour: blue\n", "field colour not found"},
+		{"missing id", "name: Dairy\nversion: 1\nkeywords: [milk]\n", "id is required"},
+		{"bad id", "id: Dairy Case\nname: Dairy\nversion: 1\nkeywords: [milk]\n", "must be lowercase"},
+		{"reserved id", "id: store\nname: Store\nversion: 1\nkeywords: [milk]\n", "is reserved"},
+		{"missing name", "id: dairy\nversion: 1\nkeywords: [milk]\n", "name is required"},
+		{"missing version", "id: dairy\nname: Dairy\nkeywords: [milk]\n", "version must be 1 or higher"},
+		{"no keywords", "id: dairy\nname: Dairy\nversion: 1\n", "at least one routing keyword"},
+		{"empty keyword", "id: dairy\nname: Dairy\nversion: 1\nkeywords: [milk, ' ']\n", "keywords must not be empty"},
+		{"bad template", "id: dairy\nname: Dairy\nversion: 1\nkeywords: [milk]\nprompt: '{{.Aisle}}'\n", "invalid prompt template"},
+		{"empty file", "", "id is required"},
+	}
+	for _, tt := range tests {
+		_, err := load(fstest.MapFS{"01-dairy.yaml": {Data: []byte(tt.yaml)}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "01-dairy.yaml") {
+			t.Errorf("%s: err = %v, want %q naming the file", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	fsys := fstest.MapFS{
+		"01-dairy.yaml":  {Data: []byte(dairyYAML)},
+		"02-dairy.yaml":  {Data: []byte(dairyYAML)},
+		"03-bakery.yaml": {Data: []byte("id: bakery\nversion: 1\nkeywords: [bread]\n")},
+	}
+
+	_, err := load(fsys)
+	if err == nil {
+		t.Fatal("load succeeded with a duplicate and an invalid definition")
+	}
+	for _, want := range []string{`02-dairy.yaml: department "dairy" is already defined in 01-dairy.yaml`, "03-bakery.yaml: name is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, missing %q", err, want)
+		}
+	}
+
+	if _, err := load(fstest.MapFS{}); err == nil {
+		t.Error("load succeeded with no definitions")
+	}
+}
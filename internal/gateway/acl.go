@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"strings"
+
+	"github.com/dokk-dev/opus/internal/auth"
+)
+
+// Channel names are a kind prefix and a name:
+//
+//	store:<id>  everyone in the store
+//	dept:<id>   the department's managers and store-level staff of the
+//	            store this server serves
+//	room:<name> shared chat rooms open to any signed-in user
+//
+// Admins may join any channel.
+
+// CanJoin reports whether client may join channel. store is the store
+// this server serves; without it only admins may join department channels.
+func CanJoin(client *Client, channel, store string) bool {
+	kind, name, ok := strings.Cut(channel, ":")
+	if !ok || name == "" {
+		return false
+	}
+	if client.Role == auth.RoleAdmin {
+		return true
+	}
+
+	switch kind {
+	case "store":
+		return name == client.Store
+	case "dept":
+		if store == "" || client.Store != store {
+			return false
+		}
+		return client.Department == "" || name == client.Department
+	case "room":
+		return true
+	default:
+		return false
+	}
+}
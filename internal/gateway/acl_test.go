@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/config"
+)
+
+func TestCanJoin(t *testing.T) {
+	manager := &Client{Role: auth.RoleManager, Store: "42", Department: "dairy"}
+	storeManager := &Client{Role: auth.RoleManager, Store: "42"}
+	otherStore := &Client{Role: auth.RoleManager, Store: "7", Department: "dairy"}
+	admin := &Client{Role: auth.RoleAdmin}
+
+	tests := []struct {
+		name    string
+		client  *Client
+		channel string
+		store   string
+		want    bool
+	}{
+		{"own store", manager, "store:42", "42", true},
+		{"another store", manager, "store:7", "42", false},
+		{"own department", manager, "dept:dairy", "42", true},
+		{"another department", manager, "dept:meat", "42", false},
+		{"store-level staff, any department", storeManager, "dept:meat", "42", true},
+		{"same department of another store", otherStore, "dept:dairy", "42", false},
+		{"department without a store configured", manager, "dept:dairy", "", false},
+		{"admin", admin, "dept:dairy", "", true},
+		{"room", otherStore, "room:general", "42", true},
+		{"unknown kind", manager, "team:dairy", "42", false},
+		{"no name", manager, "room:", "42", false},
+	}
+	for _, tt := range tests {
+		if got := CanJoin(tt.client, tt.channel, tt.store); got != tt.want {
+			t.Errorf("%s: CanJoin(%q) = %v, want %v", tt.name, tt.channel, got, tt.want)
+		}
+	}
+}
+
+func TestPostsOnlyReachRooms(t *testing.T) {
+	gw := New(&config.Config{StoreID: "42"}, nil)
+	client := &Client{
+		ID:       "jsmith",
+		Gateway:  gw,
+		Send:     make(chan []byte, 8),
+		Channels: make(map[string]bool),
+		Role:     auth.RoleManager,
+		Store:    "42",
+	}
+	gw.mu.Lock()
+	gw.clients[client] = true
+	gw.mu.Unlock()
+
+	next := func() Message {
+		t.Helper()
+		select {
+		case data := <-client.Send:
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatal(err)
+			}
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("no message received")
+			return Message{}
+		}
+	}
+
+	client.handleMessage(&Message{Type: "join", Channel: "store:42"})
+	client.handleMessage(&Message{Type: "post", ID: "1", Channel: "store:42", Content: "Truck is here"})
+	if msg := next(); msg.Type != "error" || !strings.Contains(string(msg.Data), ErrCodeForbidden) {
+		t.Errorf("post to a store channel got %s %s, want a forbidden error", msg.Type, msg.Data)
+	}
+
+	client.handleMessage(&Message{Type: "join", Channel: "room:general"})
+	client.handleMessage(&Message{Type: "post", ID: "2", Channel: "room:general", Content: "Truck is here"})
+	if msg := next(); msg.Type != "post" || msg.Content != "Truck is here" || msg.From != "jsmith" {
+		t.Errorf("post to a room got %+v", msg)
+	}
+}
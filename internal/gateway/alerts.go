@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+)
+
+// alertQueueSize is how many alert messages can wait to be pushed
+const alertQueueSize = 100
+
+// PublishAlert pushes an alert event to the alert's department channel,
+// dept:<id>, as a message whose type is the event type and whose Data is
+// the alert. Pass it to alerts.Engine.Subscribe; it queues the message
+// rather than waiting for the gateway, and drops it if the queue is full.
+func (gw *Gateway) PublishAlert(event alerts.Event) {
+	data, err := json.Marshal(event.Alert)
+	if err != nil {
+		log.Printf("Error marshaling alert %s: %v", event.Alert.ID, err)
+		return
+	}
+
+	msg := &Message{
+		Type:      event.Type,
+		Channel:   "dept:" + event.Alert.Department,
+		Content:   event.Alert.Title,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+	select {
+	case gw.alertMessages <- msg:
+	default:
+		log.Printf("Gateway: alert queue is full, dropping %s for alert %s", event.Type, event.Alert.ID)
+	}
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/config"
+)
+
+func TestPublishAlertDoesNotBlock(t *testing.T) {
+	// A gateway whose run loop never started stands in for one that's busy
+	gw := &Gateway{
+		config:        &config.Config{},
+		clients:       make(map[*Client]bool),
+		channels:      make(map[string]map[*Client]bool),
+		broadcast:     make(chan *Message),
+		alertMessages: make(chan *Message, alertQueueSize),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < alertQueueSize+10; i++ {
+			gw.PublishAlert(alerts.Event{Type: alerts.EventCreated, Alert: alerts.Alert{ID: "1", Department: "dairy"}})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("PublishAlert blocked on a busy gateway")
+	}
+	if len(gw.alertMessages) != alertQueueSize {
+		t.Errorf("queued %d alerts, want %d", len(gw.alertMessages), alertQueueSize)
+	}
+}
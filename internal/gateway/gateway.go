@@ -1,24 +1,29 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/chat"
 	"github.com/dokk-dev/opus/internal/config"
+	"github.com/dokk-dev/opus/internal/conversation"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // TODO: Implement proper origin checking in production
-	},
-}
-
 // Message represents a WebSocket message
 type Message struct {
-	Type      string          `json:"type"`
+	Type string `json:"type"`
+	// ID is chosen by the client for a request and echoed on every reply
+	// to it, so concurrent requests can be told apart
+	ID        string          `json:"id,omitempty"`
 	Channel   string          `json:"channel,omitempty"`
 	From      string          `json:"from,omitempty"`
 	Content   string          `json:"content,omitempty"`
@@ -26,55 +31,137 @@ type Message struct {
 	Timestamp int64           `json:"timestamp,omitempty"`
 }
 
-// Client represents a connected WebSocket client
+// Error codes sent in the ErrorPayload of an "error" or "chat.error"
+// message
+const (
+	ErrCodeInvalidMessage       = "invalid_message"
+	ErrCodeEmptyMessage         = "empty_message"
+	ErrCodeUnknownDepartment    = "unknown_department"
+	ErrCodeHistoryConflict      = "history_conflict"
+	ErrCodeConversationNotFound = "conversation_not_found"
+	ErrCodeNotInChannel         = "not_in_channel"
+	ErrCodeForbidden            = "forbidden"
+	ErrCodeOutOfScope           = "out_of_scope"
+	ErrCodeUnavailable          = "ai_unavailable"
+	ErrCodeBusy                 = "busy"
+)
+
+// maxChatsPerClient is how many chats one connection can have in flight
+const maxChatsPerClient = 2
+
+// ErrorPayload is the Data attached to an error message
+type ErrorPayload struct {
+	Code string `json:"code"`
+}
+
+// ChatPayload is the optional Data attached to a "chat" message
+type ChatPayload struct {
+	History []ai.Message `json:"history,omitempty"`
+	// ConversationID continues a server-side conversation
+	ConversationID string `json:"conversationId,omitempty"`
+	// Department skips routing when the user has picked one
+	Department string `json:"department,omitempty"`
+}
+
+// ChatResult is the Data attached to a "chat.done" message
+type ChatResult struct {
+	Department     string   `json:"department"`
+	Model          string   `json:"model"`
+	Ambiguous      bool     `json:"ambiguous,omitempty"`
+	Candidates     []string `json:"candidates,omitempty"`
+	Departments    []string `json:"departments,omitempty"`
+	ConversationID string   `json:"conversationId,omitempty"`
+	// Actions are suggested next steps to show as buttons
+	Actions []ai.Action `json:"actions,omitempty"`
+	// Citations are the document passages the answer cites as [n]
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation is a passage from a store document
+type Citation struct {
+	Number  int    `json:"number"`
+	Source  string `json:"source"`
+	Section string `json:"section,omitempty"`
+	Page    int    `json:"page,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Client represents a connected WebSocket client. ID, Role, Store and
+// Department come from the token the connection was opened with.
 type Client struct {
-	ID       string
-	Conn     *websocket.Conn
-	Gateway  *Gateway
-	Send     chan []byte
-	Channels map[string]bool
-	Role     string // manager, assistant_manager, admin
+	ID         string
+	Conn       *websocket.Conn
+	Gateway    *Gateway
+	Send       chan []byte
+	Channels   map[string]bool
+	Role       auth.Role
+	Store      string
+	Department string
+
+	// ctx is cancelled when the connection closes so in-flight AI
+	// requests for this client stop
+	ctx    context.Context
+	cancel context.CancelFunc
+	// chats holds a slot for each chat being answered
+	chats chan struct{}
 }
 
 // Gateway manages WebSocket connections and message routing
 type Gateway struct {
 	config     *config.Config
-	clients    map[string]*Client
+	chat       *chat.Service
+	upgrader   websocket.Upgrader
+	clients    map[*Client]bool
 	channels   map[string]map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan *Message
-	mu         sync.RWMutex
+	// alertMessages queues alert pushes so the alert engine never waits
+	// on the gateway
+	alertMessages chan *Message
+	mu            sync.RWMutex
 }
 
 // New creates a new Gateway instance
-func New(cfg *config.Config) *Gateway {
+func New(cfg *config.Config, chatService *chat.Service) *Gateway {
 	gw := &Gateway{
-		config:     cfg,
-		clients:    make(map[string]*Client),
-		channels:   make(map[string]map[*Client]bool),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan *Message),
+		config:        cfg,
+		chat:          chatService,
+		clients:       make(map[*Client]bool),
+		channels:      make(map[string]map[*Client]bool),
+		register:      make(chan *Client),
+		unregister:    make(chan *Client),
+		broadcast:     make(chan *Message),
+		alertMessages: make(chan *Message, alertQueueSize),
 	}
 
+	gw.upgrader.CheckOrigin = gw.checkOrigin
+
 	go gw.run()
 	return gw
 }
 
+// checkOrigin allows requests from CORSOrigins. Requests without an
+// Origin header don't come from a browser, so cross-site hijacking
+// doesn't apply to them.
+func (gw *Gateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || gw.config.AllowsOrigin(origin)
+}
+
 func (gw *Gateway) run() {
 	for {
 		select {
 		case client := <-gw.register:
 			gw.mu.Lock()
-			gw.clients[client.ID] = client
+			gw.clients[client] = true
 			gw.mu.Unlock()
-			log.Printf("Client connected: %s", client.ID)
+			log.Printf("Client connected: %s (%s)", client.ID, client.Role)
 
 		case client := <-gw.unregister:
 			gw.mu.Lock()
-			if _, ok := gw.clients[client.ID]; ok {
-				delete(gw.clients, client.ID)
+			if gw.clients[client] {
+				delete(gw.clients, client)
 				close(client.Send)
 				// Remove from all channels
 				for channel := range client.Channels {
@@ -88,6 +175,9 @@ func (gw *Gateway) run() {
 
 		case msg := <-gw.broadcast:
 			gw.broadcastToChannel(msg)
+
+		case msg := <-gw.alertMessages:
+			gw.broadcastToChannel(msg)
 		}
 	}
 }
@@ -98,7 +188,7 @@ func (gw *Gateway) broadcastToChannel(msg *Message) {
 
 	if msg.Channel == "" {
 		// Broadcast to all clients
-		for _, client := range gw.clients {
+		for client := range gw.clients {
 			gw.sendToClient(client, msg)
 		}
 	} else {
@@ -126,20 +216,45 @@ func (gw *Gateway) sendToClient(client *Client, msg *Message) {
 	}
 }
 
-// HandleWebSocket handles WebSocket upgrade and connection
+// HandleWebSocket authenticates the request with a JWT and upgrades it
+// to a WebSocket connection
 func (gw *Gateway) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if gw.config.JWTSecret == "" {
+		http.Error(w, `{"error": "WebSocket authentication is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	token, err := auth.TokenFromUpgrade(r)
+	if err != nil {
+		http.Error(w, `{"error": "Authentication required"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := auth.ParseToken([]byte(gw.config.JWTSecret), token)
+	if err != nil {
+		log.Printf("WebSocket auth failed from %s: %v", r.RemoteAddr, err)
+		http.Error(w, `{"error": "Invalid token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := gw.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	client := &Client{
-		ID:       generateClientID(),
-		Conn:     conn,
-		Gateway:  gw,
-		Send:     make(chan []byte, 256),
-		Channels: make(map[string]bool),
+		ID:         claims.Subject,
+		Conn:       conn,
+		Gateway:    gw,
+		Send:       make(chan []byte, 256),
+		Channels:   make(map[string]bool),
+		Role:       claims.Role,
+		Store:      claims.Store,
+		Department: claims.Department,
+		ctx:        ctx,
+		cancel:     cancel,
+		chats:      make(chan struct{}, maxChatsPerClient),
 	}
 
 	gw.register <- client
@@ -153,6 +268,26 @@ func (gw *Gateway) Broadcast(msg *Message) {
 	gw.broadcast <- msg
 }
 
+// SendTo delivers a message to a single client if it is still connected
+func (gw *Gateway) SendTo(client *Client, msg *Message) {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+
+	// The client's Send channel is closed on unregister, so check under
+	// the lock that it is still registered before writing to it
+	if !gw.clients[client] {
+		return
+	}
+	gw.sendToClient(client, msg)
+}
+
+// inChannel reports whether client has joined channel
+func (gw *Gateway) inChannel(client *Client, channel string) bool {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+	return gw.channels[channel][client]
+}
+
 // JoinChannel adds a client to a channel
 func (gw *Gateway) JoinChannel(client *Client, channel string) {
 	gw.mu.Lock()
@@ -167,6 +302,7 @@ func (gw *Gateway) JoinChannel(client *Client, channel string) {
 
 func (c *Client) readPump() {
 	defer func() {
+		c.cancel()
 		c.Gateway.unregister <- c
 		c.Conn.Close()
 	}()
@@ -183,6 +319,7 @@ func (c *Client) readPump() {
 		var msg Message
 		if err := json.Unmarshal(message, &msg); err != nil {
 			log.Printf("Error unmarshaling message: %v", err)
+			c.sendError("error", "", ErrCodeInvalidMessage, "Invalid message")
 			continue
 		}
 
@@ -205,28 +342,168 @@ func (c *Client) writePump() {
 func (c *Client) handleMessage(msg *Message) {
 	switch msg.Type {
 	case "join":
+		if !CanJoin(c, msg.Channel, c.Gateway.config.StoreID) {
+			log.Printf("Client %s (%s) denied channel %q", c.ID, c.Role, msg.Channel)
+			c.sendError("error", msg.ID, ErrCodeForbidden, "Not allowed to join this channel")
+			return
+		}
 		c.Gateway.JoinChannel(c, msg.Channel)
 	case "chat":
-		// Route to AI handler
-		msg.From = c.ID
-		c.Gateway.Broadcast(msg)
+		select {
+		case c.chats <- struct{}{}:
+		default:
+			c.sendError("chat.error", msg.ID, ErrCodeBusy, "Wait for your other questions to be answered")
+			return
+		}
+		// Answer in the background so the read loop keeps serving pings
+		// and a disconnect can cancel the model request
+		go func() {
+			defer func() { <-c.chats }()
+			c.handleChat(msg)
+		}()
+	case "post":
+		c.handlePost(msg)
 	case "ping":
-		response := &Message{Type: "pong"}
-		data, _ := json.Marshal(response)
-		c.Send <- data
+		c.Gateway.SendTo(c, &Message{Type: "pong", ID: msg.ID})
 	}
 }
 
-func generateClientID() string {
-	// Simple ID generation - use UUID in production
-	return "client_" + randomString(8)
+// scope is the department the client's chats are limited to, if any
+func (c *Client) scope() string {
+	if c.Role == auth.RoleAdmin {
+		return ""
+	}
+	return c.Department
+}
+
+// sendError sends an error message of the given type with a
+// machine-readable code alongside the human-readable content
+func (c *Client) sendError(msgType, id, code, content string) {
+	data, _ := json.Marshal(ErrorPayload{Code: code})
+	c.Gateway.SendTo(c, &Message{
+		Type:      msgType,
+		ID:        id,
+		Content:   content,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// handlePost shares a message with everyone in a room the client has
+// joined. This is the only way a client's message reaches other clients;
+// chat questions are answered privately. Store and department channels
+// are for server announcements and alerts, so clients can't post to them.
+func (c *Client) handlePost(msg *Message) {
+	if !strings.HasPrefix(msg.Channel, "room:") {
+		c.sendError("error", msg.ID, ErrCodeForbidden, "Only rooms accept posts")
+		return
+	}
+	if msg.Channel == "" || !c.Gateway.inChannel(c, msg.Channel) {
+		c.sendError("error", msg.ID, ErrCodeNotInChannel, "Join the channel before posting to it")
+		return
+	}
+	if msg.Content == "" {
+		c.sendError("error", msg.ID, ErrCodeEmptyMessage, "Message is required")
+		return
+	}
+
+	c.Gateway.Broadcast(&Message{
+		Type:      "post",
+		ID:        msg.ID,
+		Channel:   msg.Channel,
+		From:      c.ID,
+		Content:   msg.Content,
+		Timestamp: time.Now().Unix(),
+	})
 }
 
-func randomString(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = letters[i%len(letters)]
+// handleChat streams an AI answer back to the client alone as
+// "chat.delta" messages followed by a final "chat.done", or a
+// "chat.error". Every reply carries the request's ID.
+func (c *Client) handleChat(msg *Message) {
+	if msg.Content == "" {
+		c.sendError("chat.error", msg.ID, ErrCodeEmptyMessage, "Message is required")
+		return
+	}
+
+	var payload ChatPayload
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			c.sendError("chat.error", msg.ID, ErrCodeInvalidMessage, "Invalid chat data")
+			return
+		}
+	}
+
+	onDelta := func(delta string) error {
+		c.Gateway.SendTo(c, &Message{Type: "chat.delta", ID: msg.ID, Content: delta})
+		return c.ctx.Err()
+	}
+
+	result, err := c.Gateway.chat.Process(c.ctx, chat.Request{
+		Message:        msg.Content,
+		History:        payload.History,
+		ConversationID: payload.ConversationID,
+		UserID:         c.ID,
+		Department:     ai.Department(payload.Department),
+		Scope:          ai.Department(c.scope()),
+	}, onDelta)
+	if err != nil {
+		if c.ctx.Err() != nil {
+			return
+		}
+		log.Printf("Chat error for client %s: %v", c.ID, err)
+		code, content := chatError(err)
+		c.sendError("chat.error", msg.ID, code, content)
+		return
+	}
+
+	chatResult := ChatResult{
+		Department:     string(result.Department),
+		Model:          result.Model,
+		Ambiguous:      result.Ambiguous,
+		ConversationID: result.ConversationID,
+	}
+	for _, d := range result.Candidates {
+		chatResult.Candidates = append(chatResult.Candidates, string(d))
+	}
+	for _, d := range result.Departments {
+		chatResult.Departments = append(chatResult.Departments, string(d))
+	}
+	chatResult.Actions = result.Actions
+	for _, c := range result.Citations {
+		chatResult.Citations = append(chatResult.Citations, Citation{
+			Number:  c.Number,
+			Source:  c.Source,
+			Section: c.Section,
+			Page:    c.Page,
+			Text:    c.Text,
+		})
+	}
+	data, _ := json.Marshal(chatResult)
+	c.Gateway.SendTo(c, &Message{
+		Type:      "chat.done",
+		ID:        msg.ID,
+		Content:   result.Response,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// chatError maps a chat pipeline error to an error code and a message
+// that doesn't expose internal details
+func chatError(err error) (string, string) {
+	switch {
+	case errors.Is(err, chat.ErrEmptyMessage):
+		return ErrCodeEmptyMessage, "Message is required"
+	case errors.Is(err, chat.ErrUnknownDepartment):
+		return ErrCodeUnknownDepartment, "Unknown department"
+	case errors.Is(err, chat.ErrHistoryConflict):
+		return ErrCodeHistoryConflict, "History can't be sent with a conversation ID"
+	case errors.Is(err, chat.ErrOutOfScope):
+		return ErrCodeOutOfScope, "You don't have access to this department"
+	case errors.Is(err, conversation.ErrNotFound):
+		return ErrCodeConversationNotFound, "Conversation not found"
+	default:
+		return ErrCodeUnavailable, "Failed to process message. Is Ollama running?"
 	}
-	return string(b)
 }
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/chat"
+	"github.com/dokk-dev/opus/internal/config"
+	"github.com/dokk-dev/opus/internal/conversation"
+)
+
+// chatGateway returns a gateway whose dairy and meat agents answer through
+// an Ollama server streaming "Aisle 3."
+func chatGateway(t *testing.T) *Gateway {
+	t.Helper()
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"content": "Aisle "}, "done": false}` + "\n" + `{"message": {"content": "3."}, "done": true}` + "\n"))
+	}))
+	t.Cleanup(ollama.Close)
+
+	definitions := []ai.DepartmentDefinition{
+		{ID: ai.DeptDairy, Name: "Dairy", Keywords: []string{"milk"}},
+		{ID: ai.DeptMeat, Name: "Meat", Keywords: []string{"beef"}},
+	}
+	router, err := ai.NewRouter(definitions, ai.NewToolRegistry(), ai.NewOllamaClient(ollama.URL, "llama3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(&config.Config{}, chat.NewService(router, conversation.NewMemoryStore()))
+}
+
+// testClient adds a connected client to gw
+func testClient(t *testing.T, gw *Gateway, role auth.Role, dept string) *Client {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client := &Client{
+		ID:         "jsmith",
+		Gateway:    gw,
+		Send:       make(chan []byte, 16),
+		Channels:   make(map[string]bool),
+		Role:       role,
+		Department: dept,
+		ctx:        ctx,
+		cancel:     cancel,
+		chats:      make(chan struct{}, maxChatsPerClient),
+	}
+	gw.mu.Lock()
+	gw.clients[client] = true
+	gw.mu.Unlock()
+	return client
+}
+
+// receive returns the client's next message
+func receive(t *testing.T, client *Client) Message {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+		return Message{}
+	}
+}
+
+func errorCode(msg Message) string {
+	var payload ErrorPayload
+	json.Unmarshal(msg.Data, &payload)
+	return payload.Code
+}
+
+func TestChatIsAnsweredToTheSender(t *testing.T) {
+	client := testClient(t, chatGateway(t), auth.RoleManager, "")
+	client.handleMessage(&Message{Type: "chat", ID: "7", Content: "Where is the milk?"})
+
+	var streamed string
+	for {
+		msg := receive(t, client)
+		if msg.ID != "7" {
+			t.Fatalf("reply %s has ID %q, want 7", msg.Type, msg.ID)
+		}
+		if msg.Type == "chat.delta" {
+			streamed += msg.Content
+			continue
+		}
+		if msg.Type != "chat.done" {
+			t.Fatalf("got %s %s, want chat.done", msg.Type, msg.Data)
+		}
+
+		var result ChatResult
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Content != "Aisle 3." || streamed != "Aisle 3." || result.Department != "dairy" {
+			t.Errorf("answered %q streaming %q from %q, want Aisle 3. from dairy", msg.Content, streamed, result.Department)
+		}
+		return
+	}
+}
+
+func TestChatErrors(t *testing.T) {
+	gw := chatGateway(t)
+
+	tests := []struct {
+		name string
+		dept string
+		msg  *Message
+		want string
+	}{
+		{"empty", "", &Message{Type: "chat", ID: "1"}, ErrCodeEmptyMessage},
+		{"bad data", "", &Message{Type: "chat", ID: "2", Content: "Milk?", Data: json.RawMessage(`"dairy"`)}, ErrCodeInvalidMessage},
+		{"unknown department", "", &Message{Type: "chat", ID: "3", Content: "Milk?", Data: json.RawMessage(`{"department": "toys"}`)}, ErrCodeUnknownDepartment},
+		{"another department", "meat", &Message{Type: "chat", ID: "4", Content: "Milk?", Data: json.RawMessage(`{"department": "dairy"}`)}, ErrCodeOutOfScope},
+		{"missing conversation", "", &Message{Type: "chat", ID: "5", Content: "Milk?", Data: json.RawMessage(`{"conversationId": "conv_missing"}`)}, ErrCodeConversationNotFound},
+	}
+	for _, tt := range tests {
+		client := testClient(t, gw, auth.RoleManager, tt.dept)
+		client.handleMessage(tt.msg)
+
+		msg := receive(t, client)
+		if msg.Type != "chat.error" || msg.ID != tt.msg.ID || errorCode(msg) != tt.want {
+			t.Errorf("%s: got %s %q %s, want chat.error %q with %s", tt.name, msg.Type, msg.ID, msg.Data, tt.msg.ID, tt.want)
+		}
+	}
+}
+
+func TestChatsPerClientAreLimited(t *testing.T) {
+	client := testClient(t, New(&config.Config{}, nil), auth.RoleManager, "")
+
+	// Every slot is taken by a chat still being answered
+	for i := 0; i < maxChatsPerClient; i++ {
+		client.chats <- struct{}{}
+	}
+	client.handleMessage(&Message{Type: "chat", ID: "3", Content: "Where is the milk?"})
+
+	if msg := receive(t, client); msg.Type != "chat.error" || msg.ID != "3" || errorCode(msg) != ErrCodeBusy {
+		t.Errorf("got %s %q %s, want a busy chat.error for 3", msg.Type, msg.ID, msg.Data)
+	}
+	if len(client.chats) != maxChatsPerClient {
+		t.Errorf("%d chats in flight, want the refused one not counted", len(client.chats))
+	}
+}
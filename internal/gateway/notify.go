@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/notify"
+)
+
+// Name identifies the gateway as a notification channel
+func (gw *Gateway) Name() string { return "websocket" }
+
+// Notify sends a "notification" message with n as its data to every
+// connection the user has open, returning notify.ErrUnreachable if they
+// have none
+func (gw *Gateway) Notify(ctx context.Context, user *auth.User, n *notify.Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	msg := &Message{
+		Type:      "notification",
+		Content:   n.Alert.Title,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+
+	sent := false
+	for client := range gw.clients {
+		if client.ID == user.ID {
+			gw.sendToClient(client, msg)
+			sent = true
+		}
+	}
+	if !sent {
+		return notify.ErrUnreachable
+	}
+	return nil
+}
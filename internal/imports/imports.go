@@ -0,0 +1,111 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrNotFound is returned when no run has the requested ID
+var ErrNotFound = errors.New("import run not found")
+
+// MaxRowErrors is how many row errors a run keeps; later ones are only
+// counted
+const MaxRowErrors = 1000
+
+// Status is how an import run went
+type Status string
+
+const (
+	StatusRunning Status = "running"
+	// StatusSucceeded means every row was applied
+	StatusSucceeded Status = "succeeded"
+	// StatusPartial means some rows were rejected and the rest applied
+	StatusPartial Status = "partial"
+	// StatusFailed means nothing was applied
+	StatusFailed Status = "failed"
+)
+
+// RowError is why one row of an import was rejected
+type RowError struct {
+	// Line is the row's line in the file, counting the header as line 1
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// Run is one import of a file or download
+type Run struct {
+	ID string `json:"id"`
+	// Source is the connector that ran it, e.g. "periscope"
+	Source string `json:"source"`
+	// Kind is what was imported, e.g. "items" or "shrink"
+	Kind string `json:"kind"`
+	// Origin is the file name or URL read
+	Origin     string     `json:"origin"`
+	Status     Status     `json:"status"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Rows       int        `json:"rows"`
+	Created    int        `json:"created"`
+	Updated    int        `json:"updated"`
+	Unchanged  int        `json:"unchanged"`
+	Rejected   int        `json:"rejected"`
+	// Error is why the whole run failed, e.g. a missing column
+	Error  string     `json:"error,omitempty"`
+	Errors []RowError `json:"errors,omitempty"`
+}
+
+// Reject records a rejected row, keeping the first MaxRowErrors
+func (r *Run) Reject(line int, field, value, format string, args ...interface{}) {
+	r.Rejected++
+	if len(r.Errors) < MaxRowErrors {
+		r.Errors = append(r.Errors, RowError{
+			Line:    line,
+			Field:   field,
+			Value:   value,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+}
+
+// Finish sets the run's status from its counts, or to failed with err
+func (r *Run) Finish(err error) {
+	now := time.Now()
+	r.FinishedAt = &now
+	slices.SortStableFunc(r.Errors, func(a, b RowError) int { return a.Line - b.Line })
+	switch {
+	case err != nil:
+		r.Status = StatusFailed
+		r.Error = err.Error()
+	case r.Rejected == 0:
+		r.Status = StatusSucceeded
+	case r.Rejected < r.Rows:
+		r.Status = StatusPartial
+	default:
+		r.Status = StatusFailed
+		r.Error = "every row was rejected"
+	}
+}
+
+// Filter selects runs. Zero values mean no filter.
+type Filter struct {
+	Source string
+	Kind   string
+	Status Status
+	// Limit defaults to 50
+	Limit int
+}
+
+// Repository stores import runs
+type Repository interface {
+	// List returns the runs matching f, newest first, without row errors
+	List(ctx context.Context, f Filter) ([]Run, error)
+	// Get returns the run with id and its row errors, or ErrNotFound
+	Get(ctx context.Context, id string) (*Run, error)
+	// Save inserts a run, setting its ID, or replaces it by ID
+	Save(ctx context.Context, run *Run) error
+}
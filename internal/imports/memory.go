@@ -0,0 +1,87 @@
+package imports
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// keepRuns is how many runs the memory repository keeps
+const keepRuns = 500
+
+// MemoryRepository keeps the latest 500 import runs in memory
+type MemoryRepository struct {
+	runs   []Run
+	nextID int
+	mu     sync.RWMutex
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+// List returns the runs matching f, newest first, without row errors
+func (m *MemoryRepository) List(ctx context.Context, f Filter) ([]Run, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runs := []Run{}
+	for i := len(m.runs) - 1; i >= 0 && len(runs) < f.Limit; i-- {
+		run := m.runs[i]
+		switch {
+		case f.Source != "" && run.Source != f.Source:
+		case f.Kind != "" && run.Kind != f.Kind:
+		case f.Status != "" && run.Status != f.Status:
+		default:
+			run.Errors = nil
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// Get returns the run with id
+func (m *MemoryRepository) Get(ctx context.Context, id string) (*Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, run := range m.runs {
+		if run.ID == id {
+			run.Errors = slices.Clone(run.Errors)
+			return &run, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Save inserts or replaces run, dropping the oldest beyond 500
+func (m *MemoryRepository) Save(ctx context.Context, run *Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *run
+	saved.Errors = slices.Clone(run.Errors)
+	if run.ID != "" {
+		for i := range m.runs {
+			if m.runs[i].ID == run.ID {
+				m.runs[i] = saved
+				return nil
+			}
+		}
+	}
+
+	m.nextID++
+	run.ID = strconv.Itoa(m.nextID)
+	saved.ID = run.ID
+	m.runs = append(m.runs, saved)
+	if len(m.runs) > keepRuns {
+		m.runs = slices.Delete(m.runs, 0, len(m.runs)-keepRuns)
+	}
+	return nil
+}
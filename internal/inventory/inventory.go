@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors returned by repositories
+var (
+	// ErrNotFound is returned when no item has the requested SKU
+	ErrNotFound = errors.New("item not found")
+	// ErrInvalidQuery wraps problems with a Query's values
+	ErrInvalidQuery = errors.New("invalid inventory query")
+)
+
+// dateLayout is the format of expiration dates
+const dateLayout = "2006-01-02"
+
+// Lot is a batch of an item received together, with its own expiration
+type Lot struct {
+	Number   string  `json:"number"`
+	Quantity float64 `json:"quantity"`
+	// ExpiresOn is YYYY-MM-DD, empty for products that don't expire
+	ExpiresOn string `json:"expiresOn,omitempty"`
+}
+
+// Item is a product a department stocks. Quantities are in Unit, which is
+// fractional for items sold by weight.
+type Item struct {
+	SKU          string  `json:"sku"`
+	UPC          string  `json:"upc,omitempty"`
+	Description  string  `json:"description"`
+	Department   string  `json:"department"`
+	OnHand       float64 `json:"onHand"`
+	Unit         string  `json:"unit"`
+	ReorderPoint float64 `json:"reorderPoint"`
+	// ParLevel is what a reorder should bring OnHand back up to
+	ParLevel float64 `json:"parLevel"`
+	Lots     []Lot   `json:"lots,omitempty"`
+	// ExpiresOn is the earliest lot expiration, kept up to date by
+	// repositories
+	ExpiresOn string    `json:"expiresOn,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BelowReorder reports whether the item has fallen to its reorder point
+func (i *Item) BelowReorder() bool {
+	return i.OnHand <= i.ReorderPoint
+}
+
+// normalize validates the item and derives ExpiresOn from its lots
+func (i *Item) normalize() error {
+	i.SKU = strings.TrimSpace(i.SKU)
+	if i.SKU == "" {
+		return errors.New("sku is required")
+	}
+	if i.Department == "" {
+		return fmt.Errorf("item %s: department is required", i.SKU)
+	}
+
+	i.ExpiresOn = ""
+	for _, lot := range i.Lots {
+		if lot.ExpiresOn == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, lot.ExpiresOn); err != nil {
+			return fmt.Errorf("item %s: lot %s expiration must be YYYY-MM-DD, got %q", i.SKU, lot.Number, lot.ExpiresOn)
+		}
+		if i.ExpiresOn == "" || lot.ExpiresOn < i.ExpiresOn {
+			i.ExpiresOn = lot.ExpiresOn
+		}
+	}
+
+	if i.UpdatedAt.IsZero() {
+		i.UpdatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// Sortable fields for Query.Sort
+const (
+	SortSKU          = "sku"
+	SortDescription  = "description"
+	SortOnHand       = "onHand"
+	SortReorderPoint = "reorderPoint"
+	SortExpiresOn    = "expiresOn"
+	SortUpdatedAt    = "updatedAt"
+)
+
+// Page size limits for Query.Limit
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Query selects and orders items. Zero values mean no filter.
+type Query struct {
+	Department string
+	// Search matches SKU, UPC or description, case-insensitively
+	Search string
+	// BelowReorder keeps items at or below their reorder point
+	BelowReorder bool
+	// ExpiringWithin keeps items with a lot expiring before now plus the
+	// duration, including items already expired
+	ExpiringWithin time.Duration
+	// Sort is one of the Sort* fields; Desc reverses it. Ties are broken
+	// by SKU.
+	Sort string
+	Desc bool
+	// Limit defaults to DefaultLimit and is capped at MaxLimit
+	Limit  int
+	Offset int
+}
+
+// normalize applies defaults and rejects invalid values
+func (q *Query) normalize() error {
+	switch q.Sort {
+	case "":
+		q.Sort = SortSKU
+	case SortSKU, SortDescription, SortOnHand, SortReorderPoint, SortExpiresOn, SortUpdatedAt:
+	default:
+		return fmt.Errorf("%w: can't sort by %q", ErrInvalidQuery, q.Sort)
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+	if q.ExpiringWithin < 0 {
+		return fmt.Errorf("%w: expiring window must not be negative", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// expiryCutoff is the last expiration date matched by ExpiringWithin
+func (q *Query) expiryCutoff(now time.Time) string {
+	return now.Add(q.ExpiringWithin).Format(dateLayout)
+}
+
+// Page is one page of a query's results
+type Page struct {
+	Items []Item `json:"items"`
+	// Total counts every matching item, not just this page
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// Repository stores inventory items
+type Repository interface {
+	// List returns the items matching q
+	List(ctx context.Context, q Query) (*Page, error)
+	// Get returns the item with sku, or ErrNotFound
+	Get(ctx context.Context, sku string) (*Item, error)
+	// Save inserts or replaces items by SKU, including their lots
+	Save(ctx context.Context, items ...Item) error
+	Close() error
+}
@@ -0,0 +1,139 @@
+package inventory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps items in memory. It is used for demos and when
+// no database is configured.
+type MemoryRepository struct {
+	items map[string]Item
+	mu    sync.RWMutex
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{items: make(map[string]Item)}
+}
+
+// List returns the items matching q
+func (m *MemoryRepository) List(ctx context.Context, q Query) (*Page, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	cutoff := q.expiryCutoff(time.Now())
+	search := strings.ToLower(q.Search)
+
+	m.mu.RLock()
+	var matched []Item
+	for _, item := range m.items {
+		switch {
+		case q.Department != "" && item.Department != q.Department:
+		case q.BelowReorder && !item.BelowReorder():
+		case q.ExpiringWithin > 0 && (item.ExpiresOn == "" || item.ExpiresOn > cutoff):
+		case search != "" && !matchesSearch(&item, search):
+		default:
+			matched = append(matched, cloneItem(item))
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b Item) int {
+		c := compareBy(q.Sort, &a, &b)
+		if q.Desc {
+			c = -c
+		}
+		if c == 0 {
+			c = strings.Compare(a.SKU, b.SKU)
+		}
+		return c
+	})
+
+	page := &Page{Items: []Item{}, Total: len(matched), Limit: q.Limit, Offset: q.Offset}
+	if q.Offset < len(matched) {
+		end := min(q.Offset+q.Limit, len(matched))
+		page.Items = matched[q.Offset:end]
+	}
+	return page, nil
+}
+
+func matchesSearch(item *Item, search string) bool {
+	return strings.Contains(strings.ToLower(item.SKU), search) ||
+		strings.Contains(item.UPC, search) ||
+		strings.Contains(strings.ToLower(item.Description), search)
+}
+
+// compareBy orders items by a Sort field. Items without an expiration
+// sort after those with one, as in PostgreSQL.
+func compareBy(field string, a, b *Item) int {
+	switch field {
+	case SortDescription:
+		return strings.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description))
+	case SortOnHand:
+		return cmp.Compare(a.OnHand, b.OnHand)
+	case SortReorderPoint:
+		return cmp.Compare(a.ReorderPoint, b.ReorderPoint)
+	case SortExpiresOn:
+		switch {
+		case a.ExpiresOn == b.ExpiresOn:
+			return 0
+		case a.ExpiresOn == "":
+			return 1
+		case b.ExpiresOn == "":
+			return -1
+		}
+		return strings.Compare(a.ExpiresOn, b.ExpiresOn)
+	case SortUpdatedAt:
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		return strings.Compare(a.SKU, b.SKU)
+	}
+}
+
+// Get returns the item with sku
+func (m *MemoryRepository) Get(ctx context.Context, sku string) (*Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[strings.TrimSpace(sku)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	item = cloneItem(item)
+	return &item, nil
+}
+
+// Save inserts or replaces items
+func (m *MemoryRepository) Save(ctx context.Context, items ...Item) error {
+	normalized := make([]Item, 0, len(items))
+	for _, item := range items {
+		item = cloneItem(item)
+		if err := item.normalize(); err != nil {
+			return err
+		}
+		normalized = append(normalized, item)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, item := range normalized {
+		m.items[item.SKU] = item
+	}
+	return nil
+}
+
+// Close is a no-op for the in-memory repository
+func (m *MemoryRepository) Close() error {
+	return nil
+}
+
+// cloneItem copies an item so callers can't modify stored lots
+func cloneItem(item Item) Item {
+	item.Lots = slices.Clone(item.Lots)
+	return item
+}
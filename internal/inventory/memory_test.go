@@ -0,0 +1,175 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stockedRepository returns a repository holding a few dairy and bakery
+// items, one expiring tomorrow and one already expired
+func stockedRepository(t *testing.T) *MemoryRepository {
+	t.Helper()
+	now := time.Now()
+	day := func(days int) string { return now.AddDate(0, 0, days).Format(dateLayout) }
+
+	repo := NewMemoryRepository()
+	err := repo.Save(context.Background(),
+		Item{SKU: "D-100", UPC: "041900076641", Description: "Whole Milk Gallon", Department: "dairy", OnHand: 12, ReorderPoint: 20,
+			Lots: []Lot{{Number: "A", ExpiresOn: day(30)}, {Number: "B", ExpiresOn: day(1)}}, UpdatedAt: now.Add(-time.Hour)},
+		Item{SKU: "D-200", Description: "butter, salted", Department: "dairy", OnHand: 40, ReorderPoint: 10,
+			Lots: []Lot{{Number: "A", ExpiresOn: day(-2)}}, UpdatedAt: now.Add(-2 * time.Hour)},
+		Item{SKU: "D-300", Description: "Cheddar 50% less fat", Department: "dairy", OnHand: 5, ReorderPoint: 5, UpdatedAt: now},
+		Item{SKU: "B-100", Description: "Sourdough Loaf", Department: "bakery", OnHand: 8, ReorderPoint: 5,
+			Lots: []Lot{{Number: "A", ExpiresOn: day(14)}}, UpdatedAt: now.Add(-3 * time.Hour)},
+	)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return repo
+}
+
+func skus(items []Item) string {
+	var list []string
+	for _, item := range items {
+		list = append(list, item.SKU)
+	}
+	return strings.Join(list, ",")
+}
+
+func TestListFiltersAndSorts(t *testing.T) {
+	repo := stockedRepository(t)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  string
+	}{
+		{"everything by sku", Query{}, "B-100,D-100,D-200,D-300"},
+		{"department", Query{Department: "dairy"}, "D-100,D-200,D-300"},
+		{"below reorder", Query{BelowReorder: true}, "D-100,D-300"},
+		{"expiring includes expired", Query{ExpiringWithin: 3 * 24 * time.Hour}, "D-100,D-200"},
+		{"search description", Query{Search: "LOAF"}, "B-100"},
+		{"search upc", Query{Search: "00766"}, "D-100"},
+		{"search is literal", Query{Search: "50%"}, "D-300"},
+		{"combined filters", Query{Department: "dairy", BelowReorder: true, Search: "milk"}, "D-100"},
+		{"description ignores case", Query{Sort: SortDescription}, "D-200,D-300,B-100,D-100"},
+		{"on hand descending", Query{Sort: SortOnHand, Desc: true}, "D-200,D-100,B-100,D-300"},
+		{"ties stay in sku order", Query{Sort: SortReorderPoint, Desc: true}, "D-100,D-200,B-100,D-300"},
+		// As in PostgreSQL, items that don't expire sort last, or first
+		// when descending
+		{"expiration", Query{Sort: SortExpiresOn}, "D-200,D-100,B-100,D-300"},
+		{"expiration descending", Query{Sort: SortExpiresOn, Desc: true}, "D-300,B-100,D-100,D-200"},
+		{"updated", Query{Sort: SortUpdatedAt}, "B-100,D-200,D-100,D-300"},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(context.Background(), tt.query)
+		if err != nil {
+			t.Errorf("%s: List: %v", tt.name, err)
+			continue
+		}
+		if got := skus(page.Items); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListPages(t *testing.T) {
+	repo := stockedRepository(t)
+
+	tests := []struct {
+		name   string
+		query  Query
+		want   string
+		limit  int
+		offset int
+	}{
+		{"first page", Query{Limit: 3}, "B-100,D-100,D-200", 3, 0},
+		{"last page", Query{Limit: 3, Offset: 3}, "D-300", 3, 3},
+		{"past the end", Query{Offset: 10}, "", DefaultLimit, 10},
+		{"limit capped", Query{Limit: MaxLimit + 1}, "B-100,D-100,D-200,D-300", MaxLimit, 0},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(context.Background(), tt.query)
+		if err != nil {
+			t.Errorf("%s: List: %v", tt.name, err)
+			continue
+		}
+		if got := skus(page.Items); got != tt.want || page.Total != 4 || page.Limit != tt.limit || page.Offset != tt.offset {
+			t.Errorf("%s: got %q of %d, limit %d offset %d, want %q of 4, limit %d offset %d",
+				tt.name, got, page.Total, page.Limit, page.Offset, tt.want, tt.limit, tt.offset)
+		}
+	}
+}
+
+func TestListRejectsInvalidQueries(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	for _, q := range []Query{{Sort: "price"}, {Offset: -1}, {ExpiringWithin: -time.Hour}} {
+		if _, err := repo.List(context.Background(), q); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("List(%+v) err = %v, want ErrInvalidQuery", q, err)
+		}
+	}
+}
+
+func TestEverySortFieldHasAColumn(t *testing.T) {
+	for _, field := range []string{SortSKU, SortDescription, SortOnHand, SortReorderPoint, SortExpiresOn, SortUpdatedAt} {
+		q := Query{Sort: field}
+		if err := q.normalize(); err != nil {
+			t.Errorf("%s: %v", field, err)
+		}
+		if sortColumns[field] == "" {
+			t.Errorf("%s: no PostgreSQL column to sort by", field)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"milk", "milk"},
+		{"50%", `50\%`},
+		{"d_100", `d\_100`},
+		{`a\b`, `a\\b`},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.search); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.search, got, tt.want)
+		}
+	}
+}
+
+func TestSaveDerivesExpirationAndCopies(t *testing.T) {
+	repo := NewMemoryRepository()
+	item := Item{SKU: " D-100 ", Department: "dairy", Lots: []Lot{{Number: "A", ExpiresOn: "2026-11-02"}, {Number: "B"}, {Number: "C", ExpiresOn: "2026-10-30"}}}
+	if err := repo.Save(context.Background(), item); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	item.Lots[0].Quantity = 99
+
+	got, err := repo.Get(context.Background(), "D-100 ")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ExpiresOn != "2026-10-30" || got.Lots[0].Quantity != 0 || got.UpdatedAt.IsZero() {
+		t.Errorf("got %+v, want the earliest lot expiration and a copy of the lots", got)
+	}
+
+	got.Lots[0].Quantity = 99
+	if again, _ := repo.Get(context.Background(), "D-100"); again.Lots[0].Quantity != 0 {
+		t.Error("changing a returned item changed the stored one")
+	}
+
+	if _, err := repo.Get(context.Background(), "D-999"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(D-999) err = %v, want ErrNotFound", err)
+	}
+	for _, bad := range []Item{{Department: "dairy"}, {SKU: "D-1"}, {SKU: "D-1", Department: "dairy", Lots: []Lot{{ExpiresOn: "11/02/2026"}}}} {
+		if err := repo.Save(context.Background(), bad); err == nil {
+			t.Errorf("Save(%+v) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS inventory_items (
+	sku           TEXT PRIMARY KEY,
+	upc           TEXT NOT NULL DEFAULT '',
+	description   TEXT NOT NULL DEFAULT '',
+	department    TEXT NOT NULL,
+	on_hand       DOUBLE PRECISION NOT NULL DEFAULT 0,
+	unit          TEXT NOT NULL DEFAULT '',
+	reorder_point DOUBLE PRECISION NOT NULL DEFAULT 0,
+	par_level     DOUBLE PRECISION NOT NULL DEFAULT 0,
+	expires_on    DATE,
+	updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_items_department
+	ON inventory_items (department, sku);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_items_upc
+	ON inventory_items (upc);
+
+CREATE TABLE IF NOT EXISTS inventory_lots (
+	sku        TEXT NOT NULL REFERENCES inventory_items(sku) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	lot        TEXT NOT NULL DEFAULT '',
+	quantity   DOUBLE PRECISION NOT NULL DEFAULT 0,
+	expires_on DATE,
+	PRIMARY KEY (sku, position)
+);
+`
+
+// sortColumns maps Query.Sort fields to SQL expressions
+var sortColumns = map[string]string{
+	SortSKU:          "sku",
+	SortDescription:  "lower(description)",
+	SortOnHand:       "on_hand",
+	SortReorderPoint: "reorder_point",
+	SortExpiresOn:    "expires_on",
+	SortUpdatedAt:    "updated_at",
+}
+
+// PostgresRepository stores items in PostgreSQL
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository connects to the database at url and ensures the
+// schema exists
+func NewPostgresRepository(ctx context.Context, url string) (*PostgresRepository, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return &PostgresRepository{db: db}, nil
+}
+
+// List returns the items matching q
+func (p *PostgresRepository) List(ctx context.Context, q Query) (*Page, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Department != "" {
+		where = append(where, "department = "+arg(q.Department))
+	}
+	if q.BelowReorder {
+		where = append(where, "on_hand <= reorder_point")
+	}
+	if q.ExpiringWithin > 0 {
+		where = append(where, "expires_on <= "+arg(q.expiryCutoff(time.Now()))+"::date")
+	}
+	if q.Search != "" {
+		pattern := arg("%" + escapeLike(strings.ToLower(q.Search)) + "%")
+		where = append(where, fmt.Sprintf("(lower(sku) LIKE %[1]s OR upc LIKE %[1]s OR lower(description) LIKE %[1]s)", pattern))
+	}
+
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	page := &Page{Items: []Item{}, Limit: q.Limit, Offset: q.Offset}
+	if err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM inventory_items"+filter, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count items: %w", err)
+	}
+
+	order := sortColumns[q.Sort]
+	if q.Desc {
+		order += " DESC"
+	}
+	query := `SELECT sku, upc, description, department, on_hand, unit, reorder_point, par_level, expires_on, updated_at
+		FROM inventory_items` + filter +
+		" ORDER BY " + order + ", sku" +
+		" LIMIT " + arg(q.Limit) + " OFFSET " + arg(q.Offset)
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+
+	if err := p.loadLots(ctx, page.Items); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// escapeLike escapes LIKE wildcards so search text matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanItem(row scanner) (*Item, error) {
+	var item Item
+	var expires sql.NullTime
+	err := row.Scan(&item.SKU, &item.UPC, &item.Description, &item.Department, &item.OnHand,
+		&item.Unit, &item.ReorderPoint, &item.ParLevel, &expires, &item.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expires.Valid {
+		item.ExpiresOn = expires.Time.Format(dateLayout)
+	}
+	return &item, nil
+}
+
+// loadLots fills in the lots of items
+func (p *PostgresRepository) loadLots(ctx context.Context, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	index := make(map[string]*Item, len(items))
+	skus := make([]string, len(items))
+	for i := range items {
+		index[items[i].SKU] = &items[i]
+		skus[i] = items[i].SKU
+	}
+
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT sku, lot, quantity, expires_on FROM inventory_lots WHERE sku = ANY($1) ORDER BY sku, position`,
+		skus)
+	if err != nil {
+		return fmt.Errorf("failed to load lots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sku string
+		var lot Lot
+		var expires sql.NullTime
+		if err := rows.Scan(&sku, &lot.Number, &lot.Quantity, &expires); err != nil {
+			return fmt.Errorf("failed to load lots: %w", err)
+		}
+		if expires.Valid {
+			lot.ExpiresOn = expires.Time.Format(dateLayout)
+		}
+		if item := index[sku]; item != nil {
+			item.Lots = append(item.Lots, lot)
+		}
+	}
+	return rows.Err()
+}
+
+// Get returns the item with sku
+func (p *PostgresRepository) Get(ctx context.Context, sku string) (*Item, error) {
+	row := p.db.QueryRowContext(ctx,
+		`SELECT sku, upc, description, department, on_hand, unit, reorder_point, par_level, expires_on, updated_at
+		FROM inventory_items WHERE sku = $1`, strings.TrimSpace(sku))
+
+	item, err := scanItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load item: %w", err)
+	}
+
+	items := []Item{*item}
+	if err := p.loadLots(ctx, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// Save inserts or replaces items in one transaction
+func (p *PostgresRepository) Save(ctx context.Context, items ...Item) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		item = cloneItem(item)
+		if err := item.normalize(); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO inventory_items
+				(sku, upc, description, department, on_hand, unit, reorder_point, par_level, expires_on, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::date, $10)
+			ON CONFLICT (sku) DO UPDATE SET
+				upc = EXCLUDED.upc,
+				description = EXCLUDED.description,
+				department = EXCLUDED.department,
+				on_hand = EXCLUDED.on_hand,
+				unit = EXCLUDED.unit,
+				reorder_point = EXCLUDED.reorder_point,
+				par_level = EXCLUDED.par_level,
+				expires_on = EXCLUDED.expires_on,
+				updated_at = EXCLUDED.updated_at`,
+			item.SKU, item.UPC, item.Description, item.Department, item.OnHand,
+			item.Unit, item.ReorderPoint, item.ParLevel, nullDate(item.ExpiresOn), item.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save item %s: %w", item.SKU, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM inventory_lots WHERE sku = $1`, item.SKU); err != nil {
+			return fmt.Errorf("failed to save lots of %s: %w", item.SKU, err)
+		}
+		for i, lot := range item.Lots {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO inventory_lots (sku, position, lot, quantity, expires_on) VALUES ($1, $2, $3, $4, $5::date)`,
+				item.SKU, i, lot.Number, lot.Quantity, nullDate(lot.ExpiresOn))
+			if err != nil {
+				return fmt.Errorf("failed to save lots of %s: %w", item.SKU, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit items: %w", err)
+	}
+	return nil
+}
+
+// nullDate maps an empty date to NULL
+func nullDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+	return date
+}
+
+// Close closes the database connection
+func (p *PostgresRepository) Close() error {
+	return p.db.Close()
+}
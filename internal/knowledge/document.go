@@ -0,0 +1,173 @@
+package knowledge
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxChunkChars bounds a passage at roughly 350 tokens so a handful
+	// fit in the prompt alongside the conversation
+	maxChunkChars = 1400
+	// overlapChars is the longest trailing paragraph repeated at the start
+	// of the next passage so a step isn't cut off from its context
+	overlapChars = 300
+)
+
+// supportedExtensions are the document formats that can be ingested.
+// PDFs are ingested as extracted text (e.g. pdftotext output), where form
+// feeds separate pages.
+var supportedExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+	".text":     true,
+}
+
+// passage is a chunk of a document before it is embedded
+type passage struct {
+	section string
+	page    int
+	text    string
+}
+
+// paragraph is a run of text between blank lines, with the heading and
+// page it falls under
+type paragraph struct {
+	section string
+	page    int
+	text    string
+}
+
+// titleOf returns the document's first Markdown heading, or a title made
+// from its file name
+func titleOf(path, text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if heading, level := markdownHeading(line); level == 1 {
+			return heading
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name = strings.TrimSuffix(name, ".pdf")
+	name = strings.NewReplacer("_", " ", "-", " ").Replace(name)
+	return strings.TrimSpace(name)
+}
+
+// chunk splits a document into passages of at most maxChunkChars. Passages
+// never span headings or pages, so each can be cited precisely.
+func chunk(text string) []passage {
+	paragraphs := splitParagraphs(text)
+
+	var passages []passage
+	var current []string
+	var size int
+	var section string
+	var page int
+
+	flush := func() {
+		if len(current) > 0 {
+			passages = append(passages, passage{section: section, page: page, text: strings.Join(current, "\n\n")})
+		}
+		current, size = nil, 0
+	}
+
+	for _, p := range paragraphs {
+		if p.section != section || p.page != page {
+			flush()
+			section, page = p.section, p.page
+		}
+
+		for _, piece := range splitLong(p.text) {
+			if size > 0 && size+len(piece)+2 > maxChunkChars {
+				last := current[len(current)-1]
+				flush()
+				if len(last) <= overlapChars && len(last)+len(piece)+2 <= maxChunkChars {
+					current, size = []string{last}, len(last)
+				}
+			}
+			current = append(current, piece)
+			size += len(piece) + 2
+		}
+	}
+	flush()
+
+	return passages
+}
+
+// splitParagraphs breaks text into paragraphs, tracking the most recent
+// Markdown heading and, for PDF text, the page number
+func splitParagraphs(text string) []paragraph {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	pages := strings.Split(text, "\f")
+
+	var paragraphs []paragraph
+	var lines []string
+	var section string
+	page := 0
+
+	flush := func() {
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, paragraph{section: section, page: page, text: strings.Join(lines, "\n")})
+			lines = nil
+		}
+	}
+
+	for i, pageText := range pages {
+		if len(pages) > 1 {
+			page = i + 1
+		}
+
+		for _, line := range strings.Split(pageText, "\n") {
+			if heading, level := markdownHeading(line); level > 0 {
+				flush()
+				section = heading
+				continue
+			}
+
+			if strings.TrimSpace(line) == "" {
+				flush()
+				continue
+			}
+			lines = append(lines, strings.TrimRightFunc(line, unicode.IsSpace))
+		}
+		flush()
+	}
+
+	return paragraphs
+}
+
+// markdownHeading returns the text and level of a Markdown ATX heading, or
+// a zero level if line isn't one
+func markdownHeading(line string) (string, int) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return "", 0
+	}
+	return strings.TrimSpace(strings.TrimRight(line[level:], "# ")), level
+}
+
+// splitLong breaks a paragraph longer than maxChunkChars at sentence or,
+// failing that, word boundaries
+func splitLong(text string) []string {
+	var pieces []string
+	for len(text) > maxChunkChars {
+		cut := strings.LastIndex(text[:maxChunkChars], ". ")
+		if cut < maxChunkChars/2 {
+			cut = strings.LastIndexAny(text[:maxChunkChars], " \n")
+		}
+		if cut <= 0 {
+			cut = maxChunkChars - 1
+		}
+		pieces = append(pieces, strings.TrimSpace(text[:cut+1]))
+		text = strings.TrimSpace(text[cut+1:])
+	}
+	if text != "" {
+		pieces = append(pieces, text)
+	}
+	return pieces
+}
@@ -0,0 +1,108 @@
+package knowledge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitleOf(t *testing.T) {
+	tests := []struct {
+		path string
+		text string
+		want string
+	}{
+		{"dairy/cooler.md", "Intro\n\n# Cooler Temperatures\n\n## Daily", "Cooler Temperatures"},
+		{"dairy/cooler_temps.md", "## Daily checks\n\nEvery 4 hours.", "cooler temps"},
+		{"food-safety-manual.pdf.txt", "Page one", "food safety manual"},
+	}
+	for _, tt := range tests {
+		if got := titleOf(tt.path, tt.text); got != tt.want {
+			t.Errorf("titleOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownHeading(t *testing.T) {
+	tests := []struct {
+		line  string
+		text  string
+		level int
+	}{
+		{"# Receiving", "Receiving", 1},
+		{"### Temperatures ###", "Temperatures", 3},
+		{"#hashtag", "", 0},
+		{"####### Too deep", "", 0},
+		{"Step 1", "", 0},
+	}
+	for _, tt := range tests {
+		if text, level := markdownHeading(tt.line); text != tt.text || level != tt.level {
+			t.Errorf("markdownHeading(%q) = %q, %d, want %q, %d", tt.line, text, level, tt.text, tt.level)
+		}
+	}
+}
+
+func TestChunkKeepsSectionsAndPagesApart(t *testing.T) {
+	text := "# Cooler SOP\n\nCheck temps.\n\n## Receiving\n\nLog the truck.\nSign the invoice.\n\n## Rotation\n\nFirst in, first out.\fPage two text."
+
+	got := chunk(text)
+	want := []passage{
+		{section: "Cooler SOP", page: 1, text: "Check temps."},
+		{section: "Receiving", page: 1, text: "Log the truck.\nSign the invoice."},
+		{section: "Rotation", page: 1, text: "First in, first out."},
+		{section: "Rotation", page: 2, text: "Page two text."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d passages %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("passage %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestChunkSplitsLongText(t *testing.T) {
+	sentence := "Keep dairy coolers between 33 and 40 degrees at all times. "
+	long := strings.Repeat(sentence, 60)
+	short := "Call the manager."
+
+	passages := chunk("## Temps\n\n" + long + "\n\n" + short + "\n\n" + strings.Repeat(sentence, 20))
+	if len(passages) < 3 {
+		t.Fatalf("got %d passages, want the long paragraph split", len(passages))
+	}
+	for i, p := range passages {
+		if len(p.text) > maxChunkChars {
+			t.Errorf("passage %d has %d chars, over %d", i, len(p.text), maxChunkChars)
+		}
+		if p.section != "Temps" {
+			t.Errorf("passage %d section = %q", i, p.section)
+		}
+		if !strings.HasPrefix(p.text, "Keep") && !strings.HasPrefix(p.text, short) {
+			t.Errorf("passage %d starts mid-sentence: %.40q", i, p.text)
+		}
+	}
+
+	// The short paragraph ending one passage is repeated to start the next
+	var carried bool
+	for i := 1; i < len(passages); i++ {
+		if strings.HasSuffix(passages[i-1].text, short) && strings.HasPrefix(passages[i].text, short) {
+			carried = true
+		}
+	}
+	if !carried {
+		t.Error("the short paragraph wasn't carried into the next passage")
+	}
+}
+
+func TestSplitLong(t *testing.T) {
+	word := strings.Repeat("x", 9) + " "
+	pieces := splitLong(strings.Repeat(word, 300))
+	for i, piece := range pieces {
+		if len(piece) > maxChunkChars || piece != strings.TrimSpace(piece) {
+			t.Errorf("piece %d is %d chars: %.20q...", i, len(piece), piece)
+		}
+	}
+	if joined := strings.Join(pieces, " "); joined != strings.TrimSpace(strings.Repeat(word, 300)) {
+		t.Error("splitting lost or changed text")
+	}
+}
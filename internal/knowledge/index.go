@@ -0,0 +1,358 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dokk-dev/opus/internal/ai"
+)
+
+const (
+	// defaultTopK is how many passages an agent gets per query
+	defaultTopK = 4
+	// defaultMinScore drops passages too dissimilar to the query to help
+	defaultMinScore = 0.3
+	// embedBatchSize is how many passages are sent per embedding request
+	embedBatchSize = 16
+)
+
+// Embedder turns text into vectors
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Chunk is an embedded passage of a document
+type Chunk struct {
+	Section string    `json:"section,omitempty"`
+	Page    int       `json:"page,omitempty"`
+	Text    string    `json:"text"`
+	Vector  []float32 `json:"vector"`
+}
+
+// Document is an ingested file and its passages
+type Document struct {
+	// Path is relative to the knowledge directory
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	// Department is empty for store-wide documents
+	Department ai.Department `json:"department,omitempty"`
+	// Hash is the SHA-256 of the file, used to skip unchanged files
+	Hash   string  `json:"hash"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// indexFile is the on-disk form of the index
+type indexFile struct {
+	Model     string      `json:"model"`
+	Documents []*Document `json:"documents"`
+}
+
+// Index is a local vector index of store documents. It is small enough to
+// search exhaustively: a few hundred pages make a few thousand passages.
+type Index struct {
+	embedder Embedder
+	path     string
+	topK     int
+	minScore float64
+
+	mu        sync.RWMutex
+	documents map[string]*Document
+}
+
+// NewIndex creates an index that embeds with embedder. When path is set
+// the index is loaded from and saved to that file so unchanged documents
+// aren't embedded again on restart.
+func NewIndex(embedder Embedder, path string) (*Index, error) {
+	x := &Index{
+		embedder:  embedder,
+		path:      path,
+		topK:      defaultTopK,
+		minScore:  defaultMinScore,
+		documents: make(map[string]*Document),
+	}
+
+	if path == "" {
+		return x, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %w", path, err)
+	}
+
+	// Vectors from another model aren't comparable; start over
+	if file.Model != embedder.Model() {
+		log.Printf("Knowledge index was built with %s, re-embedding with %s", file.Model, embedder.Model())
+		return x, nil
+	}
+
+	for _, doc := range file.Documents {
+		x.documents[doc.Path] = doc
+	}
+	return x, nil
+}
+
+// Ingest indexes every supported file under root. Files in a directory
+// named after a department, e.g. root/dairy/cooler-sop.md, are only given
+// to that department's agent; all other files are store-wide. Unchanged
+// files are skipped and files that have been removed are dropped.
+func (x *Index) Ingest(ctx context.Context, root string, departments []ai.Department) error {
+	known := make(map[string]bool, len(departments))
+	for _, d := range departments {
+		known[string(d)] = true
+	}
+
+	seen := make(map[string]bool)
+	updated := 0
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !supportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		var dept ai.Department
+		if first, _, nested := strings.Cut(rel, "/"); nested && known[strings.ToLower(first)] {
+			dept = ai.Department(strings.ToLower(first))
+		}
+
+		changed, err := x.ingestFile(ctx, path, rel, dept)
+		if err != nil {
+			return fmt.Errorf("failed to ingest %s: %w", rel, err)
+		}
+		if changed {
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		// Keep what was embedded before the failure
+		if updated > 0 {
+			if saveErr := x.save(); saveErr != nil {
+				log.Printf("Knowledge: %v", saveErr)
+			}
+		}
+		return err
+	}
+
+	x.mu.Lock()
+	removed := 0
+	for path := range x.documents {
+		if !seen[path] {
+			delete(x.documents, path)
+			removed++
+		}
+	}
+	chunks := 0
+	for _, doc := range x.documents {
+		chunks += len(doc.Chunks)
+	}
+	total := len(x.documents)
+	x.mu.Unlock()
+
+	log.Printf("Knowledge: %d documents, %d passages (%d updated, %d removed)", total, chunks, updated, removed)
+
+	if updated > 0 || removed > 0 {
+		return x.save()
+	}
+	return nil
+}
+
+// ingestFile embeds one file unless it is already indexed unchanged
+func (x *Index) ingestFile(ctx context.Context, path, rel string, dept ai.Department) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	x.mu.RLock()
+	existing, ok := x.documents[rel]
+	x.mu.RUnlock()
+	if ok && existing.Hash == hash && existing.Department == dept {
+		return false, nil
+	}
+
+	text := string(data)
+	doc := &Document{
+		Path:       rel,
+		Title:      titleOf(rel, text),
+		Department: dept,
+		Hash:       hash,
+	}
+
+	passages := chunk(text)
+	for start := 0; start < len(passages); start += embedBatchSize {
+		batch := passages[start:min(start+embedBatchSize, len(passages))]
+
+		// Embedding the title and heading with the text helps passages
+		// like "Check every 4 hours" match questions about their topic
+		inputs := make([]string, len(batch))
+		for i, p := range batch {
+			inputs[i] = doc.Title
+			if p.section != "" {
+				inputs[i] += " - " + p.section
+			}
+			inputs[i] += "\n\n" + p.text
+		}
+
+		vectors, err := x.embedder.Embed(ctx, inputs)
+		if err != nil {
+			return false, err
+		}
+		for i, p := range batch {
+			doc.Chunks = append(doc.Chunks, Chunk{
+				Section: p.section,
+				Page:    p.page,
+				Text:    p.text,
+				Vector:  normalize(vectors[i]),
+			})
+		}
+	}
+
+	x.mu.Lock()
+	x.documents[rel] = doc
+	x.mu.Unlock()
+	return true, nil
+}
+
+// Retrieve returns the passages most similar to query from documents for
+// dept and store-wide documents
+func (x *Index) Retrieve(ctx context.Context, dept ai.Department, query string) ([]ai.Passage, error) {
+	x.mu.RLock()
+	empty := len(x.documents) == 0
+	x.mu.RUnlock()
+	if empty {
+		return nil, nil
+	}
+
+	vectors, err := x.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	q := normalize(vectors[0])
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var passages []ai.Passage
+	for _, doc := range x.documents {
+		if doc.Department != "" && doc.Department != dept {
+			continue
+		}
+		for _, c := range doc.Chunks {
+			score := dot(q, c.Vector)
+			if score < x.minScore {
+				continue
+			}
+			passages = append(passages, ai.Passage{
+				Source:  doc.Title,
+				Section: c.Section,
+				Page:    c.Page,
+				Text:    c.Text,
+				Score:   score,
+			})
+		}
+	}
+
+	sort.Slice(passages, func(i, j int) bool { return passages[i].Score > passages[j].Score })
+	if len(passages) > x.topK {
+		passages = passages[:x.topK]
+	}
+	return passages, nil
+}
+
+// save writes the index to its file, if it has one
+func (x *Index) save() error {
+	if x.path == "" {
+		return nil
+	}
+
+	x.mu.RLock()
+	file := indexFile{Model: x.embedder.Model()}
+	for _, doc := range x.documents {
+		file.Documents = append(file.Documents, doc)
+	}
+	data, err := json.Marshal(file)
+	x.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+
+	// Write then rename so a crash never leaves a truncated index
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	norm := math.Sqrt(sum)
+	if norm == 0 {
+		return v
+	}
+
+	out := make([]float32, len(v))
+	for i, f := range v {
+		out[i] = float32(float64(f) / norm)
+	}
+	return out
+}
+
+// dot is the cosine similarity of two normalized vectors
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
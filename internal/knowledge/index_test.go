@@ -0,0 +1,147 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dokk-dev/opus/internal/ai"
+)
+
+// keywordEmbedder embeds text as counts of a few keywords, so similarity
+// is shared vocabulary
+type keywordEmbedder struct {
+	model    string
+	embedded int
+}
+
+var embedKeywords = []string{"milk", "cooler", "bread", "oven", "spill"}
+
+func (e *keywordEmbedder) Model() string { return e.model }
+
+func (e *keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		vectors[i] = make([]float32, len(embedKeywords))
+		for j, keyword := range embedKeywords {
+			vectors[i][j] = float32(strings.Count(text, keyword))
+		}
+	}
+	e.embedded += len(texts)
+	return vectors, nil
+}
+
+// writeDocs creates files under a new knowledge directory
+func writeDocs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var departments = []ai.Department{ai.DeptDairy, ai.DeptBakery}
+
+func TestRetrieveScopesDocumentsToDepartments(t *testing.T) {
+	root := writeDocs(t, map[string]string{
+		"dairy/cooler.md":   "# Milk Cooler\n\nKeep the milk cooler at 38 degrees.",
+		"bakery/oven.md":    "# Oven\n\nPreheat the bread oven. Milk wash the bread.",
+		"spills.txt":        "Clean up any milk spill right away.",
+		"notes/ignored.pdf": "not a supported format",
+		".drafts/draft.md":  "Milk cooler draft",
+	})
+	index, err := NewIndex(&keywordEmbedder{model: "embed"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Ingest(context.Background(), root, departments); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	tests := []struct {
+		dept  ai.Department
+		query string
+		want  []string
+	}{
+		{ai.DeptDairy, "milk cooler", []string{"Milk Cooler", "spills"}},
+		{ai.DeptBakery, "bread oven", []string{"Oven"}},
+		// The oven passage mentions milk, but too little to pass minScore
+		{ai.DeptBakery, "milk", []string{"spills"}},
+		{ai.DeptMeat, "milk cooler", []string{"spills"}},
+	}
+	for _, tt := range tests {
+		passages, err := index.Retrieve(context.Background(), tt.dept, tt.query)
+		if err != nil {
+			t.Fatalf("Retrieve: %v", err)
+		}
+		var sources []string
+		for _, p := range passages {
+			sources = append(sources, p.Source)
+		}
+		if strings.Join(sources, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s %q: got %v, want %v", tt.dept, tt.query, sources, tt.want)
+		}
+	}
+}
+
+func TestIngestSkipsUnchangedDocuments(t *testing.T) {
+	root := writeDocs(t, map[string]string{
+		"dairy/cooler.md": "# Milk Cooler\n\nKeep the milk cooler at 38 degrees.",
+		"spills.txt":      "Clean up any milk spill right away.",
+	})
+	path := filepath.Join(t.TempDir(), "index.json")
+	embedder := &keywordEmbedder{model: "embed"}
+
+	index, err := NewIndex(embedder, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Ingest(context.Background(), root, departments); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	first := embedder.embedded
+
+	// A restart reloads the saved index and embeds nothing new
+	index, err = NewIndex(embedder, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Ingest(context.Background(), root, departments); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if embedder.embedded != first {
+		t.Errorf("re-ingesting unchanged files embedded %d passages", embedder.embedded-first)
+	}
+
+	// A removed file is dropped
+	os.Remove(filepath.Join(root, "spills.txt"))
+	if err := index.Ingest(context.Background(), root, departments); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if passages, _ := index.Retrieve(context.Background(), ai.DeptMeat, "milk spill"); len(passages) != 0 {
+		t.Errorf("removed document still retrieved: %+v", passages)
+	}
+
+	// Another embedding model starts over
+	other := &keywordEmbedder{model: "embed-v2"}
+	index, err = NewIndex(other, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Ingest(context.Background(), root, departments); err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if other.embedded == 0 {
+		t.Error("documents weren't re-embedded for a new model")
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// keepRecords is how many records the memory store keeps
+const keepRecords = 1000
+
+// MemoryStore keeps preferences and the latest 1000 records in memory
+type MemoryStore struct {
+	preferences map[string]Preferences
+	records     []Record
+	nextID      int
+	mu          sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{preferences: make(map[string]Preferences)}
+}
+
+func (m *MemoryStore) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.preferences[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clonePreferences(p), nil
+}
+
+func (m *MemoryStore) SavePreferences(ctx context.Context, p *Preferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.preferences[p.UserID] = *clonePreferences(*p)
+	return nil
+}
+
+func clonePreferences(p Preferences) *Preferences {
+	p.Channels = slices.Clone(p.Channels)
+	if p.QuietHours != nil {
+		q := *p.QuietHours
+		q.Channels = slices.Clone(q.Channels)
+		p.QuietHours = &q
+	}
+	return &p
+}
+
+func (m *MemoryStore) Records(ctx context.Context, f RecordFilter) ([]Record, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := []Record{}
+	for i := len(m.records) - 1; i >= 0 && len(records) < f.Limit; i-- {
+		if f.matches(&m.records[i]) {
+			records = append(records, m.records[i])
+		}
+	}
+	return records, nil
+}
+
+// SaveRecord inserts r, dropping the oldest beyond 1000
+func (m *MemoryStore) SaveRecord(ctx context.Context, r *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	r.ID = strconv.Itoa(m.nextID)
+	m.records = append(m.records, *r)
+	if len(m.records) > keepRecords {
+		m.records = slices.Delete(m.records, 0, len(m.records)-keepRecords)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Close() error { return nil }
@@ -0,0 +1,163 @@
+// Package notify routes alerts to the people who should handle them. The
+// router works out who that is from the alert's department and the
+// escalation tier it's at, prefers whoever is on shift or on call, and
+// reaches each of them on the first channel their preferences allow. An
+// alert nobody acknowledges within a tier's window is escalated to the
+// next tier.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+)
+
+// ErrUnreachable is returned by a channel that has no way to reach a
+// user, e.g. because they haven't linked a phone number
+var ErrUnreachable = errors.New("user can't be reached on this channel")
+
+// ErrInvalidPreferences is returned when saving preferences with unknown
+// channels or malformed quiet hours
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// Channel delivers notifications to users, e.g. by text or email
+type Channel interface {
+	// Name identifies the channel in preferences, e.g. "sms"
+	Name() string
+	// Notify tells user about the notification's alert, returning
+	// ErrUnreachable if the channel can't reach them
+	Notify(ctx context.Context, user *auth.User, n *Notification) error
+}
+
+// Notification is an alert routed to one user
+type Notification struct {
+	Alert *alerts.Alert `json:"alert"`
+	// Tier is the escalation tier the user was picked from
+	Tier string `json:"tier"`
+	// Escalated is set when the alert reached the user by escalation
+	Escalated bool `json:"escalated,omitempty"`
+}
+
+// Escalation tiers, from the first people told about an alert to the
+// last
+const (
+	// TierAssistantManager is the department's assistant managers
+	TierAssistantManager = "assistant_manager"
+	// TierDepartmentManager is the department's managers
+	TierDepartmentManager = "department_manager"
+	// TierStoreManager is the store-level managers
+	TierStoreManager = "store_manager"
+)
+
+// Tier is a step of the escalation policy
+type Tier struct {
+	Name string
+	// Wait is how long the tier has to acknowledge an alert before it's
+	// escalated to the next one
+	Wait time.Duration
+}
+
+// ValidTier reports whether name is a known tier
+func ValidTier(name string) bool {
+	switch name {
+	case TierAssistantManager, TierDepartmentManager, TierStoreManager:
+		return true
+	}
+	return false
+}
+
+// includes reports whether user is in the tier for an alert from dept
+func (t Tier) includes(user *auth.User, dept string) bool {
+	switch t.Name {
+	case TierAssistantManager:
+		return user.Role == auth.RoleAssistantManager && user.Department == dept
+	case TierDepartmentManager:
+		return user.Role == auth.RoleManager && user.Department == dept
+	case TierStoreManager:
+		return user.Role == auth.RoleManager && user.Department == ""
+	}
+	return false
+}
+
+// Preferences are how a user wants to be notified
+type Preferences struct {
+	UserID string `json:"userId"`
+	// Channels are tried in order until one reaches the user; empty means
+	// the router's default order
+	Channels []string `json:"channels,omitempty"`
+	// QuietHours hold alerts that aren't critical back from Channels
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+	UpdatedAt  time.Time   `json:"updatedAt,omitzero"`
+}
+
+// QuietHours are a daily stretch of local time, e.g. 22:00 to 06:00,
+// when only critical alerts use the usual channels
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Channels are tried for other alerts during quiet hours; empty
+	// holds them back entirely
+	Channels []string `json:"channels,omitempty"`
+}
+
+// clockFormat is how quiet hours are written
+const clockFormat = "15:04"
+
+// Contains reports whether t falls within the quiet hours
+func (q *QuietHours) Contains(t time.Time) bool {
+	start, err := time.Parse(clockFormat, q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(clockFormat, q.End)
+	if err != nil {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from <= to {
+		return now >= from && now < to
+	}
+	// Overnight, e.g. 22:00 to 06:00
+	return now >= from || now < to
+}
+
+// Validate checks the preferences name known channels and well-formed
+// quiet hours
+func (p *Preferences) Validate(channels []string) error {
+	names := p.Channels
+	if q := p.QuietHours; q != nil {
+		if _, err := time.Parse(clockFormat, q.Start); err != nil {
+			return fmt.Errorf("%w: quiet hours start %q isn't a time such as 22:00", ErrInvalidPreferences, q.Start)
+		}
+		if _, err := time.Parse(clockFormat, q.End); err != nil {
+			return fmt.Errorf("%w: quiet hours end %q isn't a time such as 06:00", ErrInvalidPreferences, q.End)
+		}
+		if q.Start == q.End {
+			return fmt.Errorf("%w: quiet hours must start and end at different times", ErrInvalidPreferences)
+		}
+		names = append(slices.Clone(names), q.Channels...)
+	}
+	for _, name := range names {
+		if !slices.Contains(channels, name) {
+			return fmt.Errorf("%w: unknown channel %q", ErrInvalidPreferences, name)
+		}
+	}
+	return nil
+}
+
+// channels returns the channels to try for an alert of severity at t
+func (p *Preferences) channels(severity alerts.Severity, t time.Time, defaults []string) []string {
+	if q := p.QuietHours; q != nil && severity != alerts.SeverityCritical && q.Contains(t) {
+		return q.Channels
+	}
+	if len(p.Channels) > 0 {
+		return p.Channels
+	}
+	return defaults
+}
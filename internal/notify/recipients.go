@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/auth"
+)
+
+// staffedTier is a tier with the users in it for one department
+type staffedTier struct {
+	Tier
+	members []*auth.User
+	// onDuty are the members on shift or on call
+	onDuty []*auth.User
+}
+
+// recipients are the tier's members on duty, or all of them if none are
+func (t staffedTier) recipients() []*auth.User {
+	if len(t.onDuty) > 0 {
+		return t.onDuty
+	}
+	return t.members
+}
+
+// tiers returns the escalation tiers for a department's alerts, leaving
+// out tiers nobody is in
+func (r *Router) tiers(ctx context.Context, dept string) ([]staffedTier, error) {
+	users, err := r.users.Users(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	onShift := r.onShift(ctx, r.now())
+
+	var tiers []staffedTier
+	for _, t := range r.config.Tiers {
+		staffed := staffedTier{Tier: t}
+		for _, u := range users {
+			if r.config.Store != "" && u.Store != "" && u.Store != r.config.Store {
+				continue
+			}
+			if !t.includes(u, dept) {
+				continue
+			}
+			staffed.members = append(staffed.members, u)
+			if (u.EmployeeID != "" && onShift[u.EmployeeID]) || r.onCall(u.ID, dept) {
+				staffed.onDuty = append(staffed.onDuty, u)
+			}
+		}
+		if len(staffed.members) > 0 {
+			tiers = append(tiers, staffed)
+		}
+	}
+	return tiers, nil
+}
+
+// onShift returns the employee IDs with a shift in progress at t
+func (r *Router) onShift(ctx context.Context, t time.Time) map[string]bool {
+	if r.schedules == nil {
+		return nil
+	}
+	shifts, err := r.schedules.Shifts(ctx, "", t, t.Add(time.Minute))
+	if err != nil {
+		log.Printf("Notify: failed to load shifts, treating nobody as on shift: %v", err)
+		return nil
+	}
+
+	onShift := make(map[string]bool, len(shifts))
+	for _, s := range shifts {
+		if !s.Start.After(t) && s.End.After(t) {
+			onShift[s.EmployeeID] = true
+		}
+	}
+	return onShift
+}
+
+// onCall reports whether the user is on call for dept
+func (r *Router) onCall(userID, dept string) bool {
+	return r.config.OnCall[dept] == userID || r.config.OnCall["*"] == userID
+}
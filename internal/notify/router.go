@@ -0,0 +1,347 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/auth"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+const (
+	// alertQueueSize is how many alert events can wait to be routed
+	alertQueueSize = 100
+	// checkInterval is how often active alerts are checked for
+	// escalation
+	checkInterval = 15 * time.Second
+	// notifyTimeout bounds one channel's attempt to reach a user
+	notifyTimeout = 30 * time.Second
+)
+
+// Config is the escalation policy and the defaults users' preferences
+// start from
+type Config struct {
+	// Tiers are escalated through in order. Tiers without anyone in them
+	// for an alert's department are skipped.
+	Tiers []Tier
+	// Channels is the order channels are tried in for users who haven't
+	// chosen their own; it defaults to the order channels are given to
+	// New
+	Channels []string
+	// MinSeverity is the least severe alert routed
+	MinSeverity alerts.Severity
+	// Store limits recipients to users of this store, if set
+	Store string
+	// OnCall maps departments to the user on call for them; "*" is on
+	// call for every department
+	OnCall map[string]string
+}
+
+// Router routes alerts to users and escalates them while nobody
+// acknowledges them
+type Router struct {
+	config    Config
+	channels  map[string]Channel
+	store     Store
+	users     auth.Directory
+	alerts    *alerts.Engine
+	schedules schedule.Repository
+	now       func() time.Time
+
+	alertEvents chan alerts.Event
+
+	mu sync.Mutex
+	// routed holds the open alerts already routed
+	routed map[string]bool
+}
+
+// New creates a router sending through channels to the users in users,
+// escalating alerts in alertEngine. schedules tells who's on shift and
+// may be nil.
+func New(cfg Config, store Store, users auth.Directory, alertEngine *alerts.Engine, schedules schedule.Repository, channels ...Channel) (*Router, error) {
+	if len(cfg.Tiers) == 0 {
+		return nil, errors.New("the escalation policy needs at least one tier")
+	}
+	for i, t := range cfg.Tiers {
+		if !ValidTier(t.Name) {
+			return nil, fmt.Errorf("unknown escalation tier %q", t.Name)
+		}
+		if i < len(cfg.Tiers)-1 && t.Wait <= 0 {
+			return nil, fmt.Errorf("escalation tier %s needs a wait before the next tier", t.Name)
+		}
+	}
+	if cfg.MinSeverity == "" {
+		cfg.MinSeverity = alerts.SeverityWarning
+	}
+	if !cfg.MinSeverity.Valid() {
+		return nil, fmt.Errorf("unknown severity %q", cfg.MinSeverity)
+	}
+
+	byName := make(map[string]Channel, len(channels))
+	var names []string
+	for _, c := range channels {
+		byName[c.Name()] = c
+		names = append(names, c.Name())
+	}
+	if len(cfg.Channels) == 0 {
+		cfg.Channels = names
+	}
+	for _, name := range cfg.Channels {
+		if byName[name] == nil {
+			return nil, fmt.Errorf("channel %q isn't enabled", name)
+		}
+	}
+
+	return &Router{
+		config:      cfg,
+		channels:    byName,
+		store:       store,
+		users:       users,
+		alerts:      alertEngine,
+		schedules:   schedules,
+		now:         time.Now,
+		alertEvents: make(chan alerts.Event, alertQueueSize),
+		routed:      make(map[string]bool),
+	}, nil
+}
+
+// PublishAlert queues an alert event to be routed. Pass it to
+// alerts.Engine.Subscribe.
+func (r *Router) PublishAlert(event alerts.Event) {
+	select {
+	case r.alertEvents <- event:
+	default:
+		log.Printf("Notify: alert queue is full, dropping %s for alert %s", event.Type, event.Alert.ID)
+	}
+}
+
+// Run routes alert events as they arrive and escalates overdue alerts
+// until ctx is cancelled
+func (r *Router) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-r.alertEvents:
+			r.route(ctx, &event.Alert)
+		case <-ticker.C:
+			r.escalate(ctx)
+		}
+	}
+}
+
+// Policy returns the escalation tiers, the default channel order and the
+// least severe alert routed
+func (r *Router) Policy() ([]Tier, []string, alerts.Severity) {
+	return slices.Clone(r.config.Tiers), slices.Clone(r.config.Channels), r.config.MinSeverity
+}
+
+// Channels returns the names of the channels users can choose from
+func (r *Router) Channels() []string {
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Preferences returns the user's preferences, or the defaults if they
+// haven't saved any
+func (r *Router) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	p, err := r.store.Preferences(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return &Preferences{UserID: userID}, nil
+	}
+	return p, err
+}
+
+// SavePreferences validates and saves a user's preferences
+func (r *Router) SavePreferences(ctx context.Context, p *Preferences) error {
+	if err := p.Validate(r.Channels()); err != nil {
+		return err
+	}
+	var channels []string
+	for _, name := range p.Channels {
+		if !slices.Contains(channels, name) {
+			channels = append(channels, name)
+		}
+	}
+	p.Channels = channels
+	p.UpdatedAt = r.now().UTC()
+	return r.store.SavePreferences(ctx, p)
+}
+
+// Records returns what was routed, newest first
+func (r *Router) Records(ctx context.Context, f RecordFilter) ([]Record, error) {
+	return r.store.Records(ctx, f)
+}
+
+// route notifies the current tier about an active alert the first time
+// it qualifies, and again when it escalates or wakes from a snooze
+func (r *Router) route(ctx context.Context, alert *alerts.Alert) {
+	if !alert.Status.Open() {
+		r.mu.Lock()
+		delete(r.routed, alert.ID)
+		r.mu.Unlock()
+		return
+	}
+	if alert.Status != alerts.StatusActive || !alert.Severity.AtLeast(r.config.MinSeverity) {
+		return
+	}
+
+	last := lastChange(alert)
+	r.mu.Lock()
+	routed := r.routed[alert.ID]
+	r.routed[alert.ID] = true
+	r.mu.Unlock()
+	if routed && last.Action != alerts.ActionEscalated && last.Action != alerts.ActionWoke {
+		return
+	}
+
+	tiers, err := r.tiers(ctx, alert.Department)
+	if err != nil {
+		log.Printf("Notify: failed to route alert %s: %v", alert.ID, err)
+		return
+	}
+	if len(tiers) == 0 {
+		log.Printf("Notify: nobody to route alert %s in %s to", alert.ID, alert.Department)
+		return
+	}
+
+	current := tiers[min(alert.EscalationLevel, len(tiers)-1)]
+	for _, user := range current.recipients() {
+		r.notify(ctx, user, &Notification{
+			Alert:     alert,
+			Tier:      current.Name,
+			Escalated: alert.EscalationLevel > 0,
+		})
+	}
+}
+
+// notify tries the user's channels in order until one reaches them, and
+// records the outcome
+func (r *Router) notify(ctx context.Context, user *auth.User, n *Notification) {
+	record := &Record{
+		AlertID:    n.Alert.ID,
+		Department: n.Alert.Department,
+		Severity:   n.Alert.Severity,
+		UserID:     user.ID,
+		Tier:       n.Tier,
+		Escalated:  n.Escalated,
+		Outcome:    OutcomeFailed,
+		At:         r.now().UTC(),
+	}
+
+	p, err := r.Preferences(ctx, user.ID)
+	if err != nil {
+		log.Printf("Notify: failed to load preferences for %s, using defaults: %v", user.ID, err)
+		p = &Preferences{UserID: user.ID}
+	}
+	names := p.channels(n.Alert.Severity, r.now(), r.config.Channels)
+	if len(names) == 0 {
+		record.Outcome = OutcomeHeld
+	}
+
+	var failures []string
+	for _, name := range names {
+		channel := r.channels[name]
+		if channel == nil {
+			continue
+		}
+		notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err := channel.Notify(notifyCtx, user, n)
+		cancel()
+		if err == nil {
+			record.Outcome, record.Channel = OutcomeDelivered, name
+			break
+		}
+		failures = append(failures, name+": "+err.Error())
+	}
+	record.Error = strings.Join(failures, "; ")
+
+	if record.Outcome == OutcomeFailed {
+		log.Printf("Notify: couldn't reach %s about alert %s: %s", user.ID, n.Alert.ID, record.Error)
+	}
+	if err := r.store.SaveRecord(ctx, record); err != nil {
+		log.Printf("Notify: failed to record notifying %s about alert %s: %v", user.ID, n.Alert.ID, err)
+	}
+}
+
+// escalate moves active alerts nobody acknowledged within their tier's
+// wait on to the next tier
+func (r *Router) escalate(ctx context.Context) {
+	active, err := r.alerts.List(ctx, alerts.Filter{
+		Statuses:    []alerts.Status{alerts.StatusActive},
+		MinSeverity: r.config.MinSeverity,
+	})
+	if err != nil {
+		log.Printf("Notify: failed to check alerts for escalation: %v", err)
+		return
+	}
+
+	now := r.now()
+	for i := range active {
+		alert := &active[i]
+		tiers, err := r.tiers(ctx, alert.Department)
+		if err != nil {
+			log.Printf("Notify: failed to check alert %s for escalation: %v", alert.ID, err)
+			continue
+		}
+		level := alert.EscalationLevel
+		if level >= len(tiers)-1 {
+			continue
+		}
+		current, next := tiers[level], tiers[level+1]
+		if now.Sub(tierStart(alert)) < current.Wait {
+			continue
+		}
+
+		note := fmt.Sprintf("Not acknowledged within %s, escalated to %s", current.Wait, tierLabels[next.Name])
+		if _, err := r.alerts.Advance(ctx, alert.ID, "", note); err != nil {
+			// Acknowledged, snoozed or resolved since it was listed
+			if !errors.Is(err, alerts.ErrInvalidTransition) {
+				log.Printf("Notify: failed to escalate alert %s: %v", alert.ID, err)
+			}
+			continue
+		}
+		log.Printf("Notify: escalated alert %s to %s", alert.ID, tierLabels[next.Name])
+	}
+}
+
+// tierLabels name tiers in escalation notes
+var tierLabels = map[string]string{
+	TierAssistantManager:  "assistant managers",
+	TierDepartmentManager: "department managers",
+	TierStoreManager:      "store managers",
+}
+
+// lastChange returns the latest entry in the alert's history
+func lastChange(a *alerts.Alert) alerts.Change {
+	if len(a.History) == 0 {
+		return alerts.Change{}
+	}
+	return a.History[len(a.History)-1]
+}
+
+// tierStart is when the alert last reached a tier: when it was raised,
+// escalated or woke from a snooze
+func tierStart(a *alerts.Alert) time.Time {
+	for i := len(a.History) - 1; i >= 0; i-- {
+		switch a.History[i].Action {
+		case alerts.ActionRaised, alerts.ActionEscalated, alerts.ActionWoke:
+			return a.History[i].At
+		}
+	}
+	return a.CreatedAt
+}
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS notify_preferences (
+	user_id        TEXT PRIMARY KEY,
+	channels       TEXT NOT NULL DEFAULT '',
+	quiet_start    TEXT NOT NULL DEFAULT '',
+	quiet_end      TEXT NOT NULL DEFAULT '',
+	quiet_channels TEXT NOT NULL DEFAULT '',
+	updated_at     INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notify_records (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	alert_id   TEXT NOT NULL,
+	department TEXT NOT NULL,
+	severity   TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	tier       TEXT NOT NULL,
+	escalated  INTEGER NOT NULL,
+	outcome    TEXT NOT NULL,
+	channel    TEXT NOT NULL DEFAULT '',
+	error      TEXT NOT NULL DEFAULT '',
+	at         INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notify_records_alert ON notify_records (alert_id);
+CREATE INDEX IF NOT EXISTS idx_notify_records_user ON notify_records (user_id);
+`
+
+// SQLiteStore persists preferences and records in a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if needed) the database at path and
+// ensures the schema exists
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	p := &Preferences{UserID: userID}
+	var channels, quietStart, quietEnd, quietChannels string
+	var updated int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT channels, quiet_start, quiet_end, quiet_channels, updated_at FROM notify_preferences WHERE user_id = ?`, userID).
+		Scan(&channels, &quietStart, &quietEnd, &quietChannels, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load preferences: %w", err)
+	}
+
+	p.Channels = splitNames(channels)
+	if quietStart != "" {
+		p.QuietHours = &QuietHours{Start: quietStart, End: quietEnd, Channels: splitNames(quietChannels)}
+	}
+	p.UpdatedAt = time.UnixMilli(updated).UTC()
+	return p, nil
+}
+
+func (s *SQLiteStore) SavePreferences(ctx context.Context, p *Preferences) error {
+	var quiet QuietHours
+	if p.QuietHours != nil {
+		quiet = *p.QuietHours
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO notify_preferences (user_id, channels, quiet_start, quiet_end, quiet_channels, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id) DO UPDATE SET channels = excluded.channels, quiet_start = excluded.quiet_start,
+		 quiet_end = excluded.quiet_end, quiet_channels = excluded.quiet_channels, updated_at = excluded.updated_at`,
+		p.UserID, strings.Join(p.Channels, ","), quiet.Start, quiet.End, strings.Join(quiet.Channels, ","), p.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
+
+func splitNames(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func (s *SQLiteStore) Records(ctx context.Context, f RecordFilter) ([]Record, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	query := `SELECT id, alert_id, department, severity, user_id, tier, escalated, outcome, channel, error, at
+		FROM notify_records WHERE 1 = 1`
+	var args []interface{}
+	if f.AlertID != "" {
+		query += ` AND alert_id = ?`
+		args = append(args, f.AlertID)
+	}
+	if f.UserID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, f.UserID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		var id, at int64
+		if err := rows.Scan(&id, &r.AlertID, &r.Department, &r.Severity, &r.UserID, &r.Tier, &r.Escalated, &r.Outcome, &r.Channel, &r.Error, &at); err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		r.ID = strconv.FormatInt(id, 10)
+		r.At = time.UnixMilli(at).UTC()
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return records, nil
+}
+
+func (s *SQLiteStore) SaveRecord(ctx context.Context, r *Record) error {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO notify_records (alert_id, department, severity, user_id, tier, escalated, outcome, channel, error, at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.AlertID, r.Department, string(r.Severity), r.UserID, r.Tier, r.Escalated, string(r.Outcome), r.Channel, r.Error, r.At.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save record: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to save record: %w", err)
+	}
+	r.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
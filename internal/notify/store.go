@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/alerts"
+)
+
+// ErrNotFound is returned when a user has no saved preferences
+var ErrNotFound = errors.New("preferences not found")
+
+// Outcome is what came of routing an alert to a user
+type Outcome string
+
+const (
+	OutcomeDelivered Outcome = "delivered"
+	// OutcomeHeld means quiet hours left no channel to try
+	OutcomeHeld Outcome = "held"
+	// OutcomeFailed means no channel reached the user
+	OutcomeFailed Outcome = "failed"
+)
+
+// Record is an alert routed to one user
+type Record struct {
+	ID         string          `json:"id"`
+	AlertID    string          `json:"alertId"`
+	Department string          `json:"department"`
+	Severity   alerts.Severity `json:"severity"`
+	UserID     string          `json:"userId"`
+	Tier       string          `json:"tier"`
+	Escalated  bool            `json:"escalated,omitempty"`
+	Outcome    Outcome         `json:"outcome"`
+	// Channel is the channel that delivered it
+	Channel string `json:"channel,omitempty"`
+	// Error lists why the channels tried before it failed
+	Error string    `json:"error,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// RecordFilter selects records. Zero values mean no filter.
+type RecordFilter struct {
+	AlertID string
+	UserID  string
+	// Limit defaults to 50
+	Limit int
+}
+
+func (f RecordFilter) matches(r *Record) bool {
+	return (f.AlertID == "" || r.AlertID == f.AlertID) && (f.UserID == "" || r.UserID == f.UserID)
+}
+
+// Store keeps preferences and a record of what was routed
+type Store interface {
+	// Preferences returns the user's saved preferences, or ErrNotFound
+	Preferences(ctx context.Context, userID string) (*Preferences, error)
+	// SavePreferences inserts or replaces the user's preferences
+	SavePreferences(ctx context.Context, p *Preferences) error
+	// Records returns the records matching f, newest first
+	Records(ctx context.Context, f RecordFilter) ([]Record, error)
+	// SaveRecord inserts a record, setting its ID
+	SaveRecord(ctx context.Context, r *Record) error
+	Close() error
+}
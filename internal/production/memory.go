@@ -0,0 +1,77 @@
+package production
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryRepository keeps production plans and shrink in memory
+type MemoryRepository struct {
+	plans  map[string]Plan
+	shrink map[string]Shrink
+	mu     sync.RWMutex
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		plans:  make(map[string]Plan),
+		shrink: make(map[string]Shrink),
+	}
+}
+
+// Plans returns the department's plans ordered by date and SKU
+func (m *MemoryRepository) Plans(ctx context.Context, dept, from, to string) ([]Plan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var plans []Plan
+	for _, p := range m.plans {
+		if (dept == "" || p.Department == dept) && p.Date >= from && p.Date <= to {
+			plans = append(plans, p)
+		}
+	}
+	slices.SortFunc(plans, func(a, b Plan) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.SKU, b.SKU))
+	})
+	return plans, nil
+}
+
+// SavePlans inserts or replaces plans by date and SKU
+func (m *MemoryRepository) SavePlans(ctx context.Context, plans ...Plan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range plans {
+		m.plans[p.Key()] = p
+	}
+	return nil
+}
+
+// Shrink returns the department's shrink ordered by date and ID
+func (m *MemoryRepository) Shrink(ctx context.Context, dept, from, to string) ([]Shrink, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []Shrink
+	for _, s := range m.shrink {
+		if (dept == "" || s.Department == dept) && s.Date >= from && s.Date <= to {
+			entries = append(entries, s)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Shrink) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.ID, b.ID))
+	})
+	return entries, nil
+}
+
+// SaveShrink inserts or replaces shrink entries by ID
+func (m *MemoryRepository) SaveShrink(ctx context.Context, entries ...Shrink) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range entries {
+		m.shrink[s.ID] = s
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package production
+
+import (
+	"context"
+	"time"
+)
+
+// Plan is how much of an item a department plans to make on a day, e.g.
+// rotisserie chickens in the deli or baguettes in the bakery
+type Plan struct {
+	// Date is YYYY-MM-DD
+	Date        string  `json:"date"`
+	SKU         string  `json:"sku"`
+	Description string  `json:"description"`
+	Department  string  `json:"department"`
+	Planned     float64 `json:"planned"`
+	// Produced is how much has been made so far, when reported
+	Produced  float64   `json:"produced"`
+	Unit      string    `json:"unit"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Key identifies a plan: one per item per day
+func (p *Plan) Key() string {
+	return p.Date + "/" + p.SKU
+}
+
+// Shrink is product lost to spoilage, damage, theft or markdowns
+type Shrink struct {
+	ID         string  `json:"id"`
+	Date       string  `json:"date"`
+	SKU        string  `json:"sku"`
+	Department string  `json:"department"`
+	Quantity   float64 `json:"quantity"`
+	// Reason is free text from the recording system, e.g. "Expired"
+	Reason string `json:"reason,omitempty"`
+	// Cost is the product cost written off
+	Cost      float64   `json:"cost"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Repository stores production plans and shrink
+type Repository interface {
+	// Plans returns the department's plans dated from..to inclusive
+	// (YYYY-MM-DD), every department's when dept is empty
+	Plans(ctx context.Context, dept, from, to string) ([]Plan, error)
+	// SavePlans inserts or replaces plans by date and SKU
+	SavePlans(ctx context.Context, plans ...Plan) error
+	// Shrink returns the department's shrink dated from..to inclusive
+	Shrink(ctx context.Context, dept, from, to string) ([]Shrink, error)
+	// SaveShrink inserts or replaces shrink entries by ID
+	SaveShrink(ctx context.Context, entries ...Shrink) error
+}
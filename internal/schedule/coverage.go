@@ -0,0 +1,188 @@
+package schedule
+
+import (
+	"context"
+	"time"
+)
+
+// minCoverage is how long someone must be on the floor during an hour to
+// count towards its coverage
+const minCoverage = 30 * time.Minute
+
+// HourCoverage compares the people on the floor during one hour with a
+// coverage target
+type HourCoverage struct {
+	Department string    `json:"department"`
+	Start      time.Time `json:"start"`
+	Role       string    `json:"role,omitempty"`
+	Required   int       `json:"required"`
+	Scheduled  int       `json:"scheduled"`
+	// Short is how many more people are needed, zero when covered
+	Short     int      `json:"short"`
+	Employees []string `json:"employees"`
+}
+
+// Period is a department's schedule between two times with its coverage
+type Period struct {
+	Department string     `json:"department,omitempty"`
+	Start      time.Time  `json:"start"`
+	End        time.Time  `json:"end"`
+	Employees  []Employee `json:"employees"`
+	Shifts     []Shift    `json:"shifts"`
+	TimeOff    []TimeOff  `json:"timeOff"`
+	// Punches are when people actually clocked in and out
+	Punches  []Punch        `json:"punches"`
+	Coverage []HourCoverage `json:"coverage"`
+	// Gaps are the hours in Coverage that are short-staffed
+	Gaps []HourCoverage `json:"gaps"`
+}
+
+// WeekStart returns midnight on the Monday of day's week
+func WeekStart(day time.Time) time.Time {
+	y, m, d := day.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, day.Location())
+	offset := (int(midnight.Weekday()) + 6) % 7
+	return midnight.AddDate(0, 0, -offset)
+}
+
+// Load returns dept's schedule and coverage for [from, to). An empty dept
+// loads every department.
+func Load(ctx context.Context, repo Repository, dept string, from, to time.Time) (*Period, error) {
+	employees, err := repo.Employees(ctx, dept)
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := repo.Shifts(ctx, dept, from, to)
+	if err != nil {
+		return nil, err
+	}
+	timeOff, err := repo.TimeOff(ctx, dept, from, to)
+	if err != nil {
+		return nil, err
+	}
+	punches, err := repo.Punches(ctx, dept, from, to)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := repo.Targets(ctx, dept)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Period{
+		Department: dept,
+		Start:      from,
+		End:        to,
+		Employees:  nonNil(employees),
+		Shifts:     nonNil(shifts),
+		TimeOff:    nonNil(timeOff),
+		Punches:    nonNil(punches),
+		Coverage:   Coverage(targets, shifts, timeOff, from, to),
+		Gaps:       []HourCoverage{},
+	}
+	for _, hour := range p.Coverage {
+		if hour.Short > 0 {
+			p.Gaps = append(p.Gaps, hour)
+		}
+	}
+	return p, nil
+}
+
+// Coverage counts the people on the floor for every target hour in
+// [from, to). Someone counts once for an hour when their shifts have them
+// working, not on break, for at least half of it, and they have no
+// approved time off then.
+func Coverage(targets []CoverageTarget, shifts []Shift, timeOff []TimeOff, from, to time.Time) []HourCoverage {
+	coverage := []HourCoverage{}
+
+	for hour := from.Truncate(time.Hour); hour.Before(to); hour = hour.Add(time.Hour) {
+		if hour.Before(from) {
+			continue
+		}
+		end := hour.Add(time.Hour)
+
+		for _, target := range targets {
+			if target.Weekday != hour.Weekday() || hour.Hour() < target.StartHour || hour.Hour() >= target.EndHour {
+				continue
+			}
+
+			hc := HourCoverage{
+				Department: target.Department,
+				Start:      hour,
+				Role:       target.Role,
+				Required:   target.Required,
+				Employees:  []string{},
+			}
+			// An employee with several shifts in the hour, split or booked
+			// twice, counts once, with the time of all of them
+			worked := make(map[string]time.Duration)
+			var order []Shift
+			for _, shift := range shifts {
+				if shift.Department != target.Department || (target.Role != "" && shift.Role != target.Role) {
+					continue
+				}
+				d := onFloor(shift, hour, end)
+				if d <= 0 {
+					continue
+				}
+				if _, ok := worked[shift.EmployeeID]; !ok {
+					order = append(order, shift)
+				}
+				worked[shift.EmployeeID] += d
+			}
+			for _, shift := range order {
+				if worked[shift.EmployeeID] < minCoverage || onLeave(timeOff, shift.EmployeeID, hour, end) {
+					continue
+				}
+				hc.Scheduled++
+				hc.Employees = append(hc.Employees, shift.EmployeeName)
+			}
+			hc.Short = max(hc.Required-hc.Scheduled, 0)
+			coverage = append(coverage, hc)
+		}
+	}
+	return coverage
+}
+
+// onFloor is how long shift has its employee working in [from, to)
+func onFloor(shift Shift, from, to time.Time) time.Duration {
+	worked := overlap(shift.Start, shift.End, from, to)
+	for _, b := range shift.Breaks {
+		worked -= overlap(b.Start, b.End, from, to)
+	}
+	return worked
+}
+
+// onLeave reports whether the employee has approved time off overlapping
+// [from, to)
+func onLeave(timeOff []TimeOff, employeeID string, from, to time.Time) bool {
+	for _, t := range timeOff {
+		if t.EmployeeID == employeeID && t.Status == TimeOffApproved && overlap(t.Start, t.End, from, to) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// overlap is how much of [aStart, aEnd) falls in [bStart, bEnd)
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start, end := aStart, aEnd
+	if bStart.After(start) {
+		start = bStart
+	}
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// nonNil returns an empty slice for nil so it encodes as [] in JSON
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
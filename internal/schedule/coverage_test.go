@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCoverageCountsEachEmployeeOnce(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return monday.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	shift := func(id, employee string, start, end time.Time) Shift {
+		return Shift{ID: id, EmployeeID: employee, EmployeeName: employee, Department: "dairy", Role: "Clerk", Start: start, End: end}
+	}
+
+	targets := []CoverageTarget{{Department: "dairy", Weekday: time.Monday, StartHour: 8, EndHour: 10, Required: 2}}
+	shifts := []Shift{
+		// Overlapping shifts from a double-booked import
+		shift("s1", "ann", at(8, 0), at(9, 0)),
+		shift("s2", "ann", at(8, 0), at(9, 0)),
+		// A split shift: 20 and 20 minutes add up to more than half the hour
+		shift("s3", "bob", at(9, 0), at(9, 20)),
+		shift("s4", "bob", at(9, 40), at(10, 0)),
+	}
+
+	coverage := Coverage(targets, shifts, nil, at(8, 0), at(10, 0))
+	if len(coverage) != 2 {
+		t.Fatalf("got %d hours, want 2", len(coverage))
+	}
+
+	eight, nine := coverage[0], coverage[1]
+	if eight.Scheduled != 1 || eight.Short != 1 || !slices.Equal(eight.Employees, []string{"ann"}) {
+		t.Errorf("8:00 = %d scheduled %v, short %d; want ann once, short 1", eight.Scheduled, eight.Employees, eight.Short)
+	}
+	if nine.Scheduled != 1 || !slices.Equal(nine.Employees, []string{"bob"}) {
+		t.Errorf("9:00 = %d scheduled %v; want bob once", nine.Scheduled, nine.Employees)
+	}
+}
+
+func TestCoverageSkipsShortStintsAndLeave(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	hour := monday.Add(8 * time.Hour)
+
+	targets := []CoverageTarget{{Department: "dairy", Weekday: time.Monday, StartHour: 8, EndHour: 9, Required: 1}}
+	shifts := []Shift{
+		{EmployeeID: "ann", EmployeeName: "ann", Department: "dairy", Start: hour, End: hour.Add(20 * time.Minute)},
+		{EmployeeID: "bob", EmployeeName: "bob", Department: "dairy", Start: hour, End: hour.Add(time.Hour)},
+		{EmployeeID: "cat", EmployeeName: "cat", Department: "dairy", Start: hour, End: hour.Add(time.Hour),
+			Breaks: []Break{{Start: hour.Add(15 * time.Minute), End: hour.Add(50 * time.Minute)}}},
+	}
+	timeOff := []TimeOff{{EmployeeID: "bob", Status: TimeOffApproved, Start: hour, End: hour.Add(time.Hour)}}
+
+	coverage := Coverage(targets, shifts, timeOff, hour, hour.Add(time.Hour))
+	if len(coverage) != 1 || coverage[0].Scheduled != 0 || coverage[0].Short != 1 {
+		t.Errorf("got %+v, want nobody counted", coverage)
+	}
+}
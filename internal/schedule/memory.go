@@ -0,0 +1,230 @@
+package schedule
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps schedules in memory
+type MemoryRepository struct {
+	employees map[string]Employee
+	shifts    map[string]Shift
+	timeOff   map[string]TimeOff
+	punches   map[string]Punch
+	targets   map[string][]CoverageTarget
+	mu        sync.RWMutex
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		employees: make(map[string]Employee),
+		shifts:    make(map[string]Shift),
+		timeOff:   make(map[string]TimeOff),
+		punches:   make(map[string]Punch),
+		targets:   make(map[string][]CoverageTarget),
+	}
+}
+
+// Employees returns the department's employees ordered by name
+func (m *MemoryRepository) Employees(ctx context.Context, dept string) ([]Employee, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var employees []Employee
+	for _, e := range m.employees {
+		if dept == "" || e.Department == dept {
+			e.Roles = slices.Clone(e.Roles)
+			employees = append(employees, e)
+		}
+	}
+	slices.SortFunc(employees, func(a, b Employee) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return employees, nil
+}
+
+// Shifts returns the department's shifts overlapping [from, to)
+func (m *MemoryRepository) Shifts(ctx context.Context, dept string, from, to time.Time) ([]Shift, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var shifts []Shift
+	for _, s := range m.shifts {
+		if (dept == "" || s.Department == dept) && overlap(s.Start, s.End, from, to) > 0 {
+			s.Breaks = slices.Clone(s.Breaks)
+			shifts = append(shifts, s)
+		}
+	}
+	slices.SortFunc(shifts, func(a, b Shift) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.EmployeeName, b.EmployeeName))
+	})
+	return shifts, nil
+}
+
+// TimeOff returns the department's time-off requests overlapping [from, to)
+func (m *MemoryRepository) TimeOff(ctx context.Context, dept string, from, to time.Time) ([]TimeOff, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var requests []TimeOff
+	for _, t := range m.timeOff {
+		if (dept == "" || t.Department == dept) && overlap(t.Start, t.End, from, to) > 0 {
+			requests = append(requests, t)
+		}
+	}
+	slices.SortFunc(requests, func(a, b TimeOff) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.ID, b.ID))
+	})
+	return requests, nil
+}
+
+// Punches returns the department's punches in [from, to)
+func (m *MemoryRepository) Punches(ctx context.Context, dept string, from, to time.Time) ([]Punch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var punches []Punch
+	for _, p := range m.punches {
+		if (dept == "" || p.Department == dept) && !p.At.Before(from) && p.At.Before(to) {
+			punches = append(punches, p)
+		}
+	}
+	slices.SortFunc(punches, func(a, b Punch) int {
+		return cmp.Or(a.At.Compare(b.At), cmp.Compare(a.ID, b.ID))
+	})
+	return punches, nil
+}
+
+// Targets returns the department's coverage targets
+func (m *MemoryRepository) Targets(ctx context.Context, dept string) ([]CoverageTarget, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if dept != "" {
+		return slices.Clone(m.targets[dept]), nil
+	}
+
+	var targets []CoverageTarget
+	for _, t := range m.targets {
+		targets = append(targets, t...)
+	}
+	slices.SortFunc(targets, func(a, b CoverageTarget) int {
+		return cmp.Compare(a.Department, b.Department)
+	})
+	return targets, nil
+}
+
+// SaveEmployees inserts or replaces employees
+func (m *MemoryRepository) SaveEmployees(ctx context.Context, employees ...Employee) error {
+	for _, e := range employees {
+		if e.ID == "" || e.Department == "" {
+			return errors.New("employees need an ID and a department")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range employees {
+		e.Roles = slices.Clone(e.Roles)
+		m.employees[e.ID] = e
+	}
+	return nil
+}
+
+// SaveShifts inserts or replaces shifts
+func (m *MemoryRepository) SaveShifts(ctx context.Context, shifts ...Shift) error {
+	for _, s := range shifts {
+		if s.ID == "" || s.Department == "" {
+			return errors.New("shifts need an ID and a department")
+		}
+		if !s.End.After(s.Start) {
+			return errors.New("shifts must end after they start")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range shifts {
+		s.Breaks = slices.Clone(s.Breaks)
+		m.shifts[s.ID] = s
+	}
+	return nil
+}
+
+// SaveTimeOff inserts or replaces time-off requests
+func (m *MemoryRepository) SaveTimeOff(ctx context.Context, requests ...TimeOff) error {
+	for _, t := range requests {
+		if t.ID == "" || t.Department == "" {
+			return errors.New("time-off requests need an ID and a department")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range requests {
+		m.timeOff[t.ID] = t
+	}
+	return nil
+}
+
+// SavePunches inserts or replaces punches
+func (m *MemoryRepository) SavePunches(ctx context.Context, punches ...Punch) error {
+	for _, p := range punches {
+		if p.ID == "" || p.Department == "" {
+			return errors.New("punches need an ID and a department")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range punches {
+		m.punches[p.ID] = p
+	}
+	return nil
+}
+
+// DeleteShifts removes shifts by ID
+func (m *MemoryRepository) DeleteShifts(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.shifts, id)
+	}
+	return nil
+}
+
+// DeleteEmployees removes employees by ID
+func (m *MemoryRepository) DeleteEmployees(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.employees, id)
+	}
+	return nil
+}
+
+// SaveTargets replaces the coverage targets of every department in targets
+func (m *MemoryRepository) SaveTargets(ctx context.Context, targets ...CoverageTarget) error {
+	byDept := make(map[string][]CoverageTarget)
+	for _, t := range targets {
+		if t.Department == "" {
+			return errors.New("coverage targets need a department")
+		}
+		if t.StartHour < 0 || t.EndHour > 24 || t.StartHour >= t.EndHour {
+			return errors.New("coverage target hours must be within 0-24 and end after they start")
+		}
+		byDept[t.Department] = append(byDept[t.Department], t)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dept, t := range byDept {
+		m.targets[dept] = t
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"context"
+	"time"
+)
+
+// Employee is someone who can be scheduled
+type Employee struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Department string `json:"department"`
+	// Roles are the positions the employee is trained to work, e.g.
+	// "Cashier" or "Meat Cutter"
+	Roles []string `json:"roles"`
+}
+
+// Break is time off the floor during a shift
+type Break struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Paid  bool      `json:"paid"`
+}
+
+// Shift is a block of time an employee is scheduled to work in a role
+type Shift struct {
+	ID           string    `json:"id"`
+	EmployeeID   string    `json:"employeeId"`
+	EmployeeName string    `json:"employee"`
+	Department   string    `json:"department"`
+	Role         string    `json:"role"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Breaks       []Break   `json:"breaks,omitempty"`
+}
+
+// TimeOffStatus is where a time-off request is in its approval
+type TimeOffStatus string
+
+const (
+	TimeOffPending  TimeOffStatus = "pending"
+	TimeOffApproved TimeOffStatus = "approved"
+	TimeOffDenied   TimeOffStatus = "denied"
+	// TimeOffCancelled is a request the employee withdrew
+	TimeOffCancelled TimeOffStatus = "cancelled"
+)
+
+// TimeOff is a request to be away. Approved time off removes the employee
+// from coverage even if a shift is still scheduled.
+type TimeOff struct {
+	ID           string        `json:"id"`
+	EmployeeID   string        `json:"employeeId"`
+	EmployeeName string        `json:"employee"`
+	Department   string        `json:"department"`
+	Start        time.Time     `json:"start"`
+	End          time.Time     `json:"end"`
+	Status       TimeOffStatus `json:"status"`
+	Reason       string        `json:"reason,omitempty"`
+}
+
+// PunchType is what a time clock punch records
+type PunchType string
+
+const (
+	PunchIn         PunchType = "in"
+	PunchOut        PunchType = "out"
+	PunchBreakStart PunchType = "break_start"
+	PunchBreakEnd   PunchType = "break_end"
+)
+
+// Punch is a time clock entry showing when someone actually worked
+type Punch struct {
+	ID           string    `json:"id"`
+	EmployeeID   string    `json:"employeeId"`
+	EmployeeName string    `json:"employee"`
+	Department   string    `json:"department"`
+	Type         PunchType `json:"type"`
+	At           time.Time `json:"at"`
+}
+
+// CoverageTarget is how many people a department needs on the floor
+// during the hours [StartHour, EndHour) of a weekday. An empty Role counts
+// everyone in the department.
+type CoverageTarget struct {
+	Department string       `json:"department"`
+	Role       string       `json:"role,omitempty"`
+	Weekday    time.Weekday `json:"weekday"`
+	StartHour  int          `json:"startHour"`
+	EndHour    int          `json:"endHour"`
+	Required   int          `json:"required"`
+}
+
+// Repository stores schedules. An empty department means every
+// department.
+type Repository interface {
+	Employees(ctx context.Context, dept string) ([]Employee, error)
+	// Shifts returns the shifts overlapping [from, to), ordered by start
+	Shifts(ctx context.Context, dept string, from, to time.Time) ([]Shift, error)
+	// TimeOff returns the requests overlapping [from, to), ordered by start
+	TimeOff(ctx context.Context, dept string, from, to time.Time) ([]TimeOff, error)
+	// Punches returns the punches in [from, to), ordered by time
+	Punches(ctx context.Context, dept string, from, to time.Time) ([]Punch, error)
+	Targets(ctx context.Context, dept string) ([]CoverageTarget, error)
+
+	// Save methods insert or replace records by ID; targets replace all
+	// targets of their departments
+	SaveEmployees(ctx context.Context, employees ...Employee) error
+	SaveShifts(ctx context.Context, shifts ...Shift) error
+	SaveTimeOff(ctx context.Context, requests ...TimeOff) error
+	SavePunches(ctx context.Context, punches ...Punch) error
+	SaveTargets(ctx context.Context, targets ...CoverageTarget) error
+	// DeleteShifts and DeleteEmployees remove records by ID, ignoring
+	// unknown IDs
+	DeleteShifts(ctx context.Context, ids ...string) error
+	DeleteEmployees(ctx context.Context, ids ...string) error
+}
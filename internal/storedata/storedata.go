@@ -0,0 +1,86 @@
+package storedata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dokk-dev/opus/internal/ai"
+	"github.com/dokk-dev/opus/internal/alerts"
+	"github.com/dokk-dev/opus/internal/checkout"
+	"github.com/dokk-dev/opus/internal/inventory"
+	"github.com/dokk-dev/opus/internal/schedule"
+)
+
+// Data answers agent tool lookups from the store's subsystems. It
+// implements ai.StoreData.
+type Data struct {
+	inventory inventory.Repository
+	schedules schedule.Repository
+	alerts    alerts.Repository
+	checkout  checkout.Repository
+}
+
+// New creates the store data agents look up
+func New(inv inventory.Repository, schedules schedule.Repository, alertRepo alerts.Repository, lanes checkout.Repository) *Data {
+	return &Data{
+		inventory: inv,
+		schedules: schedules,
+		alerts:    alertRepo,
+		checkout:  lanes,
+	}
+}
+
+// Inventory returns the department's items, or the one matching sku
+func (d *Data) Inventory(ctx context.Context, dept ai.Department, sku string) (interface{}, error) {
+	if sku != "" {
+		item, err := d.inventory.Get(ctx, sku)
+		if errors.Is(err, inventory.ErrNotFound) || (err == nil && item.Department != string(dept)) {
+			return nil, fmt.Errorf("no item with SKU %s in %s", sku, dept)
+		}
+		return item, err
+	}
+
+	page, err := d.inventory.List(ctx, inventory.Query{
+		Department: string(dept),
+		Limit:      inventory.MaxLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// Schedule returns the department's shifts, time off and hourly coverage
+// on date
+func (d *Data) Schedule(ctx context.Context, dept ai.Department, date time.Time) (interface{}, error) {
+	y, m, day := date.Date()
+	start := time.Date(y, m, day, 0, 0, 0, 0, date.Location())
+	return schedule.Load(ctx, d.schedules, string(dept), start, start.AddDate(0, 0, 1))
+}
+
+// Alerts returns the department's open alerts
+func (d *Data) Alerts(ctx context.Context, dept ai.Department) (interface{}, error) {
+	return d.alerts.List(ctx, alerts.Filter{Department: string(dept)})
+}
+
+// Lanes returns lane status with today's sales by hour and top items
+func (d *Data) Lanes(ctx context.Context, dept ai.Department) (interface{}, error) {
+	if dept != checkout.Department {
+		return nil, fmt.Errorf("%s has no checkout lanes", dept)
+	}
+
+	lanes, err := d.checkout.Lanes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	today, err := checkout.LoadDay(ctx, d.checkout, time.Now(), 10)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"lanes": lanes,
+		"today": today,
+	}, nil
+}
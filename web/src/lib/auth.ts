@@ -0,0 +1,58 @@
+import { goto } from '$app/navigation';
+
+export interface User {
+	id: string;
+	name: string;
+	role: 'manager' | 'assistant_manager' | 'admin';
+	store?: string;
+	department?: string;
+}
+
+interface Session {
+	token: string;
+	expiresAt: number;
+	user: User;
+}
+
+const storageKey = 'opus.session';
+
+export function getSession(): Session | null {
+	const raw = localStorage.getItem(storageKey);
+	if (!raw) return null;
+	const session: Session = JSON.parse(raw);
+	if (session.expiresAt * 1000 <= Date.now()) {
+		localStorage.removeItem(storageKey);
+		return null;
+	}
+	return session;
+}
+
+export async function login(username: string, password: string): Promise<User> {
+	const response = await fetch('/api/v1/auth/login', {
+		method: 'POST',
+		headers: { 'Content-Type': 'application/json' },
+		body: JSON.stringify({ username, password })
+	});
+	const data = await response.json().catch(() => ({ error: 'Sign-in failed' }));
+	if (!response.ok) throw new Error(data.error || 'Sign-in failed');
+
+	localStorage.setItem(storageKey, JSON.stringify(data));
+	return data.user;
+}
+
+export function logout() {
+	localStorage.removeItem(storageKey);
+	goto('/login');
+}
+
+// authFetch is fetch with the signed-in user's token. A 401 means the
+// token is missing or expired, so the user is sent to sign in again.
+export async function authFetch(input: string, init: RequestInit = {}): Promise<Response> {
+	const headers = new Headers(init.headers);
+	const session = getSession();
+	if (session) headers.set('Authorization', `Bearer ${session.token}`);
+
+	const response = await fetch(input, { ...init, headers });
+	if (response.status === 401) logout();
+	return response;
+}